@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/kuluruvineeth/social-go/internal/store"
+)
+
+type AccountDeletionResponse struct {
+	// DeletionScheduledAt is when the account will be deleted.
+	DeletionScheduledAt string `json:"deletion_scheduled_at"`
+}
+
+// deleteAccountHandler godoc
+//
+//	@Summary		Deletes the user's account
+//	@Description	Schedules the deletion of the authenticated user's account once the grace period is over, until then it can be restored. Their posts, follows, reactions and everything else tied to the account are deleted with it, and their comments on other users' posts are kept without an author.
+//	@Tags			users
+//	@Produce		json
+//	@Success		202	{object}	AccountDeletionResponse
+//	@Failure		401	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Security		ApiKeyAuth
+//	@Router			/users/me [delete]
+func (app *application) deleteAccountHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	ctx := r.Context()
+
+	scheduledAt, err := app.store.Users.ScheduleDeletion(ctx, user.ID, app.config.users.deletionGracePeriod)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.evictUser(ctx, user.ID)
+
+	if err := app.jsonResponse(w, http.StatusAccepted, AccountDeletionResponse{DeletionScheduledAt: scheduledAt}); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// restoreAccountHandler godoc
+//
+//	@Summary		Restores the user's account
+//	@Description	Cancels the scheduled deletion of the authenticated user's account
+//	@Tags			users
+//	@Success		204	"No Content"
+//	@Failure		401	{object}	Problem
+//	@Failure		404	{object}	Problem	"No deletion is scheduled"
+//	@Failure		500	{object}	Problem
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/restore [post]
+func (app *application) restoreAccountHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	ctx := r.Context()
+
+	if err := app.store.Users.CancelDeletion(ctx, user.ID); err != nil {
+		app.errorResponse(w, r, err)
+		return
+	}
+
+	app.evictUser(ctx, user.ID)
+
+	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// exportAccountHandler godoc
+//
+//	@Summary		Exports the user's data
+//	@Description	Downloads an archive of the authenticated user's profile, posts, comments, follows, reactions, bookmarks and reposts. Archives are built in the background: until one is ready the export is queued and its status returned, and polling again downloads it once it is.
+//	@Tags			users
+//	@Produce		json
+//	@Success		200	{file}		file			"The archive"
+//	@Success		202	{object}	store.Export	"The export in progress"
+//	@Failure		401	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/export [get]
+func (app *application) exportAccountHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	ctx := r.Context()
+
+	export, err := app.store.Exports.Request(ctx, user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if export.Status != store.ExportStatusReady {
+		if err := app.jsonResponse(w, http.StatusAccepted, export); err != nil {
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	obj, err := app.blobs.Get(ctx, export.Key)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	defer obj.Close()
+
+	w.Header().Set("Content-Type", obj.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(obj.Size, 10))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="social-go-export-%d.json"`, export.ID))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, obj); err != nil {
+		app.logger.Warnw("failed to send export", "id", export.ID, "error", err)
+	}
+}
+
+// accountDeleted is called by the deletion worker for each account it
+// deletes.
+func (app *application) accountDeleted(ctx context.Context, id int64) {
+	app.evictUser(ctx, id)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kuluruvineeth/social-go/internal/store"
+)
+
+func TestAccountDeletion(t *testing.T) {
+	app := newTestApplication(t, config{users: usersConfig{deletionGracePeriod: 24 * time.Hour}})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users := app.store.Users.(*store.MockUserStore)
+
+	request := func(t *testing.T, method, url string) *http.Request {
+		t.Helper()
+
+		req, err := http.NewRequest(method, url, http.NoBody)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		return req
+	}
+
+	t.Run("should schedule the deletion after the grace period", func(t *testing.T) {
+		users.On("ScheduleDeletion", int64(1), 24*time.Hour).Return("2026-10-20T12:00:00Z", nil).Once()
+
+		rr := executeRequest(request(t, http.MethodDelete, "/v1/users/me"), mux)
+		checkResponseCode(t, http.StatusAccepted, rr.Code)
+		users.AssertExpectations(t)
+
+		var res struct {
+			Data AccountDeletionResponse `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&res); err != nil {
+			t.Fatal(err)
+		}
+		if res.Data.DeletionScheduledAt != "2026-10-20T12:00:00Z" {
+			t.Errorf("unexpected deletion date %q", res.Data.DeletionScheduledAt)
+		}
+	})
+
+	t.Run("should restore the account", func(t *testing.T) {
+		users.On("CancelDeletion", int64(1)).Return(nil).Once()
+
+		rr := executeRequest(request(t, http.MethodPost, "/v1/users/me/restore"), mux)
+		checkResponseCode(t, http.StatusNoContent, rr.Code)
+		users.AssertExpectations(t)
+	})
+
+	t.Run("should not restore an account without a scheduled deletion", func(t *testing.T) {
+		users.On("CancelDeletion", int64(1)).Return(store.ErrNotFound).Once()
+
+		rr := executeRequest(request(t, http.MethodPost, "/v1/users/me/restore"), mux)
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+		users.AssertExpectations(t)
+	})
+}
+
+func TestAccountExport(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exports := app.store.Exports.(*store.MockExportStore)
+
+	request := func(t *testing.T, url string) *http.Request {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		return req
+	}
+
+	t.Run("should queue the export until it's ready", func(t *testing.T) {
+		exports.On("Request", int64(1)).Return(&store.Export{ID: 1, UserID: 1, Status: store.ExportStatusPending}, nil).Once()
+
+		rr := executeRequest(request(t, "/v1/users/me/export"), mux)
+		checkResponseCode(t, http.StatusAccepted, rr.Code)
+		exports.AssertExpectations(t)
+
+		var res struct {
+			Data store.Export `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&res); err != nil {
+			t.Fatal(err)
+		}
+		if res.Data.Status != store.ExportStatusPending {
+			t.Errorf("expected a pending export, got %+v", res.Data)
+		}
+	})
+
+	archive := `{"profile":{"id":1}}`
+	if err := app.blobs.Put(context.Background(), "exports/archive.json", strings.NewReader(archive), int64(len(archive)), "application/json"); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("should download the archive once it's ready", func(t *testing.T) {
+		exports.On("Request", int64(1)).Return(&store.Export{ID: 2, UserID: 1, Status: store.ExportStatusReady, Key: "exports/archive.json"}, nil).Once()
+
+		rr := executeRequest(request(t, "/v1/users/me/export"), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+		exports.AssertExpectations(t)
+
+		if rr.Body.String() != archive {
+			t.Errorf("unexpected archive %s", rr.Body.String())
+		}
+		if got := rr.Header().Get("Content-Disposition"); !strings.Contains(got, "attachment") {
+			t.Errorf("expected the archive as an attachment, got %q", got)
+		}
+	})
+
+	t.Run("should not serve archives as media", func(t *testing.T) {
+		rr := executeRequest(request(t, "/v1/media/exports/archive.json"), mux)
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+}
@@ -16,11 +16,20 @@ import (
 	"github.com/go-chi/cors"
 	"github.com/kuluruvineeth/social-go/docs"
 	"github.com/kuluruvineeth/social-go/internal/auth"
-	"github.com/kuluruvineeth/social-go/internal/env"
+	"github.com/kuluruvineeth/social-go/internal/blob"
+	"github.com/kuluruvineeth/social-go/internal/deletion"
+	"github.com/kuluruvineeth/social-go/internal/export"
 	"github.com/kuluruvineeth/social-go/internal/mailer"
+	"github.com/kuluruvineeth/social-go/internal/media"
+	"github.com/kuluruvineeth/social-go/internal/moderation"
+	"github.com/kuluruvineeth/social-go/internal/notifications"
+	"github.com/kuluruvineeth/social-go/internal/outbox"
 	"github.com/kuluruvineeth/social-go/internal/ratelimiter"
+	"github.com/kuluruvineeth/social-go/internal/realtime"
+	"github.com/kuluruvineeth/social-go/internal/scheduler"
 	"github.com/kuluruvineeth/social-go/internal/store"
 	"github.com/kuluruvineeth/social-go/internal/store/cache"
+	"github.com/kuluruvineeth/social-go/internal/timeline"
 	httpSwagger "github.com/swaggo/http-swagger"
 	"go.uber.org/zap"
 )
@@ -33,6 +42,12 @@ type application struct {
 	authenticator auth.Authenticator
 	cache         cache.Storage
 	rateLimiter   ratelimiter.Limiter
+	devInbox      *mailer.DevInbox
+	notifier      *notifications.Service
+	broker        realtime.Broker
+	tickets       realtime.Tickets
+	blobs         blob.Store
+	contentFilter moderation.Filter
 }
 
 type dbConfig struct {
@@ -40,13 +55,20 @@ type dbConfig struct {
 	maxOpenConns int
 	maxIdleConns int
 	maxIdletime  string
+	// requireCurrentSchema refuses to start against a database missing
+	// migrations.
+	requireCurrentSchema bool
 }
 
 type mailConfig struct {
+	provider  string
 	exp       time.Duration
 	sendGrid  sendGridConfig
 	fromEmail string
 	mailTrap  mailTrapConfig
+	smtp      mailer.SMTPConfig
+	devInbox  devInboxConfig
+	outbox    outbox.Config
 }
 
 type sendGridConfig struct {
@@ -57,6 +79,11 @@ type mailTrapConfig struct {
 	apiKey string
 }
 
+type devInboxConfig struct {
+	dir      string
+	capacity int
+}
+
 type config struct {
 	addr        string
 	db          dbConfig
@@ -64,9 +91,58 @@ type config struct {
 	apiURL      string
 	mail        mailConfig
 	frontendURL string
-	auth        authConfig
-	redisCfg    redisConfig
-	rateLimiter ratelimiter.Config
+	// allowedOrigins are the origins browsers may call the API from.
+	allowedOrigins []string
+	auth           authConfig
+	redisCfg       redisConfig
+	lruCfg         lruConfig
+	rateLimiter    ratelimiter.Config
+	timeline       timeline.Config
+	scheduler      scheduler.Config
+	export         export.Config
+	deletion       deletion.Config
+	blob           blobConfig
+	media          mediaConfig
+	posts          postsConfig
+	moderation     moderationConfig
+	users          usersConfig
+}
+
+type postsConfig struct {
+	// requireIfMatch rejects changes to posts without an If-Match header.
+	requireIfMatch bool
+}
+
+type blobConfig struct {
+	provider string
+	dir      string
+	s3       blob.S3Config
+	// publicURL serves the blobs instead of the API, such as a CDN in front
+	// of the bucket.
+	publicURL string
+}
+
+type usersConfig struct {
+	// usernameCooldown is how long users must wait between username changes.
+	usernameCooldown time.Duration
+	// deletionGracePeriod is how long deleted accounts can still be
+	// restored.
+	deletionGracePeriod time.Duration
+}
+
+type moderationConfig struct {
+	// hideThreshold is how many users must report a post or comment for it
+	// to be hidden until a moderator reviews it, 0 never hides it.
+	hideThreshold int
+	// bannedWords are rejected in new posts, and flaggedWords queue them for
+	// review.
+	bannedWords  []string
+	flaggedWords []string
+}
+
+type mediaConfig struct {
+	maxBytes int64
+	image    media.Config
 }
 
 type redisConfig struct {
@@ -76,6 +152,12 @@ type redisConfig struct {
 	enabled bool
 }
 
+type lruConfig struct {
+	size    int
+	ttl     time.Duration
+	enabled bool
+}
+
 type authConfig struct {
 	basic basicAuthConfig
 	token tokenConfig
@@ -100,25 +182,46 @@ func (app *application) mount() http.Handler {
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{env.GetString("CORS_ALLOWED_ORIGIN", "http://localhost:4000")},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedOrigins:   app.config.allowedOrigins,
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "If-Match", "If-None-Match"},
+		ExposedHeaders:   []string{"Link", "ETag"},
 		AllowCredentials: false,
 		MaxAge:           300,
 	}))
 	r.Use(app.RateLimiterMiddleware)
 
+	r.NotFound(app.notFoundError)
+	r.MethodNotAllowed(app.methodNotAllowedError)
+
+	// Streams are long-lived, so they sit outside the request timeout below.
+	r.Route("/v1/stream", func(r chi.Router) {
+		r.With(app.AuthTokenMiddleware).Post("/tickets", app.createStreamTicketHandler)
+
+		r.Group(func(r chi.Router) {
+			r.Use(app.streamAuthMiddleware)
+			r.Get("/", app.streamHandler)
+			r.Get("/ws", app.streamWebSocketHandler)
+		})
+	})
+
 	// Set a timeout value on the request context (ctx), that will signal
 	// through ctx.Done() that the request has timed out and further
 	// processing should be stopped.
-	r.Use(middleware.Timeout(60 * time.Second))
-
-	r.Route("/v1", func(r chi.Router) {
+	r.With(middleware.Timeout(60*time.Second)).Route("/v1", func(r chi.Router) {
 		// Operations
 		r.Get("/health", app.healthcheckHandler)
 		r.With(app.BasicAuthMiddleware()).Get("/debug/vars", expvar.Handler().ServeHTTP)
 
+		if app.devInbox != nil {
+			r.Route("/debug/mail", func(r chi.Router) {
+				r.Use(app.BasicAuthMiddleware())
+				r.Get("/", app.listDevInboxHandler)
+				r.Delete("/", app.clearDevInboxHandler)
+				r.Get("/{messageID}", app.getDevInboxMessageHandler)
+			})
+		}
+
 		docsURL := fmt.Sprintf("%s/swagger/doc.json", app.config.addr)
 		r.Get("/swagger/*", httpSwagger.Handler(
 			httpSwagger.URL(docsURL),
@@ -131,11 +234,28 @@ func (app *application) mount() http.Handler {
 			r.Route("/{postID}", func(r chi.Router) {
 				r.Use(app.postsContextMiddleware)
 				r.Get("/", app.getPostHandler)
+				r.Post("/comments", app.createCommentHandler)
+				r.Post("/reports", app.reportPostHandler)
+				r.Post("/comments/{commentID}/reports", app.reportCommentHandler)
+				r.Put("/reactions", app.reactToPostHandler)
+				r.Delete("/reactions", app.deletePostReactionHandler)
+				r.Put("/bookmark", app.bookmarkPostHandler)
+				r.Delete("/bookmark", app.deleteBookmarkHandler)
+				r.Post("/reposts", app.repostHandler)
+				r.Delete("/reposts", app.deleteRepostHandler)
+				r.Post("/attachments", app.uploadAttachmentHandler)
+				r.Delete("/attachments/{attachmentID}", app.checkPostOwnership("moderator", app.deleteAttachmentHandler))
+				r.Get("/revisions", app.getPostRevisionsHandler)
+				r.Get("/revisions/diff", app.getPostDiffHandler)
+				r.Get("/revisions/{version}", app.getPostRevisionHandler)
+				r.Post("/revisions/{version}/revert", app.checkPostOwnership("moderator", app.revertPostHandler))
 				r.Delete("/", app.checkPostOwnership("admin", app.deletePostHandler))
 				r.Patch("/", app.checkPostOwnership("moderator", app.updatePostHandler))
 			})
 		})
 
+		r.Get("/media/*", app.getMediaHandler)
+
 		r.Route("/users", func(r chi.Router) {
 			r.Put("/activate/{token}", app.activateUserHandler)
 			r.Route("/{userID}", func(r chi.Router) {
@@ -147,14 +267,54 @@ func (app *application) mount() http.Handler {
 
 			r.Group(func(r chi.Router) {
 				r.Use(app.AuthTokenMiddleware)
+				r.Patch("/me", app.updateProfileHandler)
+				r.Delete("/me", app.deleteAccountHandler)
+				r.Post("/me/restore", app.restoreAccountHandler)
+				r.Get("/me/export", app.exportAccountHandler)
 				r.Get("/feed", app.getUserFeedHandler)
+				r.Get("/bookmarks", app.getBookmarksHandler)
 			})
 		})
 
+		r.With(app.AuthTokenMiddleware).Get("/search", app.searchHandler)
+
+		r.Route("/tags", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/trending", app.getTrendingTagsHandler)
+			r.Get("/{tag}/posts", app.getTagPostsHandler)
+		})
+
+		r.Route("/notifications", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/", app.getNotificationsHandler)
+			r.Post("/read", app.markNotificationsReadHandler)
+			r.Get("/preferences", app.getNotificationPreferencesHandler)
+			r.Put("/preferences", app.updateNotificationPreferenceHandler)
+		})
+
 		r.Route("/authentication", func(r chi.Router) {
 			r.Post("/user", app.registerUserHandler)
 			r.Post("/token", app.createTokenHandler)
 		})
+
+		r.Route("/moderation", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Use(app.requireRole("moderator"))
+
+			r.Get("/reports", app.getModerationQueueHandler)
+			r.Post("/reports/{reportID}/resolve", app.resolveReportHandler)
+		})
+
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Use(app.requireRole("admin"))
+
+			r.Route("/outbox", func(r chi.Router) {
+				r.Get("/", app.listOutboxHandler)
+				r.Get("/{messageID}", app.getOutboxMessageHandler)
+				r.Post("/{messageID}/retry", app.retryOutboxMessageHandler)
+			})
+		})
 	})
 
 	return r
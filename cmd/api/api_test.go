@@ -20,6 +20,11 @@ func TestRateLimiterMiddleware(t *testing.T) {
 	}
 
 	app := newTestApplication(t, cfg)
+	app.rateLimiter = ratelimiter.NewFixedWindowRateLimiter(
+		cfg.rateLimiter.RequestsPerTimeFrame,
+		cfg.rateLimiter.TimeFrame,
+	)
+
 	ts := httptest.NewServer(app.mount())
 	defer ts.Close()
 
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/kuluruvineeth/social-go/internal/blob"
+	"github.com/kuluruvineeth/social-go/internal/media"
+	"github.com/kuluruvineeth/social-go/internal/store"
+)
+
+const (
+	// multipartOverhead is allowed on top of the image for the rest of the
+	// multipart body.
+	multipartOverhead = 64 << 10
+	blobDeleteTimeout = 10 * time.Second
+)
+
+// uploadAttachmentHandler godoc
+//
+//	@Summary		Attaches an image to a post
+//	@Description	Uploads a JPEG, PNG or WebP image in the file field. Metadata such as EXIF is stripped and a thumbnail is generated. Only the author can add images, up to 4 per post.
+//	@Tags			posts
+//	@Accept			mpfd
+//	@Produce		json
+//	@Param			id		path		int		true	"Post ID"
+//	@Param			file	formData	file	true	"Image"
+//	@Success		201		{object}	store.Attachment
+//	@Failure		400		{object}	Problem
+//	@Failure		401		{object}	Problem
+//	@Failure		403		{object}	Problem
+//	@Failure		404		{object}	Problem
+//	@Failure		413		{object}	Problem
+//	@Failure		415		{object}	Problem
+//	@Failure		500		{object}	Problem
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/attachments [post]
+func (app *application) uploadAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	post := getPostFromCtx(r)
+
+	if post.UserID != user.ID {
+		app.forbiddenError(w, r)
+		return
+	}
+
+	if len(post.Attachments) >= store.MaxAttachments {
+		app.badRequestError(w, r, store.ErrTooManyAttachments)
+		return
+	}
+
+	maxBytes := app.config.media.maxBytes
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes+multipartOverhead)
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			app.payloadTooLargeError(w, r, maxBytes)
+			return
+		}
+		app.badRequestError(w, r, err)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxBytes+1))
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+	if int64(len(data)) > maxBytes {
+		app.payloadTooLargeError(w, r, maxBytes)
+		return
+	}
+
+	original, thumbnail, err := media.Process(data, app.config.media.image)
+	if err != nil {
+		switch {
+		case errors.Is(err, media.ErrUnsupportedType):
+			app.unsupportedMediaTypeError(w, r, err)
+		case errors.Is(err, media.ErrTooLarge):
+			app.badRequestError(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	ctx := r.Context()
+
+	name := "attachments/" + uuid.NewString()
+	attachment := &store.Attachment{
+		PostID:       post.ID,
+		UserID:       user.ID,
+		Key:          name + original.Ext(),
+		ThumbnailKey: name + "_thumb" + thumbnail.Ext(),
+		ContentType:  original.ContentType,
+		Size:         int64(len(original.Data)),
+		Width:        original.Width,
+		Height:       original.Height,
+	}
+
+	if err := app.putImages(ctx, attachment, original, thumbnail); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.store.Attachments.Create(ctx, attachment); err != nil {
+		app.deleteBlobs(attachment.Key, attachment.ThumbnailKey)
+
+		app.errorResponse(w, r, err)
+		return
+	}
+
+	attachment.URL = app.mediaURL(attachment.Key)
+	attachment.ThumbnailURL = app.mediaURL(attachment.ThumbnailKey)
+
+	if err := app.jsonResponse(w, http.StatusCreated, attachment); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *application) putImages(ctx context.Context, a *store.Attachment, original, thumbnail *media.Image) error {
+	if err := app.blobs.Put(ctx, a.Key, bytes.NewReader(original.Data), int64(len(original.Data)), original.ContentType); err != nil {
+		return err
+	}
+
+	if err := app.blobs.Put(ctx, a.ThumbnailKey, bytes.NewReader(thumbnail.Data), int64(len(thumbnail.Data)), thumbnail.ContentType); err != nil {
+		app.deleteBlobs(a.Key)
+		return err
+	}
+
+	return nil
+}
+
+// deleteAttachmentHandler godoc
+//
+//	@Summary		Removes an image from a post
+//	@Description	Removes an attachment. Moderators can remove images from any post.
+//	@Tags			posts
+//	@Param			id				path	int	true	"Post ID"
+//	@Param			attachmentID	path	int	true	"Attachment ID"
+//	@Success		204				"No Content"
+//	@Failure		401				{object}	Problem
+//	@Failure		403				{object}	Problem
+//	@Failure		404				{object}	Problem
+//	@Failure		500				{object}	Problem
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/attachments/{attachmentID} [delete]
+func (app *application) deleteAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "attachmentID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	attachment, err := app.store.Attachments.GetByID(ctx, id)
+	if err == nil && attachment.PostID != post.ID {
+		err = store.ErrNotFound
+	}
+	if err == nil {
+		err = app.store.Attachments.Delete(ctx, id)
+	}
+	if err != nil {
+		app.errorResponse(w, r, err)
+		return
+	}
+
+	app.deleteBlobs(attachment.Key, attachment.ThumbnailKey)
+
+	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getMediaHandler godoc
+//
+//	@Summary		Downloads an image
+//	@Description	Serves attachment images and thumbnails from the blob store. Keys are unguessable and never reused, so responses can be cached for ever.
+//	@Tags			posts
+//	@Produce		image/jpeg,image/png
+//	@Param			key	path	string	true	"Blob key"
+//	@Success		200
+//	@Failure		404	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Router			/media/{key} [get]
+func (app *application) getMediaHandler(w http.ResponseWriter, r *http.Request) {
+	key := chi.URLParam(r, "*")
+
+	// Data exports are only downloaded by their owner
+	if strings.HasPrefix(key, "exports/") {
+		app.notFoundError(w, r)
+		return
+	}
+
+	obj, err := app.blobs.Get(r.Context(), key)
+	if err != nil {
+		switch {
+		case errors.Is(err, blob.ErrNotFound), errors.Is(err, blob.ErrInvalidKey):
+			app.notFoundError(w, r)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	defer obj.Close()
+
+	w.Header().Set("Content-Type", obj.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(obj.Size, 10))
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, obj); err != nil {
+		app.logger.Warnw("failed to send media", "path", r.URL.Path, "error", err)
+	}
+}
+
+// mediaURL returns where clients download the blob under key: the public
+// URL of the bucket or CDN when there is one, and the API otherwise.
+func (app *application) mediaURL(key string) string {
+	if app.config.blob.publicURL != "" {
+		return strings.TrimSuffix(app.config.blob.publicURL, "/") + "/" + key
+	}
+	return "/v1/media/" + key
+}
+
+func (app *application) setAttachmentURLs(attachments []store.Attachment) {
+	for i := range attachments {
+		attachments[i].URL = app.mediaURL(attachments[i].Key)
+		attachments[i].ThumbnailURL = app.mediaURL(attachments[i].ThumbnailKey)
+	}
+}
+
+func (app *application) setFeedAttachmentURLs(posts []store.PostWithMetadata) {
+	for i := range posts {
+		app.setAttachmentURLs(posts[i].Attachments)
+	}
+}
+
+// deleteBlobs removes the images of deleted attachments. Failures are only
+// logged, as the rows pointing at them are already gone.
+func (app *application) deleteBlobs(keys ...string) {
+	// The request may have been cancelled, which mustn't leave the blobs
+	// behind.
+	ctx, cancel := context.WithTimeout(context.Background(), blobDeleteTimeout)
+	defer cancel()
+
+	for _, key := range keys {
+		if err := app.blobs.Delete(ctx, key); err != nil {
+			app.logger.Errorw("failed to delete blob", "key", key, "error", err)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/kuluruvineeth/social-go/internal/media"
+	"github.com/kuluruvineeth/social-go/internal/store"
+)
+
+func TestAttachments(t *testing.T) {
+	app := newTestApplication(t, config{
+		media: mediaConfig{maxBytes: 64 << 10, image: media.DefaultConfig()},
+	})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	upload := func(t *testing.T, data []byte) *http.Request {
+		t.Helper()
+
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		fw, err := mw.CreateFormFile("file", "photo.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write(data)
+		mw.Close()
+
+		req, err := http.NewRequest(http.MethodPost, "/v1/posts/7/attachments", &body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		return req
+	}
+
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewNRGBA(image.Rect(0, 0, 40, 20))); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("should store the image and its thumbnail", func(t *testing.T) {
+		mockStore := app.store.Attachments.(*store.MockAttachmentStore)
+		mockStore.On("Create", int64(7), int64(1)).Return(nil).Once()
+
+		rr := executeRequest(upload(t, img.Bytes()), mux)
+		checkResponseCode(t, http.StatusCreated, rr.Code)
+		mockStore.AssertExpectations(t)
+
+		var resp struct {
+			Data store.Attachment `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+
+		a := resp.Data
+		if a.ContentType != "image/png" || a.Width != 40 || a.Height != 20 {
+			t.Errorf("unexpected attachment %+v", a)
+		}
+		if !strings.HasPrefix(a.URL, "/v1/media/attachments/") || !strings.HasSuffix(a.ThumbnailURL, "_thumb.png") {
+			t.Errorf("unexpected URLs %s and %s", a.URL, a.ThumbnailURL)
+		}
+
+		for _, url := range []string{a.URL, a.ThumbnailURL} {
+			req, err := http.NewRequest(http.MethodGet, url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := executeRequest(req, mux)
+			checkResponseCode(t, http.StatusOK, rr.Code)
+			if ct := rr.Header().Get("Content-Type"); ct != "image/png" {
+				t.Errorf("expected image/png, got %s", ct)
+			}
+		}
+	})
+
+	t.Run("should reject files that aren't images", func(t *testing.T) {
+		rr := executeRequest(upload(t, []byte("<html><script>alert(1)</script></html>")), mux)
+		checkResponseCode(t, http.StatusUnsupportedMediaType, rr.Code)
+	})
+
+	t.Run("should reject files over the size limit", func(t *testing.T) {
+		big := append(bytes.Clone(img.Bytes()), make([]byte, 64<<10)...)
+
+		rr := executeRequest(upload(t, big), mux)
+		checkResponseCode(t, http.StatusRequestEntityTooLarge, rr.Code)
+	})
+
+	t.Run("should delete the attachment and its images", func(t *testing.T) {
+		ctx := context.Background()
+		for _, key := range []string{"attachments/a.png", "attachments/a_thumb.png"} {
+			if err := app.blobs.Put(ctx, key, strings.NewReader("x"), 1, "image/png"); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		mockStore := app.store.Attachments.(*store.MockAttachmentStore)
+		mockStore.On("GetByID", int64(3)).Return(&store.Attachment{ID: 3, PostID: 7, Key: "attachments/a.png", ThumbnailKey: "attachments/a_thumb.png"}, nil).Once()
+		mockStore.On("Delete", int64(3)).Return(nil).Once()
+
+		req, err := http.NewRequest(http.MethodDelete, "/v1/posts/7/attachments/3", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusNoContent, rr.Code)
+		mockStore.AssertExpectations(t)
+
+		req, err = http.NewRequest(http.MethodGet, "/v1/media/attachments/a.png", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkResponseCode(t, http.StatusNotFound, executeRequest(req, mux).Code)
+	})
+
+	t.Run("should not delete attachments of other posts", func(t *testing.T) {
+		mockStore := app.store.Attachments.(*store.MockAttachmentStore)
+		mockStore.On("GetByID", int64(4)).Return(&store.Attachment{ID: 4, PostID: 8}, nil).Once()
+
+		req, err := http.NewRequest(http.MethodDelete, "/v1/posts/7/attachments/4", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+		mockStore.AssertNotCalled(t, "Delete", int64(4))
+	})
+
+	t.Run("should not serve keys outside the store", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/media/../../etc/passwd", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+}
@@ -3,8 +3,9 @@ package main
 import (
 	"crypto/sha256"
 	"encoding/hex"
-	"fmt"
+	"encoding/json"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -17,6 +18,7 @@ type RegisterUserPayload struct {
 	Username string `json:"username" validate:"required,max=100"`
 	Email    string `json:"email" validate:"required,email,max=255"`
 	Password string `json:"password" validate:"required,min=3,max=72"`
+	Locale   string `json:"locale" validate:"omitempty,bcp47_language_tag,max=35"`
 }
 
 type UserWithToken struct {
@@ -33,8 +35,9 @@ type UserWithToken struct {
 //	@Produce		json
 //	@Param			payload	body		RegisterUserPayload	true	"User credentials"
 //	@Success		201		{object}	UserWithToken		"User registered"
-//	@Failure		400		{object}	error
-//	@Failure		500		{object}	error
+//	@Failure		400		{object}	Problem
+//	@Failure		409		{object}	Problem	"Username or email taken"
+//	@Failure		500		{object}	Problem
 //	@Router			/authentication/user [post]
 func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Request) {
 	var payload RegisterUserPayload
@@ -48,9 +51,15 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	locale := payload.Locale
+	if locale == "" {
+		locale = preferredLocale(r)
+	}
+
 	user := &store.User{
 		Username: payload.Username,
 		Email:    payload.Email,
+		Locale:   locale,
 	}
 
 	// hash password
@@ -59,50 +68,44 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	token := uuid.New().String()
-
-	hash := sha256.Sum256([]byte(token))
-	hashToken := hex.EncodeToString(hash[:])
-
-	// store the user
-	if err := app.store.Users.CreateAndInvite(r.Context(), user, hashToken, app.config.mail.exp); err != nil {
-		switch err {
-		case store.ErrDuplicateEmail:
-			app.badRequestError(w, r, err)
-		case store.ErrDuplicateUsername:
-			app.badRequestError(w, r, err)
-		default:
-			app.internalServerError(w, r, err)
-		}
-		return
-	}
-
-	userWithToken := &UserWithToken{
-		User:  user,
-		Token: token,
-	}
+	token, hashToken := newInvitationToken()
 
-	activationURL := fmt.Sprintf("%s/confirm/%s", app.config.frontendURL, token)
 	isProdEnv := app.config.env == "production"
 	vars := struct {
-		Username      string
-		ActivationURL string
+		Username string
 	}{
-		Username:      user.Username,
-		ActivationURL: activationURL,
+		Username: user.Username,
 	}
 
-	_, err := app.mailer.Send(mailer.UserInvitationTemplate, user.Username, user.Email, vars, !isProdEnv)
+	data, err := json.Marshal(vars)
 	if err != nil {
-		app.logger.Errorw("failed to send user invitation email", "error", err)
-
-		if err := app.store.Users.Delete(r.Context(), user.ID); err != nil {
-			app.logger.Errorw("failed to delete user", "error", err)
-		}
 		app.internalServerError(w, r, err)
 		return
 	}
 
+	// the invitation is delivered asynchronously by the outbox worker, which
+	// builds the activation link from the token
+	invitation := &store.OutboxMessage{
+		Template: mailer.UserInvitationTemplate,
+		Locale:   user.Locale,
+		Username: user.Username,
+		Email:    user.Email,
+		Data:     data,
+		Token:    token,
+		Sandbox:  !isProdEnv,
+	}
+
+	// store the user
+	if err := app.store.Users.CreateAndInvite(r.Context(), user, hashToken, app.config.mail.exp, invitation); err != nil {
+		app.errorResponse(w, r, err)
+		return
+	}
+
+	userWithToken := &UserWithToken{
+		User:  user,
+		Token: token,
+	}
+
 	if err := app.jsonResponse(w, http.StatusCreated, userWithToken); err != nil {
 		app.internalServerError(w, r, err)
 	}
@@ -123,9 +126,9 @@ type CreateUserTokenPayload struct {
 //	@Produce		json
 //	@Param			payload	body		CreateUserTokenPayload	true	"User credentials"
 //	@Success		200		{string}	string					"Token"
-//	@Failure		400		{object}	error
-//	@Failure		401		{object}	error
-//	@Failure		500		{object}	error
+//	@Failure		400		{object}	Problem
+//	@Failure		401		{object}	Problem
+//	@Failure		500		{object}	Problem
 //	@Router			/authentication/token [post]
 func (app *application) createTokenHandler(w http.ResponseWriter, r *http.Request) {
 	var payload CreateUserTokenPayload
@@ -169,3 +172,31 @@ func (app *application) createTokenHandler(w http.ResponseWriter, r *http.Reques
 		app.internalServerError(w, r, err)
 	}
 }
+
+// newInvitationToken returns a token for a user to confirm their email
+// address with, and the hash of it that is stored.
+func newInvitationToken() (string, string) {
+	token := uuid.New().String()
+
+	hash := sha256.Sum256([]byte(token))
+	return token, hex.EncodeToString(hash[:])
+}
+
+// preferredLocale returns the first language tag from the Accept-Language
+// header, ignoring quality values, or the mailer's default locale.
+func preferredLocale(r *http.Request) string {
+	header := r.Header.Get("Accept-Language")
+	if header == "" {
+		return mailer.DefaultLocale
+	}
+
+	tag, _, _ := strings.Cut(header, ",")
+	tag, _, _ = strings.Cut(tag, ";")
+	tag = strings.TrimSpace(tag)
+
+	if tag == "" || tag == "*" || len(tag) > 35 {
+		return mailer.DefaultLocale
+	}
+
+	return tag
+}
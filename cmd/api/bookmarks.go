@@ -0,0 +1,105 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/kuluruvineeth/social-go/internal/store"
+)
+
+// bookmarkPostHandler godoc
+//
+//	@Summary		Bookmarks a post
+//	@Description	Saves a post to the authenticated user's bookmarks, which only they can see
+//	@Tags			posts
+//	@Param			id	path	int	true	"Post ID"
+//	@Success		204	"No Content"
+//	@Failure		401	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/bookmark [put]
+func (app *application) bookmarkPostHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	post := getPostFromCtx(r)
+
+	if err := app.store.Bookmarks.Add(r.Context(), user.ID, post.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// deleteBookmarkHandler godoc
+//
+//	@Summary		Removes a bookmark
+//	@Description	Removes a post from the authenticated user's bookmarks
+//	@Tags			posts
+//	@Param			id	path	int	true	"Post ID"
+//	@Success		204	"No Content"
+//	@Failure		401	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/bookmark [delete]
+func (app *application) deleteBookmarkHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	post := getPostFromCtx(r)
+
+	if err := app.store.Bookmarks.Delete(r.Context(), user.ID, post.ID); err != nil {
+		app.errorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getBookmarksHandler godoc
+//
+//	@Summary		Fetches the user's bookmarks
+//	@Description	Fetches the posts the authenticated user bookmarked, the latest bookmark first
+//	@Tags			users
+//	@Produce		json
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Success		200		{object}	[]store.PostWithMetadata
+//	@Failure		400		{object}	Problem
+//	@Failure		401		{object}	Problem
+//	@Failure		500		{object}	Problem
+//	@Security		ApiKeyAuth
+//	@Router			/users/bookmarks [get]
+func (app *application) getBookmarksHandler(w http.ResponseWriter, r *http.Request) {
+	q := store.BookmarkQuery{
+		Limit:  20,
+		Offset: 0,
+	}
+
+	q, err := q.Parse(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := validate.Struct(q); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	posts, err := app.store.Bookmarks.List(r.Context(), user.ID, q)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.setFeedAttachmentURLs(posts)
+
+	if err := app.jsonResponse(w, http.StatusOK, posts); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/kuluruvineeth/social-go/internal/store"
+)
+
+func TestBookmarks(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(t *testing.T, method, url string) *http.Request {
+		t.Helper()
+
+		req, err := http.NewRequest(method, url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		return req
+	}
+
+	mockStore := app.store.Bookmarks.(*store.MockBookmarkStore)
+
+	t.Run("should bookmark a post", func(t *testing.T) {
+		mockStore.On("Add", int64(1), int64(7)).Return(nil).Once()
+
+		rr := executeRequest(request(t, http.MethodPut, "/v1/posts/7/bookmark"), mux)
+		checkResponseCode(t, http.StatusNoContent, rr.Code)
+		mockStore.AssertExpectations(t)
+	})
+
+	t.Run("should return not found when there is no bookmark to remove", func(t *testing.T) {
+		mockStore.On("Delete", int64(1), int64(7)).Return(store.ErrNotFound).Once()
+
+		rr := executeRequest(request(t, http.MethodDelete, "/v1/posts/7/bookmark"), mux)
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("should list bookmarks", func(t *testing.T) {
+		rr := executeRequest(request(t, http.MethodGet, "/v1/users/bookmarks?limit=10&offset=10"), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("should reject an invalid page", func(t *testing.T) {
+		rr := executeRequest(request(t, http.MethodGet, "/v1/users/bookmarks?limit=100"), mux)
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+}
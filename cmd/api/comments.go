@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/kuluruvineeth/social-go/internal/content"
+	"github.com/kuluruvineeth/social-go/internal/realtime"
+	"github.com/kuluruvineeth/social-go/internal/store"
+)
+
+type CreateCommentPayload struct {
+	Content string `json:"content" validate:"required,max=1000"`
+}
+
+// createCommentHandler godoc
+//
+//	@Summary		Comments on a post
+//	@Description	Creates a comment on a post and notifies the post's author
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int						true	"Post ID"
+//	@Param			payload	body		CreateCommentPayload	true	"Comment payload"
+//	@Success		201		{object}	store.Comment
+//	@Failure		400		{object}	Problem
+//	@Failure		401		{object}	Problem
+//	@Failure		404		{object}	Problem
+//	@Failure		500		{object}	Problem
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/comments [post]
+func (app *application) createCommentHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateCommentPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if len(content.Parse(payload.Content).Mentions) > content.MaxMentions {
+		app.badRequestError(w, r, fmt.Errorf("a comment can mention at most %d users", content.MaxMentions))
+		return
+	}
+
+	user := getUserFromContext(r)
+	post := getPostFromCtx(r)
+
+	comment := &store.Comment{
+		PostID:  post.ID,
+		UserID:  user.ID,
+		Content: payload.Content,
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Comments.Create(ctx, comment); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	comment.User = *user
+
+	app.publish(ctx, realtime.PostTopic(post.ID), realtime.EventComment, comment)
+
+	// the post's author is already notified about the comment itself
+	app.notifyMentions(ctx, user.ID, post, &comment.ID, comment.Mentions, []int64{post.UserID})
+
+	app.notify(ctx, &store.Notification{
+		UserID:    post.UserID,
+		ActorID:   user.ID,
+		Type:      store.NotificationComment,
+		PostID:    &post.ID,
+		CommentID: &comment.ID,
+	})
+
+	if err := app.jsonResponse(w, http.StatusCreated, comment); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// listDevInboxHandler godoc
+//
+//	@Summary		Lists captured emails
+//	@Description	Lists emails captured by the dev inbox mailer, newest first. Only mounted when MAILER_PROVIDER=devinbox.
+//	@Tags			ops
+//	@Produce		json
+//	@Success		200	{object}	[]github_com_kuluruvineeth_social-go_internal_mailer.DevInboxMessage
+//	@Failure		401	{object}	Problem
+//	@Security		BasicAuth
+//	@Router			/debug/mail [get]
+func (app *application) listDevInboxHandler(w http.ResponseWriter, r *http.Request) {
+	if err := app.jsonResponse(w, http.StatusOK, app.devInbox.Messages()); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getDevInboxMessageHandler godoc
+//
+//	@Summary		Fetches a captured email
+//	@Description	Fetches an email captured by the dev inbox mailer
+//	@Tags			ops
+//	@Produce		json
+//	@Param			id	path		int	true	"Message ID"
+//	@Success		200	{object}	github_com_kuluruvineeth_social-go_internal_mailer.DevInboxMessage
+//	@Failure		401	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Security		BasicAuth
+//	@Router			/debug/mail/{id} [get]
+func (app *application) getDevInboxMessageHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "messageID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	msg, ok := app.devInbox.Get(id)
+	if !ok {
+		app.notFoundError(w, r)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, msg); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// clearDevInboxHandler godoc
+//
+//	@Summary		Clears captured emails
+//	@Description	Removes every email captured by the dev inbox mailer
+//	@Tags			ops
+//	@Success		204	"No Content"
+//	@Failure		401	{object}	Problem
+//	@Security		BasicAuth
+//	@Router			/debug/mail [delete]
+func (app *application) clearDevInboxHandler(w http.ResponseWriter, r *http.Request) {
+	app.devInbox.Clear()
+
+	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/kuluruvineeth/social-go/internal/mailer"
+)
+
+func TestDevInboxHandlers(t *testing.T) {
+	cfg := config{
+		auth: authConfig{
+			basic: basicAuthConfig{user: "admin", password: "password"},
+		},
+	}
+
+	t.Run("should not mount the inbox when it is disabled", func(t *testing.T) {
+		app := newTestApplication(t, cfg)
+		mux := app.mount()
+
+		req, _ := http.NewRequest(http.MethodGet, "/v1/debug/mail", nil)
+		req.SetBasicAuth("admin", "password")
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+
+	app := newTestApplication(t, cfg)
+	templates, err := mailer.NewTemplates(mailer.FS, mailer.DefaultLocale)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inbox, err := mailer.NewDevInbox("noreply@socialgo.dev", "", 10, templates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.devInbox = inbox
+	mux := app.mount()
+
+	vars := map[string]any{"Username": "alice", "ConfirmationURL": "http://localhost:4000/confirm/token"}
+	if _, err := inbox.Send(mailer.UserInvitationTemplate, "en", "alice", "alice@example.com", vars, true); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("should require basic auth", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/v1/debug/mail", nil)
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("should list captured messages", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/v1/debug/mail", nil)
+		req.SetBasicAuth("admin", "password")
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		var body struct {
+			Data []mailer.DevInboxMessage `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if len(body.Data) != 1 {
+			t.Fatalf("expected 1 message, got %d", len(body.Data))
+		}
+	})
+
+	t.Run("should return not found for unknown messages", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/v1/debug/mail/42", nil)
+		req.SetBasicAuth("admin", "password")
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+}
@@ -1,48 +1,225 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-playground/validator/v10"
+	"github.com/kuluruvineeth/social-go/internal/store"
+)
+
+// Error codes tell clients what went wrong without parsing messages. They
+// are part of the API, so existing codes must not change.
+const (
+	codeBadRequest           = "bad_request"
+	codeMalformedJSON        = "malformed_json"
+	codeValidationFailed     = "validation_failed"
+	codeUnauthorized         = "unauthorized"
+	codeForbidden            = "forbidden"
+	codeNotFound             = "not_found"
+	codeMethodNotAllowed     = "method_not_allowed"
+	codeConflict             = "conflict"
+	codeDuplicateEmail       = "duplicate_email"
+	codeDuplicateUsername    = "duplicate_username"
+	codeEditConflict         = "edit_conflict"
+	codePreconditionFailed   = "precondition_failed"
+	codePayloadTooLarge      = "payload_too_large"
+	codeUnsupportedMediaType = "unsupported_media_type"
+	codeUnprocessableEntity  = "unprocessable_entity"
+	codeUsernameCooldown     = "username_cooldown"
+	codeTooManyAttachments   = "too_many_attachments"
+	codePreconditionRequired = "precondition_required"
+	codeRateLimitExceeded    = "rate_limit_exceeded"
+	codeInternal             = "internal_error"
 )
 
+// apiError is an error as the client sees it. Message and Details are sent
+// to the client, while Err, the underlying cause, is only logged.
+type apiError struct {
+	Status  int
+	Code    string
+	Message string
+	Details any
+	Err     error
+}
+
+func (e *apiError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Code, e.Err)
+	}
+	return e.Code + ": " + e.Message
+}
+
+func (e *apiError) Unwrap() error {
+	return e.Err
+}
+
+// Problem is the RFC 9457 problem details document errors are sent as.
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail"`
+	Instance  string `json:"instance"`
+	Code      string `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+	Details   any    `json:"details,omitempty"`
+}
+
+// errorResponse writes the response for an error from the store, or any
+// other error the handler has no better answer for. Errors it doesn't know
+// are internal server errors.
+func (app *application) errorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	var apiErr *apiError
+
+	switch {
+	case errors.As(err, &apiErr):
+		app.writeError(w, r, apiErr)
+	case errors.Is(err, store.ErrNotFound):
+		app.notFoundError(w, r)
+	case errors.Is(err, store.ErrConflict):
+		app.conflictError(w, r)
+	case errors.Is(err, store.ErrDuplicateEmail):
+		app.writeError(w, r, &apiError{Status: http.StatusConflict, Code: codeDuplicateEmail, Message: "a user with that email already exists", Err: err})
+	case errors.Is(err, store.ErrDuplicateUsername):
+		app.writeError(w, r, &apiError{Status: http.StatusConflict, Code: codeDuplicateUsername, Message: "a user with that username already exists", Err: err})
+	case errors.Is(err, store.ErrVersionConflict):
+		app.editConflictError(w, r)
+	case errors.Is(err, store.ErrTooManyAttachments):
+		app.writeError(w, r, &apiError{Status: http.StatusBadRequest, Code: codeTooManyAttachments, Message: err.Error(), Err: err})
+	default:
+		app.internalServerError(w, r, err)
+	}
+}
+
+// writeError logs the error and sends it as a problem details document.
+// Server errors are logged with their cause, which clients never see.
+func (app *application) writeError(w http.ResponseWriter, r *http.Request, e *apiError) {
+	requestID := middleware.GetReqID(r.Context())
+
+	fields := []any{"method", r.Method, "path", r.URL.Path, "status", e.Status, "code", e.Code, "request_id", requestID}
+	if e.Err != nil {
+		fields = append(fields, "error", e.Err.Error())
+	}
+
+	msg := strings.ToLower(http.StatusText(e.Status))
+	if e.Status >= http.StatusInternalServerError {
+		app.logger.Errorw(msg, fields...)
+	} else {
+		app.logger.Warnw(msg, fields...)
+	}
+
+	problem := Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(e.Status),
+		Status:    e.Status,
+		Detail:    e.Message,
+		Instance:  r.URL.Path,
+		Code:      e.Code,
+		RequestID: requestID,
+		Details:   e.Details,
+	}
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(e.Status)
+
+	if err := json.NewEncoder(w).Encode(problem); err != nil {
+		app.logger.Warnw("failed to send error", "path", r.URL.Path, "error", err)
+	}
+}
+
 func (app *application) internalServerError(w http.ResponseWriter, r *http.Request, err error) {
-	app.logger.Errorf("internal server error", "method", r.Method, "path", r.URL.Path, "error", err.Error())
-	writeJSONError(w, http.StatusInternalServerError, err.Error())
+	app.writeError(w, r, &apiError{Status: http.StatusInternalServerError, Code: codeInternal, Message: "the server encountered a problem and could not process your request", Err: err})
 }
 
 func (app *application) notFoundError(w http.ResponseWriter, r *http.Request) {
-	app.logger.Errorf("not found", "method", r.Method, "path", r.URL.Path)
-	writeJSONError(w, http.StatusNotFound, "not found")
+	app.writeError(w, r, &apiError{Status: http.StatusNotFound, Code: codeNotFound, Message: "not found"})
+}
+
+func (app *application) methodNotAllowedError(w http.ResponseWriter, r *http.Request) {
+	app.writeError(w, r, &apiError{Status: http.StatusMethodNotAllowed, Code: codeMethodNotAllowed, Message: fmt.Sprintf("the %s method is not supported for this resource", r.Method)})
 }
 
+// badRequestError writes the response for an invalid request. Validation
+// errors, and the errors readJSON translates, tell the client which fields
+// are at fault.
 func (app *application) badRequestError(w http.ResponseWriter, r *http.Request, err error) {
-	app.logger.Errorf("bad request", "method", r.Method, "path", r.URL.Path, "error", err.Error())
-	writeJSONError(w, http.StatusBadRequest, err.Error())
+	var (
+		apiErr   *apiError
+		invalid  validator.ValidationErrors
+		paramErr *store.ParamError
+	)
+
+	switch {
+	case errors.As(err, &apiErr):
+		app.writeError(w, r, apiErr)
+	case errors.As(err, &invalid):
+		app.writeError(w, r, invalidFieldsError(err, fieldErrors(invalid)...))
+	case errors.As(err, &paramErr):
+		app.writeError(w, r, invalidFieldsError(err, FieldError{Field: paramErr.Param, Rule: paramErr.Rule, Message: paramErr.Error()}))
+	default:
+		app.writeError(w, r, &apiError{Status: http.StatusBadRequest, Code: codeBadRequest, Message: err.Error(), Err: err})
+	}
+}
+
+// invalidFieldsError is the error for a request with invalid fields, which
+// are listed in the problem's details.
+func invalidFieldsError(err error, fields ...FieldError) *apiError {
+	msg := "the request has invalid fields"
+	if len(fields) == 1 {
+		msg = fields[0].Message
+	}
+
+	return &apiError{Status: http.StatusBadRequest, Code: codeValidationFailed, Message: msg, Details: fields, Err: err}
 }
 
 func (app *application) conflictError(w http.ResponseWriter, r *http.Request) {
-	app.logger.Errorf("conflict", "method", r.Method, "path", r.URL.Path)
-	writeJSONError(w, http.StatusConflict, "conflict")
+	app.writeError(w, r, &apiError{Status: http.StatusConflict, Code: codeConflict, Message: "conflict"})
+}
+
+func (app *application) editConflictError(w http.ResponseWriter, r *http.Request) {
+	app.writeError(w, r, &apiError{Status: http.StatusConflict, Code: codeEditConflict, Message: "the resource was changed by another request, fetch it and try again"})
+}
+
+func (app *application) preconditionFailedError(w http.ResponseWriter, r *http.Request) {
+	app.writeError(w, r, &apiError{Status: http.StatusPreconditionFailed, Code: codePreconditionFailed, Message: "the resource was changed since it was fetched, fetch it and try again"})
+}
+
+func (app *application) preconditionRequiredError(w http.ResponseWriter, r *http.Request) {
+	app.writeError(w, r, &apiError{Status: http.StatusPreconditionRequired, Code: codePreconditionRequired, Message: "the If-Match header is required, set it to the ETag of the resource"})
+}
+
+func (app *application) payloadTooLargeError(w http.ResponseWriter, r *http.Request, maxBytes int64) {
+	app.writeError(w, r, &apiError{Status: http.StatusRequestEntityTooLarge, Code: codePayloadTooLarge, Message: fmt.Sprintf("the file must be at most %d bytes", maxBytes)})
+}
+
+func (app *application) unsupportedMediaTypeError(w http.ResponseWriter, r *http.Request, err error) {
+	app.writeError(w, r, &apiError{Status: http.StatusUnsupportedMediaType, Code: codeUnsupportedMediaType, Message: err.Error(), Err: err})
+}
+
+func (app *application) unprocessableEntityError(w http.ResponseWriter, r *http.Request, err error) {
+	app.writeError(w, r, &apiError{Status: http.StatusUnprocessableEntity, Code: codeUnprocessableEntity, Message: err.Error(), Err: err})
 }
 
 func (app *application) unauthorizedError(w http.ResponseWriter, r *http.Request, err error) {
-	app.logger.Errorf("unauthorized", "method", r.Method, "path", r.URL.Path, "error", err.Error())
-	writeJSONError(w, http.StatusUnauthorized, "unauthorized")
+	app.writeError(w, r, &apiError{Status: http.StatusUnauthorized, Code: codeUnauthorized, Message: "unauthorized", Err: err})
 }
 
 func (app *application) unauthorizedBasicError(w http.ResponseWriter, r *http.Request, err error) {
-	app.logger.Errorf("unauthorized basic error", "method", r.Method, "path", r.URL.Path, "error", err.Error())
 	// Set the WWW-Authenticate header to prompt the user for basic auth credentials
 	w.Header().Set("WWW-Authenticate", `Basic realm="restricted", charset="UTF-8"`)
-	writeJSONError(w, http.StatusUnauthorized, "unauthorized")
+	app.writeError(w, r, &apiError{Status: http.StatusUnauthorized, Code: codeUnauthorized, Message: "unauthorized", Err: err})
 }
 
 func (app *application) forbiddenError(w http.ResponseWriter, r *http.Request) {
-	app.logger.Warnw("forbidden", "method", r.Method, "path", r.URL.Path, "error")
-	writeJSONError(w, http.StatusForbidden, "forbidden")
+	app.writeError(w, r, &apiError{Status: http.StatusForbidden, Code: codeForbidden, Message: "forbidden"})
 }
 
 func (app *application) rateLimitExceededError(w http.ResponseWriter, r *http.Request, retryAfter string) {
-	app.logger.Warnw("rate limit exceeded", "method", r.Method, "path", r.URL.Path, "retry_after", retryAfter)
 	w.Header().Set("Retry-After", retryAfter)
-	writeJSONError(w, http.StatusTooManyRequests, "rate limit exceeded, retry after "+retryAfter)
+	app.writeError(w, r, &apiError{Status: http.StatusTooManyRequests, Code: codeRateLimitExceeded, Message: "rate limit exceeded, retry after " + retryAfter})
 }
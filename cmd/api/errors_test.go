@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/kuluruvineeth/social-go/internal/store"
+)
+
+func TestErrorResponse(t *testing.T) {
+	app := newTestApplication(t, config{})
+
+	respond := func(t *testing.T, err error) (*httptest.ResponseRecorder, Problem) {
+		t.Helper()
+
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/v1/posts/1", nil)
+
+		middleware.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			app.errorResponse(w, r, err)
+		})).ServeHTTP(rr, req)
+
+		if got := rr.Header().Get("Content-Type"); got != "application/problem+json" {
+			t.Errorf("expected a problem document, got %q", got)
+		}
+
+		var problem Problem
+		if err := json.NewDecoder(rr.Body).Decode(&problem); err != nil {
+			t.Fatal(err)
+		}
+		return rr, problem
+	}
+
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"not found", store.ErrNotFound, http.StatusNotFound, codeNotFound},
+		{"wrapped not found", fmt.Errorf("loading post: %w", store.ErrNotFound), http.StatusNotFound, codeNotFound},
+		{"conflict", store.ErrConflict, http.StatusConflict, codeConflict},
+		{"duplicate email", store.ErrDuplicateEmail, http.StatusConflict, codeDuplicateEmail},
+		{"duplicate username", store.ErrDuplicateUsername, http.StatusConflict, codeDuplicateUsername},
+		{"edit conflict", store.ErrVersionConflict, http.StatusConflict, codeEditConflict},
+		{"application error", &apiError{Status: http.StatusTeapot, Code: "teapot", Message: "short and stout"}, http.StatusTeapot, "teapot"},
+		{"unknown error", errors.New("boom"), http.StatusInternalServerError, codeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr, problem := respond(t, tt.err)
+
+			checkResponseCode(t, tt.status, rr.Code)
+			if problem.Status != tt.status || problem.Code != tt.code || problem.Title != http.StatusText(tt.status) {
+				t.Errorf("unexpected problem %+v", problem)
+			}
+			if problem.Type != "about:blank" || problem.Instance != "/v1/posts/1" {
+				t.Errorf("unexpected problem %+v", problem)
+			}
+			if problem.RequestID == "" {
+				t.Error("expected the request ID")
+			}
+		})
+	}
+
+	t.Run("should not leak internal errors", func(t *testing.T) {
+		_, problem := respond(t, errors.New(`pq: relation "posts" does not exist`))
+
+		if strings.Contains(problem.Detail, "pq") {
+			t.Errorf("expected a generic message, got %q", problem.Detail)
+		}
+	})
+}
+
+func TestUnknownRoutes(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	t.Run("should return problems for unknown routes", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/nope", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+
+		if got := rr.Header().Get("Content-Type"); got != "application/problem+json" {
+			t.Errorf("expected a problem document, got %q", got)
+		}
+	})
+
+	t.Run("should return problems for unsupported methods", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "/v1/health", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusMethodNotAllowed, rr.Code)
+
+		var problem Problem
+		if err := json.NewDecoder(rr.Body).Decode(&problem); err != nil {
+			t.Fatal(err)
+		}
+		if problem.Code != codeMethodNotAllowed {
+			t.Errorf("unexpected problem %+v", problem)
+		}
+	})
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/kuluruvineeth/social-go/internal/store"
+)
+
+// postETag returns the entity tag of a post response. It starts with the
+// version of the post, which is what If-Match is checked against, and ends
+// with a hash of the body, which also changes with the comments and
+// attachments returned alongside the post, for If-None-Match.
+func postETag(version int, body []byte) string {
+	h := fnv.New64a()
+	h.Write(body)
+	return fmt.Sprintf(`"%d-%x"`, version, h.Sum64())
+}
+
+// etagVersion returns the post version in a strong entity tag from postETag.
+func etagVersion(tag string) (int, bool) {
+	tag = strings.TrimSpace(tag)
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+
+	version, _, ok := strings.Cut(tag[1:len(tag)-1], "-")
+	if !ok {
+		return 0, false
+	}
+
+	v, err := strconv.Atoi(version)
+	if err != nil {
+		return 0, false
+	}
+
+	return v, true
+}
+
+// etagListed reports whether the entity tags in an If-None-Match header
+// include tag, comparing them weakly.
+func etagListed(header, tag string) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkIfMatch checks the If-Match precondition of a change to a post at
+// version. When the change can't go ahead, it writes the error response and
+// returns false.
+func (app *application) checkIfMatch(w http.ResponseWriter, r *http.Request, version int) bool {
+	header := strings.Join(r.Header.Values("If-Match"), ",")
+	if header == "" {
+		if app.config.posts.requireIfMatch {
+			app.preconditionRequiredError(w, r)
+			return false
+		}
+		return true
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimSpace(tag) == "*" {
+			return true
+		}
+		if v, ok := etagVersion(tag); ok && v == version {
+			return true
+		}
+	}
+
+	app.preconditionFailedError(w, r)
+	return false
+}
+
+// postResponse writes the post along with its ETag, or only a 304 Not Modified
+// when a read's If-None-Match lists it.
+func (app *application) postResponse(w http.ResponseWriter, r *http.Request, status int, post *store.Post) error {
+	type envelope struct {
+		Data any `json:"data"`
+	}
+
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(&envelope{Data: post}); err != nil {
+		return err
+	}
+
+	etag := postETag(post.Version, body.Bytes())
+	w.Header().Set("ETag", etag)
+
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		if header := strings.Join(r.Header.Values("If-None-Match"), ","); header != "" && etagListed(header, etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return nil
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, err := w.Write(body.Bytes())
+	return err
+}
@@ -1,18 +1,27 @@
 package main
 
 import (
+	"context"
 	"net/http"
+	"time"
 
-	"github.com/go-playground/validator/v10"
+	"github.com/kuluruvineeth/social-go/internal/ranking"
 	"github.com/kuluruvineeth/social-go/internal/store"
 )
 
-var validate = validator.New()
+var validate = newValidator()
+
+const (
+	// The ranked feed only considers recent posts from the viewer's network,
+	// newest first, and ranks them in memory.
+	feedCandidateWindow = 7 * 24 * time.Hour
+	maxFeedCandidates   = 500
+)
 
 // getUserFeedHandler godoc
 //
 //	@Summary		Fetches the user feed
-//	@Description	Fetches the user feed
+//	@Description	Fetches the user feed, either newest first or, with mode=for_you, ranked by recency, engagement, how often the user interacts with each author and author diversity
 //	@Tags			feed
 //	@Accept			json
 //	@Produce		json
@@ -23,9 +32,10 @@ var validate = validator.New()
 //	@Param			sort	query		string	false	"Sort"
 //	@Param			tags	query		string	false	"Tags"
 //	@Param			search	query		string	false	"Search"
+//	@Param			mode	query		string	false	"Mode (latest, for_you)"
 //	@Success		200		{object}	[]store.PostWithMetadata
-//	@Failure		400		{object}	error
-//	@Failure		500		{object}	error
+//	@Failure		400		{object}	Problem
+//	@Failure		500		{object}	Problem
 //	@Security		ApiKeyAuth
 //	@Router			/users/feed [get]
 func (app *application) getUserFeedHandler(w http.ResponseWriter, r *http.Request) {
@@ -36,6 +46,7 @@ func (app *application) getUserFeedHandler(w http.ResponseWriter, r *http.Reques
 		Sort:   "desc",
 		Tags:   []string{},
 		Search: "",
+		Mode:   store.FeedLatest,
 	}
 
 	fq, err := fq.Parse(r)
@@ -50,14 +61,72 @@ func (app *application) getUserFeedHandler(w http.ResponseWriter, r *http.Reques
 	}
 
 	ctx := r.Context()
+	user := getUserFromContext(r)
 
-	feed, err := app.store.Posts.GetUserFeed(ctx, int64(2), fq)
+	var feed []store.PostWithMetadata
+	switch {
+	case fq.Mode == store.FeedForYou:
+		feed, err = app.rankedFeed(ctx, user.ID, fq)
+	case isTimelineQuery(fq):
+		feed, err = app.store.Timelines.Get(ctx, user.ID, fq.Limit, fq.Offset)
+	default:
+		feed, err = app.store.Posts.GetUserFeed(ctx, user.ID, fq)
+	}
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
+	app.setFeedAttachmentURLs(feed)
+
 	if err := app.jsonResponse(w, http.StatusOK, feed); err != nil {
 		app.internalServerError(w, r, err)
 	}
 }
+
+// isTimelineQuery reports whether the latest feed can be served from the
+// materialized timeline, which only supports paging newest first. Filtered
+// or oldest-first feeds are queried directly.
+func isTimelineQuery(fq store.PaginatedFeedQuery) bool {
+	return fq.Sort == "desc" && fq.Search == "" && len(fq.Tags) == 0 && fq.Since == "" && fq.Until == ""
+}
+
+// rankedFeed returns the requested page of the "for you" feed. Offsets are
+// applied after ranking, so pages can shift slightly as scores decay.
+func (app *application) rankedFeed(ctx context.Context, userID int64, fq store.PaginatedFeedQuery) ([]store.PostWithMetadata, error) {
+	now := time.Now()
+
+	candidates, err := app.store.Posts.GetFeedCandidates(ctx, userID, fq, now.Add(-feedCandidateWindow), maxFeedCandidates)
+	if err != nil {
+		return nil, err
+	}
+
+	posts := make(map[int64]store.PostWithMetadata, len(candidates))
+	scored := make([]ranking.Candidate, 0, len(candidates))
+
+	for _, c := range candidates {
+		createdAt, err := time.Parse(time.RFC3339, c.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		posts[c.ID] = c.PostWithMetadata
+		scored = append(scored, ranking.Candidate{
+			PostID:    c.ID,
+			AuthorID:  c.UserID,
+			CreatedAt: createdAt,
+			Comments:  c.CommentCount,
+			Reactions: c.ReactionCount,
+			Affinity:  c.Affinity,
+		})
+	}
+
+	ranked := ranking.Rank(scored, now, ranking.DefaultWeights())
+
+	feed := []store.PostWithMetadata{}
+	for i := fq.Offset; i < len(ranked) && len(feed) < fq.Limit; i++ {
+		feed = append(feed, posts[ranked[i].PostID])
+	}
+
+	return feed, nil
+}
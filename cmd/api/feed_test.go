@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kuluruvineeth/social-go/internal/store"
+)
+
+func TestGetUserFeed(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	getFeed := func(t *testing.T, query string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, "/v1/users/feed?"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		return executeRequest(req, mux).Result()
+	}
+
+	t.Run("should reject an unknown mode", func(t *testing.T) {
+		checkResponseCode(t, http.StatusBadRequest, getFeed(t, "mode=popular").StatusCode)
+	})
+
+	t.Run("should name unparsable query parameters", func(t *testing.T) {
+		tests := []struct {
+			query string
+			want  FieldError
+		}{
+			{"limit=ten&tags=go", FieldError{Field: "limit", Rule: "int", Message: "limit must be an integer"}},
+			{"offset=1.5", FieldError{Field: "offset", Rule: "int", Message: "offset must be an integer"}},
+			{"since=yesterday", FieldError{Field: "since", Rule: "datetime", Message: "since must be a date and time like 2006-01-02 15:04:05"}},
+			{"until=2024-01-31", FieldError{Field: "until", Rule: "datetime", Message: "until must be a date and time like 2006-01-02 15:04:05"}},
+		}
+
+		for _, tt := range tests {
+			resp := getFeed(t, tt.query)
+			checkResponseCode(t, http.StatusBadRequest, resp.StatusCode)
+
+			var p struct {
+				Problem
+				Details []FieldError `json:"details"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
+				t.Fatal(err)
+			}
+			if p.Code != codeValidationFailed || !reflect.DeepEqual(p.Details, []FieldError{tt.want}) {
+				t.Errorf("%s: unexpected problem %+v", tt.query, p)
+			}
+		}
+	})
+
+	t.Run("should serve the latest feed from the timeline", func(t *testing.T) {
+		mockStore := app.store.Timelines.(*store.MockTimelineStore)
+		mockStore.On("Get", int64(1), 20, 0).Return([]store.PostWithMetadata{}, nil).Once()
+
+		checkResponseCode(t, http.StatusOK, getFeed(t, "").StatusCode)
+		mockStore.AssertExpectations(t)
+	})
+
+	t.Run("should query filtered feeds directly", func(t *testing.T) {
+		mockStore := app.store.Timelines.(*store.MockTimelineStore)
+		calls := len(mockStore.Calls)
+
+		checkResponseCode(t, http.StatusOK, getFeed(t, "tags=go").StatusCode)
+		checkResponseCode(t, http.StatusOK, getFeed(t, "sort=asc").StatusCode)
+		if len(mockStore.Calls) != calls {
+			t.Error("expected filtered feeds not to read the timeline")
+		}
+	})
+
+	t.Run("should rank the for you feed", func(t *testing.T) {
+		now := time.Now()
+		candidate := func(id, authorID int64, age time.Duration, comments, affinity int) store.FeedCandidate {
+			var c store.FeedCandidate
+			c.ID = id
+			c.UserID = authorID
+			c.CreatedAt = now.Add(-age).UTC().Format(time.RFC3339)
+			c.CommentCount = comments
+			c.Affinity = affinity
+			return c
+		}
+
+		mockStore := app.store.Posts.(*store.MockPostStore)
+		mockStore.On("GetFeedCandidates", int64(1), maxFeedCandidates).Return([]store.FeedCandidate{
+			candidate(1, 2, time.Hour, 0, 0),
+			candidate(2, 3, 2*time.Hour, 10, 5),
+			candidate(3, 4, 96*time.Hour, 0, 0),
+		}, nil).Once()
+
+		resp := getFeed(t, "mode=for_you&limit=2")
+		checkResponseCode(t, http.StatusOK, resp.StatusCode)
+
+		var body struct {
+			Data []store.PostWithMetadata `json:"data"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(body.Data) != 2 || body.Data[0].ID != 2 || body.Data[1].ID != 1 {
+			t.Errorf("unexpected feed %+v", body.Data)
+		}
+		mockStore.AssertExpectations(t)
+	})
+}
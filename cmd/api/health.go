@@ -11,7 +11,7 @@ import (
 //	@Tags			ops
 //	@Produce		json
 //	@Success		200	{object}	string	"ok"
-//	@Failure		500	{object}	error
+//	@Failure		500	{object}	Problem
 //	@Security		ApiKeyAuth
 //	@Router			/health [get]
 func (app *application) healthcheckHandler(w http.ResponseWriter, r *http.Request) {
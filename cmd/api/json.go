@@ -2,7 +2,12 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"reflect"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -10,7 +15,7 @@ import (
 var Validate *validator.Validate
 
 func init() {
-	Validate = validator.New(validator.WithRequiredStructEnabled())
+	Validate = newValidator(validator.WithRequiredStructEnabled())
 }
 
 func writeJSON(w http.ResponseWriter, status int, data any) error {
@@ -27,15 +32,68 @@ func readJSON(w http.ResponseWriter, r *http.Request, data any) error {
 	dec := json.NewDecoder(r.Body)
 	dec.DisallowUnknownFields()
 
-	return dec.Decode(data)
+	if err := dec.Decode(data); err != nil {
+		return decodeError(err)
+	}
+
+	return nil
 }
 
-func writeJSONError(w http.ResponseWriter, status int, message string) error {
-	type envelope struct {
-		Error string `json:"error"`
+// decodeError translates an error decoding a request body into the error
+// the client is sent, naming the field at fault when there is one. The
+// original error is kept, so callers can still check it with errors.Is.
+func decodeError(err error) error {
+	var (
+		syntaxErr   *json.SyntaxError
+		typeErr     *json.UnmarshalTypeError
+		maxBytesErr *http.MaxBytesError
+	)
+
+	switch {
+	case errors.As(err, &syntaxErr):
+		return &apiError{Status: http.StatusBadRequest, Code: codeMalformedJSON, Message: fmt.Sprintf("the body contains malformed JSON at character %d", syntaxErr.Offset), Err: err}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return &apiError{Status: http.StatusBadRequest, Code: codeMalformedJSON, Message: "the body contains malformed JSON", Err: err}
+	case errors.Is(err, io.EOF):
+		return &apiError{Status: http.StatusBadRequest, Code: codeBadRequest, Message: "the body must not be empty", Err: err}
+	case errors.As(err, &maxBytesErr):
+		return &apiError{Status: http.StatusRequestEntityTooLarge, Code: codePayloadTooLarge, Message: fmt.Sprintf("the body must be at most %d bytes", maxBytesErr.Limit), Err: err}
+	case errors.As(err, &typeErr):
+		if typeErr.Field == "" {
+			return &apiError{Status: http.StatusBadRequest, Code: codeMalformedJSON, Message: "the body must be " + jsonType(typeErr.Type), Err: err}
+		}
+		return invalidFieldsError(err, FieldError{Field: typeErr.Field, Rule: "type", Message: typeErr.Field + " must be " + jsonType(typeErr.Type)})
+	case strings.Contains(err.Error(), "json: unknown field "):
+		// The decoder has no error type for unknown fields.
+		_, field, _ := strings.Cut(err.Error(), "json: unknown field ")
+		field = strings.Trim(field, `"`)
+		return invalidFieldsError(err, FieldError{Field: field, Rule: "unknown", Message: field + " is not a known field"})
+	default:
+		return err
+	}
+}
+
+// jsonType describes the JSON type a Go type is decoded from.
+func jsonType(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
 	}
 
-	return writeJSON(w, status, &envelope{Error: message})
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
 }
 
 func (app *application) jsonResponse(w http.ResponseWriter, status int, data any) error {
@@ -113,6 +113,29 @@ func (app *application) checkPostOwnership(requiredRole string, next http.Handle
 	})
 }
 
+// requireRole rejects requests from users below the given role. It must run
+// after AuthTokenMiddleware.
+func (app *application) requireRole(requiredRole string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := getUserFromContext(r)
+
+			allowed, err := app.checkRolePrecedence(r.Context(), user, requiredRole)
+			if err != nil {
+				app.internalServerError(w, r, err)
+				return
+			}
+
+			if !allowed {
+				app.forbiddenError(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func (app *application) checkRolePrecedence(ctx context.Context, user *store.User, roleName string) (bool, error) {
 	role, err := app.store.Roles.GetByName(ctx, roleName)
 	if err != nil {
@@ -123,26 +146,39 @@ func (app *application) checkRolePrecedence(ctx context.Context, user *store.Use
 }
 
 func (app *application) getUser(ctx context.Context, id int64) (*store.User, error) {
-	if !app.config.redisCfg.enabled {
+	if !app.config.redisCfg.enabled && !app.config.lruCfg.enabled {
 		return app.store.Users.GetByID(ctx, id)
 	}
 
 	user, _ := app.cache.Users.Get(ctx, id)
+	if user != nil {
+		return user, nil
+	}
 
-	if user == nil {
-		user, err := app.store.Users.GetByID(ctx, id)
-		if err != nil {
-			return nil, err
-		}
+	user, err := app.store.Users.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
 
-		if err := app.cache.Users.Set(ctx, user); err != nil {
-			return nil, err
-		}
+	if err := app.cache.Users.Set(ctx, user); err != nil {
+		return nil, err
 	}
 
 	return user, nil
 }
 
+// evictUser drops the user from the cache after they changed, so that they
+// aren't served stale. Failures are only logged.
+func (app *application) evictUser(ctx context.Context, id int64) {
+	if !app.config.redisCfg.enabled && !app.config.lruCfg.enabled {
+		return
+	}
+
+	if err := app.cache.Users.Delete(ctx, id); err != nil {
+		app.logger.Errorw("failed to evict user from the cache", "user_id", id, "error", err)
+	}
+}
+
 func (app *application) RateLimiterMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if app.config.rateLimiter.Enabled {
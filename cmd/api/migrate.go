@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"os"
+
+	"github.com/kuluruvineeth/social-go/cmd/migrate/migrations"
+	"github.com/kuluruvineeth/social-go/internal/db"
+	"github.com/kuluruvineeth/social-go/internal/migrate"
+	"go.uber.org/zap"
+)
+
+// runMigrate handles `api migrate ...`, so images that only ship the API
+// binary can migrate their database before starting it.
+func runMigrate(cfg dbConfig, logger *zap.SugaredLogger, args []string) error {
+	conn, err := db.NewDB(cfg.addr, 1, 1, cfg.maxIdletime)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	m, err := migrate.New(conn, migrations.FS, logger)
+	if err != nil {
+		return err
+	}
+
+	return migrate.Run(context.Background(), m, args, os.Stdout)
+}
+
+// checkSchema returns an error when the database is missing migrations this
+// binary was built with.
+func checkSchema(ctx context.Context, conn *sql.DB, logger *zap.SugaredLogger) error {
+	m, err := migrate.New(conn, migrations.FS, logger)
+	if err != nil {
+		return err
+	}
+
+	return m.Check(ctx)
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kuluruvineeth/social-go/internal/moderation"
+	"github.com/kuluruvineeth/social-go/internal/store"
+)
+
+type CreateReportPayload struct {
+	Reason  string `json:"reason" validate:"required,oneof=spam harassment hate violence nudity other"`
+	Details string `json:"details" validate:"max=1000"`
+}
+
+type ResolveReportPayload struct {
+	Action string `json:"action" validate:"required,oneof=dismiss hide delete suspend"`
+	Note   string `json:"note" validate:"max=1000"`
+}
+
+type moderationQuery struct {
+	Limit  int `json:"limit" validate:"gte=1,lte=100"`
+	Offset int `json:"offset" validate:"gte=0"`
+}
+
+// reportPostHandler godoc
+//
+//	@Summary		Reports a post
+//	@Description	Reports a post to the moderators. Posts reported by enough users are hidden until a moderator reviews them.
+//	@Tags			moderation
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int					true	"Post ID"
+//	@Param			payload	body		CreateReportPayload	true	"Report payload"
+//	@Success		201		{object}	store.Report
+//	@Failure		400		{object}	Problem
+//	@Failure		404		{object}	Problem
+//	@Failure		409		{object}	Problem	"Already reported"
+//	@Failure		500		{object}	Problem
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/reports [post]
+func (app *application) reportPostHandler(w http.ResponseWriter, r *http.Request) {
+	app.createReport(w, r, nil)
+}
+
+// reportCommentHandler godoc
+//
+//	@Summary		Reports a comment
+//	@Description	Reports a comment on a post to the moderators. Comments reported by enough users are hidden until a moderator reviews them.
+//	@Tags			moderation
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int					true	"Post ID"
+//	@Param			commentID	path		int					true	"Comment ID"
+//	@Param			payload		body		CreateReportPayload	true	"Report payload"
+//	@Success		201			{object}	store.Report
+//	@Failure		400			{object}	Problem
+//	@Failure		404			{object}	Problem
+//	@Failure		409			{object}	Problem	"Already reported"
+//	@Failure		500			{object}	Problem
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/comments/{commentID}/reports [post]
+func (app *application) reportCommentHandler(w http.ResponseWriter, r *http.Request) {
+	commentID, err := strconv.ParseInt(chi.URLParam(r, "commentID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	app.createReport(w, r, &commentID)
+}
+
+func (app *application) createReport(w http.ResponseWriter, r *http.Request, commentID *int64) {
+	var payload CreateReportPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+	post := getPostFromCtx(r)
+
+	report := &store.Report{
+		ReporterID: &user.ID,
+		PostID:     post.ID,
+		CommentID:  commentID,
+		Reason:     payload.Reason,
+		Details:    payload.Details,
+	}
+
+	hidden, err := app.store.Reports.Create(r.Context(), report, app.config.moderation.hideThreshold)
+	if err != nil {
+		app.errorResponse(w, r, err)
+		return
+	}
+
+	if hidden {
+		app.logger.Infow("reported content hidden for review", "post_id", post.ID, "comment_id", commentID)
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, report); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getModerationQueueHandler godoc
+//
+//	@Summary		Fetches the moderation queue
+//	@Description	Lists the posts and comments with open reports, the most reported first. Restricted to moderators and admins.
+//	@Tags			moderation
+//	@Produce		json
+//	@Param			limit	query		int	false	"Limit"
+//	@Param			offset	query		int	false	"Offset"
+//	@Success		200		{object}	[]store.ReviewItem
+//	@Failure		400		{object}	Problem
+//	@Failure		403		{object}	Problem
+//	@Failure		500		{object}	Problem
+//	@Security		ApiKeyAuth
+//	@Router			/moderation/reports [get]
+func (app *application) getModerationQueueHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	q := moderationQuery{Limit: 20}
+
+	if limit := qs.Get("limit"); limit != "" {
+		l, err := store.ParseIntParam("limit", limit)
+		if err != nil {
+			app.badRequestError(w, r, err)
+			return
+		}
+		q.Limit = l
+	}
+
+	if offset := qs.Get("offset"); offset != "" {
+		o, err := store.ParseIntParam("offset", offset)
+		if err != nil {
+			app.badRequestError(w, r, err)
+			return
+		}
+		q.Offset = o
+	}
+
+	if err := validate.Struct(q); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	items, err := app.store.Reports.Queue(r.Context(), q.Limit, q.Offset)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, items); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// resolveReportHandler godoc
+//
+//	@Summary		Resolves a report
+//	@Description	Takes action on the content of an open report, which resolves every open report about it: dismiss the reports and unhide the content, hide it, delete it, or hide it and suspend its author. Moderators can only suspend users with a lower role. Restricted to moderators and admins.
+//	@Tags			moderation
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int						true	"Report ID"
+//	@Param			payload	body		ResolveReportPayload	true	"Action"
+//	@Success		200		{object}	store.ModerationAction
+//	@Failure		400		{object}	Problem
+//	@Failure		403		{object}	Problem
+//	@Failure		404		{object}	Problem	"Report not found or already resolved"
+//	@Failure		500		{object}	Problem
+//	@Security		ApiKeyAuth
+//	@Router			/moderation/reports/{id}/resolve [post]
+func (app *application) resolveReportHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "reportID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	var payload ResolveReportPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	user := getUserFromContext(r)
+
+	if payload.Action == store.ModerationSuspend {
+		allowed, err := app.canSuspendAuthor(ctx, user, id)
+		if err != nil {
+			app.errorResponse(w, r, err)
+			return
+		}
+		if !allowed {
+			app.forbiddenError(w, r)
+			return
+		}
+	}
+
+	action, err := app.store.Reports.Resolve(ctx, id, user.ID, payload.Action, payload.Note)
+	if err != nil {
+		app.errorResponse(w, r, err)
+		return
+	}
+
+	for _, a := range action.DeletedAttachments {
+		app.deleteBlobs(a.Key, a.ThumbnailKey)
+	}
+
+	if action.Action == store.ModerationSuspend {
+		// Suspended users must not be served from the cache
+		app.evictUser(ctx, action.AuthorID)
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, action); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// canSuspendAuthor reports whether user outranks the author of the reported
+// content. Authors that are already suspended or gone can't be looked up,
+// and suspending them again is harmless.
+func (app *application) canSuspendAuthor(ctx context.Context, user *store.User, reportID int64) (bool, error) {
+	report, err := app.store.Reports.GetByID(ctx, reportID)
+	if err != nil {
+		return false, err
+	}
+
+	author, err := app.store.Users.GetByID(ctx, report.AuthorID)
+	if errors.Is(err, store.ErrNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return user.Role.Level > author.Role.Level, nil
+}
+
+// checkContent runs the content filter over new content.
+func (app *application) checkContent(ctx context.Context, text string) (moderation.Result, error) {
+	if app.contentFilter == nil {
+		return moderation.Result{Verdict: moderation.Allow}, nil
+	}
+
+	return app.contentFilter.Check(ctx, text)
+}
+
+// flagPost queues a post the content filter flagged for review. The post is
+// saved by then, so failures are only logged.
+func (app *application) flagPost(ctx context.Context, post *store.Post, reason string) {
+	report := &store.Report{PostID: post.ID, Reason: store.ReportFilter, Details: reason}
+
+	if _, err := app.store.Reports.Create(ctx, report, app.config.moderation.hideThreshold); err != nil {
+		app.logger.Errorw("failed to flag post for review", "post_id", post.ID, "error", err)
+	}
+}
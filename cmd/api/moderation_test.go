@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/kuluruvineeth/social-go/internal/moderation"
+	"github.com/kuluruvineeth/social-go/internal/store"
+)
+
+func TestReports(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(t *testing.T, method, url, body string) *http.Request {
+		t.Helper()
+
+		req, err := http.NewRequest(method, url, bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		return req
+	}
+
+	mockStore := app.store.Reports.(*store.MockReportStore)
+
+	t.Run("should report a post", func(t *testing.T) {
+		mockStore.On("Create", int64(7), (*int64)(nil), "spam").Return(true, nil).Once()
+
+		rr := executeRequest(request(t, http.MethodPost, "/v1/posts/7/reports", `{"reason":"spam"}`), mux)
+		checkResponseCode(t, http.StatusCreated, rr.Code)
+		mockStore.AssertExpectations(t)
+	})
+
+	t.Run("should report a comment", func(t *testing.T) {
+		commentID := int64(3)
+		mockStore.On("Create", int64(7), &commentID, "harassment").Return(false, nil).Once()
+
+		rr := executeRequest(request(t, http.MethodPost, "/v1/posts/7/comments/3/reports", `{"reason":"harassment"}`), mux)
+		checkResponseCode(t, http.StatusCreated, rr.Code)
+		mockStore.AssertExpectations(t)
+	})
+
+	t.Run("should return conflict for a second report", func(t *testing.T) {
+		mockStore.On("Create", int64(7), (*int64)(nil), "spam").Return(false, store.ErrConflict).Once()
+
+		rr := executeRequest(request(t, http.MethodPost, "/v1/posts/7/reports", `{"reason":"spam"}`), mux)
+		checkResponseCode(t, http.StatusConflict, rr.Code)
+	})
+
+	t.Run("should return not found for a comment on another post", func(t *testing.T) {
+		commentID := int64(4)
+		mockStore.On("Create", int64(7), &commentID, "spam").Return(false, store.ErrNotFound).Once()
+
+		rr := executeRequest(request(t, http.MethodPost, "/v1/posts/7/comments/4/reports", `{"reason":"spam"}`), mux)
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("should reject an unknown reason", func(t *testing.T) {
+		rr := executeRequest(request(t, http.MethodPost, "/v1/posts/7/reports", `{"reason":"filter"}`), mux)
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestModerationQueue(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(t *testing.T, method, url, body string) *http.Request {
+		t.Helper()
+
+		req, err := http.NewRequest(method, url, bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		return req
+	}
+
+	users := app.store.Users.(*store.MockUserStore)
+	mockStore := app.store.Reports.(*store.MockReportStore)
+
+	t.Run("should not allow users", func(t *testing.T) {
+		users.Users = map[int64]*store.User{1: {ID: 1, Role: store.Role{Name: "user", Level: 1}}}
+
+		rr := executeRequest(request(t, http.MethodGet, "/v1/moderation/reports", ""), mux)
+		checkResponseCode(t, http.StatusForbidden, rr.Code)
+
+		rr = executeRequest(request(t, http.MethodPost, "/v1/moderation/reports/5/resolve", `{"action":"hide"}`), mux)
+		checkResponseCode(t, http.StatusForbidden, rr.Code)
+	})
+
+	users.Users = map[int64]*store.User{1: {ID: 1, Role: store.Role{Name: "moderator", Level: 2}}}
+
+	t.Run("should list the queue for moderators", func(t *testing.T) {
+		rr := executeRequest(request(t, http.MethodGet, "/v1/moderation/reports?limit=10", ""), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		rr = executeRequest(request(t, http.MethodGet, "/v1/moderation/reports?limit=500", ""), mux)
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should resolve a report", func(t *testing.T) {
+		mockStore.On("Resolve", int64(5), "hide").Return(&store.ModerationAction{ID: 1, Action: "hide", PostID: 7, AuthorID: 2, Reports: 3}, nil).Once()
+
+		rr := executeRequest(request(t, http.MethodPost, "/v1/moderation/reports/5/resolve", `{"action":"hide"}`), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+		mockStore.AssertExpectations(t)
+	})
+
+	t.Run("should return not found for a resolved report", func(t *testing.T) {
+		mockStore.On("Resolve", int64(6), "dismiss").Return(nil, store.ErrNotFound).Once()
+
+		rr := executeRequest(request(t, http.MethodPost, "/v1/moderation/reports/6/resolve", `{"action":"dismiss"}`), mux)
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("should reject an unknown action", func(t *testing.T) {
+		rr := executeRequest(request(t, http.MethodPost, "/v1/moderation/reports/5/resolve", `{"action":"ban"}`), mux)
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should suspend authors with a lower role", func(t *testing.T) {
+		mockStore.On("GetByID", int64(5)).Return(&store.Report{ID: 5, PostID: 7, AuthorID: 2}, nil).Once()
+		mockStore.On("Resolve", int64(5), "suspend").Return(&store.ModerationAction{ID: 2, Action: "suspend", PostID: 7, AuthorID: 2, Reports: 1}, nil).Once()
+
+		rr := executeRequest(request(t, http.MethodPost, "/v1/moderation/reports/5/resolve", `{"action":"suspend"}`), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+		mockStore.AssertExpectations(t)
+	})
+
+	t.Run("should not suspend authors with the same role", func(t *testing.T) {
+		users.Users[3] = &store.User{ID: 3, Role: store.Role{Name: "moderator", Level: 2}}
+		mockStore.On("GetByID", int64(8)).Return(&store.Report{ID: 8, PostID: 9, AuthorID: 3}, nil).Once()
+
+		rr := executeRequest(request(t, http.MethodPost, "/v1/moderation/reports/8/resolve", `{"action":"suspend"}`), mux)
+		checkResponseCode(t, http.StatusForbidden, rr.Code)
+		mockStore.AssertNotCalled(t, "Resolve", int64(8), "suspend")
+	})
+}
+
+func TestContentFilter(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	app.contentFilter = moderation.Chain{
+		moderation.NewWordList([]string{"scam"}, moderation.Reject),
+		moderation.NewWordList([]string{"crypto"}, moderation.Flag),
+	}
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(t *testing.T, body string) *http.Request {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodPost, "/v1/posts", bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		return req
+	}
+
+	mockStore := app.store.Reports.(*store.MockReportStore)
+
+	t.Run("should reject banned words", func(t *testing.T) {
+		rr := executeRequest(request(t, `{"title":"Offer","content":"Not a SCAM, promise"}`), mux)
+		checkResponseCode(t, http.StatusUnprocessableEntity, rr.Code)
+	})
+
+	t.Run("should queue flagged posts for review", func(t *testing.T) {
+		mockStore.On("Create", int64(0), (*int64)(nil), store.ReportFilter).Return(false, nil).Once()
+
+		rr := executeRequest(request(t, `{"title":"Crypto","content":"Thoughts on the market"}`), mux)
+		checkResponseCode(t, http.StatusCreated, rr.Code)
+		mockStore.AssertExpectations(t)
+	})
+
+	t.Run("should allow other posts", func(t *testing.T) {
+		rr := executeRequest(request(t, `{"title":"Hello","content":"A scampi recipe"}`), mux)
+		checkResponseCode(t, http.StatusCreated, rr.Code)
+	})
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"slices"
+
+	"github.com/kuluruvineeth/social-go/internal/store"
+)
+
+type NotificationsResponse struct {
+	Notifications []store.Notification `json:"notifications"`
+	UnreadCount   int                  `json:"unread_count"`
+}
+
+type MarkNotificationsReadPayload struct {
+	IDs []int64 `json:"ids" validate:"required_without=All,max=100"`
+	All bool    `json:"all"`
+}
+
+type UpdateNotificationPreferencePayload struct {
+	Type  string `json:"type" validate:"required,oneof=follow comment mention"`
+	Email *bool  `json:"email" validate:"required"`
+}
+
+// getNotificationsHandler godoc
+//
+//	@Summary		Fetches notifications
+//	@Description	Fetches the authenticated user's notifications, newest first
+//	@Tags			notifications
+//	@Produce		json
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			offset	query		int		false	"Offset"
+//	@Param			unread	query		bool	false	"Only unread notifications"
+//	@Success		200		{object}	NotificationsResponse
+//	@Failure		400		{object}	Problem
+//	@Failure		401		{object}	Problem
+//	@Failure		500		{object}	Problem
+//	@Security		ApiKeyAuth
+//	@Router			/notifications [get]
+func (app *application) getNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	q := store.NotificationQuery{
+		Limit:  20,
+		Offset: 0,
+	}
+
+	q, err := q.Parse(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := validate.Struct(q); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+	ctx := r.Context()
+
+	notifications, err := app.store.Notifications.GetByUserID(ctx, user.ID, q)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	unread, err := app.store.Notifications.CountUnread(ctx, user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	res := NotificationsResponse{
+		Notifications: notifications,
+		UnreadCount:   unread,
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, res); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// markNotificationsReadHandler godoc
+//
+//	@Summary		Marks notifications as read
+//	@Description	Marks the given notifications, or all of them when "all" is set, as read
+//	@Tags			notifications
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		MarkNotificationsReadPayload	true	"Notifications to mark"
+//	@Success		200		{object}	map[string]int64
+//	@Failure		400		{object}	Problem
+//	@Failure		401		{object}	Problem
+//	@Failure		500		{object}	Problem
+//	@Security		ApiKeyAuth
+//	@Router			/notifications/read [post]
+func (app *application) markNotificationsReadHandler(w http.ResponseWriter, r *http.Request) {
+	var payload MarkNotificationsReadPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	ids := payload.IDs
+	if payload.All {
+		ids = nil
+	}
+
+	user := getUserFromContext(r)
+
+	updated, err := app.store.Notifications.MarkRead(r.Context(), user.ID, ids)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, map[string]int64{"updated": updated}); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getNotificationPreferencesHandler godoc
+//
+//	@Summary		Fetches notification preferences
+//	@Description	Fetches whether the authenticated user gets emailed for each notification type
+//	@Tags			notifications
+//	@Produce		json
+//	@Success		200	{object}	[]store.NotificationPreference
+//	@Failure		401	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Security		ApiKeyAuth
+//	@Router			/notifications/preferences [get]
+func (app *application) getNotificationPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	prefs, err := app.store.Notifications.GetPreferences(r.Context(), user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, prefs); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// updateNotificationPreferenceHandler godoc
+//
+//	@Summary		Updates a notification preference
+//	@Description	Enables or disables email delivery for a notification type
+//	@Tags			notifications
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		UpdateNotificationPreferencePayload	true	"Preference"
+//	@Success		200		{object}	store.NotificationPreference
+//	@Failure		400		{object}	Problem
+//	@Failure		401		{object}	Problem
+//	@Failure		500		{object}	Problem
+//	@Security		ApiKeyAuth
+//	@Router			/notifications/preferences [put]
+func (app *application) updateNotificationPreferenceHandler(w http.ResponseWriter, r *http.Request) {
+	var payload UpdateNotificationPreferencePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+	pref := store.NotificationPreference{
+		Type:  payload.Type,
+		Email: *payload.Email,
+	}
+
+	if err := app.store.Notifications.SetPreference(r.Context(), user.ID, pref); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, pref); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// notify records a notification without failing the request that caused it.
+func (app *application) notify(ctx context.Context, n *store.Notification) {
+	if err := app.notifier.Notify(ctx, n); err != nil {
+		app.logger.Errorw("failed to send notification", "type", n.Type, "user_id", n.UserID, "error", err)
+	}
+}
+
+// notifyMentions notifies the users mentioned in a post or comment, except
+// for those in skip, like users that were already mentioned before an edit,
+// and those who can't see the post.
+func (app *application) notifyMentions(ctx context.Context, actorID int64, post *store.Post, commentID *int64, mentions []store.Mention, skip []int64) {
+	mentions = app.visibleMentions(ctx, post, mentions)
+
+	for _, m := range mentions {
+		if slices.Contains(skip, m.UserID) {
+			continue
+		}
+
+		app.notify(ctx, &store.Notification{
+			UserID:    m.UserID,
+			ActorID:   actorID,
+			Type:      store.NotificationMention,
+			PostID:    &post.ID,
+			CommentID: commentID,
+		})
+	}
+}
+
+// visibleMentions drops the mentions of users who can't see the post: all of
+// them for private posts, and those who don't follow the author for posts
+// only followers see.
+func (app *application) visibleMentions(ctx context.Context, post *store.Post, mentions []store.Mention) []store.Mention {
+	switch post.Visibility {
+	case store.VisibilityPrivate:
+		return nil
+	case store.VisibilityFollowers:
+		if len(mentions) == 0 {
+			return nil
+		}
+
+		ids := make([]int64, len(mentions))
+		for i, m := range mentions {
+			ids[i] = m.UserID
+		}
+
+		followers, err := app.store.Followers.FollowersAmong(ctx, post.UserID, ids)
+		if err != nil {
+			app.logger.Errorw("failed to load the followers mentioned in a post", "post_id", post.ID, "error", err)
+			return nil
+		}
+
+		return slices.DeleteFunc(slices.Clone(mentions), func(m store.Mention) bool {
+			return !slices.Contains(followers, m.UserID)
+		})
+	default:
+		return mentions
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/kuluruvineeth/social-go/internal/store"
+)
+
+func TestNotifications(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("should not allow unauthenticated requests", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/notifications", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("should list notifications", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/notifications?unread=true&limit=10", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("should reject an invalid limit", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/notifications?limit=500", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should mark all notifications as read", func(t *testing.T) {
+		mockStore := app.store.Notifications.(*store.MockNotificationStore)
+		mockStore.On("MarkRead", int64(1), []int64(nil)).Return(int64(3), nil).Once()
+
+		req, err := http.NewRequest(http.MethodPost, "/v1/notifications/read", bytes.NewBufferString(`{"all": true}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+		mockStore.AssertExpectations(t)
+	})
+
+	t.Run("should require ids unless marking all as read", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "/v1/notifications/read", bytes.NewBufferString(`{}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestVisibleMentions(t *testing.T) {
+	app := newTestApplication(t, config{})
+	app.store.Followers.(*store.MockFollowerStore).On("FollowersAmong", int64(1), []int64{2, 3}).Return([]int64{3}, nil)
+
+	mentions := []store.Mention{{UserID: 2, Username: "bob"}, {UserID: 3, Username: "carol"}}
+
+	tests := []struct {
+		visibility string
+		want       []store.Mention
+	}{
+		{store.VisibilityPublic, mentions},
+		{store.VisibilityFollowers, []store.Mention{{UserID: 3, Username: "carol"}}},
+		{store.VisibilityPrivate, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.visibility, func(t *testing.T) {
+			post := &store.Post{ID: 7, UserID: 1, Visibility: tt.visibility}
+
+			if got := app.visibleMentions(context.Background(), post, mentions); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kuluruvineeth/social-go/internal/store"
+)
+
+type outboxQuery struct {
+	Status string `json:"status" validate:"omitempty,oneof=pending sent dead"`
+	Limit  int    `json:"limit" validate:"gte=1,lte=100"`
+	Offset int    `json:"offset" validate:"gte=0"`
+}
+
+// listOutboxHandler godoc
+//
+//	@Summary		Lists outbox messages
+//	@Description	Lists queued, sent and dead-lettered emails. Restricted to admins.
+//	@Tags			admin
+//	@Produce		json
+//	@Param			status	query		string	false	"Status (pending, sent, dead)"
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			offset	query		int		false	"Offset"
+//	@Success		200		{object}	[]store.OutboxMessage
+//	@Failure		400		{object}	Problem
+//	@Failure		403		{object}	Problem
+//	@Failure		500		{object}	Problem
+//	@Security		ApiKeyAuth
+//	@Router			/admin/outbox [get]
+func (app *application) listOutboxHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	q := outboxQuery{
+		Status: qs.Get("status"),
+		Limit:  20,
+	}
+
+	if limit := qs.Get("limit"); limit != "" {
+		l, err := store.ParseIntParam("limit", limit)
+		if err != nil {
+			app.badRequestError(w, r, err)
+			return
+		}
+		q.Limit = l
+	}
+
+	if offset := qs.Get("offset"); offset != "" {
+		o, err := store.ParseIntParam("offset", offset)
+		if err != nil {
+			app.badRequestError(w, r, err)
+			return
+		}
+		q.Offset = o
+	}
+
+	if err := validate.Struct(q); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	msgs, err := app.store.Outbox.List(r.Context(), q.Status, q.Limit, q.Offset)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, msgs); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getOutboxMessageHandler godoc
+//
+//	@Summary		Fetches an outbox message
+//	@Description	Fetches an outbox message by ID. Restricted to admins.
+//	@Tags			admin
+//	@Produce		json
+//	@Param			id	path		int	true	"Message ID"
+//	@Success		200	{object}	store.OutboxMessage
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Security		ApiKeyAuth
+//	@Router			/admin/outbox/{id} [get]
+func (app *application) getOutboxMessageHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "messageID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	msg, err := app.store.Outbox.GetByID(r.Context(), id)
+	if err != nil {
+		app.errorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, msg); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// retryOutboxMessageHandler godoc
+//
+//	@Summary		Retries a dead-lettered email
+//	@Description	Moves a dead-lettered outbox message back to pending. Restricted to admins.
+//	@Tags			admin
+//	@Produce		json
+//	@Param			id	path	int	true	"Message ID"
+//	@Success		204	"No Content"
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem	"Message not found or not dead-lettered"
+//	@Failure		500	{object}	Problem
+//	@Security		ApiKeyAuth
+//	@Router			/admin/outbox/{id}/retry [post]
+func (app *application) retryOutboxMessageHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "messageID"), 10, 64)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := app.store.Outbox.Retry(r.Context(), id); err != nil {
+		app.errorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
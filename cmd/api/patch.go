@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+
+	"github.com/kuluruvineeth/social-go/internal/patch"
+)
+
+const (
+	mergePatchType = "application/merge-patch+json"
+	jsonPatchType  = "application/json-patch+json"
+)
+
+var errUnsupportedPatchType = fmt.Errorf("patches must be %s or %s", mergePatchType, jsonPatchType)
+
+// readPatch applies the patch in the request body to the JSON encoding of
+// doc, and decodes the result back into doc. The body is a JSON Patch when
+// its Content-Type says so, and a JSON Merge Patch otherwise, which plain
+// application/json bodies are taken as. Members the result can't have are an
+// error, like with readJSON.
+func readPatch(w http.ResponseWriter, r *http.Request, doc any) error {
+	apply := patch.Merge
+
+	if header := r.Header.Get("Content-Type"); header != "" {
+		mediaType, _, err := mime.ParseMediaType(header)
+		if err != nil {
+			return errUnsupportedPatchType
+		}
+
+		switch mediaType {
+		case mergePatchType, "application/json":
+		case jsonPatchType:
+			apply = patch.Apply
+		default:
+			return errUnsupportedPatchType
+		}
+	}
+
+	maxBytes := 1_048_578 // 1MB
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, int64(maxBytes)))
+	if err != nil {
+		return decodeError(err)
+	}
+
+	original, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	patched, err := apply(original, body)
+	if err != nil {
+		return err
+	}
+
+	// Members the patch removed must end up empty rather than as they were.
+	reflect.ValueOf(doc).Elem().SetZero()
+
+	dec := json.NewDecoder(bytes.NewReader(patched))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(doc); err != nil {
+		return decodeError(fmt.Errorf("%w: %w", patch.ErrInvalidPatch, err))
+	}
+
+	return nil
+}
+
+// patchError writes the response for an error from readPatch.
+func (app *application) patchError(w http.ResponseWriter, r *http.Request, err error) {
+	var maxBytesErr *http.MaxBytesError
+
+	switch {
+	case errors.Is(err, errUnsupportedPatchType):
+		app.unsupportedMediaTypeError(w, r, err)
+	case errors.Is(err, patch.ErrTestFailed):
+		app.editConflictError(w, r)
+	case errors.Is(err, patch.ErrInvalidPatch), errors.As(err, &maxBytesErr):
+		app.badRequestError(w, r, err)
+	default:
+		app.internalServerError(w, r, err)
+	}
+}
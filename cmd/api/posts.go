@@ -1,12 +1,19 @@
 package main
 
 import (
+	"cmp"
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/kuluruvineeth/social-go/internal/content"
+	"github.com/kuluruvineeth/social-go/internal/moderation"
+	"github.com/kuluruvineeth/social-go/internal/realtime"
 	"github.com/kuluruvineeth/social-go/internal/store"
 )
 
@@ -15,23 +22,27 @@ type postKey string
 const postCtxKey postKey = "post"
 
 type CreatePostPayload struct {
-	Title   string   `json:"title" validate:"required,max=100"`
-	Content string   `json:"content" validate:"required,max=1000"`
-	Tags    []string `json:"tags" validate:"required,min=1,max=5"`
+	Title      string     `json:"title" validate:"required,max=100"`
+	Content    string     `json:"content" validate:"required,max=1000"`
+	Tags       []string   `json:"tags" validate:"max=5"`
+	Status     string     `json:"status" validate:"omitempty,oneof=draft scheduled published"`
+	Visibility string     `json:"visibility" validate:"omitempty,oneof=public followers private"`
+	PublishAt  *time.Time `json:"publish_at"`
 }
 
 // CreatePost godoc
 //
 //	@Summary		Creates a post
-//	@Description	Creates a post
+//	@Description	Creates a post. #hashtags in the content are added to its tags and @mentioned users who can see the post are notified once it's published. Posts are published right away unless their status is draft, or scheduled with a future publish_at. Visibility is public, followers or private. Posts the content filters reject get a 422, and the ones they flag are queued for moderation.
 //	@Tags			posts
 //	@Accept			json
 //	@Produce		json
 //	@Param			payload	body		CreatePostPayload	true	"Post payload"
 //	@Success		201		{object}	store.Post
-//	@Failure		400		{object}	error
-//	@Failure		401		{object}	error
-//	@Failure		500		{object}	error
+//	@Failure		400		{object}	Problem
+//	@Failure		401		{object}	Problem
+//	@Failure		422		{object}	Problem
+//	@Failure		500		{object}	Problem
 //	@Security		ApiKeyAuth
 //	@Router			/posts [post]
 func (app *application) createPostHandler(w http.ResponseWriter, r *http.Request) {
@@ -46,22 +57,57 @@ func (app *application) createPostHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	user := getUserFromContext(r)
+	tags, err := postTags(payload.Content, payload.Tags)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
 
-	post := &store.Post{
-		Title:   payload.Title,
-		Content: payload.Content,
-		Tags:    payload.Tags,
-		UserID:  user.ID,
+	status := cmp.Or(payload.Status, store.PostPublished)
+
+	publishAt, err := postSchedule(status, payload.PublishAt, true)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
 	}
 
 	ctx := r.Context()
 
+	result, err := app.checkContent(ctx, payload.Title+"\n"+payload.Content)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if result.Verdict == moderation.Reject {
+		app.unprocessableEntityError(w, r, fmt.Errorf("the post was rejected: it %s", result.Reason))
+		return
+	}
+
+	user := getUserFromContext(r)
+
+	post := &store.Post{
+		Title:      payload.Title,
+		Content:    payload.Content,
+		Tags:       tags,
+		UserID:     user.ID,
+		Status:     status,
+		Visibility: cmp.Or(payload.Visibility, store.VisibilityPublic),
+		PublishAt:  publishAt,
+	}
+
 	if err := app.store.Posts.Create(ctx, post); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
+	if result.Verdict == moderation.Flag {
+		app.flagPost(ctx, post, result.Reason)
+	}
+
+	if post.Status == store.PostPublished {
+		app.postPublished(ctx, post)
+	}
+
 	if err := app.jsonResponse(w, http.StatusCreated, post); err != nil {
 		app.internalServerError(w, r, err)
 		return
@@ -71,14 +117,16 @@ func (app *application) createPostHandler(w http.ResponseWriter, r *http.Request
 // GetPost godoc
 //
 //	@Summary		Fetches a post
-//	@Description	Fetches a post by ID
+//	@Description	Fetches a post by ID. The response has an ETag to send as If-Match when updating the post, or as If-None-Match to get a 304 Not Modified while it's unchanged.
 //	@Tags			posts
 //	@Accept			json
 //	@Produce		json
-//	@Param			id	path		int	true	"Post ID"
-//	@Success		200	{object}	store.Post
-//	@Failure		404	{object}	error
-//	@Failure		500	{object}	error
+//	@Param			id				path		int		true	"Post ID"
+//	@Param			If-None-Match	header		string	false	"ETag of a cached copy"
+//	@Success		200				{object}	store.Post
+//	@Success		304				"Not Modified"
+//	@Failure		404				{object}	Problem
+//	@Failure		500	{object}	Problem
 //	@Security		ApiKeyAuth
 //	@Router			/posts/{id} [get]
 func (app *application) getPostHandler(w http.ResponseWriter, r *http.Request) {
@@ -91,8 +139,9 @@ func (app *application) getPostHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	post.Comments = comments
+	app.setAttachmentURLs(post.Attachments)
 
-	if err := app.jsonResponse(w, http.StatusOK, post); err != nil {
+	if err := app.postResponse(w, r, http.StatusOK, post); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
@@ -102,14 +151,14 @@ func (app *application) getPostHandler(w http.ResponseWriter, r *http.Request) {
 // DeletePost godoc
 //
 //	@Summary		Deletes a post
-//	@Description	Deletes a post by ID
+//	@Description	Deletes a post by ID, along with its images
 //	@Tags			posts
 //	@Accept			json
 //	@Produce		json
 //	@Param			id	path		int	true	"Post ID"
 //	@Success		200	{object}	map[string]string
-//	@Failure		404	{object}	error
-//	@Failure		500	{object}	error
+//	@Failure		404	{object}	Problem
+//	@Failure		500	{object}	Problem
 //	@Security		ApiKeyAuth
 //	@Router			/posts/{id} [delete]
 func (app *application) deletePostHandler(w http.ResponseWriter, r *http.Request) {
@@ -124,48 +173,89 @@ func (app *application) deletePostHandler(w http.ResponseWriter, r *http.Request
 	ctx := r.Context()
 
 	if err := app.store.Posts.Delete(ctx, id); err != nil {
-		switch {
-		case errors.Is(err, store.ErrNotFound):
-			app.notFoundError(w, r)
-		default:
-			app.internalServerError(w, r, err)
-		}
+		app.errorResponse(w, r, err)
 		return
 	}
 
+	// The attachment rows went with the post
+	post := getPostFromCtx(r)
+	for _, a := range post.Attachments {
+		app.deleteBlobs(a.Key, a.ThumbnailKey)
+	}
+
 	if err := app.jsonResponse(w, http.StatusOK, map[string]string{"message": "Post deleted successfully"}); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 }
 
+// UpdatePostPayload is the part of a post that updates patch. Tags include
+// the hashtags in the content, which can't be removed from them.
 type UpdatePostPayload struct {
-	Title   string `json:"title" validate:"max=100"`
-	Content string `json:"content" validate:"max=1000"`
+	Title      string     `json:"title" validate:"required,max=100"`
+	Content    string     `json:"content" validate:"required,max=1000"`
+	Tags       []string   `json:"tags"`
+	Status     string     `json:"status" validate:"required,oneof=draft scheduled published"`
+	Visibility string     `json:"visibility" validate:"required,oneof=public followers private"`
+	PublishAt  *time.Time `json:"publish_at"`
+}
+
+// postFieldRoles is the role it takes to change each field of someone else's
+// post, on top of the moderator role needed to update it at all. Authors can
+// change every field.
+var postFieldRoles = []struct {
+	field string
+	role  string
+}{
+	{"title", "moderator"},
+	{"tags", "moderator"},
+	{"content", "admin"},
+	{"status", "admin"},
+	{"visibility", "admin"},
+	{"publish_at", "admin"},
 }
 
 // UpdatePost godoc
 //
 //	@Summary		Updates a post
-//	@Description	Updates a post by ID
+//	@Description	Patches the title, content, tags, status, visibility and publish_at of a post with a JSON Merge Patch (RFC 7396), or a JSON Patch (RFC 6902) sent as application/json-patch+json. Moderators can change the title and tags of other users' posts, and admins the rest. Drafts and scheduled posts can be published, but published posts can't go back. Send the ETag the post was fetched with as If-Match, to get a 412 Precondition Failed instead of overwriting changes made since.
 //	@Tags			posts
 //	@Accept			json
+//	@Accept			application/merge-patch+json
+//	@Accept			application/json-patch+json
 //	@Produce		json
-//	@Param			id		path		int					true	"Post ID"
-//	@Param			payload	body		UpdatePostPayload	true	"Post payload"
-//	@Success		200		{object}	store.Post
-//	@Failure		400		{object}	error
-//	@Failure		401		{object}	error
-//	@Failure		404		{object}	error
-//	@Failure		500		{object}	error
+//	@Param			id			path		int					true	"Post ID"
+//	@Param			If-Match	header		string				false	"ETag of the edited version"
+//	@Param			payload		body		UpdatePostPayload	true	"Merge patch of the post"
+//	@Success		200			{object}	store.Post
+//	@Failure		400			{object}	Problem
+//	@Failure		401			{object}	Problem
+//	@Failure		403			{object}	Problem
+//	@Failure		404			{object}	Problem
+//	@Failure		409			{object}	Problem
+//	@Failure		412			{object}	Problem
+//	@Failure		415			{object}	Problem
+//	@Failure		428			{object}	Problem
+//	@Failure		500			{object}	Problem
 //	@Security		ApiKeyAuth
 //	@Router			/posts/{id} [patch]
 func (app *application) updatePostHandler(w http.ResponseWriter, r *http.Request) {
 	post := getPostFromCtx(r)
 
-	var payload UpdatePostPayload
-	if err := readJSON(w, r, &payload); err != nil {
-		app.badRequestError(w, r, err)
+	if !app.checkIfMatch(w, r, post.Version) {
+		return
+	}
+
+	payload := UpdatePostPayload{
+		Title:      post.Title,
+		Content:    post.Content,
+		Tags:       post.Tags,
+		Status:     post.Status,
+		Visibility: post.Visibility,
+		PublishAt:  post.PublishAt,
+	}
+	if err := readPatch(w, r, &payload); err != nil {
+		app.patchError(w, r, err)
 		return
 	}
 
@@ -174,24 +264,179 @@ func (app *application) updatePostHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	if payload.Content != "" {
-		post.Content = payload.Content
+	if post.Status == store.PostPublished && payload.Status != store.PostPublished {
+		app.badRequestError(w, r, errors.New("published posts can't be unpublished"))
+		return
 	}
 
-	if payload.Title != "" {
-		post.Title = payload.Title
+	publishAtChanged := !timesEqual(payload.PublishAt, post.PublishAt)
+
+	publishAt, err := postSchedule(payload.Status, payload.PublishAt, payload.Status != post.Status || publishAtChanged)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
 	}
 
-	if err := app.store.Posts.Update(r.Context(), post); err != nil {
+	ctx := r.Context()
+	user := getUserFromContext(r)
+
+	tagsChanged := !slices.Equal(payload.Tags, post.Tags)
+	changed := map[string]bool{
+		"title":      payload.Title != post.Title,
+		"content":    payload.Content != post.Content,
+		"tags":       tagsChanged,
+		"status":     payload.Status != post.Status,
+		"visibility": payload.Visibility != post.Visibility,
+		"publish_at": publishAtChanged,
+	}
+
+	allowed, err := app.canChangePostFields(ctx, user, post, changed)
+	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
+	if !allowed {
+		app.forbiddenError(w, r)
+		return
+	}
+
+	explicitTags := payload.Tags
+	if !tagsChanged {
+		// hashtags that are no longer in the content are dropped from the tags
+		explicitTags = content.Without(post.Tags, content.Parse(post.Content).Hashtags)
+	}
 
-	if err := app.jsonResponse(w, http.StatusOK, post); err != nil {
+	tags, err := postTags(payload.Content, explicitTags)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	previous := post.Mentions
+	wasPublished := post.Status == store.PostPublished
+	post.Title = payload.Title
+	post.Content = payload.Content
+	post.Tags = tags
+	post.Status = payload.Status
+	post.Visibility = payload.Visibility
+	post.PublishAt = publishAt
+
+	if err := app.store.Posts.Update(ctx, post, user.ID); err != nil {
+		app.errorResponse(w, r, err)
+		return
+	}
+
+	switch {
+	case !wasPublished && post.Status == store.PostPublished:
+		app.postPublished(ctx, post)
+	case wasPublished:
+		skip := make([]int64, len(previous))
+		for i, m := range previous {
+			skip[i] = m.UserID
+		}
+		app.notifyMentions(ctx, user.ID, post, nil, post.Mentions, skip)
+	}
+
+	app.setAttachmentURLs(post.Attachments)
+
+	if err := app.postResponse(w, r, http.StatusOK, post); err != nil {
 		app.internalServerError(w, r, err)
 	}
 }
 
+// canChangePostFields reports whether user may change the given fields of
+// post, following postFieldRoles.
+func (app *application) canChangePostFields(ctx context.Context, user *store.User, post *store.Post, changed map[string]bool) (bool, error) {
+	if post.UserID == user.ID {
+		return true, nil
+	}
+
+	for _, f := range postFieldRoles {
+		if !changed[f.field] {
+			continue
+		}
+
+		allowed, err := app.checkRolePrecedence(ctx, user, f.role)
+		if err != nil || !allowed {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// postSchedule returns the publish time of a post with the given status.
+// Scheduled posts need one, which must be in the future when it's set or the
+// post is newly scheduled, and other posts have none.
+func postSchedule(status string, publishAt *time.Time, rescheduled bool) (*time.Time, error) {
+	if status != store.PostScheduled {
+		return nil, nil
+	}
+
+	if publishAt == nil {
+		return nil, errors.New("scheduled posts need a publish_at")
+	}
+
+	if rescheduled && !publishAt.After(time.Now()) {
+		return nil, errors.New("publish_at must be in the future")
+	}
+
+	return publishAt, nil
+}
+
+func timesEqual(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// postPublished notifies the users a post mentions and streams it to the
+// author's followers, once it's published. Private posts stay quiet.
+func (app *application) postPublished(ctx context.Context, post *store.Post) {
+	if post.Visibility == store.VisibilityPrivate {
+		return
+	}
+
+	app.notifyMentions(ctx, post.UserID, post, nil, post.Mentions, nil)
+
+	app.publish(ctx, realtime.AuthorTopic(post.UserID), realtime.EventPost, post)
+}
+
+// scheduledPostPublished is called by the scheduler for each post it
+// publishes. The post is loaded again for its mentions and attachments.
+func (app *application) scheduledPostPublished(ctx context.Context, p store.Post) {
+	post, err := app.store.Posts.GetByID(ctx, p.ID, p.UserID)
+	if err != nil {
+		app.logger.Errorw("failed to load scheduled post", "post_id", p.ID, "error", err)
+		return
+	}
+
+	app.setAttachmentURLs(post.Attachments)
+	app.postPublished(ctx, post)
+}
+
+// postTags merges the explicit tags of a post with the hashtags in its
+// content, and checks the content doesn't mention too many users.
+func postTags(text string, explicit []string) ([]string, error) {
+	entities := content.Parse(text)
+
+	if len(entities.Mentions) > content.MaxMentions {
+		return nil, fmt.Errorf("a post can mention at most %d users", content.MaxMentions)
+	}
+
+	tags, err := content.MergeTags(explicit, entities.Hashtags)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tags) > content.MaxTags {
+		return nil, fmt.Errorf("a post can have at most %d tags", content.MaxTags)
+	}
+
+	return tags, nil
+}
+
 func (app *application) postsContextMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		idParam := chi.URLParam(r, "postID")
@@ -202,15 +447,12 @@ func (app *application) postsContextMiddleware(next http.Handler) http.Handler {
 			return
 		}
 		ctx := r.Context()
+		user := getUserFromContext(r)
 
-		post, err := app.store.Posts.GetByID(ctx, id)
+		// Posts the user can't see are missing as far as they're concerned
+		post, err := app.store.Posts.GetByID(ctx, id, user.ID)
 		if err != nil {
-			switch {
-			case errors.Is(err, store.ErrNotFound):
-				app.notFoundError(w, r)
-			default:
-				app.internalServerError(w, r, err)
-			}
+			app.errorResponse(w, r, err)
 			return
 		}
 
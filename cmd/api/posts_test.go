@@ -0,0 +1,329 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kuluruvineeth/social-go/internal/store"
+)
+
+func TestPostPreconditions(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(t *testing.T, method string, headers map[string]string) *http.Request {
+		t.Helper()
+
+		req, err := http.NewRequest(method, "/v1/posts/7", strings.NewReader(`{"title":"edited","content":"edited"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		return req
+	}
+
+	mockStore := app.store.Posts.(*store.MockPostStore)
+
+	rr := executeRequest(request(t, http.MethodGet, nil), mux)
+	checkResponseCode(t, http.StatusOK, rr.Code)
+
+	etag := rr.Header().Get("ETag")
+	if !strings.HasPrefix(etag, `"0-`) {
+		t.Fatalf("expected an ETag for version 0, got %q", etag)
+	}
+
+	t.Run("should return 304 for an unchanged post", func(t *testing.T) {
+		rr := executeRequest(request(t, http.MethodGet, map[string]string{"If-None-Match": `"other", W/` + etag}), mux)
+		checkResponseCode(t, http.StatusNotModified, rr.Code)
+
+		if rr.Body.Len() != 0 {
+			t.Errorf("expected no body, got %q", rr.Body.String())
+		}
+		if rr.Header().Get("ETag") != etag {
+			t.Errorf("expected the ETag %s, got %s", etag, rr.Header().Get("ETag"))
+		}
+	})
+
+	t.Run("should return the post for another ETag", func(t *testing.T) {
+		rr := executeRequest(request(t, http.MethodGet, map[string]string{"If-None-Match": `"0-123"`}), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("should update the version in If-Match", func(t *testing.T) {
+		mockStore.On("Update", int64(7), 0).Return(nil).Once()
+
+		rr := executeRequest(request(t, http.MethodPatch, map[string]string{"If-Match": etag}), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+		mockStore.AssertExpectations(t)
+
+		if got := rr.Header().Get("ETag"); !strings.HasPrefix(got, `"1-`) {
+			t.Errorf("expected an ETag for version 1, got %q", got)
+		}
+	})
+
+	t.Run("should reject a stale If-Match", func(t *testing.T) {
+		rr := executeRequest(request(t, http.MethodPatch, map[string]string{"If-Match": `"3-abc"`}), mux)
+		checkResponseCode(t, http.StatusPreconditionFailed, rr.Code)
+	})
+
+	t.Run("should return 409 when the post changes during the update", func(t *testing.T) {
+		mockStore.On("Update", int64(7), 0).Return(store.ErrVersionConflict).Once()
+
+		rr := executeRequest(request(t, http.MethodPatch, nil), mux)
+		checkResponseCode(t, http.StatusConflict, rr.Code)
+		mockStore.AssertExpectations(t)
+	})
+
+	t.Run("should require If-Match when configured", func(t *testing.T) {
+		app := newTestApplication(t, config{posts: postsConfig{requireIfMatch: true}})
+
+		rr := executeRequest(request(t, http.MethodPatch, nil), app.mount())
+		checkResponseCode(t, http.StatusPreconditionRequired, rr.Code)
+	})
+}
+
+func TestETagVersion(t *testing.T) {
+	tests := []struct {
+		tag     string
+		version int
+		ok      bool
+	}{
+		{tag: `"12-abc"`, version: 12, ok: true},
+		{tag: ` "0-1" `, version: 0, ok: true},
+		{tag: `W/"12-abc"`},
+		{tag: `"abc"`},
+		{tag: `12-abc`},
+		{tag: `"`},
+	}
+
+	for _, tt := range tests {
+		version, ok := etagVersion(tt.tag)
+		if version != tt.version || ok != tt.ok {
+			t.Errorf("%s: expected %d, %v, got %d, %v", tt.tag, tt.version, tt.ok, version, ok)
+		}
+	}
+}
+
+func TestUpdatePost(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	patch := func(t *testing.T, contentType, body string) *http.Request {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodPatch, "/v1/posts/7", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		return req
+	}
+
+	decode := func(t *testing.T, rr *httptest.ResponseRecorder) store.Post {
+		t.Helper()
+
+		var resp struct {
+			Data store.Post `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp.Data
+	}
+
+	mockStore := app.store.Posts.(*store.MockPostStore)
+
+	t.Run("should apply a merge patch", func(t *testing.T) {
+		mockStore.On("Update", int64(7), 0).Return(nil).Once()
+
+		rr := executeRequest(patch(t, mergePatchType, `{"title":"Hi","content":"hello #go","tags":["db"]}`), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+		mockStore.AssertExpectations(t)
+
+		p := decode(t, rr)
+		if p.Title != "Hi" || p.Content != "hello #go" || !reflect.DeepEqual(p.Tags, []string{"db", "go"}) {
+			t.Errorf("unexpected post %+v", p)
+		}
+	})
+
+	t.Run("should take plain JSON as a merge patch", func(t *testing.T) {
+		mockStore.On("Update", int64(7), 0).Return(nil).Once()
+
+		rr := executeRequest(patch(t, "application/json; charset=utf-8", `{"title":"Hi","content":"hello","tags":null}`), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+		mockStore.AssertExpectations(t)
+
+		if p := decode(t, rr); len(p.Tags) != 0 {
+			t.Errorf("expected the tags to be cleared, got %v", p.Tags)
+		}
+	})
+
+	t.Run("should apply a JSON patch", func(t *testing.T) {
+		mockStore.On("Update", int64(7), 0).Return(nil).Once()
+
+		body := `[
+			{"op":"test","path":"/tags","value":[]},
+			{"op":"replace","path":"/title","value":"Hi"},
+			{"op":"replace","path":"/content","value":"hello"},
+			{"op":"add","path":"/tags/-","value":"sql"}
+		]`
+		rr := executeRequest(patch(t, jsonPatchType, body), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+		mockStore.AssertExpectations(t)
+
+		if p := decode(t, rr); !reflect.DeepEqual(p.Tags, []string{"sql"}) {
+			t.Errorf("expected the sql tag, got %v", p.Tags)
+		}
+	})
+
+	t.Run("should return 409 for a failed JSON patch test", func(t *testing.T) {
+		rr := executeRequest(patch(t, jsonPatchType, `[{"op":"test","path":"/title","value":"Old"}]`), mux)
+		checkResponseCode(t, http.StatusConflict, rr.Code)
+	})
+
+	t.Run("should validate the patched post", func(t *testing.T) {
+		rr := executeRequest(patch(t, mergePatchType, `{"title":"Hi","content":null}`), mux)
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should reject fields posts don't have", func(t *testing.T) {
+		rr := executeRequest(patch(t, mergePatchType, `{"title":"Hi","content":"hello","user_id":2}`), mux)
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should reject other content types", func(t *testing.T) {
+		rr := executeRequest(patch(t, "text/plain", `title=Hi`), mux)
+		checkResponseCode(t, http.StatusUnsupportedMediaType, rr.Code)
+	})
+
+	t.Run("should change the visibility", func(t *testing.T) {
+		mockStore.On("Update", int64(7), 0).Return(nil).Once()
+
+		rr := executeRequest(patch(t, mergePatchType, `{"title":"Hi","content":"hello","visibility":"followers"}`), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+		mockStore.AssertExpectations(t)
+
+		if p := decode(t, rr); p.Visibility != store.VisibilityFollowers {
+			t.Errorf("expected followers visibility, got %q", p.Visibility)
+		}
+	})
+
+	t.Run("should not unpublish posts", func(t *testing.T) {
+		rr := executeRequest(patch(t, mergePatchType, `{"title":"Hi","content":"hello","status":"draft"}`), mux)
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestCreatePost(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		body string
+		code int
+	}{
+		{"published", `{"title":"Hi","content":"hello"}`, http.StatusCreated},
+		{"draft", `{"title":"Hi","content":"hello","status":"draft","visibility":"private"}`, http.StatusCreated},
+		{"scheduled", `{"title":"Hi","content":"hello","status":"scheduled","publish_at":"2999-01-01T00:00:00Z"}`, http.StatusCreated},
+		{"scheduled in the past", `{"title":"Hi","content":"hello","status":"scheduled","publish_at":"2000-01-01T00:00:00Z"}`, http.StatusBadRequest},
+		{"scheduled without a time", `{"title":"Hi","content":"hello","status":"scheduled"}`, http.StatusBadRequest},
+		{"unknown visibility", `{"title":"Hi","content":"hello","visibility":"friends"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/v1/posts", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Authorization", "Bearer "+testToken)
+
+			rr := executeRequest(req, mux)
+			checkResponseCode(t, tt.code, rr.Code)
+		})
+	}
+}
+
+func TestPostSchedule(t *testing.T) {
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+
+	if at, err := postSchedule(store.PostDraft, &future, true); err != nil || at != nil {
+		t.Errorf("expected drafts to have no publish time, got %v and %v", at, err)
+	}
+	if at, err := postSchedule(store.PostScheduled, &future, true); err != nil || at != &future {
+		t.Errorf("expected the future publish time, got %v and %v", at, err)
+	}
+	if _, err := postSchedule(store.PostScheduled, &past, true); err == nil {
+		t.Error("expected an error for a past publish time")
+	}
+	if _, err := postSchedule(store.PostScheduled, &past, false); err != nil {
+		t.Errorf("expected an unchanged publish time to be kept, got %v", err)
+	}
+	if _, err := postSchedule(store.PostScheduled, nil, false); err == nil {
+		t.Error("expected an error for a scheduled post without a publish time")
+	}
+}
+
+func TestCanChangePostFields(t *testing.T) {
+	app := newTestApplication(t, config{})
+
+	post := &store.Post{ID: 7, UserID: 2}
+	author := &store.User{ID: 2, Role: store.Role{Level: 1}}
+	moderator := &store.User{ID: 3, Role: store.Role{Level: 2}}
+	admin := &store.User{ID: 4, Role: store.Role{Level: 3}}
+
+	tests := []struct {
+		name    string
+		user    *store.User
+		changed map[string]bool
+		allowed bool
+	}{
+		{"author changes everything", author, map[string]bool{"title": true, "content": true, "tags": true}, true},
+		{"moderator retitles and retags", moderator, map[string]bool{"title": true, "tags": true}, true},
+		{"moderator rewrites", moderator, map[string]bool{"title": true, "content": true}, false},
+		{"admin rewrites", admin, map[string]bool{"content": true}, true},
+		{"moderator changes nothing", moderator, map[string]bool{"content": false}, true},
+		{"moderator hides", moderator, map[string]bool{"visibility": true}, false},
+		{"admin hides", admin, map[string]bool{"visibility": true}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, err := app.canChangePostFields(context.Background(), tt.user, post, tt.changed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if allowed != tt.allowed {
+				t.Errorf("expected %v, got %v", tt.allowed, allowed)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+)
+
+type ReactPayload struct {
+	Type string `json:"type" validate:"required,oneof=like love laugh wow sad angry"`
+}
+
+// reactToPostHandler godoc
+//
+//	@Summary		Reacts to a post
+//	@Description	Sets the authenticated user's reaction to a post, replacing any earlier one
+//	@Tags			posts
+//	@Accept			json
+//	@Param			id		path	int				true	"Post ID"
+//	@Param			payload	body	ReactPayload	true	"Reaction"
+//	@Success		204		"No Content"
+//	@Failure		400		{object}	Problem
+//	@Failure		401		{object}	Problem
+//	@Failure		404		{object}	Problem
+//	@Failure		500		{object}	Problem
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/reactions [put]
+func (app *application) reactToPostHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ReactPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+	post := getPostFromCtx(r)
+
+	if err := app.store.Reactions.Set(r.Context(), post.ID, user.ID, payload.Type); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// deletePostReactionHandler godoc
+//
+//	@Summary		Removes a reaction
+//	@Description	Removes the authenticated user's reaction to a post
+//	@Tags			posts
+//	@Param			id	path	int	true	"Post ID"
+//	@Success		204	"No Content"
+//	@Failure		401	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/reactions [delete]
+func (app *application) deletePostReactionHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	post := getPostFromCtx(r)
+
+	if err := app.store.Reactions.Delete(r.Context(), post.ID, user.ID); err != nil {
+		app.errorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
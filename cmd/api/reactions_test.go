@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/kuluruvineeth/social-go/internal/store"
+)
+
+func TestReactions(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("should react to a post", func(t *testing.T) {
+		mockStore := app.store.Reactions.(*store.MockReactionStore)
+		mockStore.On("Set", int64(7), int64(1), "love").Return(nil).Once()
+
+		req, err := http.NewRequest(http.MethodPut, "/v1/posts/7/reactions", bytes.NewBufferString(`{"type": "love"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusNoContent, rr.Code)
+		mockStore.AssertExpectations(t)
+	})
+
+	t.Run("should reject an unknown reaction", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPut, "/v1/posts/7/reactions", bytes.NewBufferString(`{"type": "meh"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should return not found when there is no reaction to remove", func(t *testing.T) {
+		mockStore := app.store.Reactions.(*store.MockReactionStore)
+		mockStore.On("Delete", int64(7), int64(1)).Return(store.ErrNotFound).Once()
+
+		req, err := http.NewRequest(http.MethodDelete, "/v1/posts/7/reactions", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/kuluruvineeth/social-go/internal/store"
+)
+
+type RepostPayload struct {
+	Quote string `json:"quote" validate:"max=500"`
+}
+
+// repostHandler godoc
+//
+//	@Summary		Reposts a post
+//	@Description	Shares a published post with the authenticated user's followers, optionally quoting it. Reposts show up in their feeds attributed to the reposter.
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int				true	"Post ID"
+//	@Param			payload	body		RepostPayload	false	"Quote"
+//	@Success		201		{object}	store.Repost
+//	@Failure		400		{object}	Problem
+//	@Failure		401		{object}	Problem
+//	@Failure		404		{object}	Problem
+//	@Failure		409		{object}	Problem	"Already reposted"
+//	@Failure		500		{object}	Problem
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/reposts [post]
+func (app *application) repostHandler(w http.ResponseWriter, r *http.Request) {
+	// The body can be left out for reposts without a quote.
+	var payload RepostPayload
+	if err := readJSON(w, r, &payload); err != nil && !errors.Is(err, io.EOF) {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+	post := getPostFromCtx(r)
+
+	repost := &store.Repost{
+		UserID: user.ID,
+		PostID: post.ID,
+		Quote:  payload.Quote,
+		User:   *user,
+	}
+
+	if err := app.store.Reposts.Create(r.Context(), repost); err != nil {
+		app.errorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, repost); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// deleteRepostHandler godoc
+//
+//	@Summary		Removes a repost
+//	@Description	Removes the authenticated user's repost of a post
+//	@Tags			posts
+//	@Param			id	path	int	true	"Post ID"
+//	@Success		204	"No Content"
+//	@Failure		401	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/reposts [delete]
+func (app *application) deleteRepostHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	post := getPostFromCtx(r)
+
+	if err := app.store.Reposts.Delete(r.Context(), user.ID, post.ID); err != nil {
+		app.errorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/kuluruvineeth/social-go/internal/store"
+)
+
+func TestReposts(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(t *testing.T, method string, body io.Reader) *http.Request {
+		t.Helper()
+
+		req, err := http.NewRequest(method, "/v1/posts/7/reposts", body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		return req
+	}
+
+	mockStore := app.store.Reposts.(*store.MockRepostStore)
+
+	t.Run("should repost with a quote", func(t *testing.T) {
+		mockStore.On("Create", int64(1), int64(7), "worth a read").Return(nil).Once()
+
+		rr := executeRequest(request(t, http.MethodPost, strings.NewReader(`{"quote":"worth a read"}`)), mux)
+		checkResponseCode(t, http.StatusCreated, rr.Code)
+		mockStore.AssertExpectations(t)
+	})
+
+	t.Run("should repost without a body", func(t *testing.T) {
+		mockStore.On("Create", int64(1), int64(7), "").Return(nil).Once()
+
+		rr := executeRequest(request(t, http.MethodPost, http.NoBody), mux)
+		checkResponseCode(t, http.StatusCreated, rr.Code)
+		mockStore.AssertExpectations(t)
+	})
+
+	t.Run("should return conflict for a second repost", func(t *testing.T) {
+		mockStore.On("Create", int64(1), int64(7), "").Return(store.ErrConflict).Once()
+
+		rr := executeRequest(request(t, http.MethodPost, http.NoBody), mux)
+		checkResponseCode(t, http.StatusConflict, rr.Code)
+	})
+
+	t.Run("should reject long quotes", func(t *testing.T) {
+		rr := executeRequest(request(t, http.MethodPost, strings.NewReader(`{"quote":"`+strings.Repeat("a", 501)+`"}`)), mux)
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should remove a repost", func(t *testing.T) {
+		mockStore.On("Delete", int64(1), int64(7)).Return(nil).Once()
+
+		rr := executeRequest(request(t, http.MethodDelete, http.NoBody), mux)
+		checkResponseCode(t, http.StatusNoContent, rr.Code)
+		mockStore.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kuluruvineeth/social-go/internal/diff"
+	"github.com/kuluruvineeth/social-go/internal/store"
+)
+
+// PostDiff is what changed in a post between two of its versions.
+type PostDiff struct {
+	From        int         `json:"from"`
+	To          int         `json:"to"`
+	Title       []diff.Edit `json:"title"`
+	Content     []diff.Edit `json:"content"`
+	TagsAdded   []string    `json:"tags_added"`
+	TagsRemoved []string    `json:"tags_removed"`
+}
+
+// getPostRevisionsHandler godoc
+//
+//	@Summary		Lists the versions of a post
+//	@Description	Lists every saved version of a post, newest first, including the current one.
+//	@Tags			posts
+//	@Produce		json
+//	@Param			id	path		int	true	"Post ID"
+//	@Success		200	{array}		store.PostRevision
+//	@Failure		401	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/revisions [get]
+func (app *application) getPostRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+
+	revisions, err := app.store.Revisions.GetByPostID(r.Context(), post.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, revisions); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getPostRevisionHandler godoc
+//
+//	@Summary		Fetches a version of a post
+//	@Tags			posts
+//	@Produce		json
+//	@Param			id		path		int	true	"Post ID"
+//	@Param			version	path		int	true	"Version"
+//	@Success		200		{object}	store.PostRevision
+//	@Failure		400		{object}	Problem
+//	@Failure		401		{object}	Problem
+//	@Failure		404		{object}	Problem
+//	@Failure		500		{object}	Problem
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/revisions/{version} [get]
+func (app *application) getPostRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	revision, ok := app.getRevision(w, r, chi.URLParam(r, "version"))
+	if !ok {
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, revision); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getPostDiffHandler godoc
+//
+//	@Summary		Compares two versions of a post
+//	@Description	Returns the word by word changes to the title and content, and the tags added and removed, going from one version to another. to defaults to the current version and from to the one before to.
+//	@Tags			posts
+//	@Produce		json
+//	@Param			id		path		int	true	"Post ID"
+//	@Param			from	query		int	false	"Old version"
+//	@Param			to		query		int	false	"New version"
+//	@Success		200		{object}	PostDiff
+//	@Failure		400		{object}	Problem
+//	@Failure		401		{object}	Problem
+//	@Failure		404		{object}	Problem
+//	@Failure		500		{object}	Problem
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/revisions/diff [get]
+func (app *application) getPostDiffHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+	qs := r.URL.Query()
+
+	to := qs.Get("to")
+	if to == "" {
+		to = strconv.Itoa(post.Version)
+	}
+
+	newer, ok := app.getRevision(w, r, to)
+	if !ok {
+		return
+	}
+
+	from := qs.Get("from")
+	if from == "" {
+		from = strconv.Itoa(max(newer.Version-1, 0))
+	}
+
+	older, ok := app.getRevision(w, r, from)
+	if !ok {
+		return
+	}
+
+	tagsAdded, tagsRemoved := diff.Sets(older.Tags, newer.Tags)
+
+	d := PostDiff{
+		From:        older.Version,
+		To:          newer.Version,
+		Title:       diff.Words(older.Title, newer.Title),
+		Content:     diff.Words(older.Content, newer.Content),
+		TagsAdded:   tagsAdded,
+		TagsRemoved: tagsRemoved,
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, d); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// revertPostHandler godoc
+//
+//	@Summary		Reverts a post to a previous version
+//	@Description	Saves the title, content and tags of a previous version as the post's next version, so the history is kept. Moderators can revert the title and tags of other users' posts, and admins the content.
+//	@Tags			posts
+//	@Produce		json
+//	@Param			id			path		int		true	"Post ID"
+//	@Param			version		path		int		true	"Version"
+//	@Param			If-Match	header		string	false	"ETag of the current version"
+//	@Success		200			{object}	store.Post
+//	@Failure		400			{object}	Problem
+//	@Failure		401			{object}	Problem
+//	@Failure		403			{object}	Problem
+//	@Failure		404			{object}	Problem
+//	@Failure		409			{object}	Problem
+//	@Failure		412			{object}	Problem
+//	@Failure		428			{object}	Problem
+//	@Failure		500			{object}	Problem
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{id}/revisions/{version}/revert [post]
+func (app *application) revertPostHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+
+	if !app.checkIfMatch(w, r, post.Version) {
+		return
+	}
+
+	revision, ok := app.getRevision(w, r, chi.URLParam(r, "version"))
+	if !ok {
+		return
+	}
+
+	if revision.Version == post.Version {
+		app.badRequestError(w, r, fmt.Errorf("the post is already at version %d", post.Version))
+		return
+	}
+
+	ctx := r.Context()
+	user := getUserFromContext(r)
+
+	changed := map[string]bool{
+		"title":   revision.Title != post.Title,
+		"content": revision.Content != post.Content,
+		"tags":    !slices.Equal(revision.Tags, post.Tags),
+	}
+
+	allowed, err := app.canChangePostFields(ctx, user, post, changed)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if !allowed {
+		app.forbiddenError(w, r)
+		return
+	}
+
+	// the limits may have changed since the revision was saved
+	tags, err := postTags(revision.Content, revision.Tags)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	previous := post.Mentions
+	post.Title = revision.Title
+	post.Content = revision.Content
+	post.Tags = tags
+
+	if err := app.store.Posts.Update(ctx, post, user.ID); err != nil {
+		app.errorResponse(w, r, err)
+		return
+	}
+
+	if post.Status == store.PostPublished {
+		skip := make([]int64, len(previous))
+		for i, m := range previous {
+			skip[i] = m.UserID
+		}
+		app.notifyMentions(ctx, user.ID, post, nil, post.Mentions, skip)
+	}
+
+	app.setAttachmentURLs(post.Attachments)
+
+	if err := app.postResponse(w, r, http.StatusOK, post); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getRevision loads the given version of the post in the context, and writes
+// the error response when it can't.
+func (app *application) getRevision(w http.ResponseWriter, r *http.Request, param string) (*store.PostRevision, bool) {
+	post := getPostFromCtx(r)
+
+	version, err := strconv.Atoi(param)
+	if err != nil || version < 0 {
+		app.badRequestError(w, r, fmt.Errorf("invalid version %q", param))
+		return nil, false
+	}
+
+	revision, err := app.store.Revisions.Get(r.Context(), post.ID, version)
+	if err != nil {
+		app.errorResponse(w, r, err)
+		return nil, false
+	}
+
+	return revision, true
+}
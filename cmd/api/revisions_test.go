@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/kuluruvineeth/social-go/internal/diff"
+	"github.com/kuluruvineeth/social-go/internal/store"
+)
+
+func TestPostRevisions(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(t *testing.T, method, url string) *http.Request {
+		t.Helper()
+
+		req, err := http.NewRequest(method, url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		return req
+	}
+
+	mockStore := app.store.Revisions.(*store.MockRevisionStore)
+
+	first := &store.PostRevision{PostID: 7, Version: 0, Title: "Hello", Content: "going to #gophercon", Tags: []string{"gophercon"}}
+	second := &store.PostRevision{PostID: 7, Version: 1, Title: "Hello", Content: "going to #gophercon with @bob", Tags: []string{"gophercon", "go"}}
+
+	t.Run("should list the revisions", func(t *testing.T) {
+		mockStore.On("GetByPostID", int64(7)).Return([]store.PostRevision{*second, *first}, nil).Once()
+
+		rr := executeRequest(request(t, http.MethodGet, "/v1/posts/7/revisions"), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+		mockStore.AssertExpectations(t)
+	})
+
+	t.Run("should compare two versions", func(t *testing.T) {
+		mockStore.On("Get", int64(7), 1).Return(second, nil).Once()
+		mockStore.On("Get", int64(7), 0).Return(first, nil).Once()
+
+		rr := executeRequest(request(t, http.MethodGet, "/v1/posts/7/revisions/diff?from=0&to=1"), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+		mockStore.AssertExpectations(t)
+
+		var resp struct {
+			Data PostDiff `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+
+		d := resp.Data
+		wantContent := []diff.Edit{{Op: diff.Equal, Text: "going to #gophercon"}, {Op: diff.Insert, Text: " with @bob"}}
+		if d.From != 0 || d.To != 1 || !reflect.DeepEqual(d.Content, wantContent) {
+			t.Errorf("unexpected diff %+v", d)
+		}
+		if !reflect.DeepEqual(d.TagsAdded, []string{"go"}) || len(d.TagsRemoved) != 0 {
+			t.Errorf("unexpected tag changes %v and %v", d.TagsAdded, d.TagsRemoved)
+		}
+	})
+
+	t.Run("should return 404 for a missing version", func(t *testing.T) {
+		mockStore.On("Get", int64(7), 9).Return(nil, store.ErrNotFound).Once()
+
+		rr := executeRequest(request(t, http.MethodGet, "/v1/posts/7/revisions/9"), mux)
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+		mockStore.AssertExpectations(t)
+	})
+
+	t.Run("should reject an invalid version", func(t *testing.T) {
+		rr := executeRequest(request(t, http.MethodGet, "/v1/posts/7/revisions/latest"), mux)
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should save a previous version as the next one", func(t *testing.T) {
+		app.store.Posts.(*store.MockPostStore).On("Update", int64(7), 0).Return(nil).Once()
+		mockStore.On("Get", int64(7), 3).Return(&store.PostRevision{PostID: 7, Version: 3, Title: "Old title", Content: "old content", Tags: []string{"old"}}, nil).Once()
+
+		rr := executeRequest(request(t, http.MethodPost, "/v1/posts/7/revisions/3/revert"), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+		mockStore.AssertExpectations(t)
+
+		var resp struct {
+			Data store.Post `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+
+		p := resp.Data
+		if p.Title != "Old title" || p.Content != "old content" || p.Version != 1 || !reflect.DeepEqual(p.Tags, []string{"old"}) {
+			t.Errorf("unexpected post %+v", p)
+		}
+	})
+
+	t.Run("should only let moderators revert the title and tags of other users' posts", func(t *testing.T) {
+		posts := app.store.Posts.(*store.MockPostStore)
+		posts.Posts = map[int64]*store.Post{8: {ID: 8, UserID: 2, Version: 2, Title: "Hello", Content: "going to #gophercon", Tags: []string{"gophercon"}, Mentions: []store.Mention{}, Status: store.PostPublished}}
+		app.store.Users.(*store.MockUserStore).Users = map[int64]*store.User{1: {ID: 1, Role: store.Role{Name: "moderator", Level: 2}}}
+
+		mockStore.On("Get", int64(8), 0).Return(&store.PostRevision{PostID: 8, Version: 0, Title: "Hello", Content: "going to #gophercon with @bob", Tags: []string{"gophercon"}}, nil).Once()
+
+		rr := executeRequest(request(t, http.MethodPost, "/v1/posts/8/revisions/0/revert"), mux)
+		checkResponseCode(t, http.StatusForbidden, rr.Code)
+		posts.AssertNotCalled(t, "Update", int64(8), 2)
+
+		posts.On("Update", int64(8), 2).Return(nil).Once()
+		mockStore.On("Get", int64(8), 1).Return(&store.PostRevision{PostID: 8, Version: 1, Title: "Hi", Content: "going to #gophercon", Tags: []string{"gophercon", "go"}}, nil).Once()
+
+		rr = executeRequest(request(t, http.MethodPost, "/v1/posts/8/revisions/1/revert"), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+		mockStore.AssertExpectations(t)
+		posts.AssertExpectations(t)
+	})
+
+	t.Run("should not revert to the current version", func(t *testing.T) {
+		mockStore.On("Get", int64(7), 0).Return(first, nil).Once()
+
+		rr := executeRequest(request(t, http.MethodPost, "/v1/posts/7/revisions/0/revert"), mux)
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+		mockStore.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/kuluruvineeth/social-go/internal/content"
+	"github.com/kuluruvineeth/social-go/internal/store"
+)
+
+type SearchResponse struct {
+	Posts    []store.PostSearchResult    `json:"posts"`
+	Comments []store.CommentSearchResult `json:"comments"`
+	Users    []store.UserSearchResult    `json:"users"`
+}
+
+// searchHandler godoc
+//
+//	@Summary		Searches posts, comments and users
+//	@Description	Full-text search ranked by relevance. Snippets are HTML escaped with matches wrapped in <mark> tags. Without a type, each kind of result is searched and limited separately; kinds that were not searched are null.
+//	@Tags			search
+//	@Produce		json
+//	@Param			q		query		string	true	"Search terms, supports quoted phrases, OR and -exclusions"
+//	@Param			type	query		string	false	"Result type (posts, comments, users)"
+//	@Param			author	query		string	false	"Author username"
+//	@Param			tag		query		string	false	"Tag"
+//	@Param			since	query		string	false	"Since (YYYY-MM-DD or RFC 3339)"
+//	@Param			until	query		string	false	"Until (YYYY-MM-DD or RFC 3339)"
+//	@Param			lang	query		string	false	"Language (en, es), defaults to the user's locale"
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			offset	query		int		false	"Offset"
+//	@Success		200		{object}	SearchResponse
+//	@Failure		400		{object}	Problem
+//	@Failure		401		{object}	Problem
+//	@Failure		500		{object}	Problem
+//	@Security		ApiKeyAuth
+//	@Router			/search [get]
+func (app *application) searchHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	q := store.SearchQuery{
+		Language: searchLanguage(user.Locale),
+		Limit:    10,
+	}
+
+	q, err := q.Parse(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := validate.Struct(q); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if q.Tag != "" {
+		if q.Tag, err = content.NormalizeTag(q.Tag); err != nil {
+			app.badRequestError(w, r, err)
+			return
+		}
+	}
+
+	ctx := r.Context()
+	var res SearchResponse
+
+	if q.Type == "" || q.Type == store.SearchPosts {
+		if res.Posts, err = app.store.Search.Posts(ctx, q); err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	if q.Type == "" || q.Type == store.SearchComments {
+		if res.Comments, err = app.store.Search.Comments(ctx, q); err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	if q.Type == "" || q.Type == store.SearchUsers {
+		if res.Users, err = app.store.Search.Users(ctx, q); err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, res); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// searchLanguage picks the search language for a locale like "es-MX",
+// falling back to English.
+func searchLanguage(locale string) string {
+	lang, _, _ := strings.Cut(strings.ToLower(locale), "-")
+	if _, ok := store.SearchLanguages[lang]; ok {
+		return lang
+	}
+
+	return "en"
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/kuluruvineeth/social-go/internal/store"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSearch(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	search := func(t *testing.T, query string) int {
+		req, err := http.NewRequest(http.MethodGet, "/v1/search?"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		return executeRequest(req, mux).Code
+	}
+
+	t.Run("should search only the requested type", func(t *testing.T) {
+		mockStore := app.store.Search.(*store.MockSearchStore)
+		mockStore.On("Posts", mock.MatchedBy(func(q store.SearchQuery) bool {
+			return q.Query == "golang tips" && q.Tag == "web_dev" && q.Language == "en" && q.Since != nil
+		})).Return([]store.PostSearchResult{{ID: 1}}, nil).Once()
+
+		code := search(t, "q=golang+tips&type=posts&tag=Web+Dev&since=2024-01-31")
+		checkResponseCode(t, http.StatusOK, code)
+		mockStore.AssertExpectations(t)
+	})
+
+	t.Run("should search every type by default", func(t *testing.T) {
+		mockStore := app.store.Search.(*store.MockSearchStore)
+		mockStore.On("Posts", mock.Anything).Return([]store.PostSearchResult{}, nil).Once()
+		mockStore.On("Comments", mock.Anything).Return([]store.CommentSearchResult{}, nil).Once()
+		mockStore.On("Users", mock.Anything).Return([]store.UserSearchResult{}, nil).Once()
+
+		code := search(t, "q=alice&lang=es")
+		checkResponseCode(t, http.StatusOK, code)
+		mockStore.AssertExpectations(t)
+	})
+
+	t.Run("should reject invalid queries", func(t *testing.T) {
+		for _, query := range []string{"", "q=a", "q=go&type=tags", "q=go&lang=fr", "q=go&since=yesterday", "q=go&limit=ten", "q=go&offset=-", "q=go&limit=100"} {
+			checkResponseCode(t, http.StatusBadRequest, search(t, query))
+		}
+	})
+}
+
+func TestSearchLanguage(t *testing.T) {
+	for locale, want := range map[string]string{"es-MX": "es", "en": "en", "fr": "en", "": "en"} {
+		if got := searchLanguage(locale); got != want {
+			t.Errorf("searchLanguage(%q): expected %q, got %q", locale, want, got)
+		}
+	}
+}
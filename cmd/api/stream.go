@@ -0,0 +1,334 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/kuluruvineeth/social-go/internal/realtime"
+	"github.com/kuluruvineeth/social-go/internal/store"
+)
+
+const (
+	streamHeartbeat    = 30 * time.Second
+	maxWatchedPosts    = 50
+	wsWriteTimeout     = 10 * time.Second
+	wsMaxMessageLength = 512
+)
+
+// streamTicketTTL is how long clients have to open a stream with a ticket.
+const streamTicketTTL = 30 * time.Second
+
+type StreamTicketResponse struct {
+	Ticket    string `json:"ticket"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// createStreamTicketHandler godoc
+//
+//	@Summary		Issues a stream ticket
+//	@Description	Issues a single-use ticket that opens a stream within 30 seconds, for clients that can't set the Authorization header, like the browser EventSource and WebSocket APIs
+//	@Tags			stream
+//	@Produce		json
+//	@Success		201	{object}	StreamTicketResponse
+//	@Failure		401	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Security		ApiKeyAuth
+//	@Router			/stream/tickets [post]
+func (app *application) createStreamTicketHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	expiresAt := time.Now().Add(streamTicketTTL)
+
+	ticket, err := app.tickets.Issue(r.Context(), user.ID, streamTicketTTL)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	res := StreamTicketResponse{Ticket: ticket, ExpiresAt: expiresAt.UTC().Format(time.RFC3339)}
+	if err := app.jsonResponse(w, http.StatusCreated, res); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// streamAuthMiddleware lets clients that can't set headers open streams
+// with a ticket instead of their token, which would end up in access logs.
+func (app *application) streamAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ticket := r.URL.Query().Get("ticket")
+		if ticket == "" || r.Header.Get("Authorization") != "" {
+			app.AuthTokenMiddleware(next).ServeHTTP(w, r)
+			return
+		}
+
+		ctx := r.Context()
+
+		userID, err := app.tickets.Redeem(ctx, ticket)
+		if err != nil {
+			if errors.Is(err, realtime.ErrInvalidTicket) {
+				app.unauthorizedError(w, r, err)
+			} else {
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		user, err := app.getUser(ctx, userID)
+		if err != nil {
+			app.unauthorizedError(w, r, err)
+			return
+		}
+
+		ctx = context.WithValue(ctx, userCtxKey, user)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// checkStreamOrigin only lets the allowed origins open WebSockets, since
+// browsers don't apply CORS to them. Clients other than browsers don't send
+// an Origin.
+func (app *application) checkStreamOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	for _, allowed := range app.config.allowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// streamHandler godoc
+//
+//	@Summary		Streams feed and notification events
+//	@Description	Server-Sent Events stream of new posts from followed users, new comments on watched posts and notifications
+//	@Tags			stream
+//	@Produce		text/event-stream
+//	@Param			posts	query		string	false	"Comma separated post IDs to watch for comments"
+//	@Param			ticket	query		string	false	"Stream ticket, for clients that can't set the Authorization header"
+//	@Success		200		{object}	realtime.Event
+//	@Failure		400		{object}	Problem
+//	@Failure		401		{object}	Problem
+//	@Failure		500		{object}	Problem
+//	@Security		ApiKeyAuth
+//	@Router			/stream [get]
+func (app *application) streamHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	ctx := r.Context()
+
+	watched, err := parseWatchedPosts(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	topics, err := app.streamTopics(ctx, user, watched)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	// the server-wide write timeout would otherwise cut the stream
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	sub := app.broker.Subscribe(topics)
+	defer app.broker.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if err := rc.Flush(); err != nil {
+		app.logger.Errorw("streaming not supported", "error", err)
+		return
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		case ev := <-sub.C:
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, ev.Data); err != nil {
+				return
+			}
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// streamWebSocketHandler godoc
+//
+//	@Summary		Streams feed and notification events over a WebSocket
+//	@Description	WebSocket variant of /stream. Each message is a JSON encoded event.
+//	@Tags			stream
+//	@Param			posts	query	string	false	"Comma separated post IDs to watch for comments"
+//	@Param			ticket	query	string	false	"Stream ticket, for clients that can't set the Authorization header"
+//	@Success		101		"Switching Protocols"
+//	@Failure		400		{object}	Problem
+//	@Failure		401		{object}	Problem
+//	@Failure		403		"Origin not allowed"
+//	@Security		ApiKeyAuth
+//	@Router			/stream/ws [get]
+func (app *application) streamWebSocketHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	watched, err := parseWatchedPosts(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	topics, err := app.streamTopics(r.Context(), user, watched)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     app.checkStreamOrigin,
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader has already replied to the client
+		app.logger.Warnw("websocket upgrade failed", "error", err)
+		return
+	}
+	defer conn.Close()
+
+	// the request context doesn't outlive a hijacked connection, so track
+	// the client going away through the read loop instead
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	conn.SetReadLimit(wsMaxMessageLength)
+	conn.SetReadDeadline(time.Now().Add(2 * streamHeartbeat))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(2 * streamHeartbeat))
+	})
+
+	go func() {
+		defer cancel()
+		for {
+			// clients don't send anything, but reading processes control frames
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	sub := app.broker.Subscribe(topics)
+	defer app.broker.Unsubscribe(sub)
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+				return
+			}
+		case ev := <-sub.C:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := conn.WriteJSON(ev); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// streamTopics returns the topics a user's stream listens on: their own
+// notifications, posts from everyone they follow and comments on the watched
+// posts they can see. Follows made after connecting are picked up on
+// reconnect.
+func (app *application) streamTopics(ctx context.Context, user *store.User, watchedPosts []int64) ([]string, error) {
+	topics := []string{realtime.UserTopic(user.ID)}
+
+	following, err := app.store.Followers.GetFollowing(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range following {
+		topics = append(topics, realtime.AuthorTopic(id))
+	}
+
+	for _, id := range watchedPosts {
+		// Comments on posts the user can't see aren't streamed to them
+		if _, err := app.store.Posts.GetByID(ctx, id, user.ID); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				continue
+			}
+			return nil, err
+		}
+
+		topics = append(topics, realtime.PostTopic(id))
+	}
+
+	return topics, nil
+}
+
+func parseWatchedPosts(r *http.Request) ([]int64, error) {
+	posts := r.URL.Query().Get("posts")
+	if posts == "" {
+		return nil, nil
+	}
+
+	raw := strings.Split(posts, ",")
+	if len(raw) > maxWatchedPosts {
+		return nil, fmt.Errorf("cannot watch more than %d posts", maxWatchedPosts)
+	}
+
+	ids := make([]int64, 0, len(raw))
+	for _, r := range raw {
+		id, err := strconv.ParseInt(strings.TrimSpace(r), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid post id %q", r)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// publish sends a realtime event without failing the request that caused it.
+func (app *application) publish(ctx context.Context, topic, eventType string, data any) {
+	ev, err := realtime.NewEvent(eventType, data)
+	if err == nil {
+		err = app.broker.Publish(ctx, topic, ev)
+	}
+
+	if err != nil {
+		app.logger.Errorw("failed to publish realtime event", "topic", topic, "type", eventType, "error", err)
+	}
+}
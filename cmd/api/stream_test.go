@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/kuluruvineeth/social-go/internal/realtime"
+)
+
+func TestStream(t *testing.T) {
+	app := newTestApplication(t, config{})
+	srv := httptest.NewServer(app.mount())
+	defer srv.Close()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("should not allow unauthenticated requests", func(t *testing.T) {
+		resp, err := http.Get(srv.URL + "/v1/stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		checkResponseCode(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	// issueTicket gets a stream ticket the way browsers do before opening a
+	// stream.
+	issueTicket := func(t *testing.T) string {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodPost, srv.URL+"/v1/stream/tickets", http.NoBody)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		checkResponseCode(t, http.StatusCreated, resp.StatusCode)
+
+		var res struct {
+			Data StreamTicketResponse `json:"data"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+			t.Fatal(err)
+		}
+		return res.Data.Ticket
+	}
+
+	t.Run("should not accept tokens in the URL", func(t *testing.T) {
+		resp, err := http.Get(srv.URL + "/v1/stream?access_token=" + testToken)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		checkResponseCode(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("should redeem tickets once", func(t *testing.T) {
+		ticket := issueTicket(t)
+
+		// the posts are invalid, so the stream is refused after the ticket is redeemed
+		resp, err := http.Get(srv.URL + "/v1/stream?posts=abc&ticket=" + ticket)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		checkResponseCode(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp, err = http.Get(srv.URL + "/v1/stream?ticket=" + ticket)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		checkResponseCode(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("should reject invalid watched posts", func(t *testing.T) {
+		resp, err := http.Get(srv.URL + "/v1/stream?posts=1,abc&ticket=" + issueTicket(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		checkResponseCode(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("should stream events for watched posts", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/v1/stream?posts=42&ticket="+issueTicket(t), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		checkResponseCode(t, http.StatusOK, resp.StatusCode)
+		if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+			t.Fatalf("expected an event stream, got %q", ct)
+		}
+
+		// headers are flushed after subscribing, so the event can't be missed
+		app.publish(ctx, realtime.PostTopic(42), realtime.EventComment, map[string]string{"content": "hi"})
+
+		scanner := bufio.NewScanner(resp.Body)
+		var lines []string
+		for scanner.Scan() && scanner.Text() != "" {
+			lines = append(lines, scanner.Text())
+		}
+
+		got := strings.Join(lines, "\n")
+		want := "event: comment\ndata: {\"content\":\"hi\"}"
+		if got != want {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+	})
+}
+
+func TestStreamWebSocketOrigin(t *testing.T) {
+	app := newTestApplication(t, config{allowedOrigins: []string{"http://localhost:4000"}})
+	srv := httptest.NewServer(app.mount())
+	defer srv.Close()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dial := func(t *testing.T, origin string) (*http.Response, error) {
+		t.Helper()
+
+		header := http.Header{}
+		header.Set("Authorization", "Bearer "+testToken)
+		if origin != "" {
+			header.Set("Origin", origin)
+		}
+
+		conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/v1/stream/ws", header)
+		if err == nil {
+			conn.Close()
+		}
+		return resp, err
+	}
+
+	t.Run("should accept allowed origins", func(t *testing.T) {
+		if _, err := dial(t, "http://localhost:4000"); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("should accept clients without an origin", func(t *testing.T) {
+		if _, err := dial(t, ""); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("should reject other origins", func(t *testing.T) {
+		resp, err := dial(t, "https://evil.example.com")
+		if err == nil {
+			t.Fatal("expected the handshake to fail")
+		}
+		checkResponseCode(t, http.StatusForbidden, resp.StatusCode)
+	})
+}
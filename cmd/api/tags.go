@@ -0,0 +1,124 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kuluruvineeth/social-go/internal/content"
+	"github.com/kuluruvineeth/social-go/internal/store"
+)
+
+type trendingTagsQuery struct {
+	Window time.Duration `json:"window" validate:"gte=1h,lte=720h"`
+	Limit  int           `json:"limit" validate:"gte=1,lte=50"`
+}
+
+// getTagPostsHandler godoc
+//
+//	@Summary		Fetches posts by tag
+//	@Description	Fetches the posts tagged with a tag, either explicitly or through a #hashtag in their content
+//	@Tags			tags
+//	@Produce		json
+//	@Param			tag		path		string	true	"Tag"
+//	@Param			limit	query		int		false	"Limit"
+//	@Param			offset	query		int		false	"Offset"
+//	@Param			sort	query		string	false	"Sort"
+//	@Success		200		{object}	[]store.PostWithMetadata
+//	@Failure		400		{object}	Problem
+//	@Failure		401		{object}	Problem
+//	@Failure		500		{object}	Problem
+//	@Security		ApiKeyAuth
+//	@Router			/tags/{tag}/posts [get]
+func (app *application) getTagPostsHandler(w http.ResponseWriter, r *http.Request) {
+	tag, err := content.NormalizeTag(chi.URLParam(r, "tag"))
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	fq := store.PaginatedFeedQuery{
+		Limit:  20,
+		Offset: 0,
+		Sort:   "desc",
+		Tags:   []string{},
+	}
+
+	fq, err = fq.Parse(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := validate.Struct(fq); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	posts, err := app.store.Tags.GetPosts(r.Context(), tag, fq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.setFeedAttachmentURLs(posts)
+
+	if err := app.jsonResponse(w, http.StatusOK, posts); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getTrendingTagsHandler godoc
+//
+//	@Summary		Fetches trending tags
+//	@Description	Fetches the tags used by the most users in posts and comments over a time window
+//	@Tags			tags
+//	@Produce		json
+//	@Param			window	query		string	false	"Time window, between 1h and 720h (default 24h)"
+//	@Param			limit	query		int		false	"Limit"
+//	@Success		200		{object}	[]store.TrendingTag
+//	@Failure		400		{object}	Problem
+//	@Failure		401		{object}	Problem
+//	@Failure		500		{object}	Problem
+//	@Security		ApiKeyAuth
+//	@Router			/tags/trending [get]
+func (app *application) getTrendingTagsHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	q := trendingTagsQuery{
+		Window: 24 * time.Hour,
+		Limit:  10,
+	}
+
+	if window := qs.Get("window"); window != "" {
+		d, err := time.ParseDuration(window)
+		if err != nil {
+			app.badRequestError(w, r, &store.ParamError{Param: "window", Rule: "duration", Want: "a duration like 24h", Err: err})
+			return
+		}
+		q.Window = d
+	}
+
+	if limit := qs.Get("limit"); limit != "" {
+		l, err := store.ParseIntParam("limit", limit)
+		if err != nil {
+			app.badRequestError(w, r, err)
+			return
+		}
+		q.Limit = l
+	}
+
+	if err := validate.Struct(q); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	tags, err := app.store.Tags.Trending(r.Context(), time.Now().Add(-q.Window), q.Limit)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, tags); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
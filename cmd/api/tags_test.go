@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/kuluruvineeth/social-go/internal/store"
+)
+
+func TestTags(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("should list posts for a tag", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/tags/GoLang/posts", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("should reject an invalid tag", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/tags/123/posts", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("should return trending tags", func(t *testing.T) {
+		mockStore := app.store.Tags.(*store.MockTagStore)
+		mockStore.On("Trending", 5).Return([]store.TrendingTag{{Tag: "go", Uses: 3, Users: 2}}, nil).Once()
+
+		req, err := http.NewRequest(http.MethodGet, "/v1/tags/trending?window=6h&limit=5", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(req, mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+		mockStore.AssertExpectations(t)
+	})
+
+	t.Run("should reject a window outside the allowed range", func(t *testing.T) {
+		for _, window := range []string{"10m", "1000h", "soon"} {
+			req, err := http.NewRequest(http.MethodGet, "/v1/tags/trending?window="+window, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Authorization", "Bearer "+testToken)
+
+			rr := executeRequest(req, mux)
+			checkResponseCode(t, http.StatusBadRequest, rr.Code)
+		}
+	})
+}
@@ -6,6 +6,8 @@ import (
 	"testing"
 
 	"github.com/kuluruvineeth/social-go/internal/auth"
+	"github.com/kuluruvineeth/social-go/internal/blob"
+	"github.com/kuluruvineeth/social-go/internal/realtime"
 	"github.com/kuluruvineeth/social-go/internal/store"
 	"github.com/kuluruvineeth/social-go/internal/store/cache"
 	"go.uber.org/zap"
@@ -21,12 +23,20 @@ func newTestApplication(t *testing.T, cfg config) *application {
 
 	testAuth := &auth.TestAuthenticator{}
 
+	blobs, err := blob.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	return &application{
 		logger:        logger,
 		store:         mockStore,
 		cache:         mockCacheStore,
 		authenticator: testAuth,
 		config:        cfg,
+		broker:        realtime.NewLocalBroker(),
+		tickets:       realtime.NewLocalTickets(),
+		blobs:         blobs,
 	}
 }
 
@@ -1,10 +1,15 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/kuluruvineeth/social-go/internal/mailer"
 	"github.com/kuluruvineeth/social-go/internal/store"
 )
 
@@ -21,9 +26,9 @@ const userCtxKey userContextKey = "user"
 //	@Produce		json
 //	@Param			id	path		int	true	"User ID"
 //	@Success		200	{object}	store.User
-//	@Failure		400	{object}	error
-//	@Failure		404	{object}	error
-//	@Failure		500	{object}	error
+//	@Failure		400	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		500	{object}	Problem
 //	@Security		ApiKeyAuth
 //	@Router			/users/{id} [get]
 func (app *application) getUserHandler(w http.ResponseWriter, r *http.Request) {
@@ -58,13 +63,13 @@ func (app *application) getUserHandler(w http.ResponseWriter, r *http.Request) {
 //	@Tags			users
 //	@Accept			json
 //	@Produce		json
-//	@Param			user	body	FollowUser	true	"User ID to follow"
+//	@Param			userID	path	int	true	"User ID"
 //	@Success		204		"No Content"
-//	@Failure		400		{object}	error	"Bad Request"
-//	@Failure		409		{object}	error	"Conflict - Already following or trying to follow self"
-//	@Failure		500		{object}	error	"Internal Server Error"
+//	@Failure		400		{object}	Problem	"Bad Request"
+//	@Failure		409		{object}	Problem	"Conflict - Already following or trying to follow self"
+//	@Failure		500		{object}	Problem	"Internal Server Error"
 //	@Security		ApiKeyAuth
-//	@Router			/users/follow [post]
+//	@Router			/users/{userID}/follow [put]
 func (app *application) followUserHandler(w http.ResponseWriter, r *http.Request) {
 	followerUser := getUserFromContext(r)
 	followedID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
@@ -76,15 +81,16 @@ func (app *application) followUserHandler(w http.ResponseWriter, r *http.Request
 	ctx := r.Context()
 
 	if err := app.store.Followers.Follow(ctx, followerUser.ID, followedID); err != nil {
-		switch err {
-		case store.ErrConflict:
-			app.conflictError(w, r)
-		default:
-			app.internalServerError(w, r, err)
-		}
+		app.errorResponse(w, r, err)
 		return
 	}
 
+	app.notify(ctx, &store.Notification{
+		UserID:  followedID,
+		ActorID: followerUser.ID,
+		Type:    store.NotificationFollow,
+	})
+
 	if err := app.jsonResponse(w, http.StatusNoContent, nil); err != nil {
 		app.internalServerError(w, r, err)
 	}
@@ -97,12 +103,12 @@ func (app *application) followUserHandler(w http.ResponseWriter, r *http.Request
 //	@Tags			users
 //	@Accept			json
 //	@Produce		json
-//	@Param			user	body	FollowUser	true	"User ID to unfollow"
+//	@Param			userID	path	int	true	"User ID"
 //	@Success		204		"No Content"
-//	@Failure		400		{object}	error	"Bad Request"
-//	@Failure		500		{object}	error	"Internal Server Error"
+//	@Failure		400		{object}	Problem	"Bad Request"
+//	@Failure		500		{object}	Problem	"Internal Server Error"
 //	@Security		ApiKeyAuth
-//	@Router			/users/unfollow [post]
+//	@Router			/users/{userID}/unfollow [put]
 func (app *application) unfollowUserHandler(w http.ResponseWriter, r *http.Request) {
 	followerUser := getUserFromContext(r)
 
@@ -127,34 +133,167 @@ func (app *application) unfollowUserHandler(w http.ResponseWriter, r *http.Reque
 // ActivateUser godoc
 //
 //	@Summary		Activates/Register a user
-//	@Description	Activates/Register a user by invitation token
+//	@Description	Activates/Register a user by invitation token, or confirms the change of their email address the token was sent for
 //	@Tags			users
 //	@Produce		json
 //	@Param			token	path		string	true	"Invitation token"
 //	@Success		204		{string}	string	"User activated"
-//	@Failure		404		{object}	error
-//	@Failure		500		{object}	error
+//	@Failure		404		{object}	Problem
+//	@Failure		409		{object}	Problem	"The new email address was taken in the meantime"
+//	@Failure		500		{object}	Problem
 //	@Security		ApiKeyAuth
 //	@Router			/users/activate/{token} [put]
 func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Request) {
 	token := chi.URLParam(r, "token")
+	ctx := r.Context()
 
-	err := app.store.Users.Activate(r.Context(), token)
+	id, err := app.store.Users.Activate(ctx, token)
 	if err != nil {
-		switch err {
-		case store.ErrNotFound:
-			app.notFoundError(w, r)
-		default:
+		app.errorResponse(w, r, err)
+		return
+	}
+
+	// Confirming a new email changes the user.
+	app.evictUser(ctx, id)
+
+	if err := app.jsonResponse(w, http.StatusNoContent, ""); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+type UpdateProfilePayload struct {
+	Username    string `json:"username" validate:"required,max=100"`
+	Email       string `json:"email" validate:"required,email,max=255"`
+	DisplayName string `json:"display_name" validate:"max=100"`
+	Bio         string `json:"bio" validate:"max=500"`
+	AvatarURL   string `json:"avatar_url" validate:"omitempty,http_url,max=2048"`
+	Website     string `json:"website" validate:"omitempty,http_url,max=2048"`
+	Location    string `json:"location" validate:"max=100"`
+}
+
+type ProfileResponse struct {
+	*store.User
+	// PendingEmail is the new email address waiting to be confirmed.
+	PendingEmail string `json:"pending_email,omitempty"`
+}
+
+// updateProfileHandler godoc
+//
+//	@Summary		Updates the user's profile
+//	@Description	Updates the authenticated user's profile with a JSON Merge Patch, or a JSON Patch when sent as application/json-patch+json. Usernames can only be changed once per cooldown, and a new email address only replaces the current one once it's confirmed with the link sent to it.
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		UpdateProfilePayload	true	"Profile patch"
+//	@Success		200		{object}	ProfileResponse
+//	@Failure		400		{object}	Problem
+//	@Failure		401		{object}	Problem
+//	@Failure		409		{object}	Problem	"Username or email taken"
+//	@Failure		415		{object}	Problem
+//	@Failure		422		{object}	Problem	"Username changed too recently"
+//	@Failure		500		{object}	Problem
+//	@Security		ApiKeyAuth
+//	@Router			/users/me [patch]
+func (app *application) updateProfileHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	payload := UpdateProfilePayload{
+		Username:    user.Username,
+		Email:       user.Email,
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		AvatarURL:   user.AvatarURL,
+		Website:     user.Website,
+		Location:    user.Location,
+	}
+	if err := readPatch(w, r, &payload); err != nil {
+		app.patchError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	var change *store.EmailChange
+	if !strings.EqualFold(payload.Email, user.Email) {
+		token, hashToken := newInvitationToken()
+
+		msg, err := app.emailChangeMessage(user, payload.Email, token)
+		if err != nil {
 			app.internalServerError(w, r, err)
+			return
+		}
+
+		change = &store.EmailChange{
+			Email:   payload.Email,
+			Token:   hashToken,
+			Expiry:  app.config.mail.exp,
+			Message: msg,
 		}
+	}
+
+	updated := *user
+	updated.Username = payload.Username
+	updated.DisplayName = payload.DisplayName
+	updated.Bio = payload.Bio
+	updated.AvatarURL = payload.AvatarURL
+	updated.Website = payload.Website
+	updated.Location = payload.Location
+
+	ctx := r.Context()
+
+	if err := app.store.Users.UpdateProfile(ctx, &updated, app.config.users.usernameCooldown, change); err != nil {
+		if errors.Is(err, store.ErrUsernameCooldown) {
+			err = &apiError{
+				Status:  http.StatusUnprocessableEntity,
+				Code:    codeUsernameCooldown,
+				Message: fmt.Sprintf("%s, usernames can be changed once every %s", err, app.config.users.usernameCooldown),
+				Err:     err,
+			}
+		}
+		app.errorResponse(w, r, err)
 		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusNoContent, ""); err != nil {
+	app.evictUser(ctx, user.ID)
+
+	res := ProfileResponse{User: &updated}
+	if change != nil {
+		res.PendingEmail = change.Email
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, res); err != nil {
 		app.internalServerError(w, r, err)
 	}
 }
 
+// emailChangeMessage returns the email asking the user to confirm their new
+// address with the invitation token.
+func (app *application) emailChangeMessage(user *store.User, email, token string) (*store.OutboxMessage, error) {
+	vars := struct {
+		Username string
+	}{
+		Username: user.Username,
+	}
+
+	data, err := json.Marshal(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	return &store.OutboxMessage{
+		Template: mailer.EmailChangeTemplate,
+		Locale:   user.Locale,
+		Username: user.Username,
+		Email:    email,
+		Data:     data,
+		Token:    token,
+		Sandbox:  app.config.env != "production",
+	}, nil
+}
+
 //TODO: Remove this middleware later
 // func (app *application) userContextMiddleware(next http.Handler) http.Handler {
 // 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
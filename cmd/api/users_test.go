@@ -1,9 +1,12 @@
 package main
 
 import (
+	"encoding/json"
 	"net/http"
+	"strings"
 	"testing"
 
+	"github.com/kuluruvineeth/social-go/internal/store"
 	"github.com/kuluruvineeth/social-go/internal/store/cache"
 	"github.com/stretchr/testify/mock"
 )
@@ -94,4 +97,166 @@ func TestGetUser(t *testing.T) {
 		mockCacheStore.AssertNotCalled(t, "Get")
 		mockCacheStore.Calls = nil
 	})
+	t.Run("should hit the cache if only the in-process cache is enabled", func(t *testing.T) {
+		withLRU := config{
+			lruCfg: lruConfig{
+				enabled: true,
+			},
+		}
+
+		app := newTestApplication(t, withLRU)
+		mux := app.mount()
+
+		mockCacheStore := app.cache.Users.(*cache.MockUserStore)
+		mockCacheStore.On("Get", int64(1)).Return(nil, nil)
+		mockCacheStore.On("Set", mock.Anything).Return(nil)
+
+		req, err := http.NewRequest(http.MethodGet, "/v1/users/1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		rr := executeRequest(req, mux)
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+		mockCacheStore.AssertNumberOfCalls(t, "Get", 2)
+		mockCacheStore.Calls = nil
+	})
+}
+
+func TestUpdateProfile(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users := app.store.Users.(*store.MockUserStore)
+	users.Users = map[int64]*store.User{1: {ID: 1, Username: "alice", Email: "alice@example.com", Locale: "en"}}
+
+	request := func(t *testing.T, contentType, body string) *http.Request {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodPatch, "/v1/users/me", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		req.Header.Set("Content-Type", contentType)
+		return req
+	}
+
+	t.Run("should update the profile", func(t *testing.T) {
+		users.On("UpdateProfile", "alice", "Alice", "").Return(nil).Once()
+
+		rr := executeRequest(request(t, mergePatchType, `{"display_name":"Alice","website":"https://alice.dev"}`), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+		users.AssertExpectations(t)
+
+		var res struct {
+			Data ProfileResponse `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&res); err != nil {
+			t.Fatal(err)
+		}
+		if res.Data.Website != "https://alice.dev" || res.Data.PendingEmail != "" {
+			t.Errorf("unexpected profile %+v", res.Data)
+		}
+	})
+
+	t.Run("should ask to confirm a new email", func(t *testing.T) {
+		users.On("UpdateProfile", "alice", "", "new@example.com").Return(nil).Once()
+
+		rr := executeRequest(request(t, jsonPatchType, `[{"op":"replace","path":"/email","value":"new@example.com"}]`), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+		users.AssertExpectations(t)
+
+		var res struct {
+			Data ProfileResponse `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&res); err != nil {
+			t.Fatal(err)
+		}
+		if res.Data.Email != "alice@example.com" || res.Data.PendingEmail != "new@example.com" {
+			t.Errorf("expected the email change to be pending, got %+v", res.Data)
+		}
+	})
+
+	t.Run("should not change the email for a different case", func(t *testing.T) {
+		users.On("UpdateProfile", "alice", "", "").Return(nil).Once()
+
+		rr := executeRequest(request(t, mergePatchType, `{"email":"Alice@Example.com"}`), mux)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+		users.AssertExpectations(t)
+	})
+
+	t.Run("should reject invalid fields", func(t *testing.T) {
+		for _, body := range []string{
+			`{"website":"not a url"}`,
+			`{"avatar_url":"ftp://example.com/a.png"}`,
+			`{"username":null}`,
+			`{"bio":"` + strings.Repeat("a", 501) + `"}`,
+			`{"role":"admin"}`,
+		} {
+			rr := executeRequest(request(t, mergePatchType, body), mux)
+			checkResponseCode(t, http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("should enforce the username cooldown", func(t *testing.T) {
+		users.On("UpdateProfile", "alicia", "", "").Return(store.ErrUsernameCooldown).Once()
+
+		rr := executeRequest(request(t, mergePatchType, `{"username":"alicia"}`), mux)
+		checkResponseCode(t, http.StatusUnprocessableEntity, rr.Code)
+	})
+
+	t.Run("should return conflict for a taken username", func(t *testing.T) {
+		users.On("UpdateProfile", "bob", "", "").Return(store.ErrDuplicateUsername).Once()
+
+		rr := executeRequest(request(t, mergePatchType, `{"username":"bob"}`), mux)
+		checkResponseCode(t, http.StatusConflict, rr.Code)
+	})
+
+	t.Run("should reject other media types", func(t *testing.T) {
+		rr := executeRequest(request(t, "text/plain", `{"bio":"hi"}`), mux)
+		checkResponseCode(t, http.StatusUnsupportedMediaType, rr.Code)
+	})
+}
+
+func TestActivateUser(t *testing.T) {
+	app := newTestApplication(t, config{lruCfg: lruConfig{enabled: true}})
+	mux := app.mount()
+
+	users := app.store.Users.(*store.MockUserStore)
+	cached := app.cache.Users.(*cache.MockUserStore)
+
+	activate := func(t *testing.T, token string) *http.Request {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodPut, "/v1/users/activate/"+token, http.NoBody)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return req
+	}
+
+	t.Run("should evict the activated user from the cache", func(t *testing.T) {
+		users.On("Activate", "token").Return(int64(7), nil).Once()
+		cached.On("Delete", int64(7)).Return(nil).Once()
+
+		rr := executeRequest(activate(t, "token"), mux)
+		checkResponseCode(t, http.StatusNoContent, rr.Code)
+		users.AssertExpectations(t)
+		cached.AssertExpectations(t)
+	})
+
+	t.Run("should return not found for unknown tokens", func(t *testing.T) {
+		users.On("Activate", "unknown").Return(int64(0), store.ErrNotFound).Once()
+
+		rr := executeRequest(activate(t, "unknown"), mux)
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
 }
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError is a field of the request that failed validation.
+type FieldError struct {
+	// Field is the field's JSON name, or its query parameter for queries.
+	Field string `json:"field"`
+	// Rule is the rule the field failed, like required or max.
+	Rule string `json:"rule"`
+	// Param is the rule's parameter, like the maximum length for max.
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// newValidator returns a validator that names fields by their JSON names, so
+// errors name them the way clients send them.
+func newValidator(opts ...validator.Option) *validator.Validate {
+	v := validator.New(opts...)
+
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+
+	return v
+}
+
+// fieldErrors translates the validator's errors into errors for clients.
+func fieldErrors(errs validator.ValidationErrors) []FieldError {
+	fields := make([]FieldError, 0, len(errs))
+
+	for _, fe := range errs {
+		// The namespace starts with the struct's name, which clients never see.
+		_, field, _ := strings.Cut(fe.Namespace(), ".")
+
+		fields = append(fields, FieldError{
+			Field:   field,
+			Rule:    fe.Tag(),
+			Param:   fe.Param(),
+			Message: field + " " + ruleMessage(fe),
+		})
+	}
+
+	return fields
+}
+
+// ruleMessage describes the rule a field failed.
+func ruleMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "required_without":
+		return fmt.Sprintf("is required unless %s is set", fe.Param())
+	case "email":
+		return "must be a valid email address"
+	case "http_url":
+		return "must be an http or https URL"
+	case "bcp47_language_tag":
+		return "must be a BCP 47 language tag, like en or pt-BR"
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(fe.Param()), ", ")
+	case "min", "gte":
+		return "must be at least " + sizeOf(fe)
+	case "max", "lte":
+		return "must be at most " + sizeOf(fe)
+	case "len":
+		return "must be exactly " + sizeOf(fe)
+	default:
+		return fmt.Sprintf("failed the %s rule", fe.Tag())
+	}
+}
+
+// sizeOf describes the rule's parameter in the field's terms, as lengths
+// for strings and counts for lists.
+func sizeOf(fe validator.FieldError) string {
+	switch fe.Kind() {
+	case reflect.String:
+		return fe.Param() + " characters long"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return fe.Param() + " items"
+	default:
+		return fe.Param()
+	}
+}
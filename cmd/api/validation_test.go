@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidationErrors(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type problem struct {
+		Problem
+		Details []FieldError `json:"details"`
+	}
+
+	send := func(t *testing.T, method, url string, body io.Reader) (int, problem) {
+		t.Helper()
+
+		req, err := http.NewRequest(method, url, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(req, mux)
+
+		var p problem
+		if err := json.NewDecoder(rr.Body).Decode(&p); err != nil {
+			t.Fatal(err)
+		}
+		return rr.Code, p
+	}
+
+	t.Run("should name the invalid fields", func(t *testing.T) {
+		status, p := send(t, http.MethodPost, "/v1/authentication/user", strings.NewReader(`{"username":"alice","email":"nope","password":"ab"}`))
+		checkResponseCode(t, http.StatusBadRequest, status)
+
+		want := []FieldError{
+			{Field: "email", Rule: "email", Message: "email must be a valid email address"},
+			{Field: "password", Rule: "min", Param: "3", Message: "password must be at least 3 characters long"},
+		}
+		if p.Code != codeValidationFailed || !reflect.DeepEqual(p.Details, want) {
+			t.Errorf("unexpected problem %+v", p)
+		}
+	})
+
+	t.Run("should name the invalid query parameters", func(t *testing.T) {
+		status, p := send(t, http.MethodGet, "/v1/users/feed?limit=50&sort=up", http.NoBody)
+		checkResponseCode(t, http.StatusBadRequest, status)
+
+		want := []FieldError{
+			{Field: "limit", Rule: "lte", Param: "20", Message: "limit must be at most 20"},
+			{Field: "sort", Rule: "oneof", Param: "asc desc", Message: "sort must be one of asc, desc"},
+		}
+		if !reflect.DeepEqual(p.Details, want) {
+			t.Errorf("unexpected problem %+v", p)
+		}
+	})
+
+	t.Run("should name the field a rule depends on", func(t *testing.T) {
+		status, p := send(t, http.MethodPost, "/v1/notifications/read", strings.NewReader(`{}`))
+		checkResponseCode(t, http.StatusBadRequest, status)
+
+		want := []FieldError{
+			{Field: "ids", Rule: "required_without", Param: "All", Message: "ids is required unless All is set"},
+		}
+		if !reflect.DeepEqual(p.Details, want) {
+			t.Errorf("unexpected problem %+v", p)
+		}
+	})
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		code   string
+		fields []FieldError
+	}{
+		{"malformed JSON", `{"title":`, http.StatusBadRequest, codeMalformedJSON, nil},
+		{"invalid JSON", `{"title" "hi"}`, http.StatusBadRequest, codeMalformedJSON, nil},
+		{"empty body", ``, http.StatusBadRequest, codeBadRequest, nil},
+		{"not an object", `[]`, http.StatusBadRequest, codeMalformedJSON, nil},
+		{"wrong type", `{"title":1}`, http.StatusBadRequest, codeValidationFailed, []FieldError{{Field: "title", Rule: "type", Message: "title must be a string"}}},
+		{"unknown field", `{"titel":"hi"}`, http.StatusBadRequest, codeValidationFailed, []FieldError{{Field: "titel", Rule: "unknown", Message: "titel is not a known field"}}},
+		{"too large", `{"content":"` + strings.Repeat("a", 2<<20) + `"}`, http.StatusRequestEntityTooLarge, codePayloadTooLarge, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run("should explain a body with "+tt.name, func(t *testing.T) {
+			status, p := send(t, http.MethodPost, "/v1/posts", strings.NewReader(tt.body))
+			checkResponseCode(t, tt.status, status)
+
+			if p.Code != tt.code || !reflect.DeepEqual(p.Details, tt.fields) {
+				t.Errorf("unexpected problem %+v", p)
+			}
+		})
+	}
+}
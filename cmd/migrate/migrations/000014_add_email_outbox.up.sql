@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS email_outbox (
+  id bigserial PRIMARY KEY,
+  template varchar(255) NOT NULL,
+  username varchar(255) NOT NULL,
+  email citext NOT NULL,
+  data jsonb NOT NULL DEFAULT '{}',
+  sandbox boolean NOT NULL DEFAULT false,
+  status varchar(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'dead')),
+  attempts int NOT NULL DEFAULT 0,
+  last_error text,
+  next_attempt_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  sent_at timestamp(0) with time zone
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_outbox_due ON email_outbox (next_attempt_at) WHERE status = 'pending';
@@ -0,0 +1,3 @@
+ALTER TABLE IF EXISTS email_outbox DROP COLUMN IF EXISTS locale;
+
+ALTER TABLE IF EXISTS users DROP COLUMN IF EXISTS locale;
\ No newline at end of file
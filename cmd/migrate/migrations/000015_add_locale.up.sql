@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN locale varchar(35) NOT NULL DEFAULT 'en';
+
+ALTER TABLE email_outbox ADD COLUMN locale varchar(35) NOT NULL DEFAULT 'en';
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS notification_preferences;
+
+DROP INDEX IF EXISTS idx_notifications_unread;
+
+DROP INDEX IF EXISTS idx_notifications_user_id;
+
+DROP TABLE IF EXISTS notifications;
\ No newline at end of file
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS notifications (
+  id bigserial PRIMARY KEY,
+  user_id bigint NOT NULL,
+  actor_id bigint NOT NULL,
+  type varchar(20) NOT NULL CHECK (type IN ('follow', 'comment', 'mention')),
+  post_id bigint,
+  comment_id bigint,
+  read_at timestamp(0) with time zone,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+  FOREIGN KEY (actor_id) REFERENCES users (id) ON DELETE CASCADE,
+  FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+  FOREIGN KEY (comment_id) REFERENCES comments (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications (user_id, id DESC);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_unread ON notifications (user_id) WHERE read_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS notification_preferences (
+  user_id bigint NOT NULL,
+  type varchar(20) NOT NULL CHECK (type IN ('follow', 'comment', 'mention')),
+  email boolean NOT NULL DEFAULT false,
+
+  PRIMARY KEY (user_id, type),
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS idx_tag_usages_created_at;
+
+DROP INDEX IF EXISTS idx_tag_usages_unique;
+
+DROP TABLE IF EXISTS tag_usages;
+
+DROP INDEX IF EXISTS idx_users_username_lower;
+
+DROP INDEX IF EXISTS idx_mentions_user_id;
+
+DROP INDEX IF EXISTS idx_mentions_unique;
+
+DROP TABLE IF EXISTS mentions;
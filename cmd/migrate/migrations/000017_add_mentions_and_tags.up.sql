@@ -0,0 +1,45 @@
+CREATE TABLE IF NOT EXISTS mentions (
+  post_id bigint NOT NULL,
+  comment_id bigint,
+  user_id bigint NOT NULL,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+  FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+  FOREIGN KEY (comment_id) REFERENCES comments (id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mentions_unique ON mentions (post_id, COALESCE(comment_id, 0), user_id);
+
+CREATE INDEX IF NOT EXISTS idx_mentions_user_id ON mentions (user_id);
+
+-- Mentions are matched case-insensitively
+CREATE INDEX IF NOT EXISTS idx_users_username_lower ON users (lower(username));
+
+-- Every use of a tag, by a post or by one of its comments, for trending tags
+CREATE TABLE IF NOT EXISTS tag_usages (
+  tag varchar(100) NOT NULL,
+  post_id bigint NOT NULL,
+  comment_id bigint,
+  user_id bigint NOT NULL,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+  FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+  FOREIGN KEY (comment_id) REFERENCES comments (id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tag_usages_unique ON tag_usages (post_id, COALESCE(comment_id, 0), tag);
+
+CREATE INDEX IF NOT EXISTS idx_tag_usages_created_at ON tag_usages (created_at, tag);
+
+-- Tags are now stored lower case, with words joined by underscores
+UPDATE posts SET tags = ARRAY(
+  SELECT DISTINCT regexp_replace(lower(t), '[[:space:]-]+', '_', 'g') FROM unnest(tags) AS t
+)
+WHERE tags IS NOT NULL;
+
+INSERT INTO tag_usages (tag, post_id, user_id, created_at)
+SELECT DISTINCT t, p.id, p.user_id, p.created_at
+FROM posts p, unnest(p.tags) AS t
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_users_search;
+
+DROP INDEX IF EXISTS idx_comments_search_spanish;
+
+DROP INDEX IF EXISTS idx_comments_search_english;
+
+DROP INDEX IF EXISTS idx_posts_search_spanish;
+
+DROP INDEX IF EXISTS idx_posts_search_english;
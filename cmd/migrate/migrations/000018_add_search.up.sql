@@ -0,0 +1,16 @@
+-- Full-text search indexes, one per supported search language. The
+-- expressions must match the ones built by the search store exactly.
+CREATE INDEX IF NOT EXISTS idx_posts_search_english ON posts USING gin (
+  (setweight(to_tsvector('english', title), 'A') || setweight(to_tsvector('english', content), 'B'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_posts_search_spanish ON posts USING gin (
+  (setweight(to_tsvector('spanish', title), 'A') || setweight(to_tsvector('spanish', content), 'B'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_comments_search_english ON comments USING gin (to_tsvector('english', content));
+
+CREATE INDEX IF NOT EXISTS idx_comments_search_spanish ON comments USING gin (to_tsvector('spanish', content));
+
+-- Usernames aren't natural language, so they are neither stemmed nor stripped of stop words
+CREATE INDEX IF NOT EXISTS idx_users_search ON users USING gin (to_tsvector('simple', username));
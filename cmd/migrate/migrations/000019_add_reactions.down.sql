@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_posts_user_id_created_at;
+
+DROP INDEX IF EXISTS idx_comments_user_id;
+
+DROP INDEX IF EXISTS idx_reactions_user_id;
+
+DROP TABLE IF EXISTS reactions;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS reactions (
+  post_id bigint NOT NULL,
+  user_id bigint NOT NULL,
+  type varchar(20) NOT NULL CHECK (type IN ('like', 'love', 'laugh', 'wow', 'sad', 'angry')),
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+  PRIMARY KEY (post_id, user_id),
+  FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+-- Used to compute how often a viewer interacts with an author
+CREATE INDEX IF NOT EXISTS idx_reactions_user_id ON reactions (user_id, created_at);
+
+CREATE INDEX IF NOT EXISTS idx_comments_user_id ON comments (user_id, created_at);
+
+CREATE INDEX IF NOT EXISTS idx_posts_user_id_created_at ON posts (user_id, created_at DESC);
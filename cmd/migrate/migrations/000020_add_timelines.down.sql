@@ -0,0 +1,15 @@
+DROP INDEX IF EXISTS idx_timeline_jobs_due;
+
+DROP TABLE IF EXISTS timeline_jobs;
+
+DROP INDEX IF EXISTS idx_timeline_entries_author;
+
+DROP INDEX IF EXISTS idx_timeline_entries_user_id;
+
+DROP TABLE IF EXISTS timeline_entries;
+
+DROP INDEX IF EXISTS idx_posts_pending_fanout;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS fanned_out;
+
+ALTER TABLE users DROP COLUMN IF EXISTS followers_count;
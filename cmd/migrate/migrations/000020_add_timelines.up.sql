@@ -0,0 +1,52 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS followers_count int NOT NULL DEFAULT 0;
+
+UPDATE users u SET followers_count = (SELECT COUNT(*) FROM followers f WHERE f.user_id = u.id);
+
+-- Posts that haven't been copied into their followers' timelines yet, either
+-- because the job is still queued or because the author has too many
+-- followers, are merged in when a timeline is read.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS fanned_out boolean NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS idx_posts_pending_fanout ON posts (user_id, created_at DESC) WHERE NOT fanned_out;
+
+CREATE TABLE IF NOT EXISTS timeline_entries (
+  user_id bigint NOT NULL,
+  post_id bigint NOT NULL,
+  author_id bigint NOT NULL,
+  created_at timestamp(0) with time zone NOT NULL,
+
+  PRIMARY KEY (user_id, post_id),
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+  FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_timeline_entries_user_id ON timeline_entries (user_id, created_at DESC, post_id DESC);
+
+CREATE INDEX IF NOT EXISTS idx_timeline_entries_author ON timeline_entries (user_id, author_id);
+
+CREATE TABLE IF NOT EXISTS timeline_jobs (
+  id bigserial PRIMARY KEY,
+  type varchar(20) NOT NULL CHECK (type IN ('fanout', 'follow', 'unfollow')),
+  -- the post's author for fanout, the follower otherwise
+  user_id bigint NOT NULL,
+  -- the post for fanout, the followed user otherwise
+  target_id bigint NOT NULL,
+  status varchar(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'dead')),
+  attempts int NOT NULL DEFAULT 0,
+  last_error text,
+  next_attempt_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_timeline_jobs_due ON timeline_jobs (next_attempt_at) WHERE status = 'pending';
+
+-- Materialize the timelines of existing posts
+INSERT INTO timeline_entries (user_id, post_id, author_id, created_at)
+SELECT f.follower_id, p.id, p.user_id, p.created_at
+FROM posts p JOIN followers f ON f.user_id = p.user_id
+UNION
+SELECT p.user_id, p.id, p.user_id, p.created_at
+FROM posts p
+ON CONFLICT DO NOTHING;
+
+UPDATE posts SET fanned_out = true;
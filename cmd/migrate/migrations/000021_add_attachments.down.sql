@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_attachments_post_id;
+
+DROP TABLE IF EXISTS attachments;
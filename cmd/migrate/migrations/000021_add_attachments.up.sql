@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS attachments (
+  id bigserial PRIMARY KEY,
+  post_id bigint NOT NULL,
+  user_id bigint NOT NULL,
+  -- blob store keys of the image and its thumbnail
+  key text NOT NULL,
+  thumbnail_key text NOT NULL,
+  content_type varchar(100) NOT NULL,
+  size bigint NOT NULL,
+  width int NOT NULL,
+  height int NOT NULL,
+  position int NOT NULL,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+  FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_post_id ON attachments (post_id, position);
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS post_revisions (
+  post_id bigint NOT NULL,
+  version int NOT NULL,
+  title text NOT NULL,
+  content text NOT NULL,
+  tags varchar(100) [] NOT NULL DEFAULT '{}',
+  -- who saved this version, the author or a moderator
+  edited_by bigint,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+  PRIMARY KEY (post_id, version),
+  FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+  FOREIGN KEY (edited_by) REFERENCES users (id) ON DELETE SET NULL
+);
+
+-- Existing posts start their history at their current version.
+INSERT INTO post_revisions (post_id, version, title, content, tags, edited_by, created_at)
+SELECT id, COALESCE(version, 0), title, content, COALESCE(tags, '{}'), user_id, updated_at
+FROM posts
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,12 @@
+-- Drafts and scheduled posts are only visible to their author. Once a post is
+-- published its created_at is moved to the time it went out, so it shows up
+-- in feeds from then on.
+ALTER TABLE posts
+  ADD COLUMN IF NOT EXISTS status varchar(20) NOT NULL DEFAULT 'published'
+    CHECK (status IN ('draft', 'scheduled', 'published')),
+  ADD COLUMN IF NOT EXISTS visibility varchar(20) NOT NULL DEFAULT 'public'
+    CHECK (visibility IN ('public', 'followers', 'private')),
+  ADD COLUMN IF NOT EXISTS publish_at timestamp(0) with time zone,
+  ADD CONSTRAINT posts_publish_at_check CHECK ((status = 'scheduled') = (publish_at IS NOT NULL));
+
+CREATE INDEX IF NOT EXISTS idx_posts_publish_at ON posts (publish_at) WHERE status = 'scheduled';
@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS moderation_actions;
+
+DROP INDEX IF EXISTS idx_reports_open;
+
+DROP INDEX IF EXISTS idx_reports_open_reporter;
+
+DROP TABLE IF EXISTS reports;
+
+ALTER TABLE users DROP COLUMN IF EXISTS suspended_at;
+
+ALTER TABLE comments DROP COLUMN IF EXISTS hidden_at;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS hidden_at;
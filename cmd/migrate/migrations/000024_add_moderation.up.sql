@@ -0,0 +1,45 @@
+-- Hidden posts and comments are only visible to their author, and suspended
+-- users can't sign in.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS hidden_at timestamp(0) with time zone;
+
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS hidden_at timestamp(0) with time zone;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspended_at timestamp(0) with time zone;
+
+CREATE TABLE IF NOT EXISTS reports (
+  id bigserial PRIMARY KEY,
+  -- NULL for content flagged by the moderation filters
+  reporter_id bigint,
+  post_id bigint NOT NULL,
+  -- set when the report is about a comment on the post
+  comment_id bigint,
+  reason varchar(20) NOT NULL CHECK (reason IN ('spam', 'harassment', 'hate', 'violence', 'nudity', 'other', 'filter')),
+  details text NOT NULL DEFAULT '',
+  status varchar(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'resolved')),
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  resolved_at timestamp(0) with time zone,
+
+  FOREIGN KEY (reporter_id) REFERENCES users (id) ON DELETE CASCADE,
+  FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+  FOREIGN KEY (comment_id) REFERENCES comments (id) ON DELETE CASCADE
+);
+
+-- A user can only have one open report about the same content
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reports_open_reporter ON reports (reporter_id, post_id, COALESCE(comment_id, 0)) WHERE status = 'open';
+
+CREATE INDEX IF NOT EXISTS idx_reports_open ON reports (post_id, comment_id, created_at) WHERE status = 'open';
+
+-- The log of moderator decisions, kept after the content is deleted
+CREATE TABLE IF NOT EXISTS moderation_actions (
+  id bigserial PRIMARY KEY,
+  moderator_id bigint,
+  action varchar(20) NOT NULL CHECK (action IN ('dismiss', 'hide', 'delete', 'suspend')),
+  post_id bigint NOT NULL,
+  comment_id bigint,
+  author_id bigint NOT NULL,
+  reports int NOT NULL,
+  note text NOT NULL DEFAULT '',
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+  FOREIGN KEY (moderator_id) REFERENCES users (id) ON DELETE SET NULL
+);
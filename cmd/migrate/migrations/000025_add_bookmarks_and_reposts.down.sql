@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_reposts_post_id;
+
+DROP INDEX IF EXISTS idx_reposts_user_id_created_at;
+
+DROP TABLE IF EXISTS reposts;
+
+DROP INDEX IF EXISTS idx_bookmarks_user_id_created_at;
+
+DROP TABLE IF EXISTS bookmarks;
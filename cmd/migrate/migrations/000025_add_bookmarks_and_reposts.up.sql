@@ -0,0 +1,29 @@
+-- Bookmarks are private to the user who saved the post
+CREATE TABLE IF NOT EXISTS bookmarks (
+  user_id bigint NOT NULL,
+  post_id bigint NOT NULL,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+  PRIMARY KEY (user_id, post_id),
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+  FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_bookmarks_user_id_created_at ON bookmarks (user_id, created_at DESC);
+
+-- Reposts share a post with the reposter's followers, optionally quoting it
+CREATE TABLE IF NOT EXISTS reposts (
+  id bigserial PRIMARY KEY,
+  user_id bigint NOT NULL,
+  post_id bigint NOT NULL,
+  quote text NOT NULL DEFAULT '',
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+  UNIQUE (user_id, post_id),
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+  FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_reposts_user_id_created_at ON reposts (user_id, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_reposts_post_id ON reposts (post_id);
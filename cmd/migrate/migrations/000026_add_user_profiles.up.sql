@@ -0,0 +1,11 @@
+ALTER TABLE users
+  ADD COLUMN IF NOT EXISTS display_name varchar(100) NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS bio varchar(500) NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS avatar_url varchar(2048) NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS website varchar(2048) NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS location varchar(100) NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS username_changed_at timestamp(0) with time zone;
+
+-- Invitations with an email confirm a change to that address rather than a
+-- new account
+ALTER TABLE user_invitations ADD COLUMN IF NOT EXISTS email citext;
@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS user_exports;
+
+ALTER TABLE comments
+  DROP CONSTRAINT IF EXISTS comments_user_id_fkey,
+  DROP CONSTRAINT IF EXISTS comments_post_id_fkey;
+
+DELETE FROM comments WHERE user_id IS NULL;
+
+ALTER TABLE comments ALTER COLUMN user_id SET NOT NULL;
+
+DROP INDEX IF EXISTS idx_users_deletion_scheduled_at;
+
+ALTER TABLE users DROP COLUMN IF EXISTS deletion_scheduled_at;
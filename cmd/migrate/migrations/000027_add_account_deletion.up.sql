@@ -0,0 +1,42 @@
+-- Accounts are deleted once the grace period after the user asked for it
+-- has passed, until then they can change their mind.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deletion_scheduled_at timestamp(0) with time zone;
+
+CREATE INDEX IF NOT EXISTS idx_users_deletion_scheduled_at ON users (deletion_scheduled_at) WHERE deletion_scheduled_at IS NOT NULL;
+
+-- Comments go with the post they're on, but comments on other users' posts
+-- outlive their author's account and are kept anonymized.
+DELETE FROM comments c WHERE NOT EXISTS (SELECT 1 FROM posts p WHERE p.id = c.post_id);
+
+ALTER TABLE comments ALTER COLUMN user_id DROP NOT NULL;
+
+UPDATE comments c SET user_id = NULL WHERE NOT EXISTS (SELECT 1 FROM users u WHERE u.id = c.user_id);
+
+ALTER TABLE comments
+  ADD CONSTRAINT comments_post_id_fkey FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+  ADD CONSTRAINT comments_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL;
+
+CREATE TABLE IF NOT EXISTS user_exports (
+  id bigserial PRIMARY KEY,
+  user_id bigint NOT NULL,
+  status varchar(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'ready', 'failed')),
+  -- blob store key of the archive once it's ready
+  key text NOT NULL DEFAULT '',
+  size bigint NOT NULL DEFAULT 0,
+  attempts int NOT NULL DEFAULT 0,
+  last_error text,
+  next_attempt_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  completed_at timestamp(0) with time zone,
+  -- ready archives are deleted after a while, failed exports right away
+  expires_at timestamp(0) with time zone,
+
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+-- A user can only have one export in progress
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_exports_pending ON user_exports (user_id) WHERE status = 'pending';
+
+CREATE INDEX IF NOT EXISTS idx_user_exports_due ON user_exports (next_attempt_at) WHERE status = 'pending';
+
+CREATE INDEX IF NOT EXISTS idx_user_exports_expires_at ON user_exports (expires_at) WHERE expires_at IS NOT NULL;
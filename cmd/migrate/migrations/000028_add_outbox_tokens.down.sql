@@ -0,0 +1 @@
+ALTER TABLE IF EXISTS email_outbox DROP COLUMN IF EXISTS token;
@@ -0,0 +1,11 @@
+-- Confirmation tokens are kept apart from the template data, which admins can
+-- read, and only until the message is sent or dead-lettered. The worker
+-- builds the link from the token when it delivers the message.
+ALTER TABLE email_outbox ADD COLUMN IF NOT EXISTS token text;
+
+UPDATE email_outbox
+SET token = CASE
+    WHEN status = 'pending' THEN substring(COALESCE(data->>'ActivationURL', data->>'ConfirmationURL') FROM '[^/]+$')
+  END,
+  data = data - 'ActivationURL' - 'ConfirmationURL'
+WHERE data ?| array['ActivationURL', 'ConfirmationURL'];
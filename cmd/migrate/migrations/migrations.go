@@ -0,0 +1,8 @@
+// Package migrations embeds the SQL migrations so binaries can apply them
+// without the files on disk.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
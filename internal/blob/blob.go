@@ -0,0 +1,43 @@
+// Package blob stores binary objects, such as uploaded images, by key.
+//
+// Keys are slash separated paths like "attachments/3f2a.jpg". The local
+// filesystem store is meant for development and single instance deployments,
+// the S3 store works with AWS and S3 compatible services such as MinIO.
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+)
+
+var (
+	ErrNotFound   = errors.New("blob not found")
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+type Store interface {
+	// Put stores size bytes from r under key, replacing any earlier object.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get returns the object under key, which the caller must close, or
+	// ErrNotFound.
+	Get(ctx context.Context, key string) (*Object, error)
+	// Delete removes the object under key. Deleting a missing object is not
+	// an error.
+	Delete(ctx context.Context, key string) error
+}
+
+type Object struct {
+	io.ReadCloser
+	Size        int64
+	ContentType string
+}
+
+// validKey rejects keys that could escape the store's root.
+func validKey(key string) error {
+	if !fs.ValidPath(key) || key == "." {
+		return ErrInvalidKey
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// testStore checks the behaviour every store must have.
+func testStore(t *testing.T, s Store) {
+	ctx := context.Background()
+
+	t.Run("should store and return objects", func(t *testing.T) {
+		data := []byte("\x89PNG not really")
+		if err := s.Put(ctx, "attachments/a.png", bytes.NewReader(data), int64(len(data)), "image/png"); err != nil {
+			t.Fatal(err)
+		}
+
+		obj, err := s.Get(ctx, "attachments/a.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer obj.Close()
+
+		got, err := io.ReadAll(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, data) || obj.Size != int64(len(data)) || obj.ContentType != "image/png" {
+			t.Errorf("unexpected object %q, size %d, type %s", got, obj.Size, obj.ContentType)
+		}
+	})
+
+	t.Run("should replace objects", func(t *testing.T) {
+		for _, body := range []string{"first", "second"} {
+			if err := s.Put(ctx, "attachments/b.jpg", strings.NewReader(body), int64(len(body)), "image/jpeg"); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		obj, err := s.Get(ctx, "attachments/b.jpg")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer obj.Close()
+
+		if got, _ := io.ReadAll(obj); string(got) != "second" {
+			t.Errorf("expected the second object, got %q", got)
+		}
+	})
+
+	t.Run("should delete objects", func(t *testing.T) {
+		if err := s.Put(ctx, "attachments/c.jpg", strings.NewReader("c"), 1, "image/jpeg"); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := s.Delete(ctx, "attachments/c.jpg"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Get(ctx, "attachments/c.jpg"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+
+		if err := s.Delete(ctx, "attachments/c.jpg"); err != nil {
+			t.Errorf("expected deleting a missing object to succeed, got %v", err)
+		}
+	})
+
+	t.Run("should reject invalid keys", func(t *testing.T) {
+		for _, key := range []string{"", ".", "../escape", "/absolute", "a//b"} {
+			if err := s.Put(ctx, key, strings.NewReader("x"), 1, "text/plain"); !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("expected ErrInvalidKey for %q, got %v", key, err)
+			}
+			if _, err := s.Get(ctx, key); !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("expected ErrInvalidKey for %q, got %v", key, err)
+			}
+		}
+	})
+}
+
+func TestLocalStore(t *testing.T) {
+	s, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testStore(t, s)
+
+	t.Run("should not keep short writes", func(t *testing.T) {
+		err := s.Put(context.Background(), "attachments/short.jpg", strings.NewReader("abc"), 10, "image/jpeg")
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if _, err := s.Get(context.Background(), "attachments/short.jpg"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,41 @@
+//go:build integration
+
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kuluruvineeth/social-go/internal/storetest"
+)
+
+func TestS3Store(t *testing.T) {
+	m, err := storetest.StartMinIO()
+	if errors.Is(err, storetest.ErrUnavailable) {
+		t.Skip("no S3 compatible server available")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { m.Stop() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// A new bucket every run, created by the store.
+	s, err := NewS3Store(ctx, S3Config{
+		Endpoint:  m.Endpoint,
+		Region:    "us-east-1",
+		Bucket:    fmt.Sprintf("social-go-test-%d", time.Now().UnixNano()),
+		AccessKey: m.AccessKey,
+		SecretKey: m.SecretKey,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testStore(t, s)
+}
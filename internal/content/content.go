@@ -0,0 +1,123 @@
+// Package content extracts @mentions and #hashtags from user written text.
+package content
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// MaxTagLength matches the width of the posts.tags column.
+	MaxTagLength = 100
+	MaxTags      = 10
+	MaxMentions  = 20
+)
+
+var (
+	// The leading group stands in for a lookbehind, so that e-mail addresses
+	// and things like "a#b" or "@@x" aren't picked up.
+	mentionRe = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@#.])@([\p{L}\p{N}_]+(?:[.-][\p{L}\p{N}_]+)*)`)
+	hashtagRe = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@#&])#([\p{L}\p{N}_]+)`)
+
+	separatorRe = regexp.MustCompile(`[\s-]+`)
+)
+
+// Entities are the mentions and hashtags found in a text, normalized to lower
+// case and deduplicated in order of first appearance.
+type Entities struct {
+	Mentions []string
+	Hashtags []string
+}
+
+func Parse(text string) Entities {
+	var e Entities
+
+	for _, m := range mentionRe.FindAllStringSubmatch(text, -1) {
+		e.Mentions = appendUnique(e.Mentions, strings.ToLower(m[1]))
+	}
+
+	for _, m := range hashtagRe.FindAllStringSubmatch(text, -1) {
+		if tag, err := NormalizeTag(m[1]); err == nil {
+			e.Hashtags = appendUnique(e.Hashtags, tag)
+		}
+	}
+
+	return e
+}
+
+// NormalizeTag lower-cases tag, strips a leading '#' and joins words with
+// underscores, so "Home Office" becomes "home_office". Tags are made of
+// letters, digits and underscores and need at least one letter, so "#1" is
+// not a tag.
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+	tag = separatorRe.ReplaceAllString(tag, "_")
+
+	if tag == "" {
+		return "", fmt.Errorf("tag is empty")
+	}
+
+	if utf8.RuneCountInString(tag) > MaxTagLength {
+		return "", fmt.Errorf("tag %q is longer than %d characters", tag, MaxTagLength)
+	}
+
+	hasLetter := false
+	for _, r := range tag {
+		switch {
+		case unicode.IsLetter(r):
+			hasLetter = true
+		case unicode.IsDigit(r), r == '_':
+		default:
+			return "", fmt.Errorf("tag %q may only contain letters, digits and underscores", tag)
+		}
+	}
+
+	if !hasLetter {
+		return "", fmt.Errorf("tag %q must contain a letter", tag)
+	}
+
+	return tag, nil
+}
+
+// MergeTags normalizes and deduplicates the given tag lists, keeping their
+// order. It fails on the first invalid tag.
+func MergeTags(lists ...[]string) ([]string, error) {
+	tags := []string{}
+
+	for _, list := range lists {
+		for _, t := range list {
+			tag, err := NormalizeTag(t)
+			if err != nil {
+				return nil, err
+			}
+			tags = appendUnique(tags, tag)
+		}
+	}
+
+	return tags, nil
+}
+
+// Without returns the elements of s that are not in remove.
+func Without(s, remove []string) []string {
+	out := []string{}
+
+	for _, v := range s {
+		if !slices.Contains(remove, v) {
+			out = append(out, v)
+		}
+	}
+
+	return out
+}
+
+func appendUnique(s []string, v string) []string {
+	if slices.Contains(s, v) {
+		return s
+	}
+
+	return append(s, v)
+}
@@ -0,0 +1,79 @@
+package content
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		mentions []string
+		hashtags []string
+	}{
+		{
+			name:     "mentions and hashtags",
+			text:     "Thanks @Alice and @bob_99 for #GoLang tips #go",
+			mentions: []string{"alice", "bob_99"},
+			hashtags: []string{"golang", "go"},
+		},
+		{
+			name:     "deduplicates case-insensitively",
+			text:     "@alice @ALICE #Go #go",
+			mentions: []string{"alice"},
+			hashtags: []string{"go"},
+		},
+		{
+			name:     "trailing punctuation",
+			text:     "Ask @jane.doe. Or (@bob)! #weekend, #fun.",
+			mentions: []string{"jane.doe", "bob"},
+			hashtags: []string{"weekend", "fun"},
+		},
+		{
+			name: "ignores emails, anchors and numbers",
+			text: "mail me at me@example.com, see page#top, issue #42 and &#39;",
+		},
+		{
+			name:     "unicode",
+			text:     "#café con @josé",
+			mentions: []string{"josé"},
+			hashtags: []string{"café"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Parse(tt.text)
+
+			if !reflect.DeepEqual(got.Mentions, tt.mentions) {
+				t.Errorf("mentions: expected %v, got %v", tt.mentions, got.Mentions)
+			}
+			if !reflect.DeepEqual(got.Hashtags, tt.hashtags) {
+				t.Errorf("hashtags: expected %v, got %v", tt.hashtags, got.Hashtags)
+			}
+		})
+	}
+}
+
+func TestMergeTags(t *testing.T) {
+	t.Run("should normalize and deduplicate", func(t *testing.T) {
+		got, err := MergeTags([]string{"#Go", "Web Dev"}, []string{"go", "postgres"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []string{"go", "web_dev", "postgres"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("should reject invalid tags", func(t *testing.T) {
+		for _, tag := range []string{"", "#", "c++", "123", "a.b"} {
+			if _, err := MergeTags([]string{tag}); err == nil {
+				t.Errorf("expected %q to be rejected", tag)
+			}
+		}
+	})
+}
@@ -0,0 +1,75 @@
+//go:build integration
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/kuluruvineeth/social-go/cmd/migrate/migrations"
+	"github.com/kuluruvineeth/social-go/internal/migrate"
+	"github.com/kuluruvineeth/social-go/internal/storetest"
+	"go.uber.org/zap"
+)
+
+// testDBAddr is a migrated database, empty when no Postgres is available.
+var testDBAddr string
+
+func TestMain(m *testing.M) {
+	pg, err := storetest.StartPostgres()
+	if errors.Is(err, storetest.ErrUnavailable) {
+		log.Println("no Postgres available, skipping seed integration tests")
+		os.Exit(m.Run())
+	}
+	if err != nil {
+		log.Fatalf("starting Postgres: %v", err)
+	}
+
+	code := runIntegration(m, pg)
+
+	if err := pg.Stop(); err != nil {
+		log.Printf("stopping Postgres: %v", err)
+	}
+
+	os.Exit(code)
+}
+
+func runIntegration(m *testing.M, pg *storetest.Postgres) int {
+	name := fmt.Sprintf("social_go_seed_test_%d", time.Now().UnixNano())
+
+	addr, err := pg.CreateDatabase(name)
+	if err != nil {
+		log.Printf("creating test database: %v", err)
+		return 1
+	}
+	defer func() {
+		if err := pg.DropDatabase(name); err != nil {
+			log.Printf("dropping test database: %v", err)
+		}
+	}()
+
+	db, err := sql.Open("postgres", addr)
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+
+	migrator, err := migrate.New(db, migrations.FS, zap.NewNop().Sugar())
+	if err == nil {
+		err = migrator.Up(context.Background())
+	}
+	db.Close()
+	if err != nil {
+		log.Printf("applying migrations: %v", err)
+		return 1
+	}
+
+	testDBAddr = addr
+	return m.Run()
+}
@@ -3,6 +3,7 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"math/rand"
@@ -10,6 +11,33 @@ import (
 	"github.com/kuluruvineeth/social-go/internal/store"
 )
 
+// SeedEmailDomain is the email domain of seeded users. A run first removes
+// the users with it, along with everything they created, so re-running with
+// the same config always leaves the same data behind.
+const SeedEmailDomain = "seed.example.com"
+
+// zipfS is the exponent of the power law used to pick popular users and
+// posts. Higher values concentrate more follows and comments on fewer of
+// them.
+const zipfS = 1.2
+
+type SeedConfig struct {
+	Users    int
+	Posts    int
+	Comments int
+	Follows  int
+	// Admins and Moderators are how many of the users get those roles, the
+	// rest are plain users.
+	Admins     int
+	Moderators int
+	// Password is set for every seeded user, who are all active so load
+	// tests can log in as any of them.
+	Password string
+	// Seed makes runs reproducible. Runs with the same seed and counts
+	// generate the same users, posts, comments and follows.
+	Seed int64
+}
+
 var usernames = []string{
 	"alice", "bob", "charlie", "dave", "eve", "frank", "grace", "heidi",
 	"ivan", "judy", "karl", "laura", "mallory", "nina", "oscar", "peggy",
@@ -54,10 +82,10 @@ var contents = []string{
 }
 
 var tags = []string{
-	"Self Improvement", "Minimalism", "Health", "Travel", "Mindfulness",
-	"Productivity", "Home Office", "Digital Detox", "Gardening", "DIY",
-	"Yoga", "Sustainability", "Time Management", "Nature", "Cooking",
-	"Fitness", "Personal Finance", "Writing", "Mental Health", "Learning",
+	"self_improvement", "minimalism", "health", "travel", "mindfulness",
+	"productivity", "home_office", "digital_detox", "gardening", "diy",
+	"yoga", "sustainability", "time_management", "nature", "cooking",
+	"fitness", "personal_finance", "writing", "mental_health", "learning",
 }
 
 var comments = []string{
@@ -73,70 +101,178 @@ var comments = []string{
 	"Thanks for the information, very useful.",
 }
 
-func Seed(store store.Storage, db *sql.DB) {
-	ctx := context.Background()
+func Seed(ctx context.Context, store store.Storage, db *sql.DB, cfg SeedConfig) error {
+	if cfg.Users < 2 && cfg.Follows > 0 {
+		return errors.New("seeding follows needs at least 2 users")
+	}
+	if cfg.Users == 0 && (cfg.Posts > 0 || cfg.Comments > 0) {
+		return errors.New("seeding posts and comments needs users")
+	}
+	if cfg.Posts == 0 && cfg.Comments > 0 {
+		return errors.New("seeding comments needs posts")
+	}
+	if cfg.Admins+cfg.Moderators > cfg.Users {
+		return errors.New("more admins and moderators than users")
+	}
 
-	users := generateUsers(100)
-	tx, _ := db.BeginTx(ctx, nil)
+	r := rand.New(rand.NewSource(cfg.Seed))
+
+	removed, err := deleteSeeded(ctx, db)
+	if err != nil {
+		return fmt.Errorf("failed to remove previously seeded data: %w", err)
+	}
+	if removed > 0 {
+		log.Printf("removed %d previously seeded users and their content", removed)
+	}
+
+	users, err := generateUsers(cfg)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
 
 	for _, user := range users {
 		if err := store.Users.Create(ctx, tx, user); err != nil {
 			_ = tx.Rollback()
-			log.Fatalf("failed to create user: %v", err)
-			return
+			return fmt.Errorf("failed to create user: %w", err)
 		}
 	}
 
-	tx.Commit()
+	if _, err := tx.ExecContext(ctx, `UPDATE users SET is_active = true WHERE email LIKE '%@' || $1`, SeedEmailDomain); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Printf("created %d users", len(users))
 
-	posts := generatePosts(200, users)
+	// Popular users post more and get followed more. Ranks are shuffled so
+	// they aren't always the first users created.
+	popular := r.Perm(len(users))
 
+	posts := generatePosts(r, cfg.Posts, users, popular)
 	for _, post := range posts {
 		if err := store.Posts.Create(ctx, post); err != nil {
-			log.Fatalf("failed to create post: %v", err)
-			return
+			return fmt.Errorf("failed to create post: %w", err)
 		}
 	}
+	log.Printf("created %d posts", len(posts))
 
-	cmts := generateComments(500, users, posts)
-
+	cmts := generateComments(r, cfg.Comments, users, posts)
 	for _, cmt := range cmts {
 		if err := store.Comments.Create(ctx, cmt); err != nil {
-			log.Fatalf("failed to create comment: %v", err)
+			return fmt.Errorf("failed to create comment: %w", err)
+		}
+	}
+	log.Printf("created %d comments", len(cmts))
+
+	follows := generateFollows(r, cfg.Follows, len(users), popular)
+	for _, f := range follows {
+		if err := store.Followers.Follow(ctx, users[f.follower].ID, users[f.user].ID); err != nil {
+			return fmt.Errorf("failed to create follow: %w", err)
 		}
 	}
+	log.Printf("created %d follows", len(follows))
 
 	log.Println("seed completed")
+	return nil
+}
+
+// deleteSeeded removes the users created by previous runs, and the posts,
+// comments and timeline jobs that don't cascade with them.
+func deleteSeeded(ctx context.Context, db *sql.DB) (int64, error) {
+	seeded := `SELECT id FROM users WHERE email LIKE '%@' || $1`
+
+	queries := []string{
+		`DELETE FROM comments WHERE user_id IN (` + seeded + `) OR post_id IN (SELECT id FROM posts WHERE user_id IN (` + seeded + `))`,
+		`DELETE FROM timeline_jobs WHERE user_id IN (` + seeded + `) OR (type <> 'fanout' AND target_id IN (` + seeded + `))`,
+		`DELETE FROM posts WHERE user_id IN (` + seeded + `)`,
+		`DELETE FROM user_invitations WHERE user_id IN (` + seeded + `)`,
+	}
+
+	var removed int64
+	err := func() error {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		for _, query := range queries {
+			if _, err := tx.ExecContext(ctx, query, SeedEmailDomain); err != nil {
+				return err
+			}
+		}
+
+		res, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id IN (`+seeded+`)`, SeedEmailDomain)
+		if err != nil {
+			return err
+		}
+		if removed, err = res.RowsAffected(); err != nil {
+			return err
+		}
+
+		return tx.Commit()
+	}()
+
+	return removed, err
 }
 
-func generateUsers(n int) []*store.User {
-	users := make([]*store.User, n)
+func generateUsers(cfg SeedConfig) ([]*store.User, error) {
+	users := make([]*store.User, cfg.Users)
+
+	// Hashing is slow, so every user shares the same hash.
+	var pw store.User
+	if err := pw.Password.Set(cfg.Password); err != nil {
+		return nil, err
+	}
 
-	for i := 0; i < n; i++ {
+	for i := 0; i < cfg.Users; i++ {
+		role := "user"
+		switch {
+		case i < cfg.Admins:
+			role = "admin"
+		case i < cfg.Admins+cfg.Moderators:
+			role = "moderator"
+		}
+
+		username := usernames[i%len(usernames)] + fmt.Sprintf("%d", i)
 		users[i] = &store.User{
-			Username: usernames[i%len(usernames)] + fmt.Sprintf("%d", i),
-			Email:    usernames[i%len(usernames)] + fmt.Sprintf("%d", i) + "@example.com",
+			Username: username,
+			Email:    username + "@" + SeedEmailDomain,
+			Password: pw.Password,
 			Role: store.Role{
-				Name: "user",
+				Name: role,
 			},
 		}
 	}
 
-	return users
+	return users, nil
 }
 
-func generatePosts(num int, users []*store.User) []*store.Post {
+func generatePosts(r *rand.Rand, num int, users []*store.User, popular []int) []*store.Post {
 	posts := make([]*store.Post, num)
+	if num == 0 {
+		return posts
+	}
+
+	authors := rand.NewZipf(r, zipfS, 1, uint64(len(users)-1))
 
 	for i := 0; i < num; i++ {
-		user := users[rand.Intn(len(users))]
+		user := users[popular[authors.Uint64()]]
 		posts[i] = &store.Post{
 			UserID:  user.ID,
-			Title:   titles[rand.Intn(len(titles))],
-			Content: contents[rand.Intn(len(contents))],
+			Title:   titles[r.Intn(len(titles))],
+			Content: contents[r.Intn(len(contents))],
 			Tags: []string{
-				tags[rand.Intn(len(tags))],
-				tags[rand.Intn(len(tags))],
+				tags[r.Intn(len(tags))],
+				tags[r.Intn(len(tags))],
 			},
 		}
 	}
@@ -144,16 +280,67 @@ func generatePosts(num int, users []*store.User) []*store.Post {
 	return posts
 }
 
-func generateComments(num int, users []*store.User, posts []*store.Post) []*store.Comment {
+func generateComments(r *rand.Rand, num int, users []*store.User, posts []*store.Post) []*store.Comment {
 	cmts := make([]*store.Comment, num)
+	if num == 0 {
+		return cmts
+	}
+
+	// A few posts get most of the comments.
+	popular := r.Perm(len(posts))
+	picks := rand.NewZipf(r, zipfS, 1, uint64(len(posts)-1))
 
 	for i := 0; i < num; i++ {
 		cmts[i] = &store.Comment{
-			UserID:  users[rand.Intn(len(users))].ID,
-			PostID:  posts[rand.Intn(len(posts))].ID,
-			Content: comments[rand.Intn(len(comments))],
+			UserID:  users[r.Intn(len(users))].ID,
+			PostID:  posts[popular[picks.Uint64()]].ID,
+			Content: comments[r.Intn(len(comments))],
 		}
 	}
 
 	return cmts
 }
+
+// follow is an edge of the follow graph, between indexes of the users.
+type follow struct {
+	follower int
+	user     int
+}
+
+// generateFollows draws followers uniformly and the users they follow from
+// a power law, so a few users end up with most of the followers, as on real
+// networks. There are no self or duplicate follows, and fewer than num follows
+// when the users can't make that many.
+func generateFollows(r *rand.Rand, num, users int, popular []int) []follow {
+	if max := users * (users - 1); num > max {
+		num = max
+	}
+
+	follows := make([]follow, 0, num)
+	if num == 0 {
+		return follows
+	}
+
+	seen := make(map[follow]bool, num)
+	picks := rand.NewZipf(r, zipfS, 1, uint64(users-1))
+
+	// The power law rarely picks unpopular users, so dense graphs fall back
+	// to uniform picks rather than looping for ever.
+	for attempts := 0; len(follows) < num; attempts++ {
+		f := follow{follower: r.Intn(users)}
+		if attempts < num*10 {
+			f.user = popular[picks.Uint64()]
+		} else {
+			f.user = r.Intn(users)
+		}
+
+		if f.follower == f.user || seen[f] {
+			continue
+		}
+
+		seen[f] = true
+		follows = append(follows, f)
+	}
+
+	return follows
+}
@@ -0,0 +1,102 @@
+//go:build integration
+
+package db
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/kuluruvineeth/social-go/internal/store"
+	"github.com/kuluruvineeth/social-go/internal/storetest"
+)
+
+func TestSeed(t *testing.T) {
+	if testDBAddr == "" {
+		t.Skip("no Postgres available")
+	}
+
+	db := storetest.TxDB(t, testDBAddr)
+	s := store.NewStorage(db)
+	ctx := context.Background()
+
+	cfg := SeedConfig{Users: 20, Posts: 40, Comments: 60, Follows: 80, Admins: 1, Moderators: 2, Password: "password", Seed: 7}
+
+	snapshot := func(t *testing.T) []string {
+		t.Helper()
+
+		// IDs change between runs, so rows are compared by username.
+		query := `
+			SELECT u.username || ' ' || r.name || ' ' || u.followers_count || ' ' ||
+				(SELECT COUNT(*) FROM posts p WHERE p.user_id = u.id) || ' ' ||
+				(SELECT COUNT(*) FROM comments c WHERE c.user_id = u.id)
+			FROM users u JOIN roles r ON r.id = u.role_id
+			WHERE u.is_active
+			ORDER BY u.username
+		`
+
+		rows, err := db.Query(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+
+		var got []string
+		for rows.Next() {
+			var row string
+			if err := rows.Scan(&row); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, row)
+		}
+
+		return got
+	}
+
+	count := func(t *testing.T, query string) int {
+		t.Helper()
+
+		var n int
+		if err := db.QueryRow(query).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	// Users that weren't seeded are left alone.
+	if _, err := db.Exec(`INSERT INTO users (username, email, password) VALUES ('other', 'other@example.com', '\x00')`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Seed(ctx, s, db, cfg); err != nil {
+		t.Fatal(err)
+	}
+	first := snapshot(t)
+
+	if len(first) != cfg.Users {
+		t.Fatalf("expected %d active users, got %d", cfg.Users, len(first))
+	}
+	if n := count(t, `SELECT COUNT(*) FROM followers`); n != cfg.Follows {
+		t.Errorf("expected %d follows, got %d", cfg.Follows, n)
+	}
+	if n := count(t, `SELECT COUNT(*) FROM users u JOIN roles r ON r.id = u.role_id WHERE r.name = 'moderator'`); n != cfg.Moderators {
+		t.Errorf("expected %d moderators, got %d", cfg.Moderators, n)
+	}
+
+	if err := Seed(ctx, s, db, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := snapshot(t); !slices.Equal(got, first) {
+		t.Errorf("expected a re-run to generate the same data\nfirst:  %v\nsecond: %v", first, got)
+	}
+	for table, want := range map[string]int{"users": cfg.Users + 1, "posts": cfg.Posts, "comments": cfg.Comments, "followers": cfg.Follows} {
+		if n := count(t, `SELECT COUNT(*) FROM `+table); n != want {
+			t.Errorf("expected %d rows in %s after a re-run, got %d", want, table, n)
+		}
+	}
+
+	if n := count(t, `SELECT COUNT(*) FROM users WHERE username = 'other'`); n != 1 {
+		t.Error("expected other users to be kept")
+	}
+}
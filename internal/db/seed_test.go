@@ -0,0 +1,83 @@
+package db
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestGenerateFollows(t *testing.T) {
+	generate := func(seed int64, num, users int) []follow {
+		r := rand.New(rand.NewSource(seed))
+		return generateFollows(r, num, users, r.Perm(users))
+	}
+
+	t.Run("should be reproducible", func(t *testing.T) {
+		if !reflect.DeepEqual(generate(42, 500, 100), generate(42, 500, 100)) {
+			t.Error("expected the same follows for the same seed")
+		}
+		if reflect.DeepEqual(generate(42, 500, 100), generate(43, 500, 100)) {
+			t.Error("expected different follows for different seeds")
+		}
+	})
+
+	t.Run("should not repeat or self follow", func(t *testing.T) {
+		follows := generate(1, 2000, 100)
+		if len(follows) != 2000 {
+			t.Fatalf("expected 2000 follows, got %d", len(follows))
+		}
+
+		seen := map[follow]bool{}
+		for _, f := range follows {
+			if f.follower == f.user {
+				t.Fatalf("unexpected self follow %+v", f)
+			}
+			if seen[f] {
+				t.Fatalf("unexpected duplicate follow %+v", f)
+			}
+			seen[f] = true
+		}
+	})
+
+	t.Run("should fill a complete graph", func(t *testing.T) {
+		if got := len(generate(1, 1000, 10)); got != 90 {
+			t.Errorf("expected every possible follow, got %d", got)
+		}
+	})
+
+	t.Run("should concentrate followers on a few users", func(t *testing.T) {
+		followers := make([]int, 1000)
+		for _, f := range generate(1, 10_000, 1000) {
+			followers[f.user]++
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(followers)))
+
+		top := 0
+		for _, n := range followers[:10] {
+			top += n
+		}
+
+		// Spread uniformly, the top 1% would have around 1% of the follows.
+		if top < 2_000 {
+			t.Errorf("expected the top 10 users to have at least 20%% of the follows, got %d", top)
+		}
+	})
+}
+
+func TestGenerateUsers(t *testing.T) {
+	users, err := generateUsers(SeedConfig{Users: 5, Admins: 1, Moderators: 2, Password: "password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"admin", "moderator", "moderator", "user", "user"}
+	for i, u := range users {
+		if u.Role.Name != want[i] {
+			t.Errorf("expected user %d to be a %s, got %s", i, want[i], u.Role.Name)
+		}
+		if u.Email != u.Username+"@"+SeedEmailDomain {
+			t.Errorf("unexpected email %s", u.Email)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package deletion
+
+import (
+	"context"
+	"time"
+
+	"github.com/kuluruvineeth/social-go/internal/blob"
+	"go.uber.org/zap"
+)
+
+type Store interface {
+	GetDueDeletions(context.Context, int) ([]int64, error)
+	Delete(context.Context, int64) ([]string, error)
+}
+
+type Config struct {
+	PollInterval time.Duration
+	BatchSize    int
+}
+
+// Worker deletes the accounts whose grace period is over, then the blobs
+// that belonged to them. onDelete is called for each deleted user for
+// whatever else has to forget them, like caches.
+type Worker struct {
+	store    Store
+	blobs    blob.Store
+	logger   *zap.SugaredLogger
+	cfg      Config
+	onDelete func(context.Context, int64)
+}
+
+func NewWorker(store Store, blobs blob.Store, logger *zap.SugaredLogger, cfg Config, onDelete func(context.Context, int64)) *Worker {
+	return &Worker{
+		store:    store,
+		blobs:    blobs,
+		logger:   logger,
+		cfg:      cfg,
+		onDelete: onDelete,
+	}
+}
+
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+
+	w.logger.Infow("account deletion worker has started", "poll_interval", w.cfg.PollInterval.String())
+
+	for {
+		if _, err := w.ProcessBatch(ctx); err != nil {
+			w.logger.Errorw("failed to delete accounts", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			w.logger.Info("account deletion worker has stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessBatch deletes a single batch of due accounts and returns how many
+// were deleted. Accounts that fail are left for the next batch.
+func (w *Worker) ProcessBatch(ctx context.Context) (int, error) {
+	ids, err := w.store.GetDueDeletions(ctx, w.cfg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, id := range ids {
+		keys, err := w.store.Delete(ctx, id)
+		if err != nil {
+			w.logger.Errorw("failed to delete account", "user_id", id, "error", err)
+			continue
+		}
+
+		for _, key := range keys {
+			if err := w.blobs.Delete(ctx, key); err != nil {
+				w.logger.Errorw("failed to delete blob", "key", key, "error", err)
+			}
+		}
+
+		w.logger.Infow("account deleted", "user_id", id)
+		deleted++
+
+		if w.onDelete != nil {
+			w.onDelete(ctx, id)
+		}
+	}
+
+	return deleted, nil
+}
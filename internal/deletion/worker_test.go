@@ -0,0 +1,72 @@
+package deletion
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/kuluruvineeth/social-go/internal/blob"
+	"go.uber.org/zap"
+)
+
+type fakeStore struct {
+	due     []int64
+	keys    map[int64][]string
+	fail    map[int64]bool
+	deleted []int64
+}
+
+func (s *fakeStore) GetDueDeletions(ctx context.Context, limit int) ([]int64, error) {
+	ids := s.due
+	s.due = nil
+	return ids, nil
+}
+
+func (s *fakeStore) Delete(ctx context.Context, id int64) ([]string, error) {
+	if s.fail[id] {
+		return nil, errors.New("deadlock detected")
+	}
+	s.deleted = append(s.deleted, id)
+	return s.keys[id], nil
+}
+
+func TestWorkerProcessBatch(t *testing.T) {
+	ctx := context.Background()
+
+	blobs, err := blob.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := blobs.Put(ctx, "attachments/a.jpg", strings.NewReader("jpg"), 3, "image/jpeg"); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &fakeStore{
+		due:  []int64{1, 2, 3},
+		keys: map[int64][]string{1: {"attachments/a.jpg"}},
+		fail: map[int64]bool{2: true},
+	}
+
+	var forgotten []int64
+	w := NewWorker(s, blobs, zap.NewNop().Sugar(), Config{BatchSize: 10}, func(ctx context.Context, id int64) {
+		forgotten = append(forgotten, id)
+	})
+
+	n, err := w.ProcessBatch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != 2 || len(s.deleted) != 2 || s.deleted[0] != 1 || s.deleted[1] != 3 {
+		t.Errorf("expected users 1 and 3 to be deleted, got %v", s.deleted)
+	}
+	if len(forgotten) != 2 || forgotten[0] != 1 || forgotten[1] != 3 {
+		t.Errorf("expected users 1 and 3 to be handed over, got %v", forgotten)
+	}
+
+	if _, err := blobs.Get(ctx, "attachments/a.jpg"); !errors.Is(err, blob.ErrNotFound) {
+		t.Errorf("expected the user's blobs to be deleted, got %v", err)
+	}
+}
@@ -0,0 +1,175 @@
+// Package diff compares two versions of user written text word by word.
+package diff
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+type Op string
+
+const (
+	Equal  Op = "equal"
+	Insert Op = "insert"
+	Delete Op = "delete"
+)
+
+// Edit is a run of text that is kept, inserted or deleted going from the old
+// text to the new one.
+type Edit struct {
+	Op   Op     `json:"op"`
+	Text string `json:"text"`
+}
+
+// tokenRe splits text into words and the whitespace between them, so that
+// joining the tokens gives back the text.
+var tokenRe = regexp.MustCompile(`\s+|\S+`)
+
+// Words returns the edits turning a into b. Concatenating the Equal and
+// Delete edits gives a, and the Equal and Insert ones give b. Where text was
+// replaced, the deletion comes before the insertion.
+func Words(a, b string) []Edit {
+	x := tokenRe.FindAllString(a, -1)
+	y := tokenRe.FindAllString(b, -1)
+
+	// The common prefix and suffix are left out of the table below, which is
+	// all that changes in a typical edit.
+	prefix := 0
+	for prefix < len(x) && prefix < len(y) && x[prefix] == y[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(x)-prefix && suffix < len(y)-prefix && x[len(x)-1-suffix] == y[len(y)-1-suffix] {
+		suffix++
+	}
+
+	var edits []Edit
+	for _, t := range x[:prefix] {
+		edits = appendEdit(edits, Equal, t)
+	}
+	edits = appendLCS(edits, x[prefix:len(x)-suffix], y[prefix:len(y)-suffix])
+	for _, t := range x[len(x)-suffix:] {
+		edits = appendEdit(edits, Equal, t)
+	}
+
+	return merge(edits)
+}
+
+// merge folds the whitespace kept between two changes into them, and joins
+// the changes between two kept runs into one deletion and one insertion, so
+// that rewritten sentences don't read as a word by word interleaving.
+func merge(edits []Edit) []Edit {
+	var merged []Edit
+	var deleted, inserted strings.Builder
+
+	flush := func() {
+		if deleted.Len() > 0 {
+			merged = append(merged, Edit{Op: Delete, Text: deleted.String()})
+		}
+		if inserted.Len() > 0 {
+			merged = append(merged, Edit{Op: Insert, Text: inserted.String()})
+		}
+		deleted.Reset()
+		inserted.Reset()
+	}
+
+	for i, e := range edits {
+		between := i > 0 && i < len(edits)-1 && edits[i-1].Op != Equal && edits[i+1].Op != Equal
+		if e.Op == Equal && between && strings.TrimSpace(e.Text) == "" {
+			deleted.WriteString(e.Text)
+			inserted.WriteString(e.Text)
+			continue
+		}
+
+		switch e.Op {
+		case Delete:
+			deleted.WriteString(e.Text)
+		case Insert:
+			inserted.WriteString(e.Text)
+		default:
+			flush()
+			merged = append(merged, e)
+		}
+	}
+	flush()
+
+	return merged
+}
+
+// appendLCS appends the edits between x and y along their longest common
+// subsequence.
+func appendLCS(edits []Edit, x, y []string) []Edit {
+	// lcs[i][j] is the length of the longest common subsequence of x[i:]
+	// and y[j:].
+	lcs := make([][]int32, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(y)+1)
+	}
+
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			edits = appendEdit(edits, Equal, x[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			edits = appendEdit(edits, Delete, x[i])
+			i++
+		default:
+			edits = appendEdit(edits, Insert, y[j])
+			j++
+		}
+	}
+
+	for ; i < len(x); i++ {
+		edits = appendEdit(edits, Delete, x[i])
+	}
+	for ; j < len(y); j++ {
+		edits = appendEdit(edits, Insert, y[j])
+	}
+
+	return edits
+}
+
+// appendEdit appends text to the last edit when it has the same op.
+func appendEdit(edits []Edit, op Op, text string) []Edit {
+	if n := len(edits); n > 0 && edits[n-1].Op == op {
+		edits[n-1].Text += text
+		return edits
+	}
+
+	return append(edits, Edit{Op: op, Text: text})
+}
+
+// Sets returns the elements of b missing from a, and those of a missing from
+// b, in their original order.
+func Sets(a, b []string) (added, removed []string) {
+	added, removed = []string{}, []string{}
+
+	for _, s := range b {
+		if !slices.Contains(a, s) {
+			added = append(added, s)
+		}
+	}
+
+	for _, s := range a {
+		if !slices.Contains(b, s) {
+			removed = append(removed, s)
+		}
+	}
+
+	return added, removed
+}
@@ -0,0 +1,96 @@
+package diff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWords(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []Edit
+	}{
+		{
+			name: "unchanged",
+			a:    "hello world",
+			b:    "hello world",
+			want: []Edit{{Equal, "hello world"}},
+		},
+		{
+			name: "both empty",
+		},
+		{
+			name: "from empty",
+			b:    "hello",
+			want: []Edit{{Insert, "hello"}},
+		},
+		{
+			name: "replaced word",
+			a:    "the quick fox jumps",
+			b:    "the slow fox jumps",
+			want: []Edit{{Equal, "the "}, {Delete, "quick"}, {Insert, "slow"}, {Equal, " fox jumps"}},
+		},
+		{
+			name: "appended",
+			a:    "first draft",
+			b:    "first draft, edited",
+			want: []Edit{{Equal, "first "}, {Delete, "draft"}, {Insert, "draft, edited"}},
+		},
+		{
+			name: "removed in the middle",
+			a:    "one two three four",
+			b:    "one four",
+			want: []Edit{{Equal, "one "}, {Delete, "two three "}, {Equal, "four"}},
+		},
+		{
+			name: "rewritten",
+			a:    "a b",
+			b:    "c d",
+			want: []Edit{{Delete, "a b"}, {Insert, "c d"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Words(tt.a, tt.b)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWordsReconstructs(t *testing.T) {
+	a := "Going to the #gophercon this year with @alice, who's in?\nSee you there"
+	b := "Going to #gophercon next year with @alice and @bob, who else is in?\n\nSee you"
+
+	var old, new strings.Builder
+	for _, e := range Words(a, b) {
+		if e.Op != Insert {
+			old.WriteString(e.Text)
+		}
+		if e.Op != Delete {
+			new.WriteString(e.Text)
+		}
+	}
+
+	if old.String() != a {
+		t.Errorf("expected old text %q, got %q", a, old.String())
+	}
+	if new.String() != b {
+		t.Errorf("expected new text %q, got %q", b, new.String())
+	}
+}
+
+func TestSets(t *testing.T) {
+	added, removed := Sets([]string{"go", "db", "api"}, []string{"api", "go", "web"})
+
+	if !reflect.DeepEqual(added, []string{"web"}) {
+		t.Errorf("added: expected [web], got %v", added)
+	}
+	if !reflect.DeepEqual(removed, []string{"db"}) {
+		t.Errorf("removed: expected [db], got %v", removed)
+	}
+}
@@ -3,6 +3,8 @@ package env
 import (
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 func GetString(key, fallback string) string {
@@ -41,3 +43,34 @@ func GetBool(key string, fallback bool) bool {
 
 	return boolVal
 }
+
+func GetDuration(key string, fallback time.Duration) time.Duration {
+	val, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		return fallback
+	}
+
+	return d
+}
+
+// GetStrings splits a comma separated list, dropping empty items.
+func GetStrings(key string, fallback []string) []string {
+	val, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+
+	items := []string{}
+	for _, item := range strings.Split(val, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
@@ -0,0 +1,152 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kuluruvineeth/social-go/internal/blob"
+	"github.com/kuluruvineeth/social-go/internal/outbox"
+	"github.com/kuluruvineeth/social-go/internal/store"
+	"go.uber.org/zap"
+)
+
+type Store interface {
+	ClaimDue(context.Context, int, time.Duration) ([]store.Export, error)
+	Archive(context.Context, int64) ([]byte, error)
+	MarkReady(context.Context, int64, string, int64, time.Duration) error
+	MarkFailed(context.Context, int64, string, time.Time, bool) error
+	DeleteExpired(context.Context, int) ([]string, error)
+}
+
+type Config struct {
+	PollInterval time.Duration
+	BatchSize    int
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	// Lease is how long a claimed export stays hidden from other workers
+	// while it is being built.
+	Lease time.Duration
+	// TTL is how long archives can be downloaded before they're deleted.
+	TTL time.Duration
+}
+
+// Worker builds the archives of requested data exports and stores them in
+// the blob store, retrying failures with exponential backoff. It also
+// deletes the archives once they expire.
+type Worker struct {
+	store  Store
+	blobs  blob.Store
+	logger *zap.SugaredLogger
+	cfg    Config
+	now    func() time.Time
+}
+
+func NewWorker(store Store, blobs blob.Store, logger *zap.SugaredLogger, cfg Config) *Worker {
+	return &Worker{
+		store:  store,
+		blobs:  blobs,
+		logger: logger,
+		cfg:    cfg,
+		now:    time.Now,
+	}
+}
+
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+
+	w.logger.Infow("export worker has started", "poll_interval", w.cfg.PollInterval.String())
+
+	for {
+		if _, err := w.ProcessBatch(ctx); err != nil {
+			w.logger.Errorw("failed to process exports", "error", err)
+		}
+
+		if err := w.DeleteExpired(ctx); err != nil {
+			w.logger.Errorw("failed to delete expired exports", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			w.logger.Info("export worker has stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessBatch claims and builds a single batch of exports and returns how
+// many were claimed.
+func (w *Worker) ProcessBatch(ctx context.Context) (int, error) {
+	exports, err := w.store.ClaimDue(ctx, w.cfg.BatchSize, w.cfg.Lease)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, e := range exports {
+		w.build(ctx, e)
+	}
+
+	return len(exports), nil
+}
+
+func (w *Worker) build(ctx context.Context, e store.Export) {
+	archive, err := w.store.Archive(ctx, e.UserID)
+	if err != nil {
+		w.fail(ctx, e, err)
+		return
+	}
+
+	// Blobs are served by key, so the key must not be guessable
+	key := "exports/" + uuid.NewString() + ".json"
+
+	if err := w.blobs.Put(ctx, key, bytes.NewReader(archive), int64(len(archive)), "application/json"); err != nil {
+		w.fail(ctx, e, err)
+		return
+	}
+
+	if err := w.store.MarkReady(ctx, e.ID, key, int64(len(archive)), w.cfg.TTL); err != nil {
+		w.logger.Errorw("failed to mark export as ready", "id", e.ID, "error", err)
+		w.deleteBlobs(ctx, key)
+		return
+	}
+
+	w.logger.Debugw("export ready", "id", e.ID, "user_id", e.UserID, "size", len(archive))
+}
+
+func (w *Worker) fail(ctx context.Context, e store.Export, buildErr error) {
+	next := w.now().Add(outbox.Backoff(e.Attempts, w.cfg.BaseBackoff, w.cfg.MaxBackoff))
+	dead := e.Attempts >= w.cfg.MaxAttempts
+
+	if dead {
+		w.logger.Errorw("export failed", "id", e.ID, "attempts", e.Attempts, "error", buildErr)
+	} else {
+		w.logger.Warnw("export attempt failed", "id", e.ID, "attempts", e.Attempts, "next_attempt_at", next, "error", buildErr)
+	}
+
+	if err := w.store.MarkFailed(ctx, e.ID, buildErr.Error(), next, dead); err != nil {
+		w.logger.Errorw("failed to mark export as failed", "id", e.ID, "error", err)
+	}
+}
+
+// DeleteExpired deletes a batch of expired exports and their archives.
+func (w *Worker) DeleteExpired(ctx context.Context) error {
+	keys, err := w.store.DeleteExpired(ctx, w.cfg.BatchSize)
+	if err != nil {
+		return err
+	}
+
+	w.deleteBlobs(ctx, keys...)
+	return nil
+}
+
+func (w *Worker) deleteBlobs(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		if err := w.blobs.Delete(ctx, key); err != nil {
+			w.logger.Errorw("failed to delete blob", "key", key, "error", err)
+		}
+	}
+}
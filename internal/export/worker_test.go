@@ -0,0 +1,145 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kuluruvineeth/social-go/internal/blob"
+	"github.com/kuluruvineeth/social-go/internal/store"
+	"go.uber.org/zap"
+)
+
+type fakeStore struct {
+	due        []store.Export
+	archiveErr error
+	ready      map[int64]string
+	failed     map[int64]bool
+	expired    []string
+}
+
+func (s *fakeStore) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]store.Export, error) {
+	exports := s.due
+	s.due = nil
+	return exports, nil
+}
+
+func (s *fakeStore) Archive(ctx context.Context, userID int64) ([]byte, error) {
+	if s.archiveErr != nil {
+		return nil, s.archiveErr
+	}
+	return []byte(`{"profile":{"id":1}}`), nil
+}
+
+func (s *fakeStore) MarkReady(ctx context.Context, id int64, key string, size int64, ttl time.Duration) error {
+	s.ready[id] = key
+	return nil
+}
+
+func (s *fakeStore) MarkFailed(ctx context.Context, id int64, lastErr string, next time.Time, dead bool) error {
+	s.failed[id] = dead
+	return nil
+}
+
+func (s *fakeStore) DeleteExpired(ctx context.Context, limit int) ([]string, error) {
+	keys := s.expired
+	s.expired = nil
+	return keys, nil
+}
+
+func newTestWorker(t *testing.T, s *fakeStore) (*Worker, blob.Store) {
+	blobs, err := blob.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewWorker(s, blobs, zap.NewNop().Sugar(), Config{
+		BatchSize:   10,
+		MaxAttempts: 3,
+		BaseBackoff: time.Second,
+		MaxBackoff:  time.Minute,
+		Lease:       time.Minute,
+		TTL:         time.Hour,
+	}), blobs
+}
+
+func TestWorkerProcessBatch(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should store the archive and mark the export as ready", func(t *testing.T) {
+		s := &fakeStore{ready: map[int64]string{}, failed: map[int64]bool{}, due: []store.Export{{ID: 1, UserID: 1, Attempts: 1}}}
+		w, blobs := newTestWorker(t, s)
+
+		n, err := w.ProcessBatch(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		key, ok := s.ready[1]
+		if n != 1 || !ok {
+			t.Fatalf("expected export 1 to be ready, got %v", s.ready)
+		}
+		if !strings.HasPrefix(key, "exports/") {
+			t.Errorf("expected the archive under exports/, got %q", key)
+		}
+
+		obj, err := blobs.Get(ctx, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer obj.Close()
+
+		data, _ := io.ReadAll(obj)
+		if string(data) != `{"profile":{"id":1}}` || obj.ContentType != "application/json" {
+			t.Errorf("unexpected archive %s of type %s", data, obj.ContentType)
+		}
+	})
+
+	t.Run("should retry failures below the attempt limit", func(t *testing.T) {
+		s := &fakeStore{ready: map[int64]string{}, failed: map[int64]bool{}, archiveErr: errors.New("timeout"), due: []store.Export{{ID: 1, UserID: 1, Attempts: 1}}}
+		w, _ := newTestWorker(t, s)
+
+		if _, err := w.ProcessBatch(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		if dead, ok := s.failed[1]; !ok || dead {
+			t.Errorf("expected export 1 to be retried, got %v", s.failed)
+		}
+	})
+
+	t.Run("should give up after the attempt limit", func(t *testing.T) {
+		s := &fakeStore{ready: map[int64]string{}, failed: map[int64]bool{}, archiveErr: errors.New("timeout"), due: []store.Export{{ID: 1, UserID: 1, Attempts: 3}}}
+		w, _ := newTestWorker(t, s)
+
+		if _, err := w.ProcessBatch(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		if dead := s.failed[1]; !dead {
+			t.Errorf("expected export 1 to fail, got %v", s.failed)
+		}
+	})
+}
+
+func TestWorkerDeleteExpired(t *testing.T) {
+	ctx := context.Background()
+
+	s := &fakeStore{expired: []string{"exports/old.json"}}
+	w, blobs := newTestWorker(t, s)
+
+	if err := blobs.Put(ctx, "exports/old.json", strings.NewReader("{}"), 2, "application/json"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.DeleteExpired(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := blobs.Get(ctx, "exports/old.json"); !errors.Is(err, blob.ErrNotFound) {
+		t.Errorf("expected the archive to be deleted, got %v", err)
+	}
+}
@@ -0,0 +1,209 @@
+package mailer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+type DevInboxMessage struct {
+	ID       int64  `json:"id"`
+	Template string `json:"template"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Subject  string `json:"subject"`
+	Text     string `json:"text"`
+	HTML     string `json:"html"`
+	SentAt   string `json:"sent_at"`
+}
+
+// DevInbox is a mailer that never leaves the process. Messages are kept in
+// memory (bounded by capacity) and, when dir is set, also written there as
+// .eml files so they can be opened in a regular mail client.
+type DevInbox struct {
+	mu        sync.RWMutex
+	fromEmail string
+	templates *Templates
+	dir       string
+	capacity  int
+	nextID    int64
+	messages  []DevInboxMessage
+}
+
+func NewDevInbox(fromEmail, dir string, capacity int, templates *Templates) (*DevInbox, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+
+	if capacity <= 0 {
+		capacity = 100
+	}
+
+	return &DevInbox{
+		fromEmail: fromEmail,
+		templates: templates,
+		dir:       dir,
+		capacity:  capacity,
+	}, nil
+}
+
+func (m *DevInbox) Send(templateFile, locale, username, email string, data any, isSandbox bool) (int, error) {
+	rendered, err := m.templates.Render(templateFile, locale, data)
+	if err != nil {
+		return -1, err
+	}
+
+	message := newMessage(rendered, m.fromEmail, username, email)
+
+	raw := new(bytes.Buffer)
+	if _, err := message.WriteTo(raw); err != nil {
+		return -1, err
+	}
+
+	msg, err := parseDevInboxMessage(raw.Bytes())
+	if err != nil {
+		return -1, err
+	}
+	msg.Template = templateFile
+	msg.SentAt = time.Now().UTC().Format(time.RFC3339)
+
+	m.mu.Lock()
+	m.nextID++
+	msg.ID = m.nextID
+	m.messages = append(m.messages, msg)
+	if len(m.messages) > m.capacity {
+		m.messages = m.messages[len(m.messages)-m.capacity:]
+	}
+	m.mu.Unlock()
+
+	if m.dir != "" {
+		name := filepath.Join(m.dir, fmt.Sprintf("%d-%06d.eml", time.Now().Unix(), msg.ID))
+		if err := os.WriteFile(name, raw.Bytes(), 0o644); err != nil {
+			return -1, err
+		}
+	}
+
+	return 200, nil
+}
+
+// Messages returns the captured messages, newest first.
+func (m *DevInbox) Messages() []DevInboxMessage {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	msgs := make([]DevInboxMessage, 0, len(m.messages))
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		msgs = append(msgs, m.messages[i])
+	}
+
+	return msgs
+}
+
+func (m *DevInbox) Get(id int64) (DevInboxMessage, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, msg := range m.messages {
+		if msg.ID == id {
+			return msg, true
+		}
+	}
+
+	return DevInboxMessage{}, false
+}
+
+func (m *DevInbox) Clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.messages = nil
+}
+
+// parseDevInboxMessage reads back the MIME message produced by gomail so the
+// inbox shows exactly what a real server would have received.
+func parseDevInboxMessage(raw []byte) (DevInboxMessage, error) {
+	parsed, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return DevInboxMessage{}, err
+	}
+
+	dec := new(mime.WordDecoder)
+	subject, err := dec.DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil {
+		return DevInboxMessage{}, err
+	}
+
+	msg := DevInboxMessage{
+		From:    parsed.Header.Get("From"),
+		To:      parsed.Header.Get("To"),
+		Subject: strings.TrimSpace(subject),
+	}
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil {
+		return DevInboxMessage{}, err
+	}
+
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		body, err := decodePart(parsed.Header.Get("Content-Transfer-Encoding"), parsed.Body)
+		if err != nil {
+			return DevInboxMessage{}, err
+		}
+		setDevInboxBody(&msg, mediaType, body)
+		return msg, nil
+	}
+
+	mr := multipart.NewReader(parsed.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			break
+		}
+
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		body, err := decodePart(part.Header.Get("Content-Transfer-Encoding"), part)
+		if err != nil {
+			return DevInboxMessage{}, err
+		}
+		setDevInboxBody(&msg, partType, body)
+	}
+
+	return msg, nil
+}
+
+func setDevInboxBody(msg *DevInboxMessage, mediaType, body string) {
+	switch mediaType {
+	case "text/plain":
+		msg.Text = body
+	case "text/html":
+		msg.HTML = body
+	}
+}
+
+func decodePart(encoding string, r io.Reader) (string, error) {
+	switch strings.ToLower(encoding) {
+	case "quoted-printable":
+		r = quotedprintable.NewReader(r)
+	case "base64":
+		r = base64.NewDecoder(base64.StdEncoding, r)
+	}
+
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+
+	return string(body), nil
+}
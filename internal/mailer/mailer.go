@@ -1,16 +1,42 @@
 package mailer
 
-import "embed"
+import (
+	"embed"
+
+	gomail "gopkg.in/mail.v2"
+)
 
 const (
 	FromName               = "SocialGo"
-	MaxRetries             = 3
-	UserInvitationTemplate = "user_invitation.tmpl"
+	UserInvitationTemplate = "user_invitation"
+	EmailChangeTemplate    = "email_change"
 )
 
+// LinksToken reports whether emails from the template link to a page
+// confirming a one-time token, through their ConfirmationURL.
+func LinksToken(templateFile string) bool {
+	return templateFile == UserInvitationTemplate || templateFile == EmailChangeTemplate
+}
+
 //go:embed templates
 var FS embed.FS
 
 type Client interface {
-	Send(templateFile, username, email string, data any, isSandbox bool) (int, error)
+	Send(templateFile, locale, username, email string, data any, isSandbox bool) (int, error)
+}
+
+// newMessage builds a multipart/alternative message with the plain text part
+// first, as recommended by RFC 2046.
+func newMessage(email *Email, fromEmail, username, to string) *gomail.Message {
+	message := gomail.NewMessage()
+	message.SetAddressHeader("From", fromEmail, FromName)
+	message.SetAddressHeader("To", to, username)
+	message.SetHeader("Subject", email.Subject)
+
+	message.SetBody("text/plain", email.Text)
+	if email.HTML != "" {
+		message.AddAlternative("text/html", email.HTML)
+	}
+
+	return message
 }
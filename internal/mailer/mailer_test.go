@@ -0,0 +1,210 @@
+package mailer
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var invitationData = map[string]any{
+	"Username":        "alice",
+	"ConfirmationURL": "http://localhost:4000/confirm/token",
+}
+
+func TestDevInbox(t *testing.T) {
+	dir := t.TempDir()
+
+	inbox, err := NewDevInbox("noreply@socialgo.dev", dir, 2, newTestTemplates(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("should capture sent messages", func(t *testing.T) {
+		if _, err := inbox.Send(UserInvitationTemplate, "en", "alice", "alice@example.com", invitationData, true); err != nil {
+			t.Fatal(err)
+		}
+
+		msgs := inbox.Messages()
+		if len(msgs) != 1 {
+			t.Fatalf("expected 1 message, got %d", len(msgs))
+		}
+
+		msg := msgs[0]
+		if !strings.Contains(msg.To, "alice@example.com") {
+			t.Errorf("unexpected recipient %q", msg.To)
+		}
+		if msg.Subject == "" {
+			t.Error("expected a subject")
+		}
+		if !strings.Contains(msg.HTML, "http://localhost:4000/confirm/token") {
+			t.Errorf("expected activation url in html body, got %q", msg.HTML)
+		}
+		if !strings.Contains(msg.Text, "http://localhost:4000/confirm/token") {
+			t.Errorf("expected activation url in text body, got %q", msg.Text)
+		}
+
+		files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+		if len(files) != 1 {
+			t.Fatalf("expected 1 .eml file, got %d", len(files))
+		}
+	})
+
+	t.Run("should keep only the most recent messages", func(t *testing.T) {
+		inbox.Send(UserInvitationTemplate, "en", "bob", "bob@example.com", invitationData, true)
+		inbox.Send(UserInvitationTemplate, "en", "carol", "carol@example.com", invitationData, true)
+
+		msgs := inbox.Messages()
+		if len(msgs) != 2 {
+			t.Fatalf("expected 2 messages, got %d", len(msgs))
+		}
+		if !strings.Contains(msgs[0].To, "carol@example.com") {
+			t.Errorf("expected newest message first, got %q", msgs[0].To)
+		}
+		if _, ok := inbox.Get(1); ok {
+			t.Error("expected the oldest message to be dropped")
+		}
+	})
+
+	t.Run("should clear the inbox", func(t *testing.T) {
+		inbox.Clear()
+		if len(inbox.Messages()) != 0 {
+			t.Error("expected empty inbox")
+		}
+	})
+}
+
+func TestSMTPMailer(t *testing.T) {
+	srv := newFakeSMTPServer(t)
+
+	host, port, _ := net.SplitHostPort(srv.addr)
+	p, _ := strconv.Atoi(port)
+
+	m, err := NewSMTPMailer(SMTPConfig{Host: host, Port: p}, "noreply@socialgo.dev", newTestTemplates(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Send(UserInvitationTemplate, "en", "alice", "alice@example.com", invitationData, true); err != nil {
+		t.Fatal(err)
+	}
+
+	data := <-srv.received
+	if !strings.Contains(data, "alice@example.com") {
+		t.Errorf("expected recipient in message, got %q", data)
+	}
+	if !strings.Contains(data, "multipart/alternative") {
+		t.Errorf("expected a multipart/alternative message, got %q", data)
+	}
+	if !strings.Contains(data, "Content-Type: text/plain") || !strings.Contains(data, "Content-Type: text/html") {
+		t.Errorf("expected text and html parts, got %q", data)
+	}
+}
+
+func TestSendGridMailer(t *testing.T) {
+	status := http.StatusAccepted
+	requests := 0
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(status)
+		io.WriteString(w, `{"errors":[{"message":"invalid sender"}]}`)
+	}))
+	defer srv.Close()
+
+	m := NewSendGrid("key", "noreply@socialgo.dev", newTestTemplates(t))
+	m.client.BaseURL = srv.URL + "/v3/mail/send"
+
+	t.Run("should send the email", func(t *testing.T) {
+		code, err := m.Send(UserInvitationTemplate, "en", "alice", "alice@example.com", invitationData, true)
+		if err != nil || code != http.StatusAccepted {
+			t.Fatalf("expected the email to be accepted, got %d: %v", code, err)
+		}
+	})
+
+	t.Run("should fail rejected emails without retrying", func(t *testing.T) {
+		status = http.StatusBadRequest
+		requests = 0
+
+		code, err := m.Send(UserInvitationTemplate, "en", "alice", "alice@example.com", invitationData, true)
+		if err == nil || code != http.StatusBadRequest {
+			t.Fatalf("expected the rejection as an error, got %d: %v", code, err)
+		}
+		if !strings.Contains(err.Error(), "invalid sender") {
+			t.Errorf("expected the response in the error, got %v", err)
+		}
+		if requests != 1 {
+			t.Errorf("expected a single request, got %d", requests)
+		}
+	})
+}
+
+func TestNewSMTPMailerRequiresHost(t *testing.T) {
+	if _, err := NewSMTPMailer(SMTPConfig{}, "noreply@socialgo.dev", nil); err == nil {
+		t.Error("expected error without host")
+	}
+}
+
+type fakeSMTPServer struct {
+	addr     string
+	received chan string
+}
+
+// newFakeSMTPServer accepts a single plaintext SMTP session and publishes the
+// DATA payload on received.
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	srv := &fakeSMTPServer{addr: ln.Addr().String(), received: make(chan string, 1)}
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		tp := textproto.NewConn(conn)
+		tp.PrintfLine("220 localhost ESMTP")
+
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+
+			switch cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); cmd {
+			case "EHLO", "HELO":
+				tp.PrintfLine("250 localhost")
+			case "MAIL", "RCPT", "RSET", "NOOP":
+				tp.PrintfLine("250 OK")
+			case "DATA":
+				tp.PrintfLine("354 go ahead")
+				data, err := io.ReadAll(tp.DotReader())
+				if err != nil {
+					return
+				}
+				srv.received <- string(data)
+				tp.PrintfLine("250 OK")
+			case "QUIT":
+				tp.PrintfLine("221 bye")
+				return
+			default:
+				tp.PrintfLine("502 not implemented")
+			}
+		}
+	}()
+
+	return srv
+}
@@ -1,9 +1,7 @@
 package mailer
 
 import (
-	"bytes"
 	"errors"
-	"text/template"
 
 	gomail "gopkg.in/mail.v2"
 )
@@ -11,9 +9,10 @@ import (
 type mailTrapClient struct {
 	apiKey    string
 	fromEmail string
+	templates *Templates
 }
 
-func NewMailTrapClient(apiKey, fromEmail string) (mailTrapClient, error) {
+func NewMailTrapClient(apiKey, fromEmail string, templates *Templates) (mailTrapClient, error) {
 	if apiKey == "" {
 		return mailTrapClient{}, errors.New("api key is required")
 	}
@@ -21,32 +20,17 @@ func NewMailTrapClient(apiKey, fromEmail string) (mailTrapClient, error) {
 	return mailTrapClient{
 		apiKey:    apiKey,
 		fromEmail: fromEmail,
+		templates: templates,
 	}, nil
 }
 
-func (m mailTrapClient) Send(templateFile, username, email string, data any, isSandbox bool) (int, error) {
-	tmpl, err := template.ParseFS(FS, "templates/"+templateFile)
+func (m mailTrapClient) Send(templateFile, locale, username, email string, data any, isSandbox bool) (int, error) {
+	rendered, err := m.templates.Render(templateFile, locale, data)
 	if err != nil {
 		return -1, err
 	}
 
-	subject := new(bytes.Buffer)
-	err = tmpl.ExecuteTemplate(subject, "subject", data)
-	if err != nil {
-		return -1, err
-	}
-
-	body := new(bytes.Buffer)
-	err = tmpl.ExecuteTemplate(body, "body", data)
-	if err != nil {
-		return -1, err
-	}
-
-	message := gomail.NewMessage()
-	message.SetHeader("From", m.fromEmail)
-	message.SetHeader("To", email)
-	message.SetHeader("Subject", subject.String())
-	message.SetBody("text/html", body.String())
+	message := newMessage(rendered, m.fromEmail, username, email)
 
 	dialer := gomail.NewDialer("live.smtp.mailtrap.io", 587, "api", m.apiKey)
 
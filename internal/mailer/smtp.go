@@ -0,0 +1,65 @@
+package mailer
+
+import (
+	"crypto/tls"
+	"errors"
+
+	gomail "gopkg.in/mail.v2"
+)
+
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	// StartTLS requires the server to support STARTTLS. When false, STARTTLS
+	// is still used opportunistically if the server offers it.
+	StartTLS bool
+	// SSL connects over implicit TLS (usually port 465).
+	SSL                bool
+	InsecureSkipVerify bool
+}
+
+type SMTPMailer struct {
+	fromEmail string
+	dialer    *gomail.Dialer
+	templates *Templates
+}
+
+func NewSMTPMailer(cfg SMTPConfig, fromEmail string, templates *Templates) (*SMTPMailer, error) {
+	if cfg.Host == "" {
+		return nil, errors.New("smtp host is required")
+	}
+
+	dialer := gomail.NewDialer(cfg.Host, cfg.Port, cfg.Username, cfg.Password)
+	dialer.SSL = cfg.SSL
+	dialer.StartTLSPolicy = gomail.OpportunisticStartTLS
+	if cfg.StartTLS {
+		dialer.StartTLSPolicy = gomail.MandatoryStartTLS
+	}
+	dialer.TLSConfig = &tls.Config{
+		ServerName:         cfg.Host,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+
+	return &SMTPMailer{
+		fromEmail: fromEmail,
+		dialer:    dialer,
+		templates: templates,
+	}, nil
+}
+
+func (m *SMTPMailer) Send(templateFile, locale, username, email string, data any, isSandbox bool) (int, error) {
+	rendered, err := m.templates.Render(templateFile, locale, data)
+	if err != nil {
+		return -1, err
+	}
+
+	message := newMessage(rendered, m.fromEmail, username, email)
+
+	if err := m.dialer.DialAndSend(message); err != nil {
+		return -1, err
+	}
+
+	return 200, nil
+}
@@ -0,0 +1,208 @@
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+)
+
+const (
+	DefaultLocale = "en"
+
+	layoutsDir     = "layouts"
+	htmlExt        = ".html.tmpl"
+	textExt        = ".txt.tmpl"
+	htmlLayoutFile = "base" + htmlExt
+	textLayoutFile = "base" + textExt
+)
+
+// Email is a rendered message ready to be handed to a provider.
+type Email struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// Templates holds every email template precompiled at startup, keyed by
+// locale and name. Templates live under templates/<locale>/<name>.txt.tmpl
+// (defines "subject" and "body") and templates/<locale>/<name>.html.tmpl
+// (defines "body"); bodies are rendered inside the shared layouts.
+type Templates struct {
+	defaultLocale string
+	text          map[string]*texttemplate.Template
+	html          map[string]*htmltemplate.Template
+}
+
+func NewTemplates(fsys fs.FS, defaultLocale string) (*Templates, error) {
+	htmlLayout, err := htmltemplate.ParseFS(fsys, path.Join("templates", layoutsDir, htmlLayoutFile))
+	if err != nil {
+		return nil, err
+	}
+
+	textLayout, err := texttemplate.ParseFS(fsys, path.Join("templates", layoutsDir, textLayoutFile))
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Templates{
+		defaultLocale: defaultLocale,
+		text:          make(map[string]*texttemplate.Template),
+		html:          make(map[string]*htmltemplate.Template),
+	}
+
+	locales, err := fs.ReadDir(fsys, "templates")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, locale := range locales {
+		if !locale.IsDir() || locale.Name() == layoutsDir {
+			continue
+		}
+
+		dir := path.Join("templates", locale.Name())
+		files, err := fs.ReadDir(fsys, dir)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, f := range files {
+			file := path.Join(dir, f.Name())
+
+			switch {
+			case strings.HasSuffix(f.Name(), textExt):
+				tmpl, err := textLayout.Clone()
+				if err != nil {
+					return nil, err
+				}
+				if tmpl, err = tmpl.ParseFS(fsys, file); err != nil {
+					return nil, err
+				}
+				if tmpl.Lookup("subject") == nil {
+					return nil, fmt.Errorf("mailer: %s does not define a subject", file)
+				}
+				t.text[key(locale.Name(), strings.TrimSuffix(f.Name(), textExt))] = tmpl
+			case strings.HasSuffix(f.Name(), htmlExt):
+				tmpl, err := htmlLayout.Clone()
+				if err != nil {
+					return nil, err
+				}
+				if tmpl, err = tmpl.ParseFS(fsys, file); err != nil {
+					return nil, err
+				}
+				t.html[key(locale.Name(), strings.TrimSuffix(f.Name(), htmlExt))] = tmpl
+			}
+		}
+	}
+
+	// every html template needs a text alternative, which also carries the subject
+	for k := range t.html {
+		if _, ok := t.text[k]; !ok {
+			return nil, fmt.Errorf("mailer: template %s has no text alternative", k)
+		}
+	}
+
+	for _, name := range t.Names() {
+		if _, ok := t.text[key(defaultLocale, name)]; !ok {
+			return nil, fmt.Errorf("mailer: template %s has no %s variant", name, defaultLocale)
+		}
+	}
+
+	return t, nil
+}
+
+// Render executes the named template for the closest matching locale,
+// falling back from e.g. "es-MX" to "es" and then to the default locale.
+func (t *Templates) Render(name, locale string, data any) (*Email, error) {
+	name = strings.TrimSuffix(name, ".tmpl")
+
+	for _, l := range t.candidates(locale) {
+		text, ok := t.text[key(l, name)]
+		if !ok {
+			continue
+		}
+
+		email := &Email{}
+
+		subject := new(bytes.Buffer)
+		if err := text.ExecuteTemplate(subject, "subject", data); err != nil {
+			return nil, err
+		}
+		email.Subject = strings.TrimSpace(subject.String())
+
+		body := new(bytes.Buffer)
+		if err := text.ExecuteTemplate(body, "layout", data); err != nil {
+			return nil, err
+		}
+		email.Text = body.String()
+
+		if html, ok := t.html[key(l, name)]; ok {
+			body := new(bytes.Buffer)
+			if err := html.ExecuteTemplate(body, "layout", data); err != nil {
+				return nil, err
+			}
+			email.HTML = body.String()
+		}
+
+		return email, nil
+	}
+
+	return nil, fmt.Errorf("mailer: unknown template %q", name)
+}
+
+// Names returns the distinct template names across all locales.
+func (t *Templates) Names() []string {
+	seen := make(map[string]bool)
+	for k := range t.text {
+		_, name, _ := strings.Cut(k, "/")
+		seen[name] = true
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Locales returns the locales that have at least one template.
+func (t *Templates) Locales() []string {
+	seen := make(map[string]bool)
+	for k := range t.text {
+		locale, _, _ := strings.Cut(k, "/")
+		seen[locale] = true
+	}
+
+	locales := make([]string, 0, len(seen))
+	for locale := range seen {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+
+	return locales
+}
+
+func (t *Templates) candidates(locale string) []string {
+	locale = strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+
+	var candidates []string
+	if locale != "" {
+		candidates = append(candidates, locale)
+		if base, _, ok := strings.Cut(locale, "-"); ok {
+			candidates = append(candidates, base)
+		}
+	}
+
+	return append(candidates, t.defaultLocale)
+}
+
+func key(locale, name string) string {
+	return strings.ToLower(locale) + "/" + name
+}
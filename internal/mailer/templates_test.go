@@ -0,0 +1,174 @@
+package mailer
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// goldenData holds the template data used to render each template in the
+// golden tests. Every template must have an entry.
+var goldenData = map[string]any{
+	UserInvitationTemplate: invitationData,
+	EmailChangeTemplate: map[string]any{
+		"Username":        "alice",
+		"ConfirmationURL": "http://localhost:4000/confirm/token",
+	},
+	"notification_follow":  notificationData("http://localhost:4000/users/2"),
+	"notification_comment": notificationData("http://localhost:4000/posts/1"),
+	"notification_mention": notificationData("http://localhost:4000/posts/1"),
+}
+
+func notificationData(url string) map[string]any {
+	return map[string]any{
+		"Username":      "alice",
+		"ActorUsername": "bob",
+		"URL":           url,
+	}
+}
+
+func newTestTemplates(t *testing.T) *Templates {
+	t.Helper()
+
+	templates, err := NewTemplates(FS, DefaultLocale)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return templates
+}
+
+func TestTemplatesGolden(t *testing.T) {
+	templates := newTestTemplates(t)
+
+	for _, name := range templates.Names() {
+		data, ok := goldenData[name]
+		if !ok {
+			t.Errorf("template %s has no golden data", name)
+			continue
+		}
+
+		for _, locale := range templates.Locales() {
+			t.Run(locale+"/"+name, func(t *testing.T) {
+				email, err := templates.Render(name, locale, data)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				got := fmt.Sprintf("Subject: %s\n\n--- text ---\n%s\n--- html ---\n%s", email.Subject, email.Text, email.HTML)
+				golden := filepath.Join("testdata", locale, name+".golden")
+
+				if *update {
+					if err := os.MkdirAll(filepath.Dir(golden), 0o755); err != nil {
+						t.Fatal(err)
+					}
+					if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				want, err := os.ReadFile(golden)
+				if err != nil {
+					t.Fatalf("missing golden file, run go test ./internal/mailer -update: %v", err)
+				}
+
+				if got != string(want) {
+					t.Errorf("rendered %s does not match %s\n got:\n%s\nwant:\n%s", name, golden, got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestTemplatesLocaleFallback(t *testing.T) {
+	templates := newTestTemplates(t)
+
+	cases := []struct {
+		locale  string
+		subject string
+	}{
+		{"es", "Completa tu registro en SocialGo"},
+		{"es-MX", "Completa tu registro en SocialGo"},
+		{"ES_mx", "Completa tu registro en SocialGo"},
+		{"fr", "Finish Registration with SocialGo"},
+		{"", "Finish Registration with SocialGo"},
+	}
+
+	for _, c := range cases {
+		email, err := templates.Render(UserInvitationTemplate, c.locale, invitationData)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if email.Subject != c.subject {
+			t.Errorf("locale %q: expected subject %q, got %q", c.locale, c.subject, email.Subject)
+		}
+	}
+}
+
+func TestTemplatesRender(t *testing.T) {
+	templates := newTestTemplates(t)
+
+	t.Run("should accept names with the legacy .tmpl suffix", func(t *testing.T) {
+		if _, err := templates.Render(UserInvitationTemplate+".tmpl", "en", invitationData); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("should fail for unknown templates", func(t *testing.T) {
+		if _, err := templates.Render("does_not_exist", "en", nil); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+
+	t.Run("should not html-escape the text alternative", func(t *testing.T) {
+		data := map[string]any{"Username": "a&b", "ConfirmationURL": "http://x/?a=1&b=2"}
+
+		email, err := templates.Render(UserInvitationTemplate, "en", data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !strings.Contains(email.Text, "http://x/?a=1&b=2") {
+			t.Errorf("expected raw url in text body, got %q", email.Text)
+		}
+		if !strings.Contains(email.HTML, "a&amp;b") {
+			t.Errorf("expected escaped username in html body, got %q", email.HTML)
+		}
+	})
+}
+
+func TestNewTemplatesValidation(t *testing.T) {
+	layouts := fstest.MapFS{
+		"templates/layouts/base.html.tmpl": {Data: []byte(`{{define "layout"}}{{template "body" .}}{{end}}`)},
+		"templates/layouts/base.txt.tmpl":  {Data: []byte(`{{define "layout"}}{{template "body" .}}{{end}}`)},
+	}
+
+	t.Run("should require a text alternative", func(t *testing.T) {
+		fsys := fstest.MapFS{"templates/en/welcome.html.tmpl": {Data: []byte(`{{define "body"}}hi{{end}}`)}}
+		for k, v := range layouts {
+			fsys[k] = v
+		}
+
+		if _, err := NewTemplates(fsys, "en"); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+
+	t.Run("should require a default locale variant", func(t *testing.T) {
+		fsys := fstest.MapFS{"templates/es/welcome.txt.tmpl": {Data: []byte(`{{define "subject"}}hola{{end}}{{define "body"}}hola{{end}}`)}}
+		for k, v := range layouts {
+			fsys[k] = v
+		}
+
+		if _, err := NewTemplates(fsys, "en"); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
@@ -0,0 +1,149 @@
+// Package media validates and normalizes uploaded images.
+//
+// Images are decoded and encoded again, which drops EXIF, XMP and any other
+// metadata, such as the location a photo was taken at. The EXIF orientation
+// is applied to the pixels first, so photos still display the right way up.
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+var (
+	ErrUnsupportedType = errors.New("unsupported image type, use JPEG, PNG or WebP")
+	ErrTooLarge        = errors.New("image dimensions are too large")
+)
+
+// jpegQuality is used for photos and thumbnails.
+const jpegQuality = 85
+
+type Config struct {
+	// MaxPixels bounds width times height, so small files can't decode to
+	// huge images.
+	MaxPixels int
+	// ThumbnailSize is the largest width and height of thumbnails.
+	ThumbnailSize int
+}
+
+func DefaultConfig() Config {
+	return Config{
+		MaxPixels:     40_000_000,
+		ThumbnailSize: 320,
+	}
+}
+
+type Image struct {
+	Data        []byte
+	ContentType string
+	Width       int
+	Height      int
+}
+
+// Ext returns the file extension of the image's type.
+func (i *Image) Ext() string {
+	if i.ContentType == "image/png" {
+		return ".png"
+	}
+	return ".jpg"
+}
+
+// Process checks data is a supported image and returns it without metadata,
+// along with a thumbnail. The type is sniffed from the data, whatever the
+// client claimed.
+func Process(data []byte, cfg Config) (*Image, *Image, error) {
+	contentType := http.DetectContentType(data)
+	switch contentType {
+	case "image/jpeg", "image/png", "image/webp":
+	default:
+		return nil, nil, ErrUnsupportedType
+	}
+
+	imgCfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrUnsupportedType, err)
+	}
+	if imgCfg.Width*imgCfg.Height > cfg.MaxPixels {
+		return nil, nil, ErrTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrUnsupportedType, err)
+	}
+
+	if contentType == "image/jpeg" {
+		img = orient(img, jpegOrientation(data))
+	}
+
+	// PNGs are often screenshots or have transparency, which JPEG would blur
+	// or lose.
+	lossless := contentType == "image/png" || !isOpaque(img)
+
+	original, err := encode(img, lossless)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	thumbnail, err := encode(thumbnail(img, cfg.ThumbnailSize), !isOpaque(img))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return original, thumbnail, nil
+}
+
+func encode(img image.Image, lossless bool) (*Image, error) {
+	var buf bytes.Buffer
+	out := &Image{Width: img.Bounds().Dx(), Height: img.Bounds().Dy()}
+
+	if lossless {
+		out.ContentType = "image/png"
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, err
+		}
+	} else {
+		out.ContentType = "image/jpeg"
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, err
+		}
+	}
+
+	out.Data = buf.Bytes()
+	return out, nil
+}
+
+// thumbnail scales img down to fit in a size by size square, keeping its
+// aspect ratio. Smaller images are returned as they are.
+func thumbnail(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= size && h <= size {
+		return img
+	}
+
+	if w >= h {
+		w, h = size, max(1, h*size/w)
+	} else {
+		w, h = max(1, w*size/h), size
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
+
+func isOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return false
+}
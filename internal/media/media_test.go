@@ -0,0 +1,163 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func testImage(w, h int, opaque bool) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			a := uint8(255)
+			if !opaque && x == 0 {
+				a = 0
+			}
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 100, A: a})
+		}
+	}
+	// Mark the top left corner, to check orientation. It is big enough to
+	// survive JPEG compression.
+	n := max(1, min(w, h)/4)
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			img.Set(x, y, color.NRGBA{R: 255, A: 255})
+		}
+	}
+	return img
+}
+
+func encodeJPEG(t *testing.T, img image.Image, orientation int) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	if orientation == 0 {
+		return data
+	}
+
+	// An APP1 segment with a little endian TIFF header and a single IFD
+	// entry: the orientation, plus a fake GPS tag that must not survive.
+	tiff := []byte("II*\x00\x08\x00\x00\x00")
+	tiff = binary.LittleEndian.AppendUint16(tiff, 2)
+	for _, e := range [][3]uint16{{0x0112, 3, uint16(orientation)}, {0x8825, 4, 0}} {
+		tiff = binary.LittleEndian.AppendUint16(tiff, e[0])
+		tiff = binary.LittleEndian.AppendUint16(tiff, e[1])
+		tiff = binary.LittleEndian.AppendUint32(tiff, 1)
+		tiff = binary.LittleEndian.AppendUint16(tiff, e[2])
+		tiff = append(tiff, 0, 0)
+	}
+	tiff = append(tiff, 0, 0, 0, 0)
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xFF, 0xE1}
+	app1 = binary.BigEndian.AppendUint16(app1, uint16(len(segment)+2))
+	app1 = append(app1, segment...)
+
+	return append(append([]byte{0xFF, 0xD8}, app1...), data[2:]...)
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestProcess(t *testing.T) {
+	cfg := Config{MaxPixels: 1_000_000, ThumbnailSize: 32}
+
+	t.Run("should strip EXIF and apply the orientation", func(t *testing.T) {
+		data := encodeJPEG(t, testImage(80, 40, true), 6)
+		if jpegOrientation(data) != 6 {
+			t.Fatal("expected the test image to carry an orientation")
+		}
+
+		original, thumb, err := Process(data, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if original.ContentType != "image/jpeg" || original.Width != 40 || original.Height != 80 {
+			t.Errorf("expected a rotated 40x80 JPEG, got %s %dx%d", original.ContentType, original.Width, original.Height)
+		}
+		if bytes.Contains(original.Data, []byte("Exif")) || bytes.Contains(thumb.Data, []byte("Exif")) {
+			t.Error("expected the EXIF data to be stripped")
+		}
+
+		// Rotated clockwise, the top left corner ends up top right.
+		img, err := jpeg.Decode(bytes.NewReader(original.Data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r, _, _, _ := img.At(38, 1).RGBA(); r>>8 < 200 {
+			t.Errorf("expected the marked corner at the top right, got red %d", r>>8)
+		}
+
+		if thumb.Width != 16 || thumb.Height != 32 {
+			t.Errorf("expected a 16x32 thumbnail, got %dx%d", thumb.Width, thumb.Height)
+		}
+	})
+
+	t.Run("should keep PNGs lossless", func(t *testing.T) {
+		original, thumb, err := Process(encodePNG(t, testImage(20, 10, false)), cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if original.ContentType != "image/png" || original.Ext() != ".png" {
+			t.Errorf("expected a PNG, got %s", original.ContentType)
+		}
+		// Smaller than the thumbnail size, and transparent.
+		if thumb.ContentType != "image/png" || thumb.Width != 20 || thumb.Height != 10 {
+			t.Errorf("expected a 20x10 PNG thumbnail, got %s %dx%d", thumb.ContentType, thumb.Width, thumb.Height)
+		}
+	})
+
+	t.Run("should reject other types", func(t *testing.T) {
+		for _, data := range [][]byte{
+			[]byte("just some text"),
+			[]byte("GIF89a\x01\x00\x01\x00\x00\x00\x00;"),
+			// Sniffed as a JPEG, but not one.
+			[]byte("\xFF\xD8\xFFgarbage"),
+		} {
+			if _, _, err := Process(data, cfg); !errors.Is(err, ErrUnsupportedType) {
+				t.Errorf("expected ErrUnsupportedType for %q, got %v", data, err)
+			}
+		}
+	})
+
+	t.Run("should reject images with too many pixels", func(t *testing.T) {
+		_, _, err := Process(encodePNG(t, testImage(200, 100, true)), Config{MaxPixels: 10_000, ThumbnailSize: 32})
+		if !errors.Is(err, ErrTooLarge) {
+			t.Errorf("expected ErrTooLarge, got %v", err)
+		}
+	})
+}
+
+func TestOrient(t *testing.T) {
+	img := testImage(3, 2, true)
+
+	for orientation, corner := range map[int]image.Point{
+		1: {0, 0}, 2: {2, 0}, 3: {2, 1}, 4: {0, 1},
+		5: {0, 0}, 6: {1, 0}, 7: {1, 2}, 8: {0, 2},
+	} {
+		got := orient(img, orientation)
+		if r, _, _, _ := got.At(corner.X, corner.Y).RGBA(); r>>8 != 255 {
+			t.Errorf("orientation %d: expected the marked corner at %v", orientation, corner)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+var ErrUsage = errors.New("usage: migrate up | down [N] | goto VERSION | force VERSION | version | status")
+
+// Run executes a migrate subcommand and prints its result to w.
+func Run(ctx context.Context, m *Migrator, args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return ErrUsage
+	}
+
+	cmd, args := args[0], args[1:]
+
+	switch cmd {
+	case "up":
+		if len(args) != 0 {
+			return ErrUsage
+		}
+		return m.Up(ctx)
+
+	case "down":
+		n := 1
+		if len(args) == 1 {
+			v, err := strconv.Atoi(args[0])
+			if err != nil || v < 1 {
+				return ErrUsage
+			}
+			n = v
+		} else if len(args) > 1 {
+			return ErrUsage
+		}
+		return m.Down(ctx, n)
+
+	case "goto", "force":
+		if len(args) != 1 {
+			return ErrUsage
+		}
+		v, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			return ErrUsage
+		}
+		if cmd == "goto" {
+			return m.Goto(ctx, uint(v))
+		}
+		return m.Force(ctx, uint(v))
+
+	case "version":
+		if len(args) != 0 {
+			return ErrUsage
+		}
+		v, dirty, err := m.Version(ctx)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			fmt.Fprintf(w, "%d (dirty)\n", v)
+		} else {
+			fmt.Fprintf(w, "%d\n", v)
+		}
+		return nil
+
+	case "status":
+		if len(args) != 0 {
+			return ErrUsage
+		}
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied"
+			}
+			fmt.Fprintf(w, "%06d %-8s %s\n", s.Version, state, s.Name)
+		}
+		return nil
+	}
+
+	return ErrUsage
+}
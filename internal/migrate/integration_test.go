@@ -0,0 +1,259 @@
+//go:build integration
+
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/kuluruvineeth/social-go/cmd/migrate/migrations"
+	"github.com/kuluruvineeth/social-go/internal/storetest"
+	"go.uber.org/zap"
+)
+
+// testPostgres is nil when no Postgres is available.
+var testPostgres *storetest.Postgres
+
+func TestMain(m *testing.M) {
+	pg, err := storetest.StartPostgres()
+	if errors.Is(err, storetest.ErrUnavailable) {
+		log.Println("no Postgres available, skipping migration integration tests")
+		os.Exit(m.Run())
+	}
+	if err != nil {
+		log.Fatalf("starting Postgres: %v", err)
+	}
+
+	testPostgres = pg
+	code := m.Run()
+
+	if err := pg.Stop(); err != nil {
+		log.Printf("stopping Postgres: %v", err)
+	}
+
+	os.Exit(code)
+}
+
+var dbSeq atomic.Int64
+
+// newTestDB returns an empty database that is dropped when t ends.
+// Migrations can't share a rolled back transaction, as they take their own
+// connections and locks.
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	if testPostgres == nil {
+		t.Skip("no Postgres available")
+	}
+
+	name := fmt.Sprintf("social_go_migrate_%d_%d", time.Now().UnixNano(), dbSeq.Add(1))
+	addr, err := testPostgres.CreateDatabase(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("postgres", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		db.Close()
+		if err := testPostgres.DropDatabase(name); err != nil {
+			t.Errorf("dropping %s: %v", name, err)
+		}
+	})
+
+	return db
+}
+
+func newTestMigrator(t *testing.T, db *sql.DB) *Migrator {
+	t.Helper()
+
+	m, err := New(db, migrations.FS, zap.NewNop().Sugar())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return m
+}
+
+func testContext(t *testing.T) context.Context {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func assertVersion(t *testing.T, m *Migrator, want uint) {
+	t.Helper()
+
+	got, dirty, err := m.Version(testContext(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want || dirty {
+		t.Errorf("expected clean version %d, got %d (dirty %v)", want, got, dirty)
+	}
+}
+
+func tableExists(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+
+	var exists bool
+	if err := db.QueryRow(`SELECT to_regclass($1) IS NOT NULL`, name).Scan(&exists); err != nil {
+		t.Fatal(err)
+	}
+
+	return exists
+}
+
+func TestMigrator(t *testing.T) {
+	t.Run("should apply every migration once", func(t *testing.T) {
+		db := newTestDB(t)
+		m := newTestMigrator(t, db)
+
+		if err := m.Check(testContext(t)); !errors.Is(err, ErrBehind) {
+			t.Errorf("expected ErrBehind on an empty database, got %v", err)
+		}
+
+		if err := m.Up(testContext(t)); err != nil {
+			t.Fatal(err)
+		}
+		assertVersion(t, m, m.Latest())
+
+		if !tableExists(t, db, "users") || !tableExists(t, db, "posts") {
+			t.Error("expected the schema to be created")
+		}
+		if err := m.Check(testContext(t)); err != nil {
+			t.Errorf("expected the schema to be current, got %v", err)
+		}
+
+		if err := m.Up(testContext(t)); err != nil {
+			t.Errorf("expected a second run to be a no-op, got %v", err)
+		}
+	})
+
+	t.Run("should roll back and reapply every migration", func(t *testing.T) {
+		db := newTestDB(t)
+		m := newTestMigrator(t, db)
+		latest := m.Latest()
+
+		if err := m.Up(testContext(t)); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := m.Down(testContext(t), 1); err != nil {
+			t.Fatal(err)
+		}
+		assertVersion(t, m, latest-1)
+
+		if err := m.Check(testContext(t)); !errors.Is(err, ErrBehind) {
+			t.Errorf("expected ErrBehind, got %v", err)
+		}
+
+		statuses, err := m.Status(testContext(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if last := statuses[len(statuses)-1]; last.Applied || !statuses[0].Applied {
+			t.Errorf("unexpected statuses %+v", statuses)
+		}
+
+		if err := m.Goto(testContext(t), 0); err != nil {
+			t.Fatal(err)
+		}
+		assertVersion(t, m, 0)
+		if tableExists(t, db, "users") {
+			t.Error("expected every table to be dropped")
+		}
+
+		if err := m.Goto(testContext(t), latest); err != nil {
+			t.Fatal(err)
+		}
+		assertVersion(t, m, latest)
+	})
+
+	t.Run("should serialize concurrent runs", func(t *testing.T) {
+		db := newTestDB(t)
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 4)
+		for range 4 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- newTestMigrator(t, db).Up(testContext(t))
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			if err != nil {
+				t.Errorf("expected every run to succeed, got %v", err)
+			}
+		}
+
+		m := newTestMigrator(t, db)
+		assertVersion(t, m, m.Latest())
+	})
+
+	t.Run("should leave a failed migration unapplied", func(t *testing.T) {
+		db := newTestDB(t)
+
+		fsys := fstest.MapFS{
+			"000001_create_things.up.sql": {Data: []byte("CREATE TABLE things (id int)")},
+			"000002_broken.up.sql":        {Data: []byte("CREATE TABLE others (id int); SELECT * FROM nowhere")},
+		}
+		m, err := New(db, fsys, zap.NewNop().Sugar())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := m.Up(testContext(t)); err == nil {
+			t.Fatal("expected an error")
+		}
+		assertVersion(t, m, 1)
+
+		if !tableExists(t, db, "things") || tableExists(t, db, "others") {
+			t.Error("expected the failed migration to be rolled back")
+		}
+	})
+
+	t.Run("should refuse to run on a dirty database", func(t *testing.T) {
+		db := newTestDB(t)
+		m := newTestMigrator(t, db)
+
+		if err := m.Goto(testContext(t), 2); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Exec(`UPDATE schema_migrations SET version = 3, dirty = true`); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := m.Up(testContext(t)); !errors.Is(err, ErrDirty) {
+			t.Errorf("expected ErrDirty, got %v", err)
+		}
+		if err := m.Check(testContext(t)); !errors.Is(err, ErrDirty) {
+			t.Errorf("expected ErrDirty, got %v", err)
+		}
+
+		if err := m.Force(testContext(t), 2); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.Up(testContext(t)); err != nil {
+			t.Errorf("expected the forced version to migrate, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,376 @@
+// Package migrate applies the SQL migrations in an fs.FS.
+//
+// The applied version is kept in the same schema_migrations table the
+// migrate CLI uses, so databases set up with either one can be managed by
+// the other. Every migration runs in its own transaction, and runs are
+// serialized with a Postgres advisory lock so concurrent deploys don't race.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrDirty  = errors.New("database is dirty, fix it and force the version")
+	ErrBehind = errors.New("database schema is behind")
+)
+
+// lockKey identifies the advisory lock held while migrating.
+const lockKey = 7_264_383_201
+
+var fileRe = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version uint
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Version uint   `json:"version"`
+	Name    string `json:"name"`
+	Applied bool   `json:"applied"`
+}
+
+type Migrator struct {
+	db         *sql.DB
+	logger     *zap.SugaredLogger
+	migrations []Migration
+}
+
+func New(db *sql.DB, fsys fs.FS, logger *zap.SugaredLogger) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{db: db, logger: logger, migrations: migrations}, nil
+}
+
+// Load reads the NNNNNN_name.up.sql and NNNNNN_name.down.sql files at the
+// root of fsys, ordered by version. Every migration needs an up file.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[uint]*Migration{}
+	for _, entry := range entries {
+		match := fileRe.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil || version == 0 {
+			return nil, fmt.Errorf("invalid migration version in %s", entry.Name())
+		}
+
+		m, ok := byVersion[uint(version)]
+		if !ok {
+			m = &Migration{Version: uint(version), Name: match[2]}
+			byVersion[m.Version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d is named both %s and %s", m.Version, m.Name, match[2])
+		}
+
+		body, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		if match[3] == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Latest returns the version of the newest migration, or 0 without any.
+func (m *Migrator) Latest() uint {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Version returns the applied version, 0 when nothing was applied, and
+// whether a migration failed halfway through.
+func (m *Migrator) Version(ctx context.Context) (uint, bool, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	defer conn.Close()
+
+	if err := ensureTable(ctx, conn); err != nil {
+		return 0, false, err
+	}
+
+	return version(ctx, conn)
+}
+
+// Status lists every migration and whether it was applied.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	current, _, err := m.Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, len(m.migrations))
+	for i, mig := range m.migrations {
+		statuses[i] = Status{Version: mig.Version, Name: mig.Name, Applied: mig.Version <= current}
+	}
+
+	return statuses, nil
+}
+
+// Check returns ErrBehind when migrations are pending and ErrDirty when the
+// last one failed. A database ahead of the binary is fine, so an older
+// release keeps running while a newer one is rolled out.
+func (m *Migrator) Check(ctx context.Context) error {
+	current, dirty, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+
+	if dirty {
+		return ErrDirty
+	}
+
+	if current < m.Latest() {
+		return fmt.Errorf("%w: at version %d, latest is %d", ErrBehind, current, m.Latest())
+	}
+
+	return nil
+}
+
+// Up applies every pending migration.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.Goto(ctx, m.Latest())
+}
+
+// Down reverts the last n applied migrations.
+func (m *Migrator) Down(ctx context.Context, n int) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		current, err := cleanVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		i := m.index(current)
+		target := uint(0)
+		if i-n >= 0 {
+			target = m.migrations[i-n].Version
+		}
+
+		return m.migrate(ctx, conn, current, target)
+	})
+}
+
+// Goto migrates up or down to the given version. Version 0 reverts every
+// migration.
+func (m *Migrator) Goto(ctx context.Context, target uint) error {
+	if target != 0 && m.index(target) < 0 {
+		return fmt.Errorf("unknown migration version %d", target)
+	}
+
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		current, err := cleanVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		return m.migrate(ctx, conn, current, target)
+	})
+}
+
+// Force records version as applied and clean without running anything, to
+// recover from a failed migration once the database was fixed by hand.
+func (m *Migrator) Force(ctx context.Context, version uint) error {
+	if version != 0 && m.index(version) < 0 {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		return setVersion(ctx, conn, version, false)
+	})
+}
+
+func (m *Migrator) migrate(ctx context.Context, conn *sql.Conn, current, target uint) error {
+	if current == target {
+		m.logger.Infow("database schema is up to date", "version", current)
+		return nil
+	}
+
+	if current < target {
+		for _, mig := range m.migrations {
+			if mig.Version <= current || mig.Version > target {
+				continue
+			}
+
+			m.logger.Infow("applying migration", "version", mig.Version, "name", mig.Name)
+			if err := m.apply(ctx, conn, mig, mig.Up, mig.Version); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	for i := m.index(current); i >= 0 && m.migrations[i].Version > target; i-- {
+		mig := m.migrations[i]
+
+		previous := uint(0)
+		if i > 0 {
+			previous = m.migrations[i-1].Version
+		}
+
+		m.logger.Infow("reverting migration", "version", mig.Version, "name", mig.Name)
+		if err := m.apply(ctx, conn, mig, mig.Down, previous); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// apply runs one migration and records the resulting version in the same
+// transaction. When the transaction can't be committed the version is marked
+// dirty, as the migration may have had effects outside of it.
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, mig Migration, query string, version uint) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if query != "" {
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d_%s: %w", mig.Version, mig.Name, err)
+		}
+	}
+
+	if err := setVersion(ctx, tx, version, false); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		if dirtyErr := setVersion(ctx, conn, mig.Version, true); dirtyErr != nil {
+			m.logger.Errorw("failed to mark the database as dirty", "version", mig.Version, "error", dirtyErr)
+		}
+		return fmt.Errorf("migration %d_%s: %w", mig.Version, mig.Name, err)
+	}
+
+	return nil
+}
+
+// index returns the position of version in the migrations, or -1.
+func (m *Migrator) index(version uint) int {
+	for i, mig := range m.migrations {
+		if mig.Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+// withLock runs fn while holding the migration lock, on the connection that
+// holds it.
+func (m *Migrator) withLock(ctx context.Context, fn func(*sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	m.logger.Debugw("waiting for the migration lock")
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey)
+
+	if err := ensureTable(ctx, conn); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+type execQueryer interface {
+	ExecContext(context.Context, string, ...any) (sql.Result, error)
+	QueryRowContext(context.Context, string, ...any) *sql.Row
+}
+
+func ensureTable(ctx context.Context, db execQueryer) error {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL)`)
+	return err
+}
+
+func version(ctx context.Context, db execQueryer) (uint, bool, error) {
+	var version uint
+	var dirty bool
+
+	err := db.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+
+	return version, dirty, err
+}
+
+func cleanVersion(ctx context.Context, db execQueryer) (uint, error) {
+	current, dirty, err := version(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+
+	if dirty {
+		return 0, fmt.Errorf("%w (version %d)", ErrDirty, current)
+	}
+
+	return current, nil
+}
+
+// setVersion replaces the single row of schema_migrations. Version 0 means
+// nothing is applied and leaves the table empty.
+func setVersion(ctx context.Context, db execQueryer, version uint, dirty bool) error {
+	if _, err := db.ExecContext(ctx, `DELETE FROM schema_migrations`); err != nil {
+		return err
+	}
+
+	if version == 0 && !dirty {
+		return nil
+	}
+
+	_, err := db.ExecContext(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, $2)`, int64(version), dirty)
+	return err
+}
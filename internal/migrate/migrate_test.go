@@ -0,0 +1,104 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"testing/fstest"
+
+	"github.com/kuluruvineeth/social-go/cmd/migrate/migrations"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("should order migrations by version", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"000010_add_tags.up.sql":       {Data: []byte("ALTER TABLE posts ADD tags text")},
+			"000010_add_tags.down.sql":     {Data: []byte("ALTER TABLE posts DROP tags")},
+			"000002_create_posts.up.sql":   {Data: []byte("CREATE TABLE posts ()")},
+			"000001_create_users.up.sql":   {Data: []byte("CREATE TABLE users ()")},
+			"000001_create_users.down.sql": {Data: []byte("DROP TABLE users")},
+			"README.md":                    {Data: []byte("ignored")},
+		}
+
+		got, err := Load(fsys)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(got) != 3 {
+			t.Fatalf("expected 3 migrations, got %d", len(got))
+		}
+		for i, want := range []uint{1, 2, 10} {
+			if got[i].Version != want {
+				t.Errorf("expected version %d at %d, got %d", want, i, got[i].Version)
+			}
+		}
+		if got[0].Name != "create_users" || got[0].Down != "DROP TABLE users" {
+			t.Errorf("unexpected migration %+v", got[0])
+		}
+		if got[1].Down != "" {
+			t.Errorf("expected no down migration, got %q", got[1].Down)
+		}
+	})
+
+	t.Run("should require an up migration", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"000001_create_users.down.sql": {Data: []byte("DROP TABLE users")},
+		}
+
+		if _, err := Load(fsys); err == nil {
+			t.Error("expected an error")
+		}
+	})
+
+	t.Run("should reject versions with different names", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"000001_create_users.up.sql":    {Data: []byte("CREATE TABLE users ()")},
+			"000001_create_people.down.sql": {Data: []byte("DROP TABLE people")},
+		}
+
+		if _, err := Load(fsys); err == nil {
+			t.Error("expected an error")
+		}
+	})
+
+	t.Run("should load the embedded migrations", func(t *testing.T) {
+		got, err := Load(migrations.FS)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i, m := range got {
+			if m.Version != uint(i+1) {
+				t.Fatalf("expected consecutive versions, got %d at %d", m.Version, i)
+			}
+			if m.Down == "" {
+				t.Errorf("expected migration %d_%s to have a down file", m.Version, m.Name)
+			}
+		}
+	})
+}
+
+func TestRun(t *testing.T) {
+	invalid := [][]string{
+		nil,
+		{"sideways"},
+		{"up", "1"},
+		{"down", "0"},
+		{"down", "one"},
+		{"down", "1", "2"},
+		{"goto"},
+		{"goto", "-1"},
+		{"force"},
+		{"version", "1"},
+		{"status", "all"},
+	}
+
+	for _, args := range invalid {
+		// Usage errors are returned before the migrator is used.
+		if err := Run(context.Background(), nil, args, io.Discard); !errors.Is(err, ErrUsage) {
+			t.Errorf("expected ErrUsage for %q, got %v", args, err)
+		}
+	}
+}
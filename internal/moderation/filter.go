@@ -0,0 +1,89 @@
+// Package moderation checks user content before it's accepted.
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Verdict is what a filter decided about some content, from the least to the
+// most severe.
+type Verdict int
+
+const (
+	// Allow accepts the content.
+	Allow Verdict = iota
+	// Flag accepts the content and queues it for review by a moderator.
+	Flag
+	// Reject refuses the content.
+	Reject
+)
+
+type Result struct {
+	Verdict Verdict
+	// Reason explains the verdict to moderators, and to the author when the
+	// content is rejected.
+	Reason string
+}
+
+// Filter checks content. Implementations can be as simple as a word list or
+// call out to a classification service.
+type Filter interface {
+	Check(ctx context.Context, text string) (Result, error)
+}
+
+// Chain runs every filter and returns the most severe result. An empty chain
+// allows everything.
+type Chain []Filter
+
+func (c Chain) Check(ctx context.Context, text string) (Result, error) {
+	result := Result{Verdict: Allow}
+
+	for _, f := range c {
+		r, err := f.Check(ctx, text)
+		if err != nil {
+			return Result{}, err
+		}
+
+		if r.Verdict > result.Verdict {
+			result = r
+		}
+	}
+
+	return result, nil
+}
+
+// WordList gives its verdict to content that uses any of its words. Words are
+// matched whole and case-insensitively, so "class" doesn't match "ass".
+type WordList struct {
+	words   map[string]bool
+	verdict Verdict
+}
+
+func NewWordList(words []string, verdict Verdict) *WordList {
+	l := &WordList{words: map[string]bool{}, verdict: verdict}
+
+	for _, w := range words {
+		if w = strings.ToLower(strings.TrimSpace(w)); w != "" {
+			l.words[w] = true
+		}
+	}
+
+	return l
+}
+
+func (l *WordList) Check(ctx context.Context, text string) (Result, error) {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for _, w := range words {
+		if l.words[w] {
+			return Result{Verdict: l.verdict, Reason: fmt.Sprintf("uses the word %q", w)}, nil
+		}
+	}
+
+	return Result{Verdict: Allow}, nil
+}
@@ -0,0 +1,63 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type failingFilter struct{}
+
+func (failingFilter) Check(ctx context.Context, text string) (Result, error) {
+	return Result{}, errors.New("service unavailable")
+}
+
+func TestWordList(t *testing.T) {
+	l := NewWordList([]string{"Spam", " scam ", ""}, Reject)
+
+	tests := []struct {
+		text string
+		want Verdict
+	}{
+		{"totally legit offer", Allow},
+		{"this is SPAM!", Reject},
+		{"a #scam, clearly", Reject},
+		{"spammer and scammers", Allow},
+		{"", Allow},
+	}
+
+	for _, tt := range tests {
+		r, err := l.Check(context.Background(), tt.text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Verdict != tt.want {
+			t.Errorf("%q: expected verdict %d, got %d", tt.text, tt.want, r.Verdict)
+		}
+		if r.Verdict != Allow && r.Reason == "" {
+			t.Errorf("%q: expected a reason", tt.text)
+		}
+	}
+}
+
+func TestChain(t *testing.T) {
+	ctx := context.Background()
+
+	c := Chain{
+		NewWordList([]string{"crypto"}, Flag),
+		NewWordList([]string{"scam"}, Reject),
+	}
+
+	if r, _ := c.Check(ctx, "crypto giveaway"); r.Verdict != Flag {
+		t.Errorf("expected the post to be flagged, got %+v", r)
+	}
+	if r, _ := c.Check(ctx, "crypto scam"); r.Verdict != Reject || r.Reason != `uses the word "scam"` {
+		t.Errorf("expected the most severe result, got %+v", r)
+	}
+	if r, _ := (Chain{}).Check(ctx, "scam"); r.Verdict != Allow {
+		t.Errorf("expected an empty chain to allow everything, got %+v", r)
+	}
+	if _, err := append(c, failingFilter{}).Check(ctx, "hello"); err == nil {
+		t.Error("expected the filter error")
+	}
+}
@@ -0,0 +1,140 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/kuluruvineeth/social-go/internal/realtime"
+	"github.com/kuluruvineeth/social-go/internal/store"
+	"go.uber.org/zap"
+)
+
+type Config struct {
+	FrontendURL string
+	// Sandbox is passed to the mailer for notification emails.
+	Sandbox bool
+}
+
+// Service records notification events and, depending on the recipient's
+// per-type preferences, queues an email for them in the outbox. Recipients
+// with an open stream are also pushed the notification through the broker.
+type Service struct {
+	store  store.Storage
+	broker realtime.Broker
+	logger *zap.SugaredLogger
+	cfg    Config
+}
+
+func NewService(store store.Storage, broker realtime.Broker, logger *zap.SugaredLogger, cfg Config) *Service {
+	return &Service{
+		store:  store,
+		broker: broker,
+		logger: logger,
+		cfg:    cfg,
+	}
+}
+
+// Notify records n for its recipient. Users are never notified about their
+// own actions.
+func (s *Service) Notify(ctx context.Context, n *store.Notification) error {
+	if n.UserID == n.ActorID {
+		return nil
+	}
+
+	if err := s.store.Notifications.Create(ctx, n); err != nil {
+		return err
+	}
+
+	s.push(ctx, n)
+
+	wantsEmail, err := s.wantsEmail(ctx, n.UserID, n.Type)
+	if err != nil {
+		return err
+	}
+
+	if !wantsEmail {
+		return nil
+	}
+
+	return s.enqueueEmail(ctx, n)
+}
+
+func (s *Service) push(ctx context.Context, n *store.Notification) {
+	if s.broker == nil {
+		return
+	}
+
+	ev, err := realtime.NewEvent(realtime.EventNotification, n)
+	if err == nil {
+		err = s.broker.Publish(ctx, realtime.UserTopic(n.UserID), ev)
+	}
+
+	if err != nil {
+		s.logger.Errorw("failed to push notification", "id", n.ID, "error", err)
+	}
+}
+
+func (s *Service) wantsEmail(ctx context.Context, userID int64, notificationType string) (bool, error) {
+	prefs, err := s.store.Notifications.GetPreferences(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, p := range prefs {
+		if p.Type == notificationType {
+			return p.Email, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (s *Service) enqueueEmail(ctx context.Context, n *store.Notification) error {
+	recipient, err := s.store.Users.GetByID(ctx, n.UserID)
+	if err != nil {
+		return err
+	}
+
+	actor, err := s.store.Users.GetByID(ctx, n.ActorID)
+	if err != nil {
+		return err
+	}
+
+	vars := struct {
+		Username      string
+		ActorUsername string
+		URL           string
+	}{
+		Username:      recipient.Username,
+		ActorUsername: actor.Username,
+		URL:           s.url(n),
+	}
+
+	data, err := json.Marshal(vars)
+	if err != nil {
+		return err
+	}
+
+	return s.store.Outbox.Enqueue(ctx, &store.OutboxMessage{
+		Template: Template(n.Type),
+		Locale:   recipient.Locale,
+		Username: recipient.Username,
+		Email:    recipient.Email,
+		Data:     data,
+		Sandbox:  s.cfg.Sandbox,
+	})
+}
+
+func (s *Service) url(n *store.Notification) string {
+	if n.PostID != nil {
+		return fmt.Sprintf("%s/posts/%d", s.cfg.FrontendURL, *n.PostID)
+	}
+
+	return fmt.Sprintf("%s/users/%d", s.cfg.FrontendURL, n.ActorID)
+}
+
+// Template returns the mailer template used for a notification type.
+func Template(notificationType string) string {
+	return "notification_" + notificationType
+}
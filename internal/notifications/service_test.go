@@ -0,0 +1,135 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/kuluruvineeth/social-go/internal/realtime"
+	"github.com/kuluruvineeth/social-go/internal/store"
+	"go.uber.org/zap"
+)
+
+type fakeNotificationStore struct {
+	store.NotificationStore
+	created []store.Notification
+	prefs   []store.NotificationPreference
+}
+
+func (s *fakeNotificationStore) Create(ctx context.Context, n *store.Notification) error {
+	n.ID = int64(len(s.created) + 1)
+	s.created = append(s.created, *n)
+	return nil
+}
+
+func (s *fakeNotificationStore) GetPreferences(ctx context.Context, userID int64) ([]store.NotificationPreference, error) {
+	return s.prefs, nil
+}
+
+type fakeOutboxStore struct {
+	store.OutboxStore
+	enqueued []store.OutboxMessage
+}
+
+func (s *fakeOutboxStore) Enqueue(ctx context.Context, msg *store.OutboxMessage) error {
+	s.enqueued = append(s.enqueued, *msg)
+	return nil
+}
+
+type fakeUserStore struct {
+	store.UserStore
+}
+
+func (s *fakeUserStore) GetByID(ctx context.Context, id int64) (*store.User, error) {
+	names := map[int64]string{1: "alice", 2: "bob"}
+	return &store.User{ID: id, Username: names[id], Email: names[id] + "@example.com", Locale: "es"}, nil
+}
+
+func newTestService(prefs []store.NotificationPreference) (*Service, *fakeNotificationStore, *fakeOutboxStore) {
+	notifications := &fakeNotificationStore{prefs: prefs}
+	outbox := &fakeOutboxStore{}
+
+	s := NewService(store.Storage{
+		Notifications: notifications,
+		Outbox:        outbox,
+		Users:         &fakeUserStore{},
+	}, realtime.NewLocalBroker(), zap.NewNop().Sugar(), Config{FrontendURL: "http://localhost:4000"})
+
+	return s, notifications, outbox
+}
+
+func TestNotify(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should not notify users about their own actions", func(t *testing.T) {
+		s, notifications, _ := newTestService(nil)
+
+		if err := s.Notify(ctx, &store.Notification{UserID: 1, ActorID: 1, Type: store.NotificationFollow}); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(notifications.created) != 0 {
+			t.Errorf("expected no notifications, got %d", len(notifications.created))
+		}
+	})
+
+	t.Run("should record without emailing by default", func(t *testing.T) {
+		s, notifications, outbox := newTestService([]store.NotificationPreference{{Type: store.NotificationFollow, Email: false}})
+
+		if err := s.Notify(ctx, &store.Notification{UserID: 1, ActorID: 2, Type: store.NotificationFollow}); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(notifications.created) != 1 {
+			t.Fatalf("expected 1 notification, got %d", len(notifications.created))
+		}
+		if len(outbox.enqueued) != 0 {
+			t.Errorf("expected no email, got %d", len(outbox.enqueued))
+		}
+	})
+
+	t.Run("should queue an email when the user opted in", func(t *testing.T) {
+		s, _, outbox := newTestService([]store.NotificationPreference{{Type: store.NotificationComment, Email: true}})
+
+		postID := int64(7)
+		if err := s.Notify(ctx, &store.Notification{UserID: 1, ActorID: 2, Type: store.NotificationComment, PostID: &postID}); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(outbox.enqueued) != 1 {
+			t.Fatalf("expected 1 email, got %d", len(outbox.enqueued))
+		}
+
+		msg := outbox.enqueued[0]
+		if msg.Template != "notification_comment" || msg.Email != "alice@example.com" || msg.Locale != "es" {
+			t.Errorf("unexpected message %+v", msg)
+		}
+
+		var data map[string]string
+		if err := json.Unmarshal(msg.Data, &data); err != nil {
+			t.Fatal(err)
+		}
+		if data["ActorUsername"] != "bob" || data["URL"] != "http://localhost:4000/posts/7" {
+			t.Errorf("unexpected template data %v", data)
+		}
+	})
+
+	t.Run("should push the notification to the recipient's stream", func(t *testing.T) {
+		s, _, _ := newTestService(nil)
+		sub := s.broker.Subscribe([]string{realtime.UserTopic(1)})
+		defer s.broker.Unsubscribe(sub)
+
+		if err := s.Notify(ctx, &store.Notification{UserID: 1, ActorID: 2, Type: store.NotificationFollow}); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case ev := <-sub.C:
+			if ev.Type != realtime.EventNotification {
+				t.Errorf("expected a notification event, got %q", ev.Type)
+			}
+		default:
+			t.Fatal("expected an event on the recipient's topic")
+		}
+	})
+}
@@ -0,0 +1,158 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/kuluruvineeth/social-go/internal/mailer"
+	"github.com/kuluruvineeth/social-go/internal/store"
+	"go.uber.org/zap"
+)
+
+type Store interface {
+	ClaimDue(context.Context, int, time.Duration) ([]store.OutboxMessage, error)
+	MarkSent(context.Context, int64) error
+	MarkFailed(context.Context, int64, string, time.Time, bool) error
+}
+
+type Config struct {
+	PollInterval time.Duration
+	BatchSize    int
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	// Lease is how long a claimed message stays hidden from other workers
+	// while it is being delivered.
+	Lease time.Duration
+	// ConfirmationURL is the page messages with a token link to, with the
+	// token appended to it.
+	ConfirmationURL string
+}
+
+var errTokenDiscarded = errors.New("outbox: the message's token was discarded when it was dead-lettered")
+
+// Worker polls the email outbox and delivers due messages through the mailer,
+// rescheduling failures with exponential backoff until MaxAttempts is
+// reached, after which the message is dead-lettered.
+type Worker struct {
+	store  Store
+	mailer mailer.Client
+	logger *zap.SugaredLogger
+	cfg    Config
+	now    func() time.Time
+}
+
+func NewWorker(store Store, mailer mailer.Client, logger *zap.SugaredLogger, cfg Config) *Worker {
+	return &Worker{
+		store:  store,
+		mailer: mailer,
+		logger: logger,
+		cfg:    cfg,
+		now:    time.Now,
+	}
+}
+
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+
+	w.logger.Infow("outbox worker has started", "poll_interval", w.cfg.PollInterval.String())
+
+	for {
+		if _, err := w.ProcessBatch(ctx); err != nil {
+			w.logger.Errorw("failed to process outbox batch", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			w.logger.Info("outbox worker has stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessBatch claims and delivers a single batch of due messages and returns
+// how many were claimed.
+func (w *Worker) ProcessBatch(ctx context.Context) (int, error) {
+	msgs, err := w.store.ClaimDue(ctx, w.cfg.BatchSize, w.cfg.Lease)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, msg := range msgs {
+		w.deliver(ctx, msg)
+	}
+
+	return len(msgs), nil
+}
+
+func (w *Worker) deliver(ctx context.Context, msg store.OutboxMessage) {
+	var data map[string]any
+	if err := json.Unmarshal(msg.Data, &data); err != nil {
+		w.fail(ctx, msg, err, true)
+		return
+	}
+
+	// Links with tokens are only built now, so the tokens are never part of
+	// the stored data.
+	if mailer.LinksToken(msg.Template) {
+		if msg.Token == "" {
+			w.fail(ctx, msg, errTokenDiscarded, true)
+			return
+		}
+		if data == nil {
+			data = map[string]any{}
+		}
+		data["ConfirmationURL"] = w.cfg.ConfirmationURL + "/" + msg.Token
+	}
+
+	if _, err := w.mailer.Send(msg.Template, msg.Locale, msg.Username, msg.Email, data, msg.Sandbox); err != nil {
+		w.fail(ctx, msg, err, msg.Attempts >= w.cfg.MaxAttempts)
+		return
+	}
+
+	if err := w.store.MarkSent(ctx, msg.ID); err != nil {
+		w.logger.Errorw("failed to mark outbox message as sent", "id", msg.ID, "error", err)
+	}
+}
+
+func (w *Worker) fail(ctx context.Context, msg store.OutboxMessage, sendErr error, dead bool) {
+	next := w.now().Add(Backoff(msg.Attempts, w.cfg.BaseBackoff, w.cfg.MaxBackoff))
+
+	if dead {
+		w.logger.Errorw("outbox message dead-lettered", "id", msg.ID, "attempts", msg.Attempts, "error", sendErr)
+	} else {
+		w.logger.Warnw("outbox delivery failed", "id", msg.ID, "attempts", msg.Attempts, "next_attempt_at", next, "error", sendErr)
+	}
+
+	if err := w.store.MarkFailed(ctx, msg.ID, sendErr.Error(), next, dead); err != nil {
+		w.logger.Errorw("failed to mark outbox message as failed", "id", msg.ID, "error", err)
+	}
+}
+
+// Backoff returns the delay before the next attempt after the given number
+// of attempts: base * 2^(attempts-1), capped at max, with up to 10% jitter.
+func Backoff(attempts int, base, max time.Duration) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	d := base
+	for i := 1; i < attempts && d < max; i++ {
+		d *= 2
+	}
+
+	if d > max {
+		d = max
+	}
+
+	if jitter := int64(d) / 10; jitter > 0 {
+		d += time.Duration(rand.Int63n(jitter))
+	}
+
+	return d
+}
@@ -0,0 +1,154 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kuluruvineeth/social-go/internal/mailer"
+	"github.com/kuluruvineeth/social-go/internal/store"
+	"go.uber.org/zap"
+)
+
+type fakeStore struct {
+	due    []store.OutboxMessage
+	sent   []int64
+	failed map[int64]bool
+}
+
+func (s *fakeStore) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]store.OutboxMessage, error) {
+	msgs := s.due
+	s.due = nil
+	return msgs, nil
+}
+
+func (s *fakeStore) MarkSent(ctx context.Context, id int64) error {
+	s.sent = append(s.sent, id)
+	return nil
+}
+
+func (s *fakeStore) MarkFailed(ctx context.Context, id int64, lastErr string, next time.Time, dead bool) error {
+	s.failed[id] = dead
+	return nil
+}
+
+type fakeMailer struct {
+	err  error
+	data []any
+}
+
+func (m *fakeMailer) Send(templateFile, locale, username, email string, data any, isSandbox bool) (int, error) {
+	m.data = append(m.data, data)
+	if m.err != nil {
+		return -1, m.err
+	}
+	return 200, nil
+}
+
+func newTestWorker(s *fakeStore, m *fakeMailer) *Worker {
+	return NewWorker(s, m, zap.NewNop().Sugar(), Config{
+		BatchSize:   10,
+		MaxAttempts: 3,
+		BaseBackoff: time.Second,
+		MaxBackoff:  time.Minute,
+		Lease:       time.Minute,
+
+		ConfirmationURL: "http://localhost:4000/confirm",
+	})
+}
+
+func TestWorkerProcessBatch(t *testing.T) {
+	ctx := context.Background()
+	data, _ := json.Marshal(map[string]string{"Username": "alice"})
+
+	t.Run("should mark delivered messages as sent", func(t *testing.T) {
+		s := &fakeStore{failed: map[int64]bool{}, due: []store.OutboxMessage{{ID: 1, Attempts: 1, Data: data}}}
+		m := &fakeMailer{}
+
+		n, err := newTestWorker(s, m).ProcessBatch(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if n != 1 || len(s.sent) != 1 || s.sent[0] != 1 {
+			t.Fatalf("expected message 1 to be sent, got %v", s.sent)
+		}
+		if got := m.data[0].(map[string]any)["Username"]; got != "alice" {
+			t.Errorf("expected template data to be decoded, got %v", got)
+		}
+	})
+
+	t.Run("should reschedule failures below the attempt limit", func(t *testing.T) {
+		s := &fakeStore{failed: map[int64]bool{}, due: []store.OutboxMessage{{ID: 2, Attempts: 1, Data: data}}}
+		m := &fakeMailer{err: errors.New("smtp down")}
+
+		if _, err := newTestWorker(s, m).ProcessBatch(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		dead, ok := s.failed[2]
+		if !ok || dead {
+			t.Fatalf("expected message 2 to be rescheduled, got dead=%v ok=%v", dead, ok)
+		}
+	})
+
+	t.Run("should dead-letter messages that exhausted their attempts", func(t *testing.T) {
+		s := &fakeStore{failed: map[int64]bool{}, due: []store.OutboxMessage{{ID: 3, Attempts: 3, Data: data}}}
+		m := &fakeMailer{err: errors.New("smtp down")}
+
+		if _, err := newTestWorker(s, m).ProcessBatch(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		if !s.failed[3] {
+			t.Fatal("expected message 3 to be dead-lettered")
+		}
+	})
+
+	t.Run("should build links from tokens", func(t *testing.T) {
+		s := &fakeStore{failed: map[int64]bool{}, due: []store.OutboxMessage{{ID: 4, Template: mailer.UserInvitationTemplate, Attempts: 1, Data: data, Token: "secret"}}}
+		m := &fakeMailer{}
+
+		if _, err := newTestWorker(s, m).ProcessBatch(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := m.data[0].(map[string]any)["ConfirmationURL"]; got != "http://localhost:4000/confirm/secret" {
+			t.Errorf("expected the confirmation link, got %v", got)
+		}
+	})
+
+	t.Run("should dead-letter messages whose token was discarded", func(t *testing.T) {
+		s := &fakeStore{failed: map[int64]bool{}, due: []store.OutboxMessage{{ID: 5, Template: mailer.EmailChangeTemplate, Attempts: 1, Data: data}}}
+		m := &fakeMailer{}
+
+		if _, err := newTestWorker(s, m).ProcessBatch(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		if !s.failed[5] || len(m.data) != 0 {
+			t.Fatal("expected message 5 to be dead-lettered without being sent")
+		}
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	cases := []struct {
+		attempts int
+		min      time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{4, 8 * time.Second},
+		{20, time.Minute},
+	}
+
+	for _, c := range cases {
+		got := Backoff(c.attempts, time.Second, time.Minute)
+		if got < c.min || got > c.min+c.min/10 {
+			t.Errorf("Backoff(%d) = %v, want within 10%% above %v", c.attempts, got, c.min)
+		}
+	}
+}
@@ -0,0 +1,361 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrTestFailed is returned when a test operation doesn't match the document.
+var ErrTestFailed = errors.New("patch test failed")
+
+// Operation is one operation of a JSON Patch.
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Apply applies a JSON Patch to doc. The operations are applied in order and
+// the patch fails as a whole if one of them does, with ErrTestFailed when a
+// test operation didn't match and ErrInvalidPatch otherwise.
+func Apply(doc, patch []byte) ([]byte, error) {
+	var ops []Operation
+	if err := unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	var d any
+	if err := unmarshal(doc, &d); err != nil {
+		return nil, err
+	}
+
+	for i, op := range ops {
+		var err error
+		if d, err = op.apply(d); err != nil {
+			if errors.Is(err, ErrTestFailed) {
+				return nil, fmt.Errorf("%w: operation %d on %s", err, i, op.Path)
+			}
+			return nil, fmt.Errorf("%w: operation %d: %v", ErrInvalidPatch, i, err)
+		}
+	}
+
+	return json.Marshal(d)
+}
+
+func (op Operation) apply(doc any) (any, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		value, err := op.value()
+		if err != nil {
+			return nil, err
+		}
+
+		if op.Op == "test" {
+			current, err := get(doc, path)
+			if err != nil {
+				return nil, err
+			}
+			if !equal(current, value) {
+				return nil, ErrTestFailed
+			}
+			return doc, nil
+		}
+
+		return put(doc, path, value, op.Op == "replace")
+
+	case "remove":
+		if len(path) == 0 {
+			return nil, errors.New("can't remove the whole document")
+		}
+		doc, _, err := remove(doc, path)
+		return doc, err
+
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := get(doc, from)
+		if err != nil {
+			return nil, err
+		}
+
+		if op.Op == "copy" {
+			return put(doc, path, clone(value), false)
+		}
+
+		if hasPrefix(path, from) && len(path) > len(from) {
+			return nil, errors.New("can't move a value into itself")
+		}
+		if doc, _, err = remove(doc, from); err != nil {
+			return nil, err
+		}
+		return put(doc, path, value, false)
+	}
+
+	return nil, fmt.Errorf("unknown operation %q", op.Op)
+}
+
+func (op Operation) value() (any, error) {
+	if op.Value == nil {
+		return nil, fmt.Errorf("%s needs a value", op.Op)
+	}
+
+	var v any
+	err := unmarshal(op.Value, &v)
+	return v, err
+}
+
+// parsePointer splits a JSON Pointer (RFC 6901) into its reference tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid path %q", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(t)
+	}
+
+	return tokens, nil
+}
+
+func notFound(path []string) error {
+	return fmt.Errorf("path /%s doesn't exist", strings.Join(path, "/"))
+}
+
+func get(doc any, path []string) (any, error) {
+	for i, token := range path {
+		switch c := doc.(type) {
+		case map[string]any:
+			v, ok := c[token]
+			if !ok {
+				return nil, notFound(path[:i+1])
+			}
+			doc = v
+		case []any:
+			n, err := arrayIndex(token, len(c)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = c[n]
+		default:
+			return nil, notFound(path[:i+1])
+		}
+	}
+
+	return doc, nil
+}
+
+// put adds value at path, or replaces the value there when replace is set.
+// Arrays grow on add, with "-" appending. It returns the updated document.
+func put(doc any, path []string, value any, replace bool) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	token, rest := path[0], path[1:]
+
+	switch c := doc.(type) {
+	case map[string]any:
+		current, ok := c[token]
+		if len(rest) == 0 {
+			if replace && !ok {
+				return nil, notFound(path)
+			}
+			c[token] = value
+			return c, nil
+		}
+		if !ok {
+			return nil, notFound(path[:1])
+		}
+
+		v, err := put(current, rest, value, replace)
+		if err != nil {
+			return nil, err
+		}
+		c[token] = v
+		return c, nil
+
+	case []any:
+		if len(rest) == 0 && !replace {
+			if token == "-" {
+				return append(c, value), nil
+			}
+
+			n, err := arrayIndex(token, len(c))
+			if err != nil {
+				return nil, err
+			}
+			return append(c[:n], append([]any{value}, c[n:]...)...), nil
+		}
+
+		n, err := arrayIndex(token, len(c)-1)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(rest) == 0 {
+			c[n] = value
+			return c, nil
+		}
+
+		v, err := put(c[n], rest, value, replace)
+		if err != nil {
+			return nil, err
+		}
+		c[n] = v
+		return c, nil
+	}
+
+	return nil, notFound(path[:1])
+}
+
+// remove deletes the value at path, and returns the updated document and the
+// removed value.
+func remove(doc any, path []string) (any, any, error) {
+	token, rest := path[0], path[1:]
+
+	switch c := doc.(type) {
+	case map[string]any:
+		current, ok := c[token]
+		if !ok {
+			return nil, nil, notFound(path[:1])
+		}
+
+		if len(rest) == 0 {
+			delete(c, token)
+			return c, current, nil
+		}
+
+		v, removed, err := remove(current, rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		c[token] = v
+		return c, removed, nil
+
+	case []any:
+		n, err := arrayIndex(token, len(c)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if len(rest) == 0 {
+			removed := c[n]
+			return append(c[:n], c[n+1:]...), removed, nil
+		}
+
+		v, removed, err := remove(c[n], rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		c[n] = v
+		return c, removed, nil
+	}
+
+	return nil, nil, notFound(path[:1])
+}
+
+// arrayIndex parses an array index token, which must be at most max.
+func arrayIndex(token string, max int) (int, error) {
+	n, err := strconv.Atoi(token)
+	if err != nil || n < 0 || (len(token) > 1 && token[0] == '0') || token[0] == '+' {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+
+	if n > max {
+		return 0, fmt.Errorf("array index %d out of range", n)
+	}
+
+	return n, nil
+}
+
+func hasPrefix(path, prefix []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+
+	for i := range prefix {
+		if path[i] != prefix[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// equal compares JSON values, numbers by their value.
+func equal(a, b any) bool {
+	switch x := a.(type) {
+	case map[string]any:
+		y, ok := b.(map[string]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			w, ok := y[k]
+			if !ok || !equal(v, w) {
+				return false
+			}
+		}
+		return true
+
+	case []any:
+		y, ok := b.([]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		f, errX := x.Float64()
+		g, errY := y.Float64()
+		return errX == nil && errY == nil && f == g
+	}
+
+	return a == b
+}
+
+func clone(v any) any {
+	switch x := v.(type) {
+	case map[string]any:
+		c := make(map[string]any, len(x))
+		for k, v := range x {
+			c[k] = clone(v)
+		}
+		return c
+
+	case []any:
+		c := make([]any, len(x))
+		for i, v := range x {
+			c[i] = clone(v)
+		}
+		return c
+	}
+
+	return v
+}
@@ -0,0 +1,68 @@
+// Package patch applies JSON Merge Patch (RFC 7396) and JSON Patch (RFC 6902)
+// documents to JSON values.
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var ErrInvalidPatch = errors.New("invalid patch")
+
+// Merge applies a JSON Merge Patch to doc. Members of the patch replace those
+// of the document, null members remove them, and objects are merged
+// recursively. A patch that isn't an object replaces the whole document.
+func Merge(doc, patch []byte) ([]byte, error) {
+	var p any
+	if err := unmarshal(patch, &p); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	var d any
+	if err := unmarshal(doc, &d); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(merge(d, p))
+}
+
+func merge(doc, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	d, ok := doc.(map[string]any)
+	if !ok {
+		d = map[string]any{}
+	}
+
+	for k, v := range p {
+		if v == nil {
+			delete(d, k)
+			continue
+		}
+		d[k] = merge(d[k], v)
+	}
+
+	return d
+}
+
+// unmarshal decodes a single JSON value, keeping numbers as written.
+func unmarshal(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		return errors.New("unexpected data after the JSON value")
+	}
+
+	return nil
+}
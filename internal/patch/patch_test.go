@@ -0,0 +1,216 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func assertJSON(t *testing.T, expected string, got []byte) {
+	t.Helper()
+
+	var e, g any
+	if err := json.Unmarshal([]byte(expected), &e); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(e, g) {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	// From RFC 7396, appendix A
+	tests := []struct {
+		doc, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+
+	for _, tt := range tests {
+		got, err := Merge([]byte(tt.doc), []byte(tt.patch))
+		if err != nil {
+			t.Errorf("%s + %s: %v", tt.doc, tt.patch, err)
+			continue
+		}
+		assertJSON(t, tt.want, got)
+	}
+
+	if _, err := Merge([]byte(`{}`), []byte(`{"a":`)); !errors.Is(err, ErrInvalidPatch) {
+		t.Errorf("expected ErrInvalidPatch, got %v", err)
+	}
+	if _, err := Merge([]byte(`{}`), []byte(`{} {}`)); !errors.Is(err, ErrInvalidPatch) {
+		t.Errorf("expected ErrInvalidPatch for trailing data, got %v", err)
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name       string
+		doc, patch string
+		want       string
+		err        error
+	}{
+		{
+			name:  "add to an object",
+			doc:   `{"foo":"bar"}`,
+			patch: `[{"op":"add","path":"/baz","value":"qux"}]`,
+			want:  `{"baz":"qux","foo":"bar"}`,
+		},
+		{
+			name:  "insert into an array",
+			doc:   `{"foo":["bar","baz"]}`,
+			patch: `[{"op":"add","path":"/foo/1","value":"qux"}]`,
+			want:  `{"foo":["bar","qux","baz"]}`,
+		},
+		{
+			name:  "append to an array",
+			doc:   `{"tags":["go"]}`,
+			patch: `[{"op":"add","path":"/tags/-","value":"sql"}]`,
+			want:  `{"tags":["go","sql"]}`,
+		},
+		{
+			name:  "remove from an array",
+			doc:   `{"foo":["bar","qux","baz"]}`,
+			patch: `[{"op":"remove","path":"/foo/1"}]`,
+			want:  `{"foo":["bar","baz"]}`,
+		},
+		{
+			name:  "replace",
+			doc:   `{"baz":"qux","foo":"bar"}`,
+			patch: `[{"op":"replace","path":"/baz","value":"boo"}]`,
+			want:  `{"baz":"boo","foo":"bar"}`,
+		},
+		{
+			name:  "move",
+			doc:   `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			patch: `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			want:  `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`,
+		},
+		{
+			name:  "move within an array",
+			doc:   `{"foo":["all","grass","cows","eat"]}`,
+			patch: `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`,
+			want:  `{"foo":["all","cows","eat","grass"]}`,
+		},
+		{
+			name:  "copy",
+			doc:   `{"a":{"b":[1]}}`,
+			patch: `[{"op":"copy","from":"/a","path":"/c"},{"op":"add","path":"/c/b/-","value":2}]`,
+			want:  `{"a":{"b":[1]},"c":{"b":[1,2]}}`,
+		},
+		{
+			name:  "escaped pointer",
+			doc:   `{"a/b":1,"m~n":2}`,
+			patch: `[{"op":"remove","path":"/a~1b"},{"op":"replace","path":"/m~0n","value":3}]`,
+			want:  `{"m~n":3}`,
+		},
+		{
+			name:  "passing test",
+			doc:   `{"baz":"qux","foo":["a",2,"c"],"n":1}`,
+			patch: `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2},{"op":"test","path":"/n","value":1.0}]`,
+			want:  `{"baz":"qux","foo":["a",2,"c"],"n":1}`,
+		},
+		{
+			name:  "failing test",
+			doc:   `{"baz":"qux"}`,
+			patch: `[{"op":"test","path":"/baz","value":"bar"}]`,
+			err:   ErrTestFailed,
+		},
+		{
+			name:  "missing target",
+			doc:   `{"foo":"bar"}`,
+			patch: `[{"op":"replace","path":"/baz","value":1}]`,
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "missing parent",
+			doc:   `{"foo":"bar"}`,
+			patch: `[{"op":"add","path":"/baz/bat","value":"qux"}]`,
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "array index out of range",
+			doc:   `{"foo":["bar"]}`,
+			patch: `[{"op":"add","path":"/foo/2","value":"qux"}]`,
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "leading zero index",
+			doc:   `{"foo":["bar","baz"]}`,
+			patch: `[{"op":"remove","path":"/foo/01"}]`,
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "move into itself",
+			doc:   `{"a":{"b":1}}`,
+			patch: `[{"op":"move","from":"/a","path":"/a/c"}]`,
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "missing value",
+			doc:   `{}`,
+			patch: `[{"op":"add","path":"/a"}]`,
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "unknown operation",
+			doc:   `{}`,
+			patch: `[{"op":"merge","path":"/a","value":1}]`,
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "not an array",
+			doc:   `{}`,
+			patch: `{"op":"add","path":"/a","value":1}`,
+			err:   ErrInvalidPatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply([]byte(tt.doc), []byte(tt.patch))
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected %v, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertJSON(t, tt.want, got)
+		})
+	}
+}
+
+func TestApplyIsAtomic(t *testing.T) {
+	doc := []byte(`{"a":1}`)
+
+	_, err := Apply(doc, []byte(`[{"op":"replace","path":"/a","value":2},{"op":"test","path":"/a","value":3}]`))
+	if !errors.Is(err, ErrTestFailed) {
+		t.Fatalf("expected ErrTestFailed, got %v", err)
+	}
+
+	if string(doc) != `{"a":1}` {
+		t.Errorf("expected the document to be unchanged, got %s", doc)
+	}
+}
@@ -0,0 +1,88 @@
+// Package ranking scores posts for the "for you" feed.
+package ranking
+
+import (
+	"math"
+	"time"
+)
+
+// Candidate is a post that may be shown in a viewer's feed.
+type Candidate struct {
+	PostID    int64
+	AuthorID  int64
+	CreatedAt time.Time
+	Comments  int
+	Reactions int
+	// Affinity is how many times the viewer recently interacted with the
+	// author, through comments and reactions on their posts.
+	Affinity int
+}
+
+type Weights struct {
+	// HalfLife is the age at which a post's score has halved.
+	HalfLife  time.Duration
+	Comments  float64
+	Reactions float64
+	Affinity  float64
+	// Diversity multiplies the score of a post once for every post by the
+	// same author ranked before it. 1 disables it.
+	Diversity float64
+}
+
+func DefaultWeights() Weights {
+	return Weights{
+		HalfLife:  12 * time.Hour,
+		Comments:  1,
+		Reactions: 0.5,
+		Affinity:  0.75,
+		Diversity: 0.6,
+	}
+}
+
+// Score returns the score of c at time now. Engagement and affinity are
+// counted logarithmically, so the first comments matter more than the
+// hundredth, and the result decays exponentially with the post's age.
+func Score(c Candidate, now time.Time, w Weights) float64 {
+	engagement := 1 + w.Comments*math.Log1p(float64(c.Comments)) + w.Reactions*math.Log1p(float64(c.Reactions))
+	affinity := 1 + w.Affinity*math.Log1p(float64(c.Affinity))
+
+	age := max(now.Sub(c.CreatedAt), 0)
+	decay := math.Pow(0.5, age.Hours()/w.HalfLife.Hours())
+
+	return engagement * affinity * decay
+}
+
+// Rank orders candidates by score, best first. Posts by an author that is
+// already in the feed are penalized, so a prolific author can't take over
+// the top of it.
+func Rank(candidates []Candidate, now time.Time, w Weights) []Candidate {
+	scores := make([]float64, len(candidates))
+	for i, c := range candidates {
+		scores[i] = Score(c, now, w)
+	}
+
+	remaining := make([]int, len(candidates))
+	for i := range remaining {
+		remaining[i] = i
+	}
+
+	ranked := make([]Candidate, 0, len(candidates))
+	seen := make(map[int64]int)
+
+	for len(remaining) > 0 {
+		best, bestScore := 0, math.Inf(-1)
+		for j, i := range remaining {
+			s := scores[i] * math.Pow(w.Diversity, float64(seen[candidates[i].AuthorID]))
+			if s > bestScore || (s == bestScore && candidates[i].CreatedAt.After(candidates[remaining[best]].CreatedAt)) {
+				best, bestScore = j, s
+			}
+		}
+
+		c := candidates[remaining[best]]
+		ranked = append(ranked, c)
+		seen[c.AuthorID]++
+		remaining = append(remaining[:best], remaining[best+1:]...)
+	}
+
+	return ranked
+}
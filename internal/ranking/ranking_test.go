@@ -0,0 +1,107 @@
+package ranking
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScore(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	w := DefaultWeights()
+
+	t.Run("should halve after the half-life", func(t *testing.T) {
+		fresh := Score(Candidate{CreatedAt: now}, now, w)
+		old := Score(Candidate{CreatedAt: now.Add(-w.HalfLife)}, now, w)
+
+		if diff := old - fresh/2; diff > 1e-9 || diff < -1e-9 {
+			t.Errorf("expected %f, got %f", fresh/2, old)
+		}
+	})
+
+	t.Run("should not boost posts from the future", func(t *testing.T) {
+		if got := Score(Candidate{CreatedAt: now.Add(time.Hour)}, now, w); got != 1 {
+			t.Errorf("expected 1, got %f", got)
+		}
+	})
+
+	t.Run("should favour engagement and affinity", func(t *testing.T) {
+		base := Candidate{CreatedAt: now}
+		engaged := Candidate{CreatedAt: now, Comments: 3, Reactions: 10}
+		familiar := Candidate{CreatedAt: now, Affinity: 5}
+
+		if Score(engaged, now, w) <= Score(base, now, w) {
+			t.Error("expected engagement to increase the score")
+		}
+		if Score(familiar, now, w) <= Score(base, now, w) {
+			t.Error("expected affinity to increase the score")
+		}
+	})
+
+	t.Run("should let a fresh post beat a stale popular one", func(t *testing.T) {
+		stale := Candidate{CreatedAt: now.Add(-72 * time.Hour), Comments: 50, Reactions: 200}
+		fresh := Candidate{CreatedAt: now.Add(-time.Hour), Comments: 1}
+
+		if Score(stale, now, w) >= Score(fresh, now, w) {
+			t.Error("expected the fresh post to rank higher")
+		}
+	})
+}
+
+func TestRank(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("should order by score", func(t *testing.T) {
+		candidates := []Candidate{
+			{PostID: 1, AuthorID: 1, CreatedAt: now.Add(-48 * time.Hour)},
+			{PostID: 2, AuthorID: 2, CreatedAt: now.Add(-time.Hour), Comments: 4},
+			{PostID: 3, AuthorID: 3, CreatedAt: now.Add(-time.Hour)},
+		}
+
+		assertOrder(t, Rank(candidates, now, DefaultWeights()), 2, 3, 1)
+	})
+
+	t.Run("should spread out posts by the same author", func(t *testing.T) {
+		candidates := []Candidate{
+			{PostID: 1, AuthorID: 1, CreatedAt: now},
+			{PostID: 2, AuthorID: 1, CreatedAt: now.Add(-time.Minute)},
+			{PostID: 3, AuthorID: 1, CreatedAt: now.Add(-2 * time.Minute)},
+			{PostID: 4, AuthorID: 2, CreatedAt: now.Add(-3 * time.Hour)},
+		}
+
+		assertOrder(t, Rank(candidates, now, DefaultWeights()), 1, 4, 2, 3)
+
+		w := DefaultWeights()
+		w.Diversity = 1
+		assertOrder(t, Rank(candidates, now, w), 1, 2, 3, 4)
+	})
+
+	t.Run("should break ties by recency", func(t *testing.T) {
+		w := DefaultWeights()
+		w.HalfLife = time.Duration(1<<63 - 1)
+
+		candidates := []Candidate{
+			{PostID: 1, AuthorID: 1, CreatedAt: now.Add(-time.Hour)},
+			{PostID: 2, AuthorID: 2, CreatedAt: now},
+		}
+
+		assertOrder(t, Rank(candidates, now, w), 2, 1)
+	})
+}
+
+func assertOrder(t *testing.T, ranked []Candidate, ids ...int64) {
+	t.Helper()
+
+	if len(ranked) != len(ids) {
+		t.Fatalf("expected %d posts, got %d", len(ids), len(ranked))
+	}
+
+	for i, c := range ranked {
+		if c.PostID != ids[i] {
+			got := make([]int64, len(ranked))
+			for j, c := range ranked {
+				got[j] = c.PostID
+			}
+			t.Fatalf("expected order %v, got %v", ids, got)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	EventPost         = "post"
+	EventComment      = "comment"
+	EventNotification = "notification"
+
+	// SubscriptionBuffer is how many events a slow subscriber may fall
+	// behind before new events are dropped for it.
+	SubscriptionBuffer = 64
+)
+
+type Event struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data" swaggertype:"object"`
+}
+
+func NewEvent(eventType string, data any) (Event, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+
+	return Event{Type: eventType, Data: raw}, nil
+}
+
+// Topics a subscriber can listen on.
+func UserTopic(userID int64) string   { return fmt.Sprintf("user:%d", userID) }
+func AuthorTopic(userID int64) string { return fmt.Sprintf("author:%d", userID) }
+func PostTopic(postID int64) string   { return fmt.Sprintf("post:%d", postID) }
+
+type Broker interface {
+	Publish(ctx context.Context, topic string, ev Event) error
+	Subscribe(topics []string) *Subscription
+	Unsubscribe(sub *Subscription)
+}
+
+type Subscription struct {
+	C       <-chan Event
+	c       chan Event
+	topics  []string
+	dropped atomic.Int64
+}
+
+// Dropped returns how many events were discarded because the subscriber
+// wasn't keeping up.
+func (s *Subscription) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// LocalBroker fans events out to subscribers within this process.
+type LocalBroker struct {
+	mu     sync.RWMutex
+	topics map[string]map[*Subscription]struct{}
+}
+
+func NewLocalBroker() *LocalBroker {
+	return &LocalBroker{
+		topics: make(map[string]map[*Subscription]struct{}),
+	}
+}
+
+func (b *LocalBroker) Publish(ctx context.Context, topic string, ev Event) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for sub := range b.topics[topic] {
+		select {
+		case sub.c <- ev:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+
+	return nil
+}
+
+func (b *LocalBroker) Subscribe(topics []string) *Subscription {
+	c := make(chan Event, SubscriptionBuffer)
+	sub := &Subscription{C: c, c: c, topics: topics}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, topic := range topics {
+		subs, ok := b.topics[topic]
+		if !ok {
+			subs = make(map[*Subscription]struct{})
+			b.topics[topic] = subs
+		}
+		subs[sub] = struct{}{}
+	}
+
+	return sub
+}
+
+func (b *LocalBroker) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, topic := range sub.topics {
+		delete(b.topics[topic], sub)
+		if len(b.topics[topic]) == 0 {
+			delete(b.topics, topic)
+		}
+	}
+}
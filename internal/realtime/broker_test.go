@@ -0,0 +1,71 @@
+package realtime
+
+import (
+	"context"
+	"testing"
+)
+
+func TestLocalBroker(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should deliver events only to subscribers of the topic", func(t *testing.T) {
+		b := NewLocalBroker()
+		alice := b.Subscribe([]string{UserTopic(1), AuthorTopic(2)})
+		bob := b.Subscribe([]string{UserTopic(2)})
+
+		ev, err := NewEvent(EventPost, map[string]int{"id": 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := b.Publish(ctx, AuthorTopic(2), ev); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case got := <-alice.C:
+			if got.Type != EventPost || string(got.Data) != `{"id":1}` {
+				t.Errorf("unexpected event %+v", got)
+			}
+		default:
+			t.Fatal("expected alice to receive the event")
+		}
+
+		select {
+		case got := <-bob.C:
+			t.Errorf("bob should not receive %+v", got)
+		default:
+		}
+	})
+
+	t.Run("should drop events for subscribers that fall behind", func(t *testing.T) {
+		b := NewLocalBroker()
+		sub := b.Subscribe([]string{PostTopic(1)})
+
+		for i := 0; i < SubscriptionBuffer+3; i++ {
+			b.Publish(ctx, PostTopic(1), Event{Type: EventComment})
+		}
+
+		if got := sub.Dropped(); got != 3 {
+			t.Errorf("expected 3 dropped events, got %d", got)
+		}
+		if got := len(sub.C); got != SubscriptionBuffer {
+			t.Errorf("expected a full buffer, got %d events", got)
+		}
+	})
+
+	t.Run("should stop delivering after unsubscribe", func(t *testing.T) {
+		b := NewLocalBroker()
+		sub := b.Subscribe([]string{UserTopic(1)})
+		b.Unsubscribe(sub)
+
+		b.Publish(ctx, UserTopic(1), Event{Type: EventNotification})
+
+		if len(sub.C) != 0 {
+			t.Error("expected no events after unsubscribe")
+		}
+		if len(b.topics) != 0 {
+			t.Errorf("expected empty topics to be removed, got %v", b.topics)
+		}
+	})
+}
@@ -0,0 +1,69 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const RedisChannel = "realtime:events"
+
+type envelope struct {
+	Topic string `json:"topic"`
+	Event Event  `json:"event"`
+}
+
+// RedisBroker fans events out across instances. Publish goes through Redis
+// only; every instance, including the publisher, delivers what it receives
+// from the channel to its local subscribers.
+type RedisBroker struct {
+	*LocalBroker
+	rdb    *redis.Client
+	logger *zap.SugaredLogger
+}
+
+func NewRedisBroker(rdb *redis.Client, logger *zap.SugaredLogger) *RedisBroker {
+	return &RedisBroker{
+		LocalBroker: NewLocalBroker(),
+		rdb:         rdb,
+		logger:      logger,
+	}
+}
+
+func (b *RedisBroker) Publish(ctx context.Context, topic string, ev Event) error {
+	payload, err := json.Marshal(envelope{Topic: topic, Event: ev})
+	if err != nil {
+		return err
+	}
+
+	return b.rdb.Publish(ctx, RedisChannel, payload).Err()
+}
+
+// Listen relays events from Redis to local subscribers until ctx is
+// cancelled.
+func (b *RedisBroker) Listen(ctx context.Context) {
+	sub := b.rdb.Subscribe(ctx, RedisChannel)
+	defer sub.Close()
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+
+			var env envelope
+			if err := json.Unmarshal([]byte(msg.Payload), &env); err != nil {
+				b.logger.Warnw("invalid realtime message", "payload", msg.Payload, "error", err)
+				continue
+			}
+
+			b.LocalBroker.Publish(ctx, env.Topic, env.Event)
+		}
+	}
+}
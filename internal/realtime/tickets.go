@@ -0,0 +1,113 @@
+package realtime
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const redisTicketPrefix = "realtime:ticket:"
+
+var ErrInvalidTicket = errors.New("realtime: invalid or expired ticket")
+
+// Tickets issue short-lived, single-use credentials for opening a stream.
+// Browsers can't set headers on EventSource and WebSocket requests, so the
+// credential goes in the URL, where a ticket is harmless once redeemed
+// while a token would stay valid wherever the URL was logged.
+type Tickets interface {
+	Issue(ctx context.Context, userID int64, ttl time.Duration) (string, error)
+	// Redeem returns the user the ticket was issued to, and invalidates it.
+	Redeem(ctx context.Context, ticket string) (int64, error)
+}
+
+type localTicket struct {
+	userID    int64
+	expiresAt time.Time
+}
+
+// LocalTickets keeps tickets in memory, so they can only be redeemed on the
+// instance that issued them.
+type LocalTickets struct {
+	mu      sync.Mutex
+	tickets map[string]localTicket
+	now     func() time.Time
+}
+
+func NewLocalTickets() *LocalTickets {
+	return &LocalTickets{
+		tickets: make(map[string]localTicket),
+		now:     time.Now,
+	}
+}
+
+func (t *LocalTickets) Issue(ctx context.Context, userID int64, ttl time.Duration) (string, error) {
+	ticket := rand.Text()
+	now := t.now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Tickets that were never redeemed are dropped as new ones are issued.
+	for k, v := range t.tickets {
+		if !now.Before(v.expiresAt) {
+			delete(t.tickets, k)
+		}
+	}
+
+	t.tickets[ticket] = localTicket{userID: userID, expiresAt: now.Add(ttl)}
+
+	return ticket, nil
+}
+
+func (t *LocalTickets) Redeem(ctx context.Context, ticket string) (int64, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	v, ok := t.tickets[ticket]
+	if !ok {
+		return 0, ErrInvalidTicket
+	}
+	delete(t.tickets, ticket)
+
+	if !t.now().Before(v.expiresAt) {
+		return 0, ErrInvalidTicket
+	}
+
+	return v.userID, nil
+}
+
+// RedisTickets keeps tickets in Redis, so any instance can redeem them.
+type RedisTickets struct {
+	rdb *redis.Client
+}
+
+func NewRedisTickets(rdb *redis.Client) *RedisTickets {
+	return &RedisTickets{rdb: rdb}
+}
+
+func (t *RedisTickets) Issue(ctx context.Context, userID int64, ttl time.Duration) (string, error) {
+	ticket := rand.Text()
+
+	if err := t.rdb.Set(ctx, redisTicketPrefix+ticket, userID, ttl).Err(); err != nil {
+		return "", err
+	}
+
+	return ticket, nil
+}
+
+func (t *RedisTickets) Redeem(ctx context.Context, ticket string) (int64, error) {
+	v, err := t.rdb.GetDel(ctx, redisTicketPrefix+ticket).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return 0, ErrInvalidTicket
+		}
+		return 0, err
+	}
+
+	return strconv.ParseInt(v, 10, 64)
+}
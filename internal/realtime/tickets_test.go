@@ -0,0 +1,72 @@
+package realtime
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLocalTickets(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should redeem tickets once", func(t *testing.T) {
+		tickets := NewLocalTickets()
+
+		ticket, err := tickets.Issue(ctx, 7, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		userID, err := tickets.Redeem(ctx, ticket)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if userID != 7 {
+			t.Errorf("expected user 7, got %d", userID)
+		}
+
+		if _, err := tickets.Redeem(ctx, ticket); !errors.Is(err, ErrInvalidTicket) {
+			t.Errorf("expected ErrInvalidTicket, got %v", err)
+		}
+	})
+
+	t.Run("should not redeem expired or unknown tickets", func(t *testing.T) {
+		tickets := NewLocalTickets()
+		now := time.Now()
+		tickets.now = func() time.Time { return now }
+
+		ticket, err := tickets.Issue(ctx, 7, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		now = now.Add(time.Minute)
+
+		if _, err := tickets.Redeem(ctx, ticket); !errors.Is(err, ErrInvalidTicket) {
+			t.Errorf("expected ErrInvalidTicket, got %v", err)
+		}
+		if _, err := tickets.Redeem(ctx, "unknown"); !errors.Is(err, ErrInvalidTicket) {
+			t.Errorf("expected ErrInvalidTicket, got %v", err)
+		}
+	})
+
+	t.Run("should drop expired tickets", func(t *testing.T) {
+		tickets := NewLocalTickets()
+		now := time.Now()
+		tickets.now = func() time.Time { return now }
+
+		if _, err := tickets.Issue(ctx, 7, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+
+		now = now.Add(time.Hour)
+
+		if _, err := tickets.Issue(ctx, 8, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		if len(tickets.tickets) != 1 {
+			t.Errorf("expected the expired ticket to be dropped, got %d tickets", len(tickets.tickets))
+		}
+	})
+}
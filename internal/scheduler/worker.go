@@ -0,0 +1,76 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/kuluruvineeth/social-go/internal/store"
+	"go.uber.org/zap"
+)
+
+type Store interface {
+	PublishDue(context.Context, int) ([]store.Post, error)
+}
+
+type Config struct {
+	PollInterval time.Duration
+	BatchSize    int
+}
+
+// Worker publishes scheduled posts once their time comes. The store queues
+// them for fan-out, and onPublish is called for each of them for whatever
+// else happens when a post goes out, like notifying mentioned users.
+type Worker struct {
+	store     Store
+	logger    *zap.SugaredLogger
+	cfg       Config
+	onPublish func(context.Context, store.Post)
+}
+
+func NewWorker(store Store, logger *zap.SugaredLogger, cfg Config, onPublish func(context.Context, store.Post)) *Worker {
+	return &Worker{
+		store:     store,
+		logger:    logger,
+		cfg:       cfg,
+		onPublish: onPublish,
+	}
+}
+
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+
+	w.logger.Infow("post scheduler has started", "poll_interval", w.cfg.PollInterval.String())
+
+	for {
+		if _, err := w.ProcessBatch(ctx); err != nil {
+			w.logger.Errorw("failed to publish scheduled posts", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			w.logger.Info("post scheduler has stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessBatch publishes a single batch of due posts and returns how many
+// were published.
+func (w *Worker) ProcessBatch(ctx context.Context) (int, error) {
+	posts, err := w.store.PublishDue(ctx, w.cfg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, p := range posts {
+		w.logger.Debugw("scheduled post published", "post_id", p.ID, "author_id", p.UserID)
+
+		if w.onPublish != nil {
+			w.onPublish(ctx, p)
+		}
+	}
+
+	return len(posts), nil
+}
@@ -0,0 +1,60 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/kuluruvineeth/social-go/internal/store"
+	"go.uber.org/zap"
+)
+
+type fakeStore struct {
+	due   []store.Post
+	limit int
+	err   error
+}
+
+func (s *fakeStore) PublishDue(ctx context.Context, limit int) ([]store.Post, error) {
+	s.limit = limit
+	posts := s.due
+	s.due = nil
+	return posts, s.err
+}
+
+func TestWorkerProcessBatch(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should hand every published post over", func(t *testing.T) {
+		s := &fakeStore{due: []store.Post{{ID: 1, UserID: 1}, {ID: 2, UserID: 2}}}
+
+		var published []int64
+		w := NewWorker(s, zap.NewNop().Sugar(), Config{BatchSize: 10}, func(ctx context.Context, p store.Post) {
+			published = append(published, p.ID)
+		})
+
+		n, err := w.ProcessBatch(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if n != 2 || len(published) != 2 || published[0] != 1 || published[1] != 2 {
+			t.Errorf("expected posts 1 and 2 to be published, got %v", published)
+		}
+		if s.limit != 10 {
+			t.Errorf("expected a batch of 10, got %d", s.limit)
+		}
+	})
+
+	t.Run("should return store errors", func(t *testing.T) {
+		s := &fakeStore{err: errors.New("db down")}
+
+		w := NewWorker(s, zap.NewNop().Sugar(), Config{BatchSize: 10}, func(ctx context.Context, p store.Post) {
+			t.Errorf("unexpected post %d", p.ID)
+		})
+
+		if _, err := w.ProcessBatch(ctx); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
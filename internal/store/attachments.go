@@ -0,0 +1,154 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// MaxAttachments is how many images a post can have.
+const MaxAttachments = 4
+
+// Attachment is an image attached to a post. The image and its thumbnail
+// live in the blob store, the API sets their URLs from the keys.
+type Attachment struct {
+	ID           int64  `json:"id"`
+	PostID       int64  `json:"post_id"`
+	UserID       int64  `json:"user_id"`
+	Key          string `json:"-"`
+	ThumbnailKey string `json:"-"`
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	ContentType  string `json:"content_type"`
+	Size         int64  `json:"size"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	CreatedAt    string `json:"created_at"`
+}
+
+// attachmentsColumn selects the attachments of the post aliased p as a JSON
+// array, scanned with attachmentList.
+const attachmentsColumn = `
+	COALESCE((
+		SELECT json_agg(json_build_object(
+			'id', a.id, 'post_id', a.post_id, 'user_id', a.user_id,
+			'key', a.key, 'thumbnail_key', a.thumbnail_key,
+			'content_type', a.content_type, 'size', a.size,
+			'width', a.width, 'height', a.height, 'created_at', a.created_at
+		) ORDER BY a.position, a.id)
+		FROM attachments a WHERE a.post_id = p.id
+	), '[]')
+`
+
+// attachmentList scans attachmentsColumn. The keys are hidden from the
+// attachments' JSON, so the rows are decoded through their own type.
+type attachmentList []Attachment
+
+func (l *attachmentList) Scan(src any) error {
+	data, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("unexpected attachments type %T", src)
+	}
+
+	var rows []struct {
+		Attachment
+		Key          string `json:"key"`
+		ThumbnailKey string `json:"thumbnail_key"`
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return err
+	}
+
+	*l = make(attachmentList, len(rows))
+	for i, row := range rows {
+		(*l)[i] = row.Attachment
+		(*l)[i].Key = row.Key
+		(*l)[i].ThumbnailKey = row.ThumbnailKey
+	}
+
+	return nil
+}
+
+type AttachmentStore struct {
+	db *sql.DB
+}
+
+// Create adds the attachment after the post's other ones. It returns
+// ErrTooManyAttachments when the post already has MaxAttachments.
+func (s *AttachmentStore) Create(ctx context.Context, a *Attachment) error {
+	query := `
+		INSERT INTO attachments (post_id, user_id, key, thumbnail_key, content_type, size, width, height, position)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		// Locking the post serializes concurrent uploads to it, so they
+		// can't exceed the limit together.
+		var count int
+		err := tx.QueryRowContext(ctx, `SELECT (SELECT COUNT(*) FROM attachments WHERE post_id = $1) FROM posts WHERE id = $1 FOR UPDATE`, a.PostID).Scan(&count)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		if count >= MaxAttachments {
+			return ErrTooManyAttachments
+		}
+
+		return tx.QueryRowContext(ctx, query, a.PostID, a.UserID, a.Key, a.ThumbnailKey, a.ContentType, a.Size, a.Width, a.Height, count).Scan(&a.ID, &a.CreatedAt)
+	})
+}
+
+func (s *AttachmentStore) GetByID(ctx context.Context, id int64) (*Attachment, error) {
+	query := `
+		SELECT id, post_id, user_id, key, thumbnail_key, content_type, size, width, height, created_at
+		FROM attachments WHERE id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var a Attachment
+	err := s.db.QueryRowContext(ctx, query, id).Scan(&a.ID, &a.PostID, &a.UserID, &a.Key, &a.ThumbnailKey, &a.ContentType, &a.Size, &a.Width, &a.Height, &a.CreatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &a, nil
+}
+
+func (s *AttachmentStore) Delete(ctx context.Context, id int64) error {
+	query := `DELETE FROM attachments WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+//go:build integration
+
+package store
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestAttachmentStore(t *testing.T) {
+	newAttachment := func(postID, userID int64, i int) *Attachment {
+		return &Attachment{
+			PostID:       postID,
+			UserID:       userID,
+			Key:          fmt.Sprintf("attachments/%d.jpg", i),
+			ThumbnailKey: fmt.Sprintf("attachments/%d_thumb.jpg", i),
+			ContentType:  "image/jpeg",
+			Size:         1024,
+			Width:        640,
+			Height:       480,
+		}
+	}
+
+	t.Run("should return attachments with their posts, in order", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &AttachmentStore{db: db}
+		alice := createTestUser(t, db, "alice")
+		postID := createTestPost(t, db, alice, "hi", "there", []string{}, time.Now())
+
+		for i := range MaxAttachments {
+			if err := s.Create(testContext(t), newAttachment(postID, alice, i)); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := s.Create(testContext(t), newAttachment(postID, alice, 9)); !errors.Is(err, ErrTooManyAttachments) {
+			t.Errorf("expected ErrTooManyAttachments, got %v", err)
+		}
+
+		post, err := (&PostStore{db: db}).GetByID(testContext(t), postID, alice)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(post.Attachments) != MaxAttachments {
+			t.Fatalf("expected %d attachments, got %d", MaxAttachments, len(post.Attachments))
+		}
+		for i, a := range post.Attachments {
+			if a.Key != fmt.Sprintf("attachments/%d.jpg", i) || a.ThumbnailKey == "" || a.Width != 640 || a.CreatedAt == "" {
+				t.Errorf("unexpected attachment %d: %+v", i, a)
+			}
+		}
+
+		feed, err := (&PostStore{db: db}).GetUserFeed(testContext(t), alice, PaginatedFeedQuery{Limit: 10, Sort: "desc", Tags: []string{}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(feed) != 1 || len(feed[0].Attachments) != MaxAttachments {
+			t.Errorf("expected the feed to include the attachments, got %+v", feed)
+		}
+	})
+
+	t.Run("should get and delete attachments", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &AttachmentStore{db: db}
+		alice := createTestUser(t, db, "alice")
+		postID := createTestPost(t, db, alice, "hi", "there", []string{}, time.Now())
+
+		a := newAttachment(postID, alice, 1)
+		if err := s.Create(testContext(t), a); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := s.GetByID(testContext(t), a.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.PostID != postID || got.Key != a.Key || got.ThumbnailKey != a.ThumbnailKey {
+			t.Errorf("unexpected attachment %+v", got)
+		}
+
+		if err := s.Delete(testContext(t), a.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.GetByID(testContext(t), a.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+		if err := s.Delete(testContext(t), a.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("should go with their post", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &AttachmentStore{db: db}
+		alice := createTestUser(t, db, "alice")
+		postID := createTestPost(t, db, alice, "hi", "there", []string{}, time.Now())
+
+		if err := s.Create(testContext(t), newAttachment(postID, alice, 1)); err != nil {
+			t.Fatal(err)
+		}
+		if err := (&PostStore{db: db}).Delete(testContext(t), postID); err != nil {
+			t.Fatal(err)
+		}
+		if n := count(t, db, "attachments", "post_id = $1", postID); n != 0 {
+			t.Errorf("expected the attachments to be deleted, got %d", n)
+		}
+
+		if err := s.Create(testContext(t), newAttachment(postID, alice, 2)); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound for a missing post, got %v", err)
+		}
+	})
+}
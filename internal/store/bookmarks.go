@@ -0,0 +1,123 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	"github.com/lib/pq"
+)
+
+type BookmarkQuery struct {
+	Limit  int `json:"limit" validate:"gte=1,lte=50"`
+	Offset int `json:"offset" validate:"gte=0"`
+}
+
+func (q BookmarkQuery) Parse(r *http.Request) (BookmarkQuery, error) {
+	qs := r.URL.Query()
+
+	if limit := qs.Get("limit"); limit != "" {
+		l, err := ParseIntParam("limit", limit)
+		if err != nil {
+			return q, err
+		}
+		q.Limit = l
+	}
+
+	if offset := qs.Get("offset"); offset != "" {
+		o, err := ParseIntParam("offset", offset)
+		if err != nil {
+			return q, err
+		}
+		q.Offset = o
+	}
+
+	return q, nil
+}
+
+type BookmarkStore struct {
+	db *sql.DB
+}
+
+// Add bookmarks the post for userID. Bookmarking a post again keeps it where
+// it was in their list.
+func (s *BookmarkStore) Add(ctx context.Context, userID, postID int64) error {
+	query := `
+		INSERT INTO bookmarks (user_id, post_id)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id, post_id) DO NOTHING
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, userID, postID)
+	return err
+}
+
+func (s *BookmarkStore) Delete(ctx context.Context, userID, postID int64) error {
+	query := `DELETE FROM bookmarks WHERE user_id = $1 AND post_id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, userID, postID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// List returns a page of the posts userID bookmarked, the latest bookmark
+// first. Posts they can no longer see are left out.
+func (s *BookmarkStore) List(ctx context.Context, userID int64, q BookmarkQuery) ([]PostWithMetadata, error) {
+	query := `
+		SELECT p.id, p.title, p.content, p.user_id, p.tags, p.created_at, p.updated_at, p.version, p.status, p.visibility,
+		u.username,
+		(SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id) AS comment_count,
+		(SELECT COUNT(*) FROM reactions r WHERE r.post_id = p.id) AS reaction_count,
+		(SELECT COUNT(*) FROM reposts rc WHERE rc.post_id = p.id) AS repost_count,
+		` + attachmentsColumn + `
+		FROM bookmarks b
+		JOIN posts p ON p.id = b.post_id
+		JOIN users u ON u.id = p.user_id
+		WHERE b.user_id = $1 AND ` + visibleTo("$1") + `
+		ORDER BY b.created_at DESC, p.id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID, q.Limit, q.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []PostWithMetadata{}
+	for rows.Next() {
+		var p PostWithMetadata
+		if err := rows.Scan(&p.ID, &p.Title, &p.Content, &p.UserID, pq.Array(&p.Tags), &p.CreatedAt, &p.UpdatedAt, &p.Version, &p.Status, &p.Visibility, &p.User.Username, &p.CommentCount, &p.ReactionCount, &p.RepostCount, (*attachmentList)(&p.Attachments)); err != nil {
+			return nil, err
+		}
+		p.User.ID = p.UserID
+		posts = append(posts, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
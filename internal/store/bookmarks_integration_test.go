@@ -0,0 +1,47 @@
+//go:build integration
+
+package store
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBookmarkStore(t *testing.T) {
+	db := newTestDB(t)
+	s := &BookmarkStore{db: db}
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+	first := createTestPost(t, db, alice, "first", "post", []string{}, time.Now())
+	second := createTestPost(t, db, alice, "second", "post", []string{}, time.Now())
+	private := createTestPost(t, db, alice, "private", "post", []string{}, time.Now())
+
+	for _, postID := range []int64{first, second, private, first} {
+		if err := s.Add(testContext(t), bob, postID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// NOW() is frozen for the whole test transaction.
+	if _, err := db.Exec(`UPDATE bookmarks SET created_at = created_at + interval '1 minute' WHERE post_id = $1`, second); err != nil {
+		t.Fatal(err)
+	}
+	setPostState(t, db, private, PostPublished, VisibilityPrivate)
+
+	bookmarks, err := s.List(testContext(t), bob, BookmarkQuery{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFeed(t, bookmarks, second, first)
+
+	if bookmarks, _ := s.List(testContext(t), alice, BookmarkQuery{Limit: 10}); len(bookmarks) != 0 {
+		t.Errorf("expected bookmarks to be private, got %v", bookmarks)
+	}
+
+	if err := s.Delete(testContext(t), bob, first); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(testContext(t), bob, first); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
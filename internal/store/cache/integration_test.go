@@ -0,0 +1,46 @@
+//go:build integration
+
+package cache
+
+import (
+	"errors"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/kuluruvineeth/social-go/internal/storetest"
+	"github.com/redis/go-redis/v9"
+)
+
+// testRedis is nil when no Redis is available.
+var testRedis *storetest.Redis
+
+func TestMain(m *testing.M) {
+	r, err := storetest.StartRedis()
+	if errors.Is(err, storetest.ErrUnavailable) {
+		log.Println("no Redis available, skipping cache integration tests")
+		os.Exit(m.Run())
+	}
+	if err != nil {
+		log.Fatalf("starting Redis: %v", err)
+	}
+
+	testRedis = r
+	code := m.Run()
+
+	if err := r.Stop(); err != nil {
+		log.Printf("stopping Redis: %v", err)
+	}
+
+	os.Exit(code)
+}
+
+func newTestRedis(t *testing.T) *redis.Client {
+	t.Helper()
+
+	if testRedis == nil {
+		t.Skip("no Redis available")
+	}
+
+	return testRedis.Client(t)
+}
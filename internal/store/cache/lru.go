@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/kuluruvineeth/social-go/internal/store"
+)
+
+// LRUUserStore is a bounded in-process user cache. Entries are evicted in
+// least-recently-used order once capacity is reached, and expire after ttl.
+type LRUUserStore struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	ll       *list.List
+	items    map[int64]*list.Element
+	now      func() time.Time
+}
+
+type lruEntry struct {
+	user      store.User
+	expiresAt time.Time
+}
+
+func NewLRUUserStore(capacity int, ttl time.Duration) *LRUUserStore {
+	if capacity <= 0 {
+		capacity = 1
+	}
+
+	return &LRUUserStore{
+		capacity: capacity,
+		ttl:      ttl,
+		ll:       list.New(),
+		items:    make(map[int64]*list.Element),
+		now:      time.Now,
+	}
+}
+
+func (s *LRUUserStore) Get(ctx context.Context, id int64) (*store.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.items[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+
+	entry := el.Value.(*lruEntry)
+	if s.now().After(entry.expiresAt) {
+		s.removeElement(el)
+		return nil, store.ErrNotFound
+	}
+
+	s.ll.MoveToFront(el)
+
+	// hand out a copy so callers can't mutate the cached value
+	user := entry.user
+	return &user, nil
+}
+
+func (s *LRUUserStore) Set(ctx context.Context, user *store.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expiresAt := s.now().Add(s.ttl)
+
+	if el, ok := s.items[user.ID]; ok {
+		entry := el.Value.(*lruEntry)
+		entry.user = *user
+		entry.expiresAt = expiresAt
+		s.ll.MoveToFront(el)
+		return nil
+	}
+
+	el := s.ll.PushFront(&lruEntry{user: *user, expiresAt: expiresAt})
+	s.items[user.ID] = el
+
+	if s.ll.Len() > s.capacity {
+		s.removeElement(s.ll.Back())
+	}
+
+	return nil
+}
+
+func (s *LRUUserStore) Delete(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.items[id]; ok {
+		s.removeElement(el)
+	}
+
+	return nil
+}
+
+func (s *LRUUserStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.ll.Len()
+}
+
+func (s *LRUUserStore) removeElement(el *list.Element) {
+	s.ll.Remove(el)
+	delete(s.items, el.Value.(*lruEntry).user.ID)
+}
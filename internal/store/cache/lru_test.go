@@ -0,0 +1,103 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kuluruvineeth/social-go/internal/store"
+)
+
+func TestLRUUserStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should return not found on a miss", func(t *testing.T) {
+		s := NewLRUUserStore(2, time.Minute)
+
+		if _, err := s.Get(ctx, 1); err != store.ErrNotFound {
+			t.Fatalf("expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("should evict the least recently used user", func(t *testing.T) {
+		s := NewLRUUserStore(2, time.Minute)
+
+		s.Set(ctx, &store.User{ID: 1})
+		s.Set(ctx, &store.User{ID: 2})
+
+		// touch 1 so that 2 becomes the eviction candidate
+		if _, err := s.Get(ctx, 1); err != nil {
+			t.Fatal(err)
+		}
+
+		s.Set(ctx, &store.User{ID: 3})
+
+		if s.Len() != 2 {
+			t.Fatalf("expected 2 entries, got %d", s.Len())
+		}
+		if _, err := s.Get(ctx, 2); err != store.ErrNotFound {
+			t.Errorf("expected user 2 to be evicted")
+		}
+		if _, err := s.Get(ctx, 1); err != nil {
+			t.Errorf("expected user 1 to be cached, got %v", err)
+		}
+	})
+
+	t.Run("should expire entries after the ttl", func(t *testing.T) {
+		s := NewLRUUserStore(2, time.Minute)
+		now := time.Now()
+		s.now = func() time.Time { return now }
+
+		s.Set(ctx, &store.User{ID: 1, Username: "alice"})
+
+		now = now.Add(30 * time.Second)
+		if _, err := s.Get(ctx, 1); err != nil {
+			t.Fatalf("expected hit before ttl, got %v", err)
+		}
+
+		now = now.Add(time.Minute)
+		if _, err := s.Get(ctx, 1); err != store.ErrNotFound {
+			t.Fatalf("expected ErrNotFound after ttl, got %v", err)
+		}
+		if s.Len() != 0 {
+			t.Errorf("expected expired entry to be removed")
+		}
+	})
+
+	t.Run("should not share the cached value with callers", func(t *testing.T) {
+		s := NewLRUUserStore(2, time.Minute)
+		s.Set(ctx, &store.User{ID: 1, Username: "alice"})
+
+		user, _ := s.Get(ctx, 1)
+		user.Username = "mallory"
+
+		user, _ = s.Get(ctx, 1)
+		if user.Username != "alice" {
+			t.Errorf("expected cached username alice, got %s", user.Username)
+		}
+	})
+
+	t.Run("should delete entries", func(t *testing.T) {
+		s := NewLRUUserStore(2, time.Minute)
+		s.Set(ctx, &store.User{ID: 1})
+		s.Delete(ctx, 1)
+
+		if _, err := s.Get(ctx, 1); err != store.ErrNotFound {
+			t.Fatalf("expected ErrNotFound after delete, got %v", err)
+		}
+	})
+}
+
+func TestParseInvalidation(t *testing.T) {
+	origin, id, err := parseInvalidation("instance-a:42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if origin != "instance-a" || id != 42 {
+		t.Errorf("unexpected result %q %d", origin, id)
+	}
+
+	if _, _, err := parseInvalidation("garbage"); err == nil {
+		t.Error("expected error for payload without separator")
+	}
+}
@@ -26,3 +26,8 @@ func (m *MockUserStore) Set(ctx context.Context, user *store.User) error {
 	args := m.Called(user)
 	return args.Error(0)
 }
+
+func (m *MockUserStore) Delete(ctx context.Context, userID int64) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
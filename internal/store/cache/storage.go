@@ -2,15 +2,18 @@ package cache
 
 import (
 	"context"
+	"time"
 
 	"github.com/kuluruvineeth/social-go/internal/store"
 	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
 )
 
 type Storage struct {
 	Users interface {
 		Get(context.Context, int64) (*store.User, error)
 		Set(context.Context, *store.User) error
+		Delete(context.Context, int64) error
 	}
 }
 
@@ -19,3 +22,19 @@ func NewRedisStorage(rdb *redis.Client) Storage {
 		Users: &UserStore{rdb: rdb},
 	}
 }
+
+func NewLRUStorage(capacity int, ttl time.Duration) Storage {
+	return Storage{
+		Users: NewLRUUserStore(capacity, ttl),
+	}
+}
+
+// NewTieredStorage layers an in-process LRU in front of Redis. The returned
+// store must have Listen running for cross-instance invalidation to work.
+func NewTieredStorage(rdb *redis.Client, capacity int, ttl time.Duration, logger *zap.SugaredLogger) (Storage, *TieredUserStore) {
+	users := NewTieredUserStore(rdb, NewLRUUserStore(capacity, ttl), logger)
+
+	return Storage{
+		Users: users,
+	}, users
+}
@@ -0,0 +1,127 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/kuluruvineeth/social-go/internal/store"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const UserInvalidationChannel = "cache:users:invalidate"
+
+// TieredUserStore keeps an in-process LRU (L1) in front of Redis (L2).
+// Writes and deletes are broadcast over Redis pub/sub so that other
+// instances drop their local copy of the user.
+type TieredUserStore struct {
+	l1         *LRUUserStore
+	l2         *UserStore
+	rdb        *redis.Client
+	logger     *zap.SugaredLogger
+	instanceID string
+}
+
+func NewTieredUserStore(rdb *redis.Client, l1 *LRUUserStore, logger *zap.SugaredLogger) *TieredUserStore {
+	return &TieredUserStore{
+		l1:         l1,
+		l2:         &UserStore{rdb: rdb},
+		rdb:        rdb,
+		logger:     logger,
+		instanceID: uuid.New().String(),
+	}
+}
+
+func (s *TieredUserStore) Get(ctx context.Context, id int64) (*store.User, error) {
+	if user, err := s.l1.Get(ctx, id); err == nil {
+		return user, nil
+	}
+
+	user, err := s.l2.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.l1.Set(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *TieredUserStore) Set(ctx context.Context, user *store.User) error {
+	if err := s.l2.Set(ctx, user); err != nil {
+		return err
+	}
+
+	if err := s.l1.Set(ctx, user); err != nil {
+		return err
+	}
+
+	return s.publish(ctx, user.ID)
+}
+
+func (s *TieredUserStore) Delete(ctx context.Context, id int64) error {
+	if err := s.l2.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	if err := s.l1.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	return s.publish(ctx, id)
+}
+
+// Listen evicts users from L1 when another instance publishes an
+// invalidation. It blocks until ctx is cancelled.
+func (s *TieredUserStore) Listen(ctx context.Context) {
+	sub := s.rdb.Subscribe(ctx, UserInvalidationChannel)
+	defer sub.Close()
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+
+			origin, id, err := parseInvalidation(msg.Payload)
+			if err != nil {
+				s.logger.Warnw("invalid cache invalidation message", "payload", msg.Payload, "error", err)
+				continue
+			}
+
+			if origin == s.instanceID {
+				continue
+			}
+
+			s.l1.Delete(ctx, id)
+		}
+	}
+}
+
+func (s *TieredUserStore) publish(ctx context.Context, id int64) error {
+	payload := fmt.Sprintf("%s:%d", s.instanceID, id)
+	return s.rdb.Publish(ctx, UserInvalidationChannel, payload).Err()
+}
+
+func parseInvalidation(payload string) (string, int64, error) {
+	origin, rawID, ok := strings.Cut(payload, ":")
+	if !ok {
+		return "", 0, fmt.Errorf("missing separator")
+	}
+
+	id, err := strconv.ParseInt(rawID, 10, 64)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return origin, id, nil
+}
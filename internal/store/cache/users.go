@@ -45,3 +45,9 @@ func (s *UserStore) Set(ctx context.Context, user *store.User) error {
 	}
 	return s.rdb.SetEx(ctx, cacheKey, jsonData, UserExpTime).Err()
 }
+
+func (s *UserStore) Delete(ctx context.Context, id int64) error {
+	cacheKey := fmt.Sprintf("user:%v", id)
+
+	return s.rdb.Del(ctx, cacheKey).Err()
+}
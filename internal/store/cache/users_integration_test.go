@@ -0,0 +1,109 @@
+//go:build integration
+
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kuluruvineeth/social-go/internal/store"
+	"go.uber.org/zap"
+)
+
+func TestRedisUserStore(t *testing.T) {
+	ctx := context.Background()
+	rdb := newTestRedis(t)
+	s := &UserStore{rdb: rdb}
+
+	if _, err := s.Get(ctx, 1); err != store.ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	if err := s.Set(ctx, &store.User{ID: 1, Username: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := s.Get(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Username != "alice" {
+		t.Errorf("expected alice, got %q", user.Username)
+	}
+
+	if ttl := rdb.TTL(ctx, "user:1").Val(); ttl <= 0 || ttl > UserExpTime {
+		t.Errorf("expected the user to expire within %v, got %v", UserExpTime, ttl)
+	}
+
+	if err := s.Delete(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, 1); err != store.ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestTieredUserStore(t *testing.T) {
+	rdb := newTestRedis(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a := NewTieredUserStore(rdb, NewLRUUserStore(10, time.Minute), zap.NewNop().Sugar())
+	b := NewTieredUserStore(rdb, NewLRUUserStore(10, time.Minute), zap.NewNop().Sugar())
+	go a.Listen(ctx)
+	go b.Listen(ctx)
+
+	// Give the subscriptions time to be registered.
+	waitFor(t, func() bool {
+		channels, _ := rdb.PubSubNumSub(ctx, UserInvalidationChannel).Result()
+		return channels[UserInvalidationChannel] == 2
+	})
+
+	if err := a.Set(ctx, &store.User{ID: 1, Username: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// b reads through Redis and keeps a local copy.
+	if _, err := b.Get(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if b.l1.Len() != 1 {
+		t.Fatalf("expected b to cache the user locally")
+	}
+
+	if err := a.Set(ctx, &store.User{ID: 1, Username: "alicia"}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return b.l1.Len() == 0 })
+
+	user, err := b.Get(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Username != "alicia" {
+		t.Errorf("expected the updated user, got %q", user.Username)
+	}
+
+	if err := a.Delete(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return b.l1.Len() == 0 })
+
+	if _, err := b.Get(ctx, 1); err != store.ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
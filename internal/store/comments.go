@@ -3,26 +3,33 @@ package store
 import (
 	"context"
 	"database/sql"
+
+	"github.com/kuluruvineeth/social-go/internal/content"
 )
 
 type Comment struct {
-	ID        int64  `json:"id"`
-	PostID    int64  `json:"post_id"`
-	UserID    int64  `json:"user_id"`
-	Content   string `json:"content"`
-	CreatedAt string `json:"created_at"`
-	User      User   `json:"user"`
+	ID        int64     `json:"id"`
+	PostID    int64     `json:"post_id"`
+	UserID    int64     `json:"user_id"`
+	Content   string    `json:"content"`
+	CreatedAt string    `json:"created_at"`
+	Tags      []string  `json:"tags,omitempty"`
+	Mentions  []Mention `json:"mentions,omitempty"`
+	User      User      `json:"user"`
 }
 
 type CommentStore struct {
 	db *sql.DB
 }
 
+// GetByPostID returns the comments on the post, leaving out the ones hidden
+// by moderators. Comments whose author deleted their account have a zero
+// UserID and no username.
 func (s *CommentStore) GetByPostID(ctx context.Context, postID int64) ([]Comment, error) {
 	query := `
-		SELECT c.id, c.post_id, c.user_id, c.content, c.created_at, users.username, users.id FROM comments c
-		JOIN users ON c.user_id = users.id
-		WHERE c.post_id = $1
+		SELECT c.id, c.post_id, COALESCE(c.user_id, 0), c.content, c.created_at, COALESCE(users.username, ''), COALESCE(users.id, 0) FROM comments c
+		LEFT JOIN users ON c.user_id = users.id
+		WHERE c.post_id = $1 AND c.hidden_at IS NULL
 		ORDER BY c.created_at DESC
 	`
 
@@ -48,6 +55,8 @@ func (s *CommentStore) GetByPostID(ctx context.Context, postID int64) ([]Comment
 	return comments, nil
 }
 
+// Create stores the comment along with the hashtags it uses and the users it
+// mentions, which are parsed from its content.
 func (s *CommentStore) Create(ctx context.Context, cmt *Comment) error {
 	query := `
 		INSERT INTO comments (post_id, user_id, content)
@@ -58,9 +67,24 @@ func (s *CommentStore) Create(ctx context.Context, cmt *Comment) error {
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	if err := s.db.QueryRowContext(ctx, query, cmt.PostID, cmt.UserID, cmt.Content).Scan(&cmt.ID, &cmt.CreatedAt); err != nil {
-		return err
-	}
+	entities := content.Parse(cmt.Content)
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		if err := tx.QueryRowContext(ctx, query, cmt.PostID, cmt.UserID, cmt.Content).Scan(&cmt.ID, &cmt.CreatedAt); err != nil {
+			return err
+		}
+
+		if err := setTagUsages(ctx, tx, cmt.PostID, &cmt.ID, cmt.UserID, entities.Hashtags); err != nil {
+			return err
+		}
+
+		mentions, err := setMentions(ctx, tx, cmt.PostID, &cmt.ID, entities.Mentions)
+		if err != nil {
+			return err
+		}
 
-	return nil
+		cmt.Tags = entities.Hashtags
+		cmt.Mentions = mentions
+		return nil
+	})
 }
@@ -0,0 +1,70 @@
+//go:build integration
+
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCommentStore(t *testing.T) {
+	t.Run("should create comments with their tags and mentions", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &CommentStore{db: db}
+		author := createTestUser(t, db, "author")
+		bob := createTestUser(t, db, "bob")
+		postID := createTestPost(t, db, author, "hi", "there", []string{}, time.Now())
+
+		cmt := &Comment{PostID: postID, UserID: author, Content: "#Go is great, right @bob?"}
+		if err := s.Create(testContext(t), cmt); err != nil {
+			t.Fatal(err)
+		}
+
+		if cmt.ID == 0 || cmt.CreatedAt == "" {
+			t.Fatalf("expected the id and creation time to be set, got %+v", cmt)
+		}
+		if len(cmt.Tags) != 1 || cmt.Tags[0] != "go" {
+			t.Errorf("expected the go tag, got %v", cmt.Tags)
+		}
+		if len(cmt.Mentions) != 1 || cmt.Mentions[0].UserID != bob {
+			t.Errorf("expected bob to be mentioned, got %v", cmt.Mentions)
+		}
+		if n := count(t, db, "tag_usages", "post_id = $1 AND comment_id = $2 AND tag = 'go'", postID, cmt.ID); n != 1 {
+			t.Errorf("expected 1 tag usage, got %d", n)
+		}
+		if n := count(t, db, "mentions", "post_id = $1 AND comment_id = $2", postID, cmt.ID); n != 1 {
+			t.Errorf("expected 1 mention, got %d", n)
+		}
+	})
+
+	t.Run("should list a post's comments with their authors", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &CommentStore{db: db}
+		author := createTestUser(t, db, "author")
+		bob := createTestUser(t, db, "bob")
+		postID := createTestPost(t, db, author, "hi", "there", []string{}, time.Now())
+		otherID := createTestPost(t, db, author, "other", "post", []string{}, time.Now())
+
+		createTestComment(t, db, postID, bob, "first")
+		createTestComment(t, db, otherID, bob, "elsewhere")
+
+		comments, err := s.GetByPostID(testContext(t), postID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(comments) != 1 || comments[0].Content != "first" {
+			t.Fatalf("expected the post's comment, got %+v", comments)
+		}
+		if comments[0].User.ID != bob || comments[0].User.Username != "bob" {
+			t.Errorf("expected bob as the author, got %+v", comments[0].User)
+		}
+
+		comments, err = s.GetByPostID(testContext(t), -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if comments == nil || len(comments) != 0 {
+			t.Errorf("expected no comments, got %v", comments)
+		}
+	})
+}
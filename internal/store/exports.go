@@ -0,0 +1,279 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const (
+	ExportStatusPending = "pending"
+	ExportStatusReady   = "ready"
+	ExportStatusFailed  = "failed"
+)
+
+// Export is an archive of a user's data, built in the background. Ready
+// archives are kept in the blob store under Key until they expire.
+type Export struct {
+	ID          int64   `json:"id"`
+	UserID      int64   `json:"user_id"`
+	Status      string  `json:"status"`
+	Key         string  `json:"-"`
+	Size        int64   `json:"size"`
+	Attempts    int     `json:"-"`
+	CreatedAt   string  `json:"created_at"`
+	CompletedAt *string `json:"completed_at"`
+	ExpiresAt   *string `json:"expires_at"`
+}
+
+type ExportStore struct {
+	db *sql.DB
+}
+
+const exportColumns = `id, user_id, status, key, size, attempts, created_at, completed_at, expires_at`
+
+func exportFields(e *Export) []any {
+	return []any{&e.ID, &e.UserID, &e.Status, &e.Key, &e.Size, &e.Attempts, &e.CreatedAt, &e.CompletedAt, &e.ExpiresAt}
+}
+
+// Request returns the user's export in progress or the latest ready one,
+// and queues a new export when there is neither.
+func (s *ExportStore) Request(ctx context.Context, userID int64) (*Export, error) {
+	query := `
+		SELECT ` + exportColumns + ` FROM user_exports
+		WHERE user_id = $1 AND (status = 'pending' OR (status = 'ready' AND expires_at > NOW()))
+		ORDER BY id DESC
+		LIMIT 1
+	`
+
+	insert := `
+		INSERT INTO user_exports (user_id) VALUES ($1)
+		ON CONFLICT (user_id) WHERE status = 'pending' DO NOTHING
+		RETURNING ` + exportColumns
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var e Export
+	err := s.db.QueryRowContext(ctx, query, userID).Scan(exportFields(&e)...)
+	if !errors.Is(err, sql.ErrNoRows) {
+		if err != nil {
+			return nil, err
+		}
+		return &e, nil
+	}
+
+	err = s.db.QueryRowContext(ctx, insert, userID).Scan(exportFields(&e)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Another request queued it in the meantime
+		err = s.db.QueryRowContext(ctx, query, userID).Scan(exportFields(&e)...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &e, nil
+}
+
+// ClaimDue leases up to limit pending exports, which are hidden from other
+// workers until the lease expires.
+func (s *ExportStore) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]Export, error) {
+	query := `
+		UPDATE user_exports SET attempts = attempts + 1, next_attempt_at = $2
+		WHERE id IN (
+			SELECT id FROM user_exports
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + exportColumns
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, limit, time.Now().Add(lease))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exports := []Export{}
+	for rows.Next() {
+		var e Export
+		if err := rows.Scan(exportFields(&e)...); err != nil {
+			return nil, err
+		}
+		exports = append(exports, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return exports, nil
+}
+
+// Archive returns the user's data as a JSON document: their profile, posts,
+// comments, follows, reactions, bookmarks and reposts.
+func (s *ExportStore) Archive(ctx context.Context, userID int64) ([]byte, error) {
+	query := `
+		SELECT json_build_object(
+			'generated_at', NOW(),
+			'profile', json_build_object(
+				'id', u.id, 'username', u.username, 'email', u.email,
+				'display_name', u.display_name, 'bio', u.bio, 'avatar_url', u.avatar_url,
+				'website', u.website, 'location', u.location, 'locale', u.locale,
+				'role', r.name, 'created_at', u.created_at
+			),
+			'posts', COALESCE((
+				SELECT json_agg(json_build_object(
+					'id', p.id, 'title', p.title, 'content', p.content, 'tags', p.tags,
+					'status', p.status, 'visibility', p.visibility, 'publish_at', p.publish_at,
+					'created_at', p.created_at, 'updated_at', p.updated_at,
+					'attachments', COALESCE((
+						SELECT json_agg(json_build_object(
+							'id', a.id, 'key', a.key, 'content_type', a.content_type,
+							'size', a.size, 'width', a.width, 'height', a.height
+						) ORDER BY a.position, a.id)
+						FROM attachments a WHERE a.post_id = p.id
+					), '[]')
+				) ORDER BY p.id)
+				FROM posts p WHERE p.user_id = u.id
+			), '[]'),
+			'comments', COALESCE((
+				SELECT json_agg(json_build_object(
+					'id', c.id, 'post_id', c.post_id, 'content', c.content, 'created_at', c.created_at
+				) ORDER BY c.id)
+				FROM comments c WHERE c.user_id = u.id
+			), '[]'),
+			'following', COALESCE((
+				SELECT json_agg(json_build_object(
+					'user_id', fu.id, 'username', fu.username, 'created_at', f.created_at
+				) ORDER BY f.created_at, fu.id)
+				FROM followers f JOIN users fu ON fu.id = f.user_id
+				WHERE f.follower_id = u.id
+			), '[]'),
+			'followers', COALESCE((
+				SELECT json_agg(json_build_object(
+					'user_id', fu.id, 'username', fu.username, 'created_at', f.created_at
+				) ORDER BY f.created_at, fu.id)
+				FROM followers f JOIN users fu ON fu.id = f.follower_id
+				WHERE f.user_id = u.id
+			), '[]'),
+			'reactions', COALESCE((
+				SELECT json_agg(json_build_object(
+					'post_id', re.post_id, 'type', re.type, 'created_at', re.created_at
+				) ORDER BY re.created_at, re.post_id)
+				FROM reactions re WHERE re.user_id = u.id
+			), '[]'),
+			'bookmarks', COALESCE((
+				SELECT json_agg(json_build_object(
+					'post_id', b.post_id, 'created_at', b.created_at
+				) ORDER BY b.created_at, b.post_id)
+				FROM bookmarks b WHERE b.user_id = u.id
+			), '[]'),
+			'reposts', COALESCE((
+				SELECT json_agg(json_build_object(
+					'post_id', rp.post_id, 'quote', rp.quote, 'created_at', rp.created_at
+				) ORDER BY rp.id)
+				FROM reposts rp WHERE rp.user_id = u.id
+			), '[]')
+		)
+		FROM users u JOIN roles r ON r.id = u.role_id
+		WHERE u.id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var archive []byte
+	err := s.db.QueryRowContext(ctx, query, userID).Scan(&archive)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return archive, nil
+}
+
+// MarkReady records where the export's archive was stored, which is kept
+// for ttl.
+func (s *ExportStore) MarkReady(ctx context.Context, id int64, key string, size int64, ttl time.Duration) error {
+	query := `
+		UPDATE user_exports
+		SET status = 'ready', key = $2, size = $3, last_error = NULL,
+		completed_at = NOW(), expires_at = NOW() + make_interval(secs => $4)
+		WHERE id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, id, key, size, ttl.Seconds())
+	return err
+}
+
+// MarkFailed records a failed attempt at the export. Exports that are given
+// up on expire right away, so the next request queues a new one.
+func (s *ExportStore) MarkFailed(ctx context.Context, id int64, lastErr string, nextAttemptAt time.Time, dead bool) error {
+	query := `
+		UPDATE user_exports
+		SET status = CASE WHEN $4 THEN 'failed' ELSE 'pending' END, last_error = $2, next_attempt_at = $3,
+		completed_at = CASE WHEN $4 THEN NOW() END, expires_at = CASE WHEN $4 THEN NOW() END
+		WHERE id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, id, lastErr, nextAttemptAt, dead)
+	return err
+}
+
+// DeleteExpired deletes up to limit expired exports and returns the blob
+// keys of their archives.
+func (s *ExportStore) DeleteExpired(ctx context.Context, limit int) ([]string, error) {
+	query := `
+		DELETE FROM user_exports
+		WHERE id IN (
+			SELECT id FROM user_exports
+			WHERE expires_at <= NOW()
+			ORDER BY expires_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING key
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []string{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,163 @@
+//go:build integration
+
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestExportStore(t *testing.T) {
+	t.Run("should queue a single export at a time", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &ExportStore{db: db}
+		alice := createTestUser(t, db, "alice")
+
+		first, err := s.Request(testContext(t), alice)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if first.Status != ExportStatusPending {
+			t.Errorf("expected a pending export, got %q", first.Status)
+		}
+
+		second, err := s.Request(testContext(t), alice)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if second.ID != first.ID {
+			t.Errorf("expected export %d again, got %d", first.ID, second.ID)
+		}
+	})
+
+	t.Run("should return the ready export until it expires", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &ExportStore{db: db}
+		alice := createTestUser(t, db, "alice")
+
+		e, err := s.Request(testContext(t), alice)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		claimed, err := s.ClaimDue(testContext(t), 10, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(claimed) != 1 || claimed[0].ID != e.ID || claimed[0].Attempts != 1 {
+			t.Fatalf("expected export %d to be claimed, got %+v", e.ID, claimed)
+		}
+
+		if err := s.MarkReady(testContext(t), e.ID, "exports/a.json", 42, time.Hour); err != nil {
+			t.Fatal(err)
+		}
+
+		ready, err := s.Request(testContext(t), alice)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ready.ID != e.ID || ready.Status != ExportStatusReady || ready.Key != "exports/a.json" || ready.ExpiresAt == nil {
+			t.Errorf("expected the ready export, got %+v", ready)
+		}
+
+		if _, err := db.Exec(`UPDATE user_exports SET expires_at = NOW() - interval '1 minute' WHERE id = $1`, e.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		keys, err := s.DeleteExpired(testContext(t), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(keys) != 1 || keys[0] != "exports/a.json" {
+			t.Errorf("expected the archive's key, got %v", keys)
+		}
+
+		next, err := s.Request(testContext(t), alice)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next.ID == e.ID || next.Status != ExportStatusPending {
+			t.Errorf("expected a new export, got %+v", next)
+		}
+	})
+
+	t.Run("should queue a new export after one failed", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &ExportStore{db: db}
+		alice := createTestUser(t, db, "alice")
+
+		e, err := s.Request(testContext(t), alice)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := s.MarkFailed(testContext(t), e.ID, "timeout", time.Now(), true); err != nil {
+			t.Fatal(err)
+		}
+
+		next, err := s.Request(testContext(t), alice)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next.ID == e.ID || next.Status != ExportStatusPending {
+			t.Errorf("expected a new export, got %+v", next)
+		}
+	})
+
+	t.Run("should archive the user's data", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &ExportStore{db: db}
+		alice := createTestUser(t, db, "alice")
+		bob := createTestUser(t, db, "bob")
+
+		createTestFollow(t, db, alice, bob)
+		post := createTestPost(t, db, alice, "Hello", "world", []string{"go"}, time.Now())
+		bobsPost := createTestPost(t, db, bob, "Hi", "there", nil, time.Now())
+		createTestComment(t, db, bobsPost, alice, "nice")
+		createTestComment(t, db, post, bob, "thanks")
+		createTestReaction(t, db, bobsPost, alice)
+
+		data, err := s.Archive(testContext(t), alice)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var archive struct {
+			Profile struct {
+				Username string `json:"username"`
+				Email    string `json:"email"`
+			} `json:"profile"`
+			Posts     []struct{ ID int64 }        `json:"posts"`
+			Comments  []struct{ Content string }  `json:"comments"`
+			Following []struct{ Username string } `json:"following"`
+			Followers []struct{ Username string } `json:"followers"`
+			Reactions []struct{ PostID int64 }    `json:"reactions"`
+			Bookmarks []struct{ PostID int64 }    `json:"bookmarks"`
+		}
+		if err := json.Unmarshal(data, &archive); err != nil {
+			t.Fatal(err)
+		}
+
+		if archive.Profile.Username != "alice" || archive.Profile.Email != "alice@example.com" {
+			t.Errorf("unexpected profile %+v", archive.Profile)
+		}
+		if len(archive.Posts) != 1 || archive.Posts[0].ID != post {
+			t.Errorf("expected alice's post, got %+v", archive.Posts)
+		}
+		if len(archive.Comments) != 1 || archive.Comments[0].Content != "nice" {
+			t.Errorf("expected alice's comment, got %+v", archive.Comments)
+		}
+		if len(archive.Following) != 1 || archive.Following[0].Username != "bob" || len(archive.Followers) != 0 {
+			t.Errorf("expected alice to follow bob, got %+v and %+v", archive.Following, archive.Followers)
+		}
+		if len(archive.Reactions) != 1 || archive.Bookmarks == nil {
+			t.Errorf("expected one reaction and no bookmarks, got %+v and %+v", archive.Reactions, archive.Bookmarks)
+		}
+
+		if _, err := s.Archive(testContext(t), 0); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+}
@@ -17,6 +17,8 @@ type FollowerStore struct {
 	db *sql.DB
 }
 
+// Follow records the follow and queues the backfill of the followed user's
+// posts into the follower's timeline.
 func (s *FollowerStore) Follow(ctx context.Context, followerID, userID int64) error {
 	query := `
 		INSERT INTO followers (follower_id, user_id)
@@ -26,18 +28,26 @@ func (s *FollowerStore) Follow(ctx context.Context, followerID, userID int64) er
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	_, err := s.db.ExecContext(ctx, query, followerID, userID)
-	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
-			return ErrConflict
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, query, followerID, userID)
+		if err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+				return ErrConflict
+			}
+
+			return err
 		}
 
-		return err
-	}
+		if err := updateFollowersCount(ctx, tx, userID, 1); err != nil {
+			return err
+		}
 
-	return nil
+		return enqueueTimelineJob(ctx, tx, TimelineJobFollow, followerID, userID)
+	})
 }
 
+// Unfollow removes the follow, if any, and queues the removal of the
+// unfollowed user's posts from the follower's timeline.
 func (s *FollowerStore) Unfollow(ctx context.Context, followerID, userID int64) error {
 	query := `
 		DELETE FROM followers
@@ -47,10 +57,86 @@ func (s *FollowerStore) Unfollow(ctx context.Context, followerID, userID int64)
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	_, err := s.db.ExecContext(ctx, query, followerID, userID)
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, query, followerID, userID)
+		if err != nil {
+			return err
+		}
+
+		deleted, err := result.RowsAffected()
+		if err != nil || deleted == 0 {
+			return err
+		}
+
+		if err := updateFollowersCount(ctx, tx, userID, -1); err != nil {
+			return err
+		}
+
+		return enqueueTimelineJob(ctx, tx, TimelineJobUnfollow, followerID, userID)
+	})
+}
+
+func updateFollowersCount(ctx context.Context, tx *sql.Tx, userID int64, delta int) error {
+	query := `UPDATE users SET followers_count = followers_count + $2 WHERE id = $1`
+
+	_, err := tx.ExecContext(ctx, query, userID, delta)
+	return err
+}
+
+// GetFollowing returns the IDs of the users followerID follows.
+func (s *FollowerStore) GetFollowing(ctx context.Context, followerID int64) ([]int64, error) {
+	query := `SELECT user_id FROM followers WHERE follower_id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, followerID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// FollowersAmong returns the IDs in ids of the users who follow userID.
+func (s *FollowerStore) FollowersAmong(ctx context.Context, userID int64, ids []int64) ([]int64, error) {
+	query := `SELECT follower_id FROM followers WHERE user_id = $1 AND follower_id = ANY($2)`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	followers := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		followers = append(followers, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	return nil
+	return followers, nil
 }
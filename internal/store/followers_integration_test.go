@@ -0,0 +1,92 @@
+//go:build integration
+
+package store
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFollowerStore(t *testing.T) {
+	t.Run("should follow once and queue a backfill", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &FollowerStore{db: db}
+		alice := createTestUser(t, db, "alice")
+		bob := createTestUser(t, db, "bob")
+
+		if err := s.Follow(testContext(t), alice, bob); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Follow(testContext(t), alice, bob); !errors.Is(err, ErrConflict) {
+			t.Errorf("expected ErrConflict, got %v", err)
+		}
+
+		if n := count(t, db, "users", "id = $1 AND followers_count = 1", bob); n != 1 {
+			t.Error("expected bob to have 1 follower")
+		}
+		if n := count(t, db, "timeline_jobs", "type = 'follow' AND user_id = $1 AND target_id = $2", alice, bob); n != 1 {
+			t.Errorf("expected 1 follow job, got %d", n)
+		}
+
+		following, err := s.GetFollowing(testContext(t), alice)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(following) != 1 || following[0] != bob {
+			t.Errorf("expected alice to follow bob, got %v", following)
+		}
+	})
+
+	t.Run("should unfollow and queue a removal", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &FollowerStore{db: db}
+		alice := createTestUser(t, db, "alice")
+		bob := createTestUser(t, db, "bob")
+
+		if err := s.Follow(testContext(t), alice, bob); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Unfollow(testContext(t), alice, bob); err != nil {
+			t.Fatal(err)
+		}
+		// Unfollowing again is a no-op.
+		if err := s.Unfollow(testContext(t), alice, bob); err != nil {
+			t.Fatal(err)
+		}
+
+		if n := count(t, db, "users", "id = $1 AND followers_count = 0", bob); n != 1 {
+			t.Error("expected bob to have no followers")
+		}
+		if n := count(t, db, "timeline_jobs", "type = 'unfollow' AND user_id = $1 AND target_id = $2", alice, bob); n != 1 {
+			t.Errorf("expected 1 unfollow job, got %d", n)
+		}
+
+		following, err := s.GetFollowing(testContext(t), alice)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(following) != 0 {
+			t.Errorf("expected alice to follow nobody, got %v", following)
+		}
+	})
+
+	t.Run("should find the followers among users", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &FollowerStore{db: db}
+		alice := createTestUser(t, db, "alice")
+		bob := createTestUser(t, db, "bob")
+		carol := createTestUser(t, db, "carol")
+
+		if err := s.Follow(testContext(t), bob, alice); err != nil {
+			t.Fatal(err)
+		}
+
+		followers, err := s.FollowersAmong(testContext(t), alice, []int64{bob, carol})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(followers) != 1 || followers[0] != bob {
+			t.Errorf("expected only bob to follow alice, got %v", followers)
+		}
+	})
+}
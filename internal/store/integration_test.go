@@ -0,0 +1,176 @@
+//go:build integration
+
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/kuluruvineeth/social-go/cmd/migrate/migrations"
+	"github.com/kuluruvineeth/social-go/internal/migrate"
+	"github.com/kuluruvineeth/social-go/internal/storetest"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// testDBAddr is a database created for the run with every migration applied.
+// Tests get their own transaction on it from newTestDB. It is empty when no
+// Postgres is available.
+var testDBAddr string
+
+func TestMain(m *testing.M) {
+	pg, err := storetest.StartPostgres()
+	if errors.Is(err, storetest.ErrUnavailable) {
+		log.Println("no Postgres available, skipping store integration tests")
+		os.Exit(m.Run())
+	}
+	if err != nil {
+		log.Fatalf("starting Postgres: %v", err)
+	}
+
+	code := runIntegration(m, pg)
+
+	if err := pg.Stop(); err != nil {
+		log.Printf("stopping Postgres: %v", err)
+	}
+
+	os.Exit(code)
+}
+
+func runIntegration(m *testing.M, pg *storetest.Postgres) int {
+	name := fmt.Sprintf("social_go_test_%d", time.Now().UnixNano())
+
+	addr, err := pg.CreateDatabase(name)
+	if err != nil {
+		log.Printf("creating test database: %v", err)
+		return 1
+	}
+	defer func() {
+		if err := pg.DropDatabase(name); err != nil {
+			log.Printf("dropping test database: %v", err)
+		}
+	}()
+
+	db, err := sql.Open("postgres", addr)
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+
+	err = migrateUp(db)
+	db.Close()
+	if err != nil {
+		log.Printf("applying migrations: %v", err)
+		return 1
+	}
+
+	testDBAddr = addr
+	return m.Run()
+}
+
+func migrateUp(db *sql.DB) error {
+	m, err := migrate.New(db, migrations.FS, zap.NewNop().Sugar())
+	if err != nil {
+		return err
+	}
+
+	return m.Up(context.Background())
+}
+
+// newTestDB returns a handle whose changes are rolled back when t ends.
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	if testDBAddr == "" {
+		t.Skip("no Postgres available")
+	}
+
+	return storetest.TxDB(t, testDBAddr)
+}
+
+func createTestUser(t *testing.T, db *sql.DB, username string) int64 {
+	t.Helper()
+
+	query := `
+		INSERT INTO users (username, email, password, role_id, is_active)
+		VALUES ($1, $1 || '@example.com', '\x00', (SELECT id FROM roles WHERE name = 'user'), true)
+		RETURNING id
+	`
+
+	var id int64
+	if err := db.QueryRow(query, username).Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+
+	return id
+}
+
+func createTestPost(t *testing.T, db *sql.DB, userID int64, title, content string, tags []string, createdAt time.Time) int64 {
+	t.Helper()
+
+	query := `
+		INSERT INTO posts (user_id, title, content, tags, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $5)
+		RETURNING id
+	`
+
+	var id int64
+	if err := db.QueryRow(query, userID, title, content, pq.Array(tags), createdAt).Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+
+	return id
+}
+
+func createTestFollow(t *testing.T, db *sql.DB, followerID, userID int64) {
+	t.Helper()
+
+	if _, err := db.Exec(`INSERT INTO followers (user_id, follower_id) VALUES ($1, $2)`, userID, followerID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func createTestComment(t *testing.T, db *sql.DB, postID, userID int64, content string) int64 {
+	t.Helper()
+
+	var id int64
+	if err := db.QueryRow(`INSERT INTO comments (post_id, user_id, content) VALUES ($1, $2, $3) RETURNING id`, postID, userID, content).Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+
+	return id
+}
+
+func createTestReaction(t *testing.T, db *sql.DB, postID, userID int64) {
+	t.Helper()
+
+	if _, err := db.Exec(`INSERT INTO reactions (post_id, user_id, type) VALUES ($1, $2, 'like')`, postID, userID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// count returns the number of rows in table matching where.
+func count(t *testing.T, db *sql.DB, table, where string, args ...any) int {
+	t.Helper()
+
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM `+table+` WHERE `+where, args...).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+
+	return n
+}
+
+func testContext(t *testing.T) context.Context {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
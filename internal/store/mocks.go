@@ -10,12 +10,30 @@ import (
 
 func NewMockStorage() Storage {
 	return Storage{
-		Users: &MockUserStore{},
+		Posts:         &MockPostStore{},
+		Comments:      &MockCommentStore{},
+		Users:         &MockUserStore{},
+		Exports:       &MockExportStore{},
+		Followers:     &MockFollowerStore{},
+		Roles:         &MockRoleStore{},
+		Notifications: &MockNotificationStore{},
+		Tags:          &MockTagStore{},
+		Search:        &MockSearchStore{},
+		Reactions:     &MockReactionStore{},
+		Bookmarks:     &MockBookmarkStore{},
+		Reposts:       &MockRepostStore{},
+		Revisions:     &MockRevisionStore{},
+		Attachments:   &MockAttachmentStore{},
+		Timelines:     &MockTimelineStore{},
+		Reports:       &MockReportStore{},
 	}
 }
 
 type MockUserStore struct {
 	mock.Mock
+	// Users are returned by GetByID, which returns any other user with
+	// only their ID set.
+	Users map[int64]*User
 }
 
 func (m *MockUserStore) Create(ctx context.Context, tx *sql.Tx, u *User) error {
@@ -23,6 +41,9 @@ func (m *MockUserStore) Create(ctx context.Context, tx *sql.Tx, u *User) error {
 }
 
 func (m *MockUserStore) GetByID(ctx context.Context, userID int64) (*User, error) {
+	if u, ok := m.Users[userID]; ok {
+		return u, nil
+	}
 	return &User{ID: userID}, nil
 }
 
@@ -30,14 +51,364 @@ func (m *MockUserStore) GetByEmail(ctx context.Context, email string) (*User, er
 	return &User{}, nil
 }
 
-func (m *MockUserStore) CreateAndInvite(ctx context.Context, user *User, token string, exp time.Duration) error {
+func (m *MockUserStore) CreateAndInvite(ctx context.Context, user *User, token string, exp time.Duration, msg *OutboxMessage) error {
+	return nil
+}
+
+func (m *MockUserStore) Activate(ctx context.Context, t string) (int64, error) {
+	args := m.Called(t)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockUserStore) UpdateProfile(ctx context.Context, u *User, usernameCooldown time.Duration, change *EmailChange) error {
+	email := ""
+	if change != nil {
+		email = change.Email
+	}
+
+	args := m.Called(u.Username, u.DisplayName, email)
+	return args.Error(0)
+}
+
+func (m *MockUserStore) ScheduleDeletion(ctx context.Context, id int64, grace time.Duration) (string, error) {
+	args := m.Called(id, grace)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockUserStore) CancelDeletion(ctx context.Context, id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockUserStore) GetDueDeletions(ctx context.Context, limit int) ([]int64, error) {
+	return []int64{}, nil
+}
+
+func (m *MockUserStore) Delete(ctx context.Context, id int64) ([]string, error) {
+	return []string{}, nil
+}
+
+type MockExportStore struct {
+	mock.Mock
+}
+
+func (m *MockExportStore) Request(ctx context.Context, userID int64) (*Export, error) {
+	args := m.Called(userID)
+	e, _ := args.Get(0).(*Export)
+	return e, args.Error(1)
+}
+
+func (m *MockExportStore) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]Export, error) {
+	return []Export{}, nil
+}
+
+func (m *MockExportStore) Archive(ctx context.Context, userID int64) ([]byte, error) {
+	return []byte(`{}`), nil
+}
+
+func (m *MockExportStore) MarkReady(ctx context.Context, id int64, key string, size int64, ttl time.Duration) error {
+	return nil
+}
+
+func (m *MockExportStore) MarkFailed(ctx context.Context, id int64, lastErr string, nextAttemptAt time.Time, dead bool) error {
+	return nil
+}
+
+func (m *MockExportStore) DeleteExpired(ctx context.Context, limit int) ([]string, error) {
+	return []string{}, nil
+}
+
+type MockNotificationStore struct {
+	mock.Mock
+}
+
+func (m *MockNotificationStore) Create(ctx context.Context, n *Notification) error {
+	return nil
+}
+
+func (m *MockNotificationStore) GetByUserID(ctx context.Context, userID int64, q NotificationQuery) ([]Notification, error) {
+	return []Notification{}, nil
+}
+
+func (m *MockNotificationStore) CountUnread(ctx context.Context, userID int64) (int, error) {
+	return 0, nil
+}
+
+func (m *MockNotificationStore) MarkRead(ctx context.Context, userID int64, ids []int64) (int64, error) {
+	args := m.Called(userID, ids)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockNotificationStore) GetPreferences(ctx context.Context, userID int64) ([]NotificationPreference, error) {
+	return []NotificationPreference{}, nil
+}
+
+func (m *MockNotificationStore) SetPreference(ctx context.Context, userID int64, pref NotificationPreference) error {
+	return nil
+}
+
+type MockFollowerStore struct {
+	mock.Mock
+}
+
+func (m *MockFollowerStore) Follow(ctx context.Context, followerID, userID int64) error {
+	return nil
+}
+
+func (m *MockFollowerStore) Unfollow(ctx context.Context, followerID, userID int64) error {
+	return nil
+}
+
+func (m *MockFollowerStore) GetFollowing(ctx context.Context, followerID int64) ([]int64, error) {
+	return []int64{}, nil
+}
+
+func (m *MockFollowerStore) FollowersAmong(ctx context.Context, userID int64, ids []int64) ([]int64, error) {
+	args := m.Called(userID, ids)
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+// MockRoleStore has the roles seeded by the migrations.
+type MockRoleStore struct {
+	mock.Mock
+}
+
+func (m *MockRoleStore) GetByName(ctx context.Context, name string) (*Role, error) {
+	levels := map[string]int{"user": 1, "moderator": 2, "admin": 3}
+
+	level, ok := levels[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return &Role{Name: name, Level: level}, nil
+}
+
+type MockTagStore struct {
+	mock.Mock
+}
+
+func (m *MockTagStore) GetPosts(ctx context.Context, tag string, fq PaginatedFeedQuery) ([]PostWithMetadata, error) {
+	return []PostWithMetadata{}, nil
+}
+
+func (m *MockTagStore) Trending(ctx context.Context, since time.Time, limit int) ([]TrendingTag, error) {
+	args := m.Called(limit)
+	return args.Get(0).([]TrendingTag), args.Error(1)
+}
+
+type MockSearchStore struct {
+	mock.Mock
+}
+
+func (m *MockSearchStore) Posts(ctx context.Context, q SearchQuery) ([]PostSearchResult, error) {
+	args := m.Called(q)
+	return args.Get(0).([]PostSearchResult), args.Error(1)
+}
+
+func (m *MockSearchStore) Comments(ctx context.Context, q SearchQuery) ([]CommentSearchResult, error) {
+	args := m.Called(q)
+	return args.Get(0).([]CommentSearchResult), args.Error(1)
+}
+
+func (m *MockSearchStore) Users(ctx context.Context, q SearchQuery) ([]UserSearchResult, error) {
+	args := m.Called(q)
+	return args.Get(0).([]UserSearchResult), args.Error(1)
+}
+
+type MockPostStore struct {
+	mock.Mock
+	// Posts are returned by GetByID, which returns any other post as a
+	// published, public post by user 1.
+	Posts map[int64]*Post
+}
+
+func (m *MockPostStore) Create(ctx context.Context, post *Post) error {
 	return nil
 }
 
-func (m *MockUserStore) Activate(ctx context.Context, t string) error {
+func (m *MockPostStore) GetByID(ctx context.Context, id, viewerID int64) (*Post, error) {
+	if p, ok := m.Posts[id]; ok {
+		post := *p
+		return &post, nil
+	}
+	return &Post{ID: id, UserID: 1, Tags: []string{}, Mentions: []Mention{}, Status: PostPublished, Visibility: VisibilityPublic}, nil
+}
+
+func (m *MockPostStore) Delete(ctx context.Context, id int64) error {
 	return nil
 }
 
-func (m *MockUserStore) Delete(ctx context.Context, id int64) error {
+func (m *MockPostStore) Update(ctx context.Context, post *Post, editorID int64) error {
+	args := m.Called(post.ID, post.Version)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+
+	post.Version++
 	return nil
 }
+
+func (m *MockPostStore) PublishDue(ctx context.Context, limit int) ([]Post, error) {
+	return []Post{}, nil
+}
+
+func (m *MockPostStore) GetUserFeed(ctx context.Context, userID int64, fq PaginatedFeedQuery) ([]PostWithMetadata, error) {
+	return []PostWithMetadata{}, nil
+}
+
+func (m *MockPostStore) GetFeedCandidates(ctx context.Context, userID int64, fq PaginatedFeedQuery, since time.Time, max int) ([]FeedCandidate, error) {
+	args := m.Called(userID, max)
+	return args.Get(0).([]FeedCandidate), args.Error(1)
+}
+
+type MockCommentStore struct {
+	mock.Mock
+}
+
+func (m *MockCommentStore) GetByPostID(ctx context.Context, postID int64) ([]Comment, error) {
+	return []Comment{}, nil
+}
+
+func (m *MockCommentStore) Create(ctx context.Context, c *Comment) error {
+	return nil
+}
+
+type MockReactionStore struct {
+	mock.Mock
+}
+
+func (m *MockReactionStore) Set(ctx context.Context, postID, userID int64, reactionType string) error {
+	args := m.Called(postID, userID, reactionType)
+	return args.Error(0)
+}
+
+func (m *MockReactionStore) Delete(ctx context.Context, postID, userID int64) error {
+	args := m.Called(postID, userID)
+	return args.Error(0)
+}
+
+type MockBookmarkStore struct {
+	mock.Mock
+}
+
+func (m *MockBookmarkStore) Add(ctx context.Context, userID, postID int64) error {
+	args := m.Called(userID, postID)
+	return args.Error(0)
+}
+
+func (m *MockBookmarkStore) Delete(ctx context.Context, userID, postID int64) error {
+	args := m.Called(userID, postID)
+	return args.Error(0)
+}
+
+func (m *MockBookmarkStore) List(ctx context.Context, userID int64, q BookmarkQuery) ([]PostWithMetadata, error) {
+	return []PostWithMetadata{}, nil
+}
+
+type MockRepostStore struct {
+	mock.Mock
+}
+
+func (m *MockRepostStore) Create(ctx context.Context, r *Repost) error {
+	args := m.Called(r.UserID, r.PostID, r.Quote)
+	return args.Error(0)
+}
+
+func (m *MockRepostStore) Delete(ctx context.Context, userID, postID int64) error {
+	args := m.Called(userID, postID)
+	return args.Error(0)
+}
+
+type MockRevisionStore struct {
+	mock.Mock
+}
+
+func (m *MockRevisionStore) GetByPostID(ctx context.Context, postID int64) ([]PostRevision, error) {
+	args := m.Called(postID)
+	return args.Get(0).([]PostRevision), args.Error(1)
+}
+
+func (m *MockRevisionStore) Get(ctx context.Context, postID int64, version int) (*PostRevision, error) {
+	args := m.Called(postID, version)
+	r, _ := args.Get(0).(*PostRevision)
+	return r, args.Error(1)
+}
+
+type MockAttachmentStore struct {
+	mock.Mock
+}
+
+func (m *MockAttachmentStore) Create(ctx context.Context, a *Attachment) error {
+	args := m.Called(a.PostID, a.UserID)
+	return args.Error(0)
+}
+
+func (m *MockAttachmentStore) GetByID(ctx context.Context, id int64) (*Attachment, error) {
+	args := m.Called(id)
+	a, _ := args.Get(0).(*Attachment)
+	return a, args.Error(1)
+}
+
+func (m *MockAttachmentStore) Delete(ctx context.Context, id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+type MockTimelineStore struct {
+	mock.Mock
+}
+
+func (m *MockTimelineStore) Get(ctx context.Context, userID int64, limit, offset int) ([]PostWithMetadata, error) {
+	args := m.Called(userID, limit, offset)
+	return args.Get(0).([]PostWithMetadata), args.Error(1)
+}
+
+func (m *MockTimelineStore) ClaimJobs(ctx context.Context, limit int, lease time.Duration) ([]TimelineJob, error) {
+	return []TimelineJob{}, nil
+}
+
+func (m *MockTimelineStore) CompleteJob(ctx context.Context, id int64) error {
+	return nil
+}
+
+func (m *MockTimelineStore) FailJob(ctx context.Context, id int64, lastErr string, nextAttemptAt time.Time, dead bool) error {
+	return nil
+}
+
+func (m *MockTimelineStore) FanOut(ctx context.Context, postID int64, threshold int) (bool, error) {
+	return true, nil
+}
+
+func (m *MockTimelineStore) Backfill(ctx context.Context, followerID, authorID int64, limit int) error {
+	return nil
+}
+
+func (m *MockTimelineStore) Remove(ctx context.Context, followerID, authorID int64) error {
+	return nil
+}
+
+type MockReportStore struct {
+	mock.Mock
+}
+
+func (m *MockReportStore) Create(ctx context.Context, r *Report, hideThreshold int) (bool, error) {
+	args := m.Called(r.PostID, r.CommentID, r.Reason)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockReportStore) Queue(ctx context.Context, limit, offset int) ([]ReviewItem, error) {
+	return []ReviewItem{}, nil
+}
+
+func (m *MockReportStore) GetByID(ctx context.Context, id int64) (*Report, error) {
+	args := m.Called(id)
+	r, _ := args.Get(0).(*Report)
+	return r, args.Error(1)
+}
+
+func (m *MockReportStore) Resolve(ctx context.Context, reportID, moderatorID int64, action, note string) (*ModerationAction, error) {
+	args := m.Called(reportID, action)
+	a, _ := args.Get(0).(*ModerationAction)
+	return a, args.Error(1)
+}
@@ -0,0 +1,206 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/lib/pq"
+)
+
+const (
+	NotificationFollow  = "follow"
+	NotificationComment = "comment"
+	NotificationMention = "mention"
+)
+
+var NotificationTypes = []string{NotificationFollow, NotificationComment, NotificationMention}
+
+type Notification struct {
+	ID        int64   `json:"id"`
+	UserID    int64   `json:"user_id"`
+	ActorID   int64   `json:"actor_id"`
+	Type      string  `json:"type"`
+	PostID    *int64  `json:"post_id"`
+	CommentID *int64  `json:"comment_id"`
+	ReadAt    *string `json:"read_at"`
+	CreatedAt string  `json:"created_at"`
+	Actor     User    `json:"actor"`
+}
+
+type NotificationPreference struct {
+	Type  string `json:"type"`
+	Email bool   `json:"email"`
+}
+
+type NotificationQuery struct {
+	Limit  int  `json:"limit" validate:"gte=1,lte=50"`
+	Offset int  `json:"offset" validate:"gte=0"`
+	Unread bool `json:"unread"`
+}
+
+func (q NotificationQuery) Parse(r *http.Request) (NotificationQuery, error) {
+	qs := r.URL.Query()
+
+	if limit := qs.Get("limit"); limit != "" {
+		l, err := ParseIntParam("limit", limit)
+		if err != nil {
+			return q, err
+		}
+		q.Limit = l
+	}
+
+	if offset := qs.Get("offset"); offset != "" {
+		o, err := ParseIntParam("offset", offset)
+		if err != nil {
+			return q, err
+		}
+		q.Offset = o
+	}
+
+	if unread := qs.Get("unread"); unread != "" {
+		u, err := strconv.ParseBool(unread)
+		if err != nil {
+			return q, &ParamError{Param: "unread", Rule: "boolean", Want: "true or false", Err: err}
+		}
+		q.Unread = u
+	}
+
+	return q, nil
+}
+
+type NotificationStore struct {
+	db *sql.DB
+}
+
+func (s *NotificationStore) Create(ctx context.Context, n *Notification) error {
+	query := `
+		INSERT INTO notifications (user_id, actor_id, type, post_id, comment_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return s.db.QueryRowContext(ctx, query, n.UserID, n.ActorID, n.Type, n.PostID, n.CommentID).Scan(&n.ID, &n.CreatedAt)
+}
+
+func (s *NotificationStore) GetByUserID(ctx context.Context, userID int64, q NotificationQuery) ([]Notification, error) {
+	query := `
+		SELECT n.id, n.user_id, n.actor_id, n.type, n.post_id, n.comment_id, n.read_at, n.created_at,
+		u.id, u.username
+		FROM notifications n
+		JOIN users u ON u.id = n.actor_id
+		WHERE n.user_id = $1 AND (NOT $2 OR n.read_at IS NULL)
+		ORDER BY n.id DESC
+		LIMIT $3 OFFSET $4
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID, q.Unread, q.Limit, q.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []Notification{}
+	for rows.Next() {
+		var n Notification
+		if err := rows.Scan(&n.ID, &n.UserID, &n.ActorID, &n.Type, &n.PostID, &n.CommentID, &n.ReadAt, &n.CreatedAt, &n.Actor.ID, &n.Actor.Username); err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+func (s *NotificationStore) CountUnread(ctx context.Context, userID int64) (int, error) {
+	query := `SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var count int
+	err := s.db.QueryRowContext(ctx, query, userID).Scan(&count)
+	return count, err
+}
+
+// MarkRead marks the given notifications of userID as read. An empty ids
+// slice marks every unread notification. It returns how many were updated.
+func (s *NotificationStore) MarkRead(ctx context.Context, userID int64, ids []int64) (int64, error) {
+	query := `
+		UPDATE notifications SET read_at = NOW()
+		WHERE user_id = $1 AND read_at IS NULL AND (cardinality($2::bigint[]) = 0 OR id = ANY($2))
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	if ids == nil {
+		ids = []int64{}
+	}
+
+	result, err := s.db.ExecContext(ctx, query, userID, pq.Array(ids))
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// GetPreferences returns the preference for every notification type,
+// defaulting to no email for types the user never configured.
+func (s *NotificationStore) GetPreferences(ctx context.Context, userID int64) ([]NotificationPreference, error) {
+	query := `
+		SELECT t.type, COALESCE(np.email, false)
+		FROM unnest($2::varchar[]) AS t(type)
+		LEFT JOIN notification_preferences np ON np.type = t.type AND np.user_id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID, pq.Array(NotificationTypes))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prefs := []NotificationPreference{}
+	for rows.Next() {
+		var p NotificationPreference
+		if err := rows.Scan(&p.Type, &p.Email); err != nil {
+			return nil, err
+		}
+		prefs = append(prefs, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return prefs, nil
+}
+
+func (s *NotificationStore) SetPreference(ctx context.Context, userID int64, pref NotificationPreference) error {
+	query := `
+		INSERT INTO notification_preferences (user_id, type, email)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, type) DO UPDATE SET email = EXCLUDED.email
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, userID, pref.Type, pref.Email)
+	return err
+}
@@ -0,0 +1,117 @@
+//go:build integration
+
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNotificationStore(t *testing.T) {
+	t.Run("should list, count and mark notifications read", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &NotificationStore{db: db}
+		alice := createTestUser(t, db, "alice")
+		bob := createTestUser(t, db, "bob")
+		postID := createTestPost(t, db, alice, "hi", "there", []string{}, time.Now())
+
+		follow := &Notification{UserID: alice, ActorID: bob, Type: NotificationFollow}
+		comment := &Notification{UserID: alice, ActorID: bob, Type: NotificationComment, PostID: &postID}
+		other := &Notification{UserID: bob, ActorID: alice, Type: NotificationFollow}
+		for _, n := range []*Notification{follow, comment, other} {
+			if err := s.Create(testContext(t), n); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		list, err := s.GetByUserID(testContext(t), alice, NotificationQuery{Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 2 || list[0].ID != comment.ID || list[1].ID != follow.ID {
+			t.Fatalf("expected the newest notification first, got %+v", list)
+		}
+		if list[0].Actor.Username != "bob" || list[0].PostID == nil || *list[0].PostID != postID {
+			t.Errorf("unexpected notification %+v", list[0])
+		}
+
+		unread, err := s.CountUnread(testContext(t), alice)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if unread != 2 {
+			t.Errorf("expected 2 unread, got %d", unread)
+		}
+
+		// Other users' notifications can't be marked read.
+		marked, err := s.MarkRead(testContext(t), alice, []int64{follow.ID, other.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if marked != 1 {
+			t.Errorf("expected 1 notification marked read, got %d", marked)
+		}
+
+		list, err = s.GetByUserID(testContext(t), alice, NotificationQuery{Limit: 10, Unread: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 1 || list[0].ID != comment.ID {
+			t.Fatalf("expected only the comment to be unread, got %+v", list)
+		}
+
+		marked, err = s.MarkRead(testContext(t), alice, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if marked != 1 {
+			t.Errorf("expected 1 notification marked read, got %d", marked)
+		}
+
+		if unread, _ := s.CountUnread(testContext(t), alice); unread != 0 {
+			t.Errorf("expected nothing unread, got %d", unread)
+		}
+		if unread, _ := s.CountUnread(testContext(t), bob); unread != 1 {
+			t.Errorf("expected bob's notification to stay unread, got %d", unread)
+		}
+	})
+
+	t.Run("should default and update preferences", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &NotificationStore{db: db}
+		alice := createTestUser(t, db, "alice")
+
+		prefs, err := s.GetPreferences(testContext(t), alice)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(prefs) != len(NotificationTypes) {
+			t.Fatalf("expected a preference per type, got %v", prefs)
+		}
+		for _, p := range prefs {
+			if p.Email {
+				t.Errorf("expected email to be off by default, got %v", p)
+			}
+		}
+
+		for _, email := range []bool{false, true} {
+			if err := s.SetPreference(testContext(t), alice, NotificationPreference{Type: NotificationFollow, Email: email}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		prefs, err = s.GetPreferences(testContext(t), alice)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range prefs {
+			if p.Email != (p.Type == NotificationFollow) {
+				t.Errorf("unexpected preference %v", p)
+			}
+		}
+
+		if err := s.SetPreference(testContext(t), alice, NotificationPreference{Type: "digest", Email: true}); err == nil {
+			t.Error("expected unknown types to be rejected")
+		}
+	})
+}
@@ -0,0 +1,240 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+const (
+	OutboxStatusPending = "pending"
+	OutboxStatusSent    = "sent"
+	OutboxStatusDead    = "dead"
+)
+
+type OutboxMessage struct {
+	ID       int64           `json:"id"`
+	Template string          `json:"template"`
+	Locale   string          `json:"locale"`
+	Username string          `json:"username"`
+	Email    string          `json:"email"`
+	Data     json.RawMessage `json:"data" swaggertype:"object"`
+	// Token is the one-time token the email links to, which the worker builds
+	// the link from. It's never sent to clients, and is discarded once the
+	// message is sent or dead-lettered.
+	Token         string  `json:"-"`
+	Sandbox       bool    `json:"sandbox"`
+	Status        string  `json:"status"`
+	Attempts      int     `json:"attempts"`
+	LastError     *string `json:"last_error"`
+	NextAttemptAt string  `json:"next_attempt_at"`
+	CreatedAt     string  `json:"created_at"`
+	SentAt        *string `json:"sent_at"`
+}
+
+type OutboxStore struct {
+	db *sql.DB
+}
+
+func (s *OutboxStore) Enqueue(ctx context.Context, msg *OutboxMessage) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		return enqueueOutboxMessage(ctx, tx, msg)
+	})
+}
+
+func enqueueOutboxMessage(ctx context.Context, tx *sql.Tx, msg *OutboxMessage) error {
+	query := `
+		INSERT INTO email_outbox (template, locale, username, email, data, token, sandbox)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7)
+		RETURNING id, status, next_attempt_at, created_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	data := msg.Data
+	if len(data) == 0 {
+		data = json.RawMessage(`{}`)
+	}
+
+	if msg.Locale == "" {
+		msg.Locale = "en"
+	}
+
+	return tx.QueryRowContext(ctx, query, msg.Template, msg.Locale, msg.Username, msg.Email, []byte(data), msg.Token, msg.Sandbox).Scan(
+		&msg.ID,
+		&msg.Status,
+		&msg.NextAttemptAt,
+		&msg.CreatedAt,
+	)
+}
+
+// ClaimDue leases up to limit pending messages whose next attempt is due.
+// Claimed messages have their attempt counter bumped and are hidden from
+// other workers until the lease expires, so a crashed worker's messages are
+// picked up again.
+func (s *OutboxStore) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]OutboxMessage, error) {
+	query := `
+		UPDATE email_outbox SET attempts = attempts + 1, next_attempt_at = $2
+		WHERE id IN (
+			SELECT id FROM email_outbox
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, template, locale, username, email, data, COALESCE(token, ''), sandbox, status, attempts, last_error, next_attempt_at, created_at, sent_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, limit, time.Now().Add(lease))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanOutboxMessages(rows)
+}
+
+func (s *OutboxStore) MarkSent(ctx context.Context, id int64) error {
+	query := `
+		UPDATE email_outbox SET status = 'sent', sent_at = NOW(), last_error = NULL, data = '{}', token = NULL
+		WHERE id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// MarkFailed records a failed delivery. When dead is true the message is
+// moved to the dead-letter state and won't be claimed again until retried,
+// and its token is discarded, so messages linking to one fail again if
+// they are retried.
+func (s *OutboxStore) MarkFailed(ctx context.Context, id int64, lastErr string, nextAttemptAt time.Time, dead bool) error {
+	query := `
+		UPDATE email_outbox
+		SET status = CASE WHEN $4 THEN 'dead' ELSE 'pending' END, last_error = $2, next_attempt_at = $3,
+			token = CASE WHEN $4 THEN NULL ELSE token END
+		WHERE id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, id, lastErr, nextAttemptAt, dead)
+	return err
+}
+
+func (s *OutboxStore) GetByID(ctx context.Context, id int64) (*OutboxMessage, error) {
+	query := `
+		SELECT id, template, locale, username, email, data, COALESCE(token, ''), sandbox, status, attempts, last_error, next_attempt_at, created_at, sent_at
+		FROM email_outbox WHERE id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var msg OutboxMessage
+	err := s.db.QueryRowContext(ctx, query, id).Scan(outboxMessageFields(&msg)...)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &msg, nil
+}
+
+func (s *OutboxStore) List(ctx context.Context, status string, limit, offset int) ([]OutboxMessage, error) {
+	query := `
+		SELECT id, template, locale, username, email, data, COALESCE(token, ''), sandbox, status, attempts, last_error, next_attempt_at, created_at, sent_at
+		FROM email_outbox
+		WHERE status = $1 OR $1 = ''
+		ORDER BY id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanOutboxMessages(rows)
+}
+
+// Retry moves a dead-lettered message back to pending with a fresh attempt
+// budget.
+func (s *OutboxStore) Retry(ctx context.Context, id int64) error {
+	query := `
+		UPDATE email_outbox SET status = 'pending', attempts = 0, next_attempt_at = NOW()
+		WHERE id = $1 AND status = 'dead'
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func outboxMessageFields(msg *OutboxMessage) []any {
+	return []any{
+		&msg.ID,
+		&msg.Template,
+		&msg.Locale,
+		&msg.Username,
+		&msg.Email,
+		&msg.Data,
+		&msg.Token,
+		&msg.Sandbox,
+		&msg.Status,
+		&msg.Attempts,
+		&msg.LastError,
+		&msg.NextAttemptAt,
+		&msg.CreatedAt,
+		&msg.SentAt,
+	}
+}
+
+func scanOutboxMessages(rows *sql.Rows) ([]OutboxMessage, error) {
+	msgs := []OutboxMessage{}
+	for rows.Next() {
+		var msg OutboxMessage
+		if err := rows.Scan(outboxMessageFields(&msg)...); err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return msgs, nil
+}
@@ -0,0 +1,150 @@
+//go:build integration
+
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestOutboxStore(t *testing.T) {
+	enqueue := func(t *testing.T, s *OutboxStore, email string) *OutboxMessage {
+		t.Helper()
+
+		msg := &OutboxMessage{Template: "user_invitation", Username: "alice", Email: email, Data: json.RawMessage(`{"url":"x"}`), Token: "token"}
+		if err := s.Enqueue(testContext(t), msg); err != nil {
+			t.Fatal(err)
+		}
+
+		return msg
+	}
+
+	// makeDue moves every pending message's next attempt into the past, since
+	// NOW() is frozen for the whole test transaction.
+	makeDue := func(t *testing.T, db *sql.DB) {
+		t.Helper()
+
+		if _, err := db.Exec(`UPDATE email_outbox SET next_attempt_at = NOW() - interval '1 minute' WHERE status = 'pending'`); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("should enqueue and get messages", func(t *testing.T) {
+		s := &OutboxStore{db: newTestDB(t)}
+		msg := enqueue(t, s, "alice@example.com")
+
+		if msg.ID == 0 || msg.Status != OutboxStatusPending || msg.Locale != "en" {
+			t.Fatalf("unexpected message %+v", msg)
+		}
+
+		got, err := s.GetByID(testContext(t), msg.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Email != msg.Email || string(got.Data) != `{"url": "x"}` || got.Token != "token" {
+			t.Errorf("unexpected message %+v", got)
+		}
+
+		if _, err := s.GetByID(testContext(t), -1); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("should lease due messages", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &OutboxStore{db: db}
+		first := enqueue(t, s, "a@example.com")
+		second := enqueue(t, s, "b@example.com")
+		makeDue(t, db)
+
+		claimed, err := s.ClaimDue(testContext(t), 1, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(claimed) != 1 || claimed[0].ID != first.ID || claimed[0].Attempts != 1 {
+			t.Fatalf("expected the oldest message, got %+v", claimed)
+		}
+
+		claimed, err = s.ClaimDue(testContext(t), 10, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(claimed) != 1 || claimed[0].ID != second.ID {
+			t.Fatalf("expected leased messages to be skipped, got %+v", claimed)
+		}
+	})
+
+	t.Run("should record failures, deliveries and retries", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &OutboxStore{db: db}
+		sent := enqueue(t, s, "a@example.com")
+		failed := enqueue(t, s, "b@example.com")
+		dead := enqueue(t, s, "c@example.com")
+
+		if err := s.MarkSent(testContext(t), sent.ID); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.MarkFailed(testContext(t), failed.ID, "timeout", time.Now().Add(-time.Minute), false); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.MarkFailed(testContext(t), dead.ID, "bounced", time.Now().Add(-time.Minute), true); err != nil {
+			t.Fatal(err)
+		}
+
+		claimed, err := s.ClaimDue(testContext(t), 10, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(claimed) != 1 || claimed[0].ID != failed.ID || claimed[0].LastError == nil || *claimed[0].LastError != "timeout" {
+			t.Fatalf("expected only the failed message to be due, got %+v", claimed)
+		}
+
+		got, err := s.GetByID(testContext(t), sent.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != OutboxStatusSent || got.SentAt == nil {
+			t.Errorf("expected the message to be sent, got %+v", got)
+		}
+		if string(got.Data) != `{}` || got.Token != "" {
+			t.Errorf("expected the sent message's data to be discarded, got %+v", got)
+		}
+
+		list, err := s.List(testContext(t), OutboxStatusDead, 10, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 1 || list[0].ID != dead.ID {
+			t.Fatalf("expected the dead message, got %+v", list)
+		}
+
+		list, err = s.List(testContext(t), "", 10, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 3 || list[0].ID != dead.ID {
+			t.Errorf("expected every message, newest first, got %+v", list)
+		}
+
+		if err := s.Retry(testContext(t), dead.ID); err != nil {
+			t.Fatal(err)
+		}
+		got, err = s.GetByID(testContext(t), dead.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != OutboxStatusPending || got.Attempts != 0 {
+			t.Errorf("expected the message to be pending again, got %+v", got)
+		}
+		if got.Token != "" {
+			t.Errorf("expected the dead message's token to be discarded, got %q", got.Token)
+		}
+
+		if err := s.Retry(testContext(t), sent.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected only dead messages to be retried, got %v", err)
+		}
+	})
+}
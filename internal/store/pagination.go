@@ -7,6 +7,11 @@ import (
 	"time"
 )
 
+const (
+	FeedLatest = "latest"
+	FeedForYou = "for_you"
+)
+
 type PaginatedFeedQuery struct {
 	Limit  int      `json:"limit" validate:"gte=1,lte=20"`
 	Offset int      `json:"offset" validate:"gte=0"`
@@ -15,6 +20,7 @@ type PaginatedFeedQuery struct {
 	Search string   `json:"search" validate:"max=100"`
 	Since  string   `json:"since"`
 	Until  string   `json:"until"`
+	Mode   string   `json:"mode" validate:"omitempty,oneof=latest for_you"`
 }
 
 func (q PaginatedFeedQuery) Parse(r *http.Request) (PaginatedFeedQuery, error) {
@@ -22,9 +28,9 @@ func (q PaginatedFeedQuery) Parse(r *http.Request) (PaginatedFeedQuery, error) {
 
 	limit := qs.Get("limit")
 	if limit != "" {
-		l, err := strconv.Atoi(limit)
+		l, err := ParseIntParam("limit", limit)
 		if err != nil {
-			return q, nil
+			return q, err
 		}
 
 		q.Limit = l
@@ -32,9 +38,9 @@ func (q PaginatedFeedQuery) Parse(r *http.Request) (PaginatedFeedQuery, error) {
 
 	offset := qs.Get("offset")
 	if offset != "" {
-		o, err := strconv.Atoi(offset)
+		o, err := ParseIntParam("offset", offset)
 		if err != nil {
-			return q, nil
+			return q, err
 		}
 
 		q.Offset = o
@@ -52,6 +58,11 @@ func (q PaginatedFeedQuery) Parse(r *http.Request) (PaginatedFeedQuery, error) {
 		q.Tags = []string{}
 	}
 
+	mode := qs.Get("mode")
+	if mode != "" {
+		q.Mode = mode
+	}
+
 	search := qs.Get("search")
 	if search != "" {
 		q.Search = search
@@ -59,22 +70,49 @@ func (q PaginatedFeedQuery) Parse(r *http.Request) (PaginatedFeedQuery, error) {
 
 	since := qs.Get("since")
 	if since != "" {
-		q.Since = parseTime(since)
+		t, err := time.Parse(time.DateTime, since)
+		if err != nil {
+			return q, &ParamError{Param: "since", Rule: "datetime", Want: "a date and time like " + time.DateTime, Err: err}
+		}
+		q.Since = t.Format(time.DateTime)
 	}
 
 	until := qs.Get("until")
 	if until != "" {
-		q.Until = parseTime(until)
+		t, err := time.Parse(time.DateTime, until)
+		if err != nil {
+			return q, &ParamError{Param: "until", Rule: "datetime", Want: "a date and time like " + time.DateTime, Err: err}
+		}
+		q.Until = t.Format(time.DateTime)
 	}
 
 	return q, nil
 }
 
-func parseTime(s string) string {
-	t, err := time.Parse(time.DateTime, s)
+// ParamError is a query parameter that couldn't be parsed.
+type ParamError struct {
+	Param string
+	// Rule is what the parameter failed to parse as, like int or datetime.
+	Rule string
+	// Want describes the values the parameter takes.
+	Want string
+	Err  error
+}
+
+func (e *ParamError) Error() string {
+	return e.Param + " must be " + e.Want
+}
+
+func (e *ParamError) Unwrap() error {
+	return e.Err
+}
+
+// ParseIntParam parses the value of the integer query parameter param.
+func ParseIntParam(param, s string) (int, error) {
+	n, err := strconv.Atoi(s)
 	if err != nil {
-		return ""
+		return 0, &ParamError{Param: param, Rule: "int", Want: "an integer", Err: err}
 	}
 
-	return t.Format(time.DateTime)
+	return n, nil
 }
@@ -3,60 +3,159 @@ package store
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"time"
 
+	"github.com/kuluruvineeth/social-go/internal/content"
 	"github.com/lib/pq"
 )
 
 type Post struct {
-	ID        int64     `json:"id"`
-	Content   string    `json:"content"`
-	Title     string    `json:"title"`
-	UserID    int64     `json:"user_id"`
-	Tags      []string  `json:"tags"`
-	CreatedAt string    `json:"created_at"`
-	UpdatedAt string    `json:"updated_at"`
-	Version   int       `json:"version"`
-	Comments  []Comment `json:"comments"`
-	User      User      `json:"user"`
+	ID          int64        `json:"id"`
+	Content     string       `json:"content"`
+	Title       string       `json:"title"`
+	UserID      int64        `json:"user_id"`
+	Tags        []string     `json:"tags"`
+	CreatedAt   string       `json:"created_at"`
+	UpdatedAt   string       `json:"updated_at"`
+	Version     int          `json:"version"`
+	Status      string       `json:"status"`
+	Visibility  string       `json:"visibility"`
+	PublishAt   *time.Time   `json:"publish_at"`
+	Comments    []Comment    `json:"comments"`
+	Mentions    []Mention    `json:"mentions"`
+	Attachments []Attachment `json:"attachments"`
+	User        User         `json:"user"`
+}
+
+const (
+	PostDraft     = "draft"
+	PostScheduled = "scheduled"
+	PostPublished = "published"
+
+	VisibilityPublic    = "public"
+	VisibilityFollowers = "followers"
+	VisibilityPrivate   = "private"
+)
+
+// publicPost is the condition for a post p anyone can see. Listings that
+// aren't tied to a viewer, like search and tags, only show these.
+const publicPost = `(p.status = 'published' AND p.visibility = 'public' AND p.hidden_at IS NULL)`
+
+// visibleTo returns the condition for a post p to be visible to the user
+// whose ID is the given query parameter: their own posts, and published
+// posts that weren't hidden by moderators and are public or for followers
+// when they follow the author.
+func visibleTo(viewer string) string {
+	return `(p.user_id = ` + viewer + ` OR (p.status = 'published' AND p.hidden_at IS NULL AND (p.visibility = 'public' OR (p.visibility = 'followers' AND EXISTS (SELECT 1 FROM followers vf WHERE vf.user_id = p.user_id AND vf.follower_id = ` + viewer + `)))))`
 }
 
 type PostWithMetadata struct {
 	Post
-	CommentCount int `json:"comment_count"`
+	CommentCount  int `json:"comment_count"`
+	ReactionCount int `json:"reaction_count"`
+	RepostCount   int `json:"repost_count"`
+	// RepostedBy is the repost that put the post in a feed, when it's there
+	// because someone the viewer follows reposted it.
+	RepostedBy *Repost `json:"reposted_by,omitempty"`
 }
 
+// FeedCandidate is a post considered for the ranked feed.
+type FeedCandidate struct {
+	PostWithMetadata
+	// Affinity is how many times the viewer commented on or reacted to the
+	// author's posts over the last AffinityWindow.
+	Affinity int `json:"-"`
+}
+
+const AffinityWindow = 90 * 24 * time.Hour
+
 type PostStore struct {
 	db *sql.DB
 }
 
+// Create stores the post along with its first revision, the tags it uses and
+// the users it mentions, which are parsed from its content. Posts are
+// published and public unless they say otherwise, and published posts are
+// queued for fan-out to followers' timelines.
 func (s *PostStore) Create(ctx context.Context, post *Post) error {
-	query := `INSERT INTO posts (content, title, user_id, tags) VALUES ($1, $2, $3, $4) RETURNING id, created_at, updated_at`
+	query := `
+		INSERT INTO posts (content, title, user_id, tags, status, visibility, publish_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, version, created_at, updated_at
+	`
+
+	if post.Status == "" {
+		post.Status = PostPublished
+	}
+	if post.Visibility == "" {
+		post.Visibility = VisibilityPublic
+	}
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	row := s.db.QueryRowContext(ctx, query, post.Content, post.Title, post.UserID, pq.Array(post.Tags))
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		row := tx.QueryRowContext(ctx, query, post.Content, post.Title, post.UserID, pq.Array(post.Tags), post.Status, post.Visibility, post.PublishAt)
+
+		if err := row.Scan(&post.ID, &post.Version, &post.CreatedAt, &post.UpdatedAt); err != nil {
+			return err
+		}
+
+		if err := saveRevision(ctx, tx, post, post.UserID); err != nil {
+			return err
+		}
 
-	err := row.Scan(&post.ID, &post.CreatedAt, &post.UpdatedAt)
+		if err := s.setEntities(ctx, tx, post); err != nil {
+			return err
+		}
+
+		if post.Status != PostPublished {
+			return nil
+		}
+
+		return enqueueTimelineJob(ctx, tx, TimelineJobFanout, post.UserID, post.ID)
+	})
+}
+
+func (s *PostStore) setEntities(ctx context.Context, tx *sql.Tx, post *Post) error {
+	if err := setTagUsages(ctx, tx, post.ID, nil, post.UserID, post.Tags); err != nil {
+		return err
+	}
+
+	mentions, err := setMentions(ctx, tx, post.ID, nil, content.Parse(post.Content).Mentions)
 	if err != nil {
 		return err
 	}
 
+	post.Mentions = mentions
 	return nil
 }
 
-func (s *PostStore) GetByID(ctx context.Context, id int64) (*Post, error) {
-	query := `SELECT id, title, content, user_id, tags, created_at, updated_at, version FROM posts WHERE id = $1`
+// GetByID returns the post if viewerID can see it, and ErrNotFound
+// otherwise, so restricted posts look the same as missing ones.
+func (s *PostStore) GetByID(ctx context.Context, id, viewerID int64) (*Post, error) {
+	query := `
+		SELECT id, title, content, user_id, tags, created_at, updated_at, version, status, visibility, publish_at,
+		COALESCE((
+			SELECT json_agg(json_build_object('user_id', u.id, 'username', u.username) ORDER BY u.username)
+			FROM mentions m JOIN users u ON u.id = m.user_id
+			WHERE m.post_id = p.id AND m.comment_id IS NULL
+		), '[]'),
+		` + attachmentsColumn + `
+		FROM posts p WHERE id = $1 AND ` + visibleTo("$2") + `
+	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	row := s.db.QueryRowContext(ctx, query, id)
+	row := s.db.QueryRowContext(ctx, query, id, viewerID)
 
 	var post Post
+	var mentions []byte
 
-	if err := row.Scan(&post.ID, &post.Title, &post.Content, &post.UserID, pq.Array(&post.Tags), &post.CreatedAt, &post.UpdatedAt, &post.Version); err != nil {
+	if err := row.Scan(&post.ID, &post.Title, &post.Content, &post.UserID, pq.Array(&post.Tags), &post.CreatedAt, &post.UpdatedAt, &post.Version, &post.Status, &post.Visibility, &post.PublishAt, &mentions, (*attachmentList)(&post.Attachments)); err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
 			return nil, ErrNotFound
@@ -65,6 +164,10 @@ func (s *PostStore) GetByID(ctx context.Context, id int64) (*Post, error) {
 		}
 	}
 
+	if err := json.Unmarshal(mentions, &post.Mentions); err != nil {
+		return nil, err
+	}
+
 	return &post, nil
 }
 
@@ -91,56 +194,173 @@ func (s *PostStore) Delete(ctx context.Context, id int64) error {
 	return nil
 }
 
-func (s *PostStore) Update(ctx context.Context, post *Post) error {
-	query := `UPDATE posts SET content = $1, title = $2, version = version + 1 WHERE id = $3 AND version = $4 RETURNING version`
+// Update saves the post as its next version, edited by editorID, provided it
+// is still at post.Version, and returns ErrVersionConflict when it isn't. The
+// previous versions are kept as revisions. A post that gets published is
+// dated to now and queued for fan-out.
+func (s *PostStore) Update(ctx context.Context, post *Post, editorID int64) error {
+	query := `
+		WITH old AS (SELECT status FROM posts WHERE id = $4)
+		UPDATE posts p SET content = $1, title = $2, tags = $3, status = $6, visibility = $7, publish_at = $8,
+		created_at = CASE WHEN old.status <> 'published' AND $6 = 'published' THEN NOW() ELSE p.created_at END,
+		version = version + 1
+		FROM old
+		WHERE p.id = $4 AND p.version = $5
+		RETURNING p.version, p.created_at, old.status
+	`
 
-	err := s.db.QueryRowContext(ctx, query, post.Content, post.Title, post.ID, post.Version).Scan(&post.Version)
-	if err != nil {
-		switch {
-		case errors.Is(err, sql.ErrNoRows):
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		var previous string
+
+		err := tx.QueryRowContext(ctx, query, post.Content, post.Title, pq.Array(post.Tags), post.ID, post.Version, post.Status, post.Visibility, post.PublishAt).Scan(&post.Version, &post.CreatedAt, &previous)
+		if errors.Is(err, sql.ErrNoRows) {
+			var exists bool
+			if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM posts WHERE id = $1)`, post.ID).Scan(&exists); err != nil {
+				return err
+			}
+			if exists {
+				return ErrVersionConflict
+			}
 			return ErrNotFound
-		default:
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := saveRevision(ctx, tx, post, editorID); err != nil {
+			return err
+		}
+
+		if err := s.setEntities(ctx, tx, post); err != nil {
 			return err
 		}
+
+		if previous == PostPublished || post.Status != PostPublished {
+			return nil
+		}
+
+		return enqueueTimelineJob(ctx, tx, TimelineJobFanout, post.UserID, post.ID)
+	})
+}
+
+// PublishDue publishes up to limit scheduled posts whose time has come, dated
+// to now, queues them for fan-out and returns them.
+func (s *PostStore) PublishDue(ctx context.Context, limit int) ([]Post, error) {
+	query := `
+		UPDATE posts SET status = 'published', publish_at = NULL, created_at = NOW()
+		WHERE id IN (
+			SELECT id FROM posts
+			WHERE status = 'scheduled' AND publish_at <= NOW()
+			ORDER BY publish_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, title, content, user_id, tags, created_at, updated_at, version, status, visibility
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	posts := []Post{}
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, query, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var p Post
+			if err := rows.Scan(&p.ID, &p.Title, &p.Content, &p.UserID, pq.Array(&p.Tags), &p.CreatedAt, &p.UpdatedAt, &p.Version, &p.Status, &p.Visibility); err != nil {
+				return err
+			}
+			posts = append(posts, p)
+		}
+
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, p := range posts {
+			if err := enqueueTimelineJob(ctx, tx, TimelineJobFanout, p.UserID, p.ID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return nil
+
+	return posts, nil
 }
 
+// GetUserFeed returns a page of the published posts by userID and the users
+// they follow that userID can see, along with the posts they reposted, which
+// are placed at the time of the repost and attributed to the reposter. Each
+// post is in the feed at most once, at its latest appearance. Search, tag
+// and time filters apply to every post in the feed.
 func (s *PostStore) GetUserFeed(ctx context.Context, userID int64, fq PaginatedFeedQuery) ([]PostWithMetadata, error) {
 	query := `
-		SELECT p.id, p.title, p.content, p.user_id, p.tags, p.created_at, p.updated_at, p.version,
+		WITH network AS (
+			SELECT $1::bigint AS user_id
+			UNION
+			SELECT user_id FROM followers WHERE follower_id = $1
+		), entries AS (
+			SELECT DISTINCT ON (post_id) post_id, feed_at, repost_id
+			FROM (
+				SELECT p.id AS post_id, p.created_at AS feed_at, NULL::bigint AS repost_id
+				FROM posts p WHERE p.user_id IN (SELECT user_id FROM network)
+				UNION ALL
+				SELECT r.post_id, r.created_at, r.id
+				FROM reposts r WHERE r.user_id IN (SELECT user_id FROM network)
+			) e
+			ORDER BY post_id, feed_at DESC
+		)
+		SELECT p.id, p.title, p.content, p.user_id, p.tags, p.created_at, p.updated_at, p.version, p.status, p.visibility,
 		u.username,
-		COUNT(c.id) AS comment_count
-		FROM posts p
-		LEFT JOIN comments c ON p.id = c.post_id
-		LEFT JOIN users u ON p.user_id = u.id
-		JOIN followers f ON f.follower_id = p.user_id OR p.user_id = $1
-		WHERE 
-			f.user_id = $1 OR p.user_id = $1 AND
-			(p.title ILIKE '%' || $4 || '%' OR p.content ILIKE '%' || $4 || '%') AND
-			(p.tags @> $5 OR $5 = '{}')
-		GROUP BY p.id, u.username
-		ORDER BY p.created_at ` + fq.Sort + `
+		(SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id) AS comment_count,
+		(SELECT COUNT(*) FROM reactions r WHERE r.post_id = p.id) AS reaction_count,
+		(SELECT COUNT(*) FROM reposts rc WHERE rc.post_id = p.id) AS repost_count,
+		` + attachmentsColumn + `,
+		` + repostColumn + `
+		FROM entries e
+		JOIN posts p ON p.id = e.post_id
+		JOIN users u ON u.id = p.user_id
+		LEFT JOIN reposts rp ON rp.id = e.repost_id
+		LEFT JOIN users ru ON ru.id = rp.user_id
+		WHERE p.status = 'published' AND ` + visibleTo("$1") + `
+			AND (p.title ILIKE '%' || $4 || '%' OR p.content ILIKE '%' || $4 || '%')
+			AND (p.tags @> $5 OR $5 = '{}')
+			AND e.feed_at >= COALESCE(NULLIF($6, '')::timestamptz, '-infinity')
+			AND e.feed_at <= COALESCE(NULLIF($7, '')::timestamptz, 'infinity')
+		ORDER BY e.feed_at ` + fq.Sort + `, p.id ` + fq.Sort + `
 		LIMIT $2 OFFSET $3
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	rows, err := s.db.QueryContext(ctx, query, userID, fq.Limit, fq.Offset, fq.Search, pq.Array(fq.Tags))
+	rows, err := s.db.QueryContext(ctx, query, userID, fq.Limit, fq.Offset, fq.Search, pq.Array(fq.Tags), fq.Since, fq.Until)
 	if err != nil {
 		return nil, err
 	}
 
 	defer rows.Close()
 
-	var feed []PostWithMetadata
+	feed := []PostWithMetadata{}
 
 	for rows.Next() {
 		var post PostWithMetadata
-		if err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.UserID, pq.Array(&post.Tags), &post.CreatedAt, &post.UpdatedAt, &post.Version, &post.User.Username, &post.CommentCount); err != nil {
+		if err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.UserID, pq.Array(&post.Tags), &post.CreatedAt, &post.UpdatedAt, &post.Version, &post.Status, &post.Visibility, &post.User.Username, &post.CommentCount, &post.ReactionCount, &post.RepostCount, (*attachmentList)(&post.Attachments), repostRef{&post.RepostedBy}); err != nil {
 			return nil, err
 		}
+		post.User.ID = post.UserID
 
 		feed = append(feed, post)
 	}
@@ -151,3 +371,66 @@ func (s *PostStore) GetUserFeed(ctx context.Context, userID int64, fq PaginatedF
 
 	return feed, nil
 }
+
+// GetFeedCandidates returns up to max posts created since the given time by
+// userID and the users they follow, newest first, for the ranked feed.
+func (s *PostStore) GetFeedCandidates(ctx context.Context, userID int64, fq PaginatedFeedQuery, since time.Time, max int) ([]FeedCandidate, error) {
+	query := `
+		WITH interactions AS (
+			SELECT p.user_id AS author_id
+			FROM comments c JOIN posts p ON p.id = c.post_id
+			WHERE c.user_id = $1 AND c.created_at >= $2
+			UNION ALL
+			SELECT p.user_id
+			FROM reactions r JOIN posts p ON p.id = r.post_id
+			WHERE r.user_id = $1 AND r.created_at >= $2
+		), affinity AS (
+			SELECT author_id, COUNT(*) AS interactions
+			FROM interactions
+			WHERE author_id <> $1
+			GROUP BY author_id
+		)
+		SELECT p.id, p.title, p.content, p.user_id, p.tags, p.created_at, p.updated_at, p.version, p.status, p.visibility,
+		u.username,
+		(SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id) AS comment_count,
+		(SELECT COUNT(*) FROM reactions r WHERE r.post_id = p.id) AS reaction_count,
+		(SELECT COUNT(*) FROM reposts rc WHERE rc.post_id = p.id) AS repost_count,
+		COALESCE(af.interactions, 0),
+		` + attachmentsColumn + `
+		FROM posts p
+		JOIN users u ON u.id = p.user_id
+		LEFT JOIN affinity af ON af.author_id = p.user_id
+		WHERE (p.user_id = $1 OR p.user_id IN (SELECT user_id FROM followers WHERE follower_id = $1))
+			AND p.status = 'published' AND ` + visibleTo("$1") + `
+			AND p.created_at >= $3
+			AND (p.title ILIKE '%' || $4 || '%' OR p.content ILIKE '%' || $4 || '%')
+			AND (p.tags @> $5 OR $5 = '{}')
+		ORDER BY p.created_at DESC
+		LIMIT $6
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID, time.Now().Add(-AffinityWindow), since, fq.Search, pq.Array(fq.Tags), max)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	candidates := []FeedCandidate{}
+	for rows.Next() {
+		var c FeedCandidate
+		if err := rows.Scan(&c.ID, &c.Title, &c.Content, &c.UserID, pq.Array(&c.Tags), &c.CreatedAt, &c.UpdatedAt, &c.Version, &c.Status, &c.Visibility, &c.User.Username, &c.CommentCount, &c.ReactionCount, &c.RepostCount, &c.Affinity, (*attachmentList)(&c.Attachments)); err != nil {
+			return nil, err
+		}
+		c.User.ID = c.UserID
+		candidates = append(candidates, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return candidates, nil
+}
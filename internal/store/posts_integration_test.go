@@ -0,0 +1,477 @@
+//go:build integration
+
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestGetUserFeed(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	feedQuery := func(mods ...func(*PaginatedFeedQuery)) PaginatedFeedQuery {
+		fq := PaginatedFeedQuery{Limit: 20, Sort: "desc", Tags: []string{}}
+		for _, mod := range mods {
+			mod(&fq)
+		}
+		return fq
+	}
+
+	// viewer follows alice and bob but not carol; alice and carol follow the
+	// viewer back, which used to duplicate the viewer's own posts.
+	type fixture struct {
+		db                                             *sql.DB
+		posts                                          *PostStore
+		viewer, alice, bob, carol                      int64
+		own, aliceGo, aliceSQL, bobGo, bobOld, carolGo int64
+	}
+
+	setup := func(t *testing.T) fixture {
+		db := newTestDB(t)
+		f := fixture{db: db, posts: &PostStore{db: db}}
+		f.viewer = createTestUser(t, db, "viewer")
+		f.alice = createTestUser(t, db, "alice")
+		f.bob = createTestUser(t, db, "bob")
+		f.carol = createTestUser(t, db, "carol")
+
+		createTestFollow(t, db, f.viewer, f.alice)
+		createTestFollow(t, db, f.viewer, f.bob)
+		createTestFollow(t, db, f.alice, f.viewer)
+		createTestFollow(t, db, f.carol, f.viewer)
+
+		f.own = createTestPost(t, db, f.viewer, "my golang notes", "learning", []string{"go"}, base)
+		f.aliceGo = createTestPost(t, db, f.alice, "generics", "a Golang deep dive", []string{"go", "generics"}, base.Add(-time.Hour))
+		f.aliceSQL = createTestPost(t, db, f.alice, "indexes", "postgres tips", []string{"sql"}, base.Add(-2*time.Hour))
+		f.bobGo = createTestPost(t, db, f.bob, "channels", "concurrency in go", []string{"go"}, base.Add(-3*time.Hour))
+		f.bobOld = createTestPost(t, db, f.bob, "hello", "first post", []string{}, base.Add(-48*time.Hour))
+		f.carolGo = createTestPost(t, db, f.carol, "golang", "not followed", []string{"go"}, base.Add(-time.Minute))
+
+		return f
+	}
+
+	t.Run("should include own and followed posts exactly once", func(t *testing.T) {
+		f := setup(t)
+
+		feed, err := f.posts.GetUserFeed(testContext(t), f.viewer, feedQuery())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertFeed(t, feed, f.own, f.aliceGo, f.aliceSQL, f.bobGo, f.bobOld)
+	})
+
+	t.Run("should apply search to every post", func(t *testing.T) {
+		f := setup(t)
+
+		feed, err := f.posts.GetUserFeed(testContext(t), f.viewer, feedQuery(func(fq *PaginatedFeedQuery) {
+			fq.Search = "golang"
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertFeed(t, feed, f.own, f.aliceGo)
+	})
+
+	t.Run("should apply tag filters to every post", func(t *testing.T) {
+		f := setup(t)
+
+		feed, err := f.posts.GetUserFeed(testContext(t), f.viewer, feedQuery(func(fq *PaginatedFeedQuery) {
+			fq.Tags = []string{"go"}
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertFeed(t, feed, f.own, f.aliceGo, f.bobGo)
+
+		feed, err = f.posts.GetUserFeed(testContext(t), f.viewer, feedQuery(func(fq *PaginatedFeedQuery) {
+			fq.Tags = []string{"go", "generics"}
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertFeed(t, feed, f.aliceGo)
+	})
+
+	t.Run("should combine search and tag filters", func(t *testing.T) {
+		f := setup(t)
+
+		feed, err := f.posts.GetUserFeed(testContext(t), f.viewer, feedQuery(func(fq *PaginatedFeedQuery) {
+			fq.Search = "go"
+			fq.Tags = []string{"go"}
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertFeed(t, feed, f.own, f.aliceGo, f.bobGo)
+	})
+
+	t.Run("should filter by time window", func(t *testing.T) {
+		f := setup(t)
+
+		feed, err := f.posts.GetUserFeed(testContext(t), f.viewer, feedQuery(func(fq *PaginatedFeedQuery) {
+			fq.Since = base.Add(-2 * time.Hour).Format(time.DateTime)
+			fq.Until = base.Add(-time.Hour).Format(time.DateTime)
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertFeed(t, feed, f.aliceGo, f.aliceSQL)
+	})
+
+	t.Run("should order and paginate", func(t *testing.T) {
+		f := setup(t)
+
+		feed, err := f.posts.GetUserFeed(testContext(t), f.viewer, feedQuery(func(fq *PaginatedFeedQuery) {
+			fq.Sort = "asc"
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertFeed(t, feed, f.bobOld, f.bobGo, f.aliceSQL, f.aliceGo, f.own)
+
+		first, err := f.posts.GetUserFeed(testContext(t), f.viewer, feedQuery(func(fq *PaginatedFeedQuery) {
+			fq.Limit = 2
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertFeed(t, first, f.own, f.aliceGo)
+
+		second, err := f.posts.GetUserFeed(testContext(t), f.viewer, feedQuery(func(fq *PaginatedFeedQuery) {
+			fq.Limit = 2
+			fq.Offset = 2
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertFeed(t, second, f.aliceSQL, f.bobGo)
+	})
+
+	t.Run("should break ties by id", func(t *testing.T) {
+		f := setup(t)
+		tied := createTestPost(t, f.db, f.alice, "same time", "as my notes", []string{}, base)
+
+		feed, err := f.posts.GetUserFeed(testContext(t), f.viewer, feedQuery(func(fq *PaginatedFeedQuery) {
+			fq.Limit = 2
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertFeed(t, feed, tied, f.own)
+	})
+
+	t.Run("should count comments and reactions without duplicates", func(t *testing.T) {
+		f := setup(t)
+		createTestComment(t, f.db, f.own, f.alice, "nice")
+		createTestComment(t, f.db, f.own, f.bob, "great")
+		createTestReaction(t, f.db, f.own, f.alice)
+
+		feed, err := f.posts.GetUserFeed(testContext(t), f.viewer, feedQuery(func(fq *PaginatedFeedQuery) {
+			fq.Limit = 1
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertFeed(t, feed, f.own)
+		if feed[0].CommentCount != 2 || feed[0].ReactionCount != 1 {
+			t.Errorf("expected 2 comments and 1 reaction, got %d and %d", feed[0].CommentCount, feed[0].ReactionCount)
+		}
+		if feed[0].User.Username != "viewer" {
+			t.Errorf("expected author viewer, got %q", feed[0].User.Username)
+		}
+	})
+
+	t.Run("should leave out unpublished posts and posts the viewer can't see", func(t *testing.T) {
+		f := setup(t)
+		setPostState(t, f.db, f.own, PostDraft, VisibilityPublic)
+		setPostState(t, f.db, f.aliceSQL, PostDraft, VisibilityPublic)
+		setPostState(t, f.db, f.bobGo, PostPublished, VisibilityPrivate)
+		setPostState(t, f.db, f.bobOld, PostPublished, VisibilityFollowers)
+
+		feed, err := f.posts.GetUserFeed(testContext(t), f.viewer, feedQuery())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertFeed(t, feed, f.aliceGo, f.bobOld)
+	})
+
+	t.Run("should return an empty feed", func(t *testing.T) {
+		db := newTestDB(t)
+		loner := createTestUser(t, db, "loner")
+
+		feed, err := (&PostStore{db: db}).GetUserFeed(testContext(t), loner, feedQuery())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if feed == nil || len(feed) != 0 {
+			t.Errorf("expected an empty feed, got %v", feed)
+		}
+	})
+}
+
+func setPostState(t *testing.T, db *sql.DB, postID int64, status, visibility string) {
+	t.Helper()
+
+	if _, err := db.Exec(`UPDATE posts SET status = $2, visibility = $3 WHERE id = $1`, postID, status, visibility); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func assertFeed(t *testing.T, feed []PostWithMetadata, ids ...int64) {
+	t.Helper()
+
+	got := make([]int64, len(feed))
+	for i, p := range feed {
+		got[i] = p.ID
+	}
+
+	if len(got) != len(ids) {
+		t.Fatalf("expected posts %v, got %v", ids, got)
+	}
+	for i := range ids {
+		if got[i] != ids[i] {
+			t.Fatalf("expected posts %v, got %v", ids, got)
+		}
+	}
+}
+
+func TestPostStore(t *testing.T) {
+	t.Run("should create posts with their tags, mentions and fan-out job", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &PostStore{db: db}
+		author := createTestUser(t, db, "author")
+		bob := createTestUser(t, db, "Bob")
+
+		post := &Post{UserID: author, Title: "hi", Content: "hello @bob and @ghost", Tags: []string{"go", "sql"}}
+		if err := s.Create(testContext(t), post); err != nil {
+			t.Fatal(err)
+		}
+
+		if post.ID == 0 || post.CreatedAt == "" {
+			t.Fatalf("expected the id and creation time to be set, got %+v", post)
+		}
+		if len(post.Mentions) != 1 || post.Mentions[0].UserID != bob {
+			t.Errorf("expected bob to be mentioned, got %v", post.Mentions)
+		}
+		if n := count(t, db, "tag_usages", "post_id = $1 AND comment_id IS NULL", post.ID); n != 2 {
+			t.Errorf("expected 2 tag usages, got %d", n)
+		}
+		if n := count(t, db, "timeline_jobs", "type = 'fanout' AND user_id = $1 AND target_id = $2", author, post.ID); n != 1 {
+			t.Errorf("expected a fan-out job, got %d", n)
+		}
+
+		got, err := s.GetByID(testContext(t), post.ID, author)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Title != "hi" || got.Version != 0 || len(got.Tags) != 2 {
+			t.Errorf("unexpected post %+v", got)
+		}
+		if len(got.Mentions) != 1 || got.Mentions[0].Username != "Bob" {
+			t.Errorf("expected bob to be mentioned, got %v", got.Mentions)
+		}
+	})
+
+	t.Run("should update with optimistic locking", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &PostStore{db: db}
+		author := createTestUser(t, db, "author")
+		createTestUser(t, db, "bob")
+		alice := createTestUser(t, db, "alice")
+
+		post := &Post{UserID: author, Title: "hi", Content: "hello @bob", Tags: []string{"go"}}
+		if err := s.Create(testContext(t), post); err != nil {
+			t.Fatal(err)
+		}
+
+		stale := *post
+
+		post.Content = "hello @alice"
+		post.Tags = []string{"rust"}
+		if err := s.Update(testContext(t), post, author); err != nil {
+			t.Fatal(err)
+		}
+
+		if post.Version != 1 {
+			t.Errorf("expected version 1, got %d", post.Version)
+		}
+		if len(post.Mentions) != 1 || post.Mentions[0].UserID != alice {
+			t.Errorf("expected only alice to be mentioned, got %v", post.Mentions)
+		}
+		if n := count(t, db, "tag_usages", "post_id = $1 AND tag = 'go'", post.ID); n != 0 {
+			t.Errorf("expected the removed tag to be dropped, got %d", n)
+		}
+
+		stale.Title = "conflict"
+		if err := s.Update(testContext(t), &stale, author); !errors.Is(err, ErrVersionConflict) {
+			t.Errorf("expected ErrVersionConflict for a stale version, got %v", err)
+		}
+
+		missing := Post{ID: post.ID + 1000, Title: "gone", Version: post.Version}
+		if err := s.Update(testContext(t), &missing, author); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound for a missing post, got %v", err)
+		}
+	})
+
+	t.Run("should only get posts the viewer can see", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &PostStore{db: db}
+		author := createTestUser(t, db, "author")
+		follower := createTestUser(t, db, "follower")
+		stranger := createTestUser(t, db, "stranger")
+		createTestFollow(t, db, follower, author)
+
+		create := func(status, visibility string) int64 {
+			post := &Post{UserID: author, Title: "hi", Content: "there", Tags: []string{}, Status: status, Visibility: visibility}
+			if status == PostScheduled {
+				publishAt := time.Now().Add(time.Hour)
+				post.PublishAt = &publishAt
+			}
+			if err := s.Create(testContext(t), post); err != nil {
+				t.Fatal(err)
+			}
+			return post.ID
+		}
+
+		tests := []struct {
+			name    string
+			post    int64
+			visible []int64
+		}{
+			{"public", create(PostPublished, VisibilityPublic), []int64{author, follower, stranger}},
+			{"followers", create(PostPublished, VisibilityFollowers), []int64{author, follower}},
+			{"private", create(PostPublished, VisibilityPrivate), []int64{author}},
+			{"draft", create(PostDraft, VisibilityPublic), []int64{author}},
+			{"scheduled", create(PostScheduled, VisibilityPublic), []int64{author}},
+		}
+
+		for _, tt := range tests {
+			for _, viewer := range []int64{author, follower, stranger} {
+				_, err := s.GetByID(testContext(t), tt.post, viewer)
+				if visible := slices.Contains(tt.visible, viewer); visible != (err == nil) {
+					t.Errorf("%s post: expected visible to %d to be %v, got %v", tt.name, viewer, visible, err)
+				}
+				if err != nil && !errors.Is(err, ErrNotFound) {
+					t.Errorf("%s post: expected ErrNotFound, got %v", tt.name, err)
+				}
+			}
+		}
+
+		if n := count(t, db, "timeline_jobs", "type = 'fanout' AND user_id = $1", author); n != 3 {
+			t.Errorf("expected fan-out jobs for the 3 published posts, got %d", n)
+		}
+	})
+
+	t.Run("should publish drafts and due scheduled posts", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &PostStore{db: db}
+		author := createTestUser(t, db, "author")
+
+		// Tests share a transaction, so NOW() wouldn't move between a create
+		// and an update
+		draftID := createTestPost(t, db, author, "draft", "soon", []string{}, time.Now().Add(-24*time.Hour))
+		setPostState(t, db, draftID, PostDraft, VisibilityPublic)
+
+		draft, err := s.GetByID(testContext(t), draftID, author)
+		if err != nil {
+			t.Fatal(err)
+		}
+		created := draft.CreatedAt
+
+		draft.Status = PostPublished
+		if err := s.Update(testContext(t), draft, author); err != nil {
+			t.Fatal(err)
+		}
+		if draft.CreatedAt == created {
+			t.Errorf("expected the post to be dated to its publication")
+		}
+		if n := count(t, db, "timeline_jobs", "type = 'fanout' AND target_id = $1", draft.ID); n != 1 {
+			t.Errorf("expected a fan-out job once published, got %d", n)
+		}
+
+		past, future := time.Now().Add(-time.Minute), time.Now().Add(time.Hour)
+		due := &Post{UserID: author, Title: "due", Content: "now", Tags: []string{}, Status: PostScheduled, PublishAt: &past}
+		later := &Post{UserID: author, Title: "later", Content: "not yet", Tags: []string{}, Status: PostScheduled, PublishAt: &future}
+		for _, p := range []*Post{due, later} {
+			if err := s.Create(testContext(t), p); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		published, err := s.PublishDue(testContext(t), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(published) != 1 || published[0].ID != due.ID || published[0].Status != PostPublished {
+			t.Fatalf("expected only post %d to be published, got %+v", due.ID, published)
+		}
+		if n := count(t, db, "timeline_jobs", "type = 'fanout' AND target_id = $1", due.ID); n != 1 {
+			t.Errorf("expected a fan-out job for the due post, got %d", n)
+		}
+		if n := count(t, db, "posts", "id = $1 AND status = 'scheduled'", later.ID); n != 1 {
+			t.Errorf("expected the later post to stay scheduled")
+		}
+	})
+
+	t.Run("should delete posts", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &PostStore{db: db}
+		author := createTestUser(t, db, "author")
+		postID := createTestPost(t, db, author, "hi", "there", []string{}, time.Now())
+
+		if err := s.Delete(testContext(t), postID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.GetByID(testContext(t), postID, author); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+		if err := s.Delete(testContext(t), postID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("should return feed candidates with affinity", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &PostStore{db: db}
+		now := time.Now()
+
+		viewer := createTestUser(t, db, "viewer")
+		alice := createTestUser(t, db, "alice")
+		carol := createTestUser(t, db, "carol")
+		createTestFollow(t, db, viewer, alice)
+
+		own := createTestPost(t, db, viewer, "mine", "own post", []string{}, now.Add(-2*time.Hour))
+		recent := createTestPost(t, db, alice, "recent", "alice post", []string{}, now.Add(-time.Hour))
+		createTestPost(t, db, alice, "old", "outside the window", []string{}, now.Add(-10*24*time.Hour))
+		createTestPost(t, db, carol, "stranger", "not followed", []string{}, now)
+
+		createTestComment(t, db, recent, viewer, "nice")
+		createTestReaction(t, db, recent, viewer)
+
+		candidates, err := s.GetFeedCandidates(testContext(t), viewer, PaginatedFeedQuery{Tags: []string{}}, now.Add(-7*24*time.Hour), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(candidates) != 2 || candidates[0].ID != recent || candidates[1].ID != own {
+			t.Fatalf("expected posts %d and %d, got %+v", recent, own, candidates)
+		}
+		if c := candidates[0]; c.Affinity != 2 || c.CommentCount != 1 || c.ReactionCount != 1 {
+			t.Errorf("expected affinity 2 with 1 comment and 1 reaction, got %+v", c)
+		}
+		if candidates[1].Affinity != 0 {
+			t.Errorf("expected no affinity with yourself, got %d", candidates[1].Affinity)
+		}
+	})
+}
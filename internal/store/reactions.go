@@ -0,0 +1,50 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+)
+
+var ReactionTypes = []string{"like", "love", "laugh", "wow", "sad", "angry"}
+
+type ReactionStore struct {
+	db *sql.DB
+}
+
+// Set records userID's reaction to a post, replacing any earlier one.
+func (s *ReactionStore) Set(ctx context.Context, postID, userID int64, reactionType string) error {
+	query := `
+		INSERT INTO reactions (post_id, user_id, type)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (post_id, user_id) DO UPDATE SET type = EXCLUDED.type, created_at = NOW()
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, postID, userID, reactionType)
+	return err
+}
+
+func (s *ReactionStore) Delete(ctx context.Context, postID, userID int64) error {
+	query := `DELETE FROM reactions WHERE post_id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, postID, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
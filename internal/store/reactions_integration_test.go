@@ -0,0 +1,37 @@
+//go:build integration
+
+package store
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReactionStore(t *testing.T) {
+	db := newTestDB(t)
+	s := &ReactionStore{db: db}
+	alice := createTestUser(t, db, "alice")
+	postID := createTestPost(t, db, alice, "hi", "there", []string{}, time.Now())
+
+	if err := s.Set(testContext(t), postID, alice, "like"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set(testContext(t), postID, alice, "love"); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(t, db, "reactions", "post_id = $1 AND user_id = $2 AND type = 'love'", postID, alice); n != 1 {
+		t.Error("expected the reaction to be replaced")
+	}
+
+	if err := s.Set(testContext(t), postID, alice, "meh"); err == nil {
+		t.Error("expected unknown reaction types to be rejected")
+	}
+
+	if err := s.Delete(testContext(t), postID, alice); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(testContext(t), postID, alice); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,315 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+const (
+	ReportSpam       = "spam"
+	ReportHarassment = "harassment"
+	ReportHate       = "hate"
+	ReportViolence   = "violence"
+	ReportNudity     = "nudity"
+	ReportOther      = "other"
+	// ReportFilter is for content flagged by a moderation filter when it was
+	// created, and has no reporter.
+	ReportFilter = "filter"
+
+	ModerationDismiss = "dismiss"
+	ModerationHide    = "hide"
+	ModerationDelete  = "delete"
+	ModerationSuspend = "suspend"
+)
+
+// Report is a user's report about a post, or about one of its comments when
+// CommentID is set.
+type Report struct {
+	ID         int64  `json:"id"`
+	ReporterID *int64 `json:"reporter_id"`
+	PostID     int64  `json:"post_id"`
+	CommentID  *int64 `json:"comment_id"`
+	Reason     string `json:"reason"`
+	Details    string `json:"details"`
+	Status     string `json:"status"`
+	CreatedAt  string `json:"created_at"`
+	// AuthorID is the author of the reported content, zero for comments
+	// whose author deleted their account.
+	AuthorID int64 `json:"-"`
+}
+
+// ReviewItem is reported content waiting for a moderator, with its open
+// reports summed up. Actions are taken on ReportID, the oldest of them.
+type ReviewItem struct {
+	ReportID        int64    `json:"report_id"`
+	PostID          int64    `json:"post_id"`
+	CommentID       *int64   `json:"comment_id"`
+	AuthorID        int64    `json:"author_id"`
+	AuthorUsername  string   `json:"author_username"`
+	Title           string   `json:"title,omitempty"`
+	Content         string   `json:"content"`
+	Hidden          bool     `json:"hidden"`
+	Reports         int      `json:"reports"`
+	Reasons         []string `json:"reasons"`
+	FirstReportedAt string   `json:"first_reported_at"`
+	LastReportedAt  string   `json:"last_reported_at"`
+}
+
+// ModerationAction is a moderator's decision on reported content, which
+// resolved its open reports.
+type ModerationAction struct {
+	ID          int64  `json:"id"`
+	ModeratorID int64  `json:"moderator_id"`
+	Action      string `json:"action"`
+	PostID      int64  `json:"post_id"`
+	CommentID   *int64 `json:"comment_id"`
+	AuthorID    int64  `json:"author_id"`
+	Reports     int    `json:"reports"`
+	Note        string `json:"note"`
+	CreatedAt   string `json:"created_at"`
+	// DeletedAttachments are the attachments of a deleted post, whose blobs
+	// are left to the caller.
+	DeletedAttachments []Attachment `json:"-"`
+}
+
+type ReportStore struct {
+	db *sql.DB
+}
+
+// Create stores the report and hides the content once hideThreshold users
+// have open reports about it, which it returns. It returns ErrNotFound when
+// the comment isn't on the post, and ErrConflict when the reporter already
+// has an open report about the content. A threshold of 0 never hides it.
+func (s *ReportStore) Create(ctx context.Context, r *Report, hideThreshold int) (bool, error) {
+	query := `
+		INSERT INTO reports (reporter_id, post_id, comment_id, reason, details)
+		SELECT $1::bigint, $2::bigint, $3::bigint, $4::varchar, $5::text
+		WHERE $3::bigint IS NULL OR EXISTS (SELECT 1 FROM comments WHERE id = $3 AND post_id = $2)
+		RETURNING id, status, created_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	hidden := false
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, query, r.ReporterID, r.PostID, r.CommentID, r.Reason, r.Details).Scan(&r.ID, &r.Status, &r.CreatedAt)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrNotFound
+			default:
+				if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+					return ErrConflict
+				}
+				return err
+			}
+		}
+
+		if hideThreshold <= 0 {
+			return nil
+		}
+
+		table, id := reportTarget(r.PostID, r.CommentID)
+
+		query := `
+			UPDATE ` + table + ` SET hidden_at = NOW()
+			WHERE id = $1 AND hidden_at IS NULL
+			AND (
+				SELECT COUNT(DISTINCT reporter_id) FROM reports
+				WHERE post_id = $2 AND comment_id IS NOT DISTINCT FROM $3::bigint AND status = 'open'
+			) >= $4
+		`
+
+		res, err := tx.ExecContext(ctx, query, id, r.PostID, r.CommentID, hideThreshold)
+		if err != nil {
+			return err
+		}
+
+		n, err := res.RowsAffected()
+		hidden = n > 0
+		return err
+	})
+
+	return hidden, err
+}
+
+// reportTarget returns the table and ID of the reported content.
+func reportTarget(postID int64, commentID *int64) (string, int64) {
+	if commentID != nil {
+		return "comments", *commentID
+	}
+	return "posts", postID
+}
+
+// Queue returns a page of the content with open reports, the most reported
+// first and then the longest waiting.
+func (s *ReportStore) Queue(ctx context.Context, limit, offset int) ([]ReviewItem, error) {
+	query := `
+		SELECT MIN(r.id), r.post_id, r.comment_id, COALESCE(u.id, 0), COALESCE(u.username, ''),
+		CASE WHEN r.comment_id IS NULL THEN p.title ELSE '' END,
+		COALESCE(c.content, p.content),
+		CASE WHEN r.comment_id IS NULL THEN p.hidden_at ELSE c.hidden_at END IS NOT NULL,
+		COUNT(*), array_agg(DISTINCT r.reason), MIN(r.created_at), MAX(r.created_at)
+		FROM reports r
+		JOIN posts p ON p.id = r.post_id
+		LEFT JOIN comments c ON c.id = r.comment_id
+		LEFT JOIN users u ON u.id = CASE WHEN r.comment_id IS NULL THEN p.user_id ELSE c.user_id END
+		WHERE r.status = 'open'
+		GROUP BY r.post_id, r.comment_id, p.id, c.id, u.id
+		ORDER BY COUNT(*) DESC, MIN(r.created_at), MIN(r.id)
+		LIMIT $1 OFFSET $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []ReviewItem{}
+	for rows.Next() {
+		var i ReviewItem
+		if err := rows.Scan(&i.ReportID, &i.PostID, &i.CommentID, &i.AuthorID, &i.AuthorUsername, &i.Title, &i.Content, &i.Hidden, &i.Reports, pq.Array(&i.Reasons), &i.FirstReportedAt, &i.LastReportedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func (s *ReportStore) GetByID(ctx context.Context, id int64) (*Report, error) {
+	query := `
+		SELECT r.id, r.reporter_id, r.post_id, r.comment_id, r.reason, r.details, r.status, r.created_at,
+		CASE WHEN r.comment_id IS NULL THEN p.user_id ELSE COALESCE(c.user_id, 0) END
+		FROM reports r
+		JOIN posts p ON p.id = r.post_id
+		LEFT JOIN comments c ON c.id = r.comment_id
+		WHERE r.id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var r Report
+	err := s.db.QueryRowContext(ctx, query, id).Scan(&r.ID, &r.ReporterID, &r.PostID, &r.CommentID, &r.Reason, &r.Details, &r.Status, &r.CreatedAt, &r.AuthorID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &r, nil
+}
+
+// Resolve takes the moderator's action on the content of an open report,
+// resolves every open report about it and logs the action. Dismissing
+// reports unhides the content, and suspending its author hides it. It
+// returns ErrNotFound when the report is missing or was already resolved.
+func (s *ReportStore) Resolve(ctx context.Context, reportID, moderatorID int64, action, note string) (*ModerationAction, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	a := &ModerationAction{ModeratorID: moderatorID, Action: action, Note: note}
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `
+			SELECT r.post_id, r.comment_id, CASE WHEN r.comment_id IS NULL THEN p.user_id ELSE COALESCE(c.user_id, 0) END
+			FROM reports r
+			JOIN posts p ON p.id = r.post_id
+			LEFT JOIN comments c ON c.id = r.comment_id
+			WHERE r.id = $1 AND r.status = 'open'
+			FOR UPDATE OF r
+		`
+
+		err := tx.QueryRowContext(ctx, query, reportID).Scan(&a.PostID, &a.CommentID, &a.AuthorID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		query = `
+			UPDATE reports SET status = 'resolved', resolved_at = NOW()
+			WHERE post_id = $1 AND comment_id IS NOT DISTINCT FROM $2::bigint AND status = 'open'
+		`
+
+		res, err := tx.ExecContext(ctx, query, a.PostID, a.CommentID)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		a.Reports = int(n)
+
+		if err := s.apply(ctx, tx, a); err != nil {
+			return err
+		}
+
+		query = `
+			INSERT INTO moderation_actions (moderator_id, action, post_id, comment_id, author_id, reports, note)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			RETURNING id, created_at
+		`
+
+		return tx.QueryRowContext(ctx, query, a.ModeratorID, a.Action, a.PostID, a.CommentID, a.AuthorID, a.Reports, a.Note).Scan(&a.ID, &a.CreatedAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+func (s *ReportStore) apply(ctx context.Context, tx *sql.Tx, a *ModerationAction) error {
+	table, id := reportTarget(a.PostID, a.CommentID)
+
+	switch a.Action {
+	case ModerationDismiss:
+		_, err := tx.ExecContext(ctx, `UPDATE `+table+` SET hidden_at = NULL WHERE id = $1`, id)
+		return err
+
+	case ModerationHide:
+		_, err := tx.ExecContext(ctx, `UPDATE `+table+` SET hidden_at = COALESCE(hidden_at, NOW()) WHERE id = $1`, id)
+		return err
+
+	case ModerationSuspend:
+		if _, err := tx.ExecContext(ctx, `UPDATE `+table+` SET hidden_at = COALESCE(hidden_at, NOW()) WHERE id = $1`, id); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `UPDATE users SET suspended_at = COALESCE(suspended_at, NOW()) WHERE id = $1`, a.AuthorID)
+		return err
+
+	case ModerationDelete:
+		if a.CommentID == nil {
+			query := `SELECT ` + attachmentsColumn + ` FROM posts p WHERE p.id = $1`
+			if err := tx.QueryRowContext(ctx, query, id).Scan((*attachmentList)(&a.DeletedAttachments)); err != nil {
+				return err
+			}
+		}
+
+		_, err := tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE id = $1`, id)
+		return err
+	}
+
+	return fmt.Errorf("unknown moderation action %q", a.Action)
+}
@@ -0,0 +1,172 @@
+//go:build integration
+
+package store
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReportStore(t *testing.T) {
+	t.Run("should hide content reported by enough users", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &ReportStore{db: db}
+		alice := createTestUser(t, db, "alice")
+		bob := createTestUser(t, db, "bob")
+		carol := createTestUser(t, db, "carol")
+		postID := createTestPost(t, db, alice, "hi", "there", []string{}, time.Now())
+
+		hidden, err := s.Create(testContext(t), &Report{ReporterID: &bob, PostID: postID, Reason: ReportSpam}, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hidden {
+			t.Error("expected the post to stay visible after one report")
+		}
+
+		if _, err := s.Create(testContext(t), &Report{ReporterID: &bob, PostID: postID, Reason: ReportHate}, 2); !errors.Is(err, ErrConflict) {
+			t.Errorf("expected ErrConflict, got %v", err)
+		}
+
+		hidden, err = s.Create(testContext(t), &Report{ReporterID: &carol, PostID: postID, Reason: ReportSpam}, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !hidden {
+			t.Error("expected the post to be hidden after two reports")
+		}
+
+		posts := &PostStore{db: db}
+		if _, err := posts.GetByID(testContext(t), postID, bob); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected the hidden post to be not found, got %v", err)
+		}
+		if _, err := posts.GetByID(testContext(t), postID, alice); err != nil {
+			t.Errorf("expected the author to still see the post, got %v", err)
+		}
+
+		queue, err := s.Queue(testContext(t), 10, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(queue) != 1 || queue[0].PostID != postID || queue[0].Reports != 2 || !queue[0].Hidden || queue[0].AuthorID != alice {
+			t.Errorf("expected the post in the queue with 2 reports, got %+v", queue)
+		}
+	})
+
+	t.Run("should only report comments on the post", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &ReportStore{db: db}
+		alice := createTestUser(t, db, "alice")
+		bob := createTestUser(t, db, "bob")
+		postID := createTestPost(t, db, alice, "hi", "there", []string{}, time.Now())
+		otherID := createTestPost(t, db, alice, "other", "post", []string{}, time.Now())
+		commentID := createTestComment(t, db, otherID, alice, "nice")
+
+		if _, err := s.Create(testContext(t), &Report{ReporterID: &bob, PostID: postID, CommentID: &commentID, Reason: ReportSpam}, 1); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+
+		hidden, err := s.Create(testContext(t), &Report{ReporterID: &bob, PostID: otherID, CommentID: &commentID, Reason: ReportSpam}, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !hidden {
+			t.Error("expected the comment to be hidden")
+		}
+		if n := count(t, db, "comments", "id = $1 AND hidden_at IS NOT NULL", commentID); n != 1 {
+			t.Error("expected the comment to be hidden")
+		}
+		if n := count(t, db, "posts", "id = $1 AND hidden_at IS NULL", otherID); n != 1 {
+			t.Error("expected the post to stay visible")
+		}
+	})
+
+	t.Run("should dismiss reports and unhide the content", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &ReportStore{db: db}
+		alice := createTestUser(t, db, "alice")
+		bob := createTestUser(t, db, "bob")
+		mod := createTestUser(t, db, "mod")
+		postID := createTestPost(t, db, alice, "hi", "there", []string{}, time.Now())
+
+		r := &Report{ReporterID: &bob, PostID: postID, Reason: ReportSpam}
+		if _, err := s.Create(testContext(t), r, 1); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Create(testContext(t), &Report{PostID: postID, Reason: ReportFilter}, 1); err != nil {
+			t.Fatal(err)
+		}
+
+		a, err := s.Resolve(testContext(t), r.ID, mod, ModerationDismiss, "fine")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a.Reports != 2 || a.AuthorID != alice {
+			t.Errorf("expected 2 resolved reports about alice, got %+v", a)
+		}
+		if n := count(t, db, "posts", "id = $1 AND hidden_at IS NULL", postID); n != 1 {
+			t.Error("expected the post to be visible again")
+		}
+		if n := count(t, db, "reports", "post_id = $1 AND status = 'open'", postID); n != 0 {
+			t.Errorf("expected no open reports, got %d", n)
+		}
+		if n := count(t, db, "moderation_actions", "post_id = $1 AND moderator_id = $2 AND action = 'dismiss'", postID, mod); n != 1 {
+			t.Error("expected the action to be logged")
+		}
+
+		if _, err := s.Resolve(testContext(t), r.ID, mod, ModerationHide, ""); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound for a resolved report, got %v", err)
+		}
+	})
+
+	t.Run("should suspend the author", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &ReportStore{db: db}
+		alice := createTestUser(t, db, "alice")
+		bob := createTestUser(t, db, "bob")
+		mod := createTestUser(t, db, "mod")
+		postID := createTestPost(t, db, alice, "hi", "there", []string{}, time.Now())
+
+		r := &Report{ReporterID: &bob, PostID: postID, Reason: ReportHarassment}
+		if _, err := s.Create(testContext(t), r, 0); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := s.Resolve(testContext(t), r.ID, mod, ModerationSuspend, ""); err != nil {
+			t.Fatal(err)
+		}
+		if n := count(t, db, "posts", "id = $1 AND hidden_at IS NOT NULL", postID); n != 1 {
+			t.Error("expected the post to be hidden")
+		}
+
+		users := &UserStore{db: db}
+		if _, err := users.GetByID(testContext(t), alice); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected suspended users to be not found, got %v", err)
+		}
+	})
+
+	t.Run("should delete the content", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &ReportStore{db: db}
+		alice := createTestUser(t, db, "alice")
+		bob := createTestUser(t, db, "bob")
+		mod := createTestUser(t, db, "mod")
+		postID := createTestPost(t, db, alice, "hi", "there", []string{}, time.Now())
+
+		r := &Report{ReporterID: &bob, PostID: postID, Reason: ReportViolence}
+		if _, err := s.Create(testContext(t), r, 0); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := s.Resolve(testContext(t), r.ID, mod, ModerationDelete, "graphic"); err != nil {
+			t.Fatal(err)
+		}
+		if n := count(t, db, "posts", "id = $1", postID); n != 0 {
+			t.Error("expected the post to be deleted")
+		}
+		if n := count(t, db, "moderation_actions", "post_id = $1 AND action = 'delete'", postID); n != 1 {
+			t.Error("expected the action to be logged")
+		}
+	})
+}
@@ -0,0 +1,113 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// Repost shares a post with the reposter's followers, with an optional
+// quote.
+type Repost struct {
+	ID        int64  `json:"id"`
+	UserID    int64  `json:"user_id"`
+	PostID    int64  `json:"post_id"`
+	Quote     string `json:"quote"`
+	CreatedAt string `json:"created_at"`
+	User      User   `json:"user"`
+}
+
+// repostColumn selects the repost rp, by the user ru, that put a post in a
+// feed as a JSON object, or NULL for posts that are there on their own.
+// It's scanned with repostRef.
+const repostColumn = `
+	CASE WHEN rp.id IS NULL THEN NULL ELSE json_build_object(
+		'id', rp.id, 'user_id', rp.user_id, 'post_id', rp.post_id,
+		'quote', rp.quote, 'created_at', rp.created_at,
+		'user', json_build_object('id', ru.id, 'username', ru.username)
+	) END
+`
+
+// repostRef scans repostColumn into the repost it points to.
+type repostRef struct {
+	repost **Repost
+}
+
+func (r repostRef) Scan(src any) error {
+	if src == nil {
+		*r.repost = nil
+		return nil
+	}
+
+	data, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("unexpected repost type %T", src)
+	}
+
+	var repost Repost
+	if err := json.Unmarshal(data, &repost); err != nil {
+		return err
+	}
+
+	*r.repost = &repost
+	return nil
+}
+
+type RepostStore struct {
+	db *sql.DB
+}
+
+// Create reposts a published post. It returns ErrNotFound when the post
+// isn't published, and ErrConflict when the user already reposted it.
+func (s *RepostStore) Create(ctx context.Context, r *Repost) error {
+	query := `
+		INSERT INTO reposts (user_id, post_id, quote)
+		SELECT $1::bigint, id, $3::text FROM posts WHERE id = $2 AND status = 'published'
+		RETURNING id, created_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, query, r.UserID, r.PostID, r.Quote).Scan(&r.ID, &r.CreatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrNotFound
+		default:
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+				return ErrConflict
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *RepostStore) Delete(ctx context.Context, userID, postID int64) error {
+	query := `DELETE FROM reposts WHERE user_id = $1 AND post_id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, userID, postID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
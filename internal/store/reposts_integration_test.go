@@ -0,0 +1,112 @@
+//go:build integration
+
+package store
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRepostStore(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	feedQuery := PaginatedFeedQuery{Limit: 20, Sort: "desc", Tags: []string{}}
+
+	repost := func(t *testing.T, s *RepostStore, userID, postID int64, quote string, at time.Time) *Repost {
+		t.Helper()
+
+		r := &Repost{UserID: userID, PostID: postID, Quote: quote}
+		if err := s.Create(testContext(t), r); err != nil {
+			t.Fatal(err)
+		}
+		// NOW() is frozen for the whole test transaction.
+		if _, err := s.db.Exec(`UPDATE reposts SET created_at = $2 WHERE id = $1`, r.ID, at); err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	t.Run("should repost published posts once", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &RepostStore{db: db}
+		alice := createTestUser(t, db, "alice")
+		bob := createTestUser(t, db, "bob")
+		postID := createTestPost(t, db, alice, "hi", "there", []string{}, base)
+		draftID := createTestPost(t, db, alice, "draft", "not yet", []string{}, base)
+		setPostState(t, db, draftID, PostDraft, VisibilityPublic)
+
+		if err := s.Create(testContext(t), &Repost{UserID: bob, PostID: postID}); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Create(testContext(t), &Repost{UserID: bob, PostID: postID}); !errors.Is(err, ErrConflict) {
+			t.Errorf("expected ErrConflict, got %v", err)
+		}
+		if err := s.Create(testContext(t), &Repost{UserID: bob, PostID: draftID}); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound for a draft, got %v", err)
+		}
+
+		if err := s.Delete(testContext(t), bob, postID); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Delete(testContext(t), bob, postID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("should put reposts in followers' feeds", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &RepostStore{db: db}
+		posts := &PostStore{db: db}
+		timelines := &TimelineStore{db: db}
+		viewer := createTestUser(t, db, "viewer")
+		alice := createTestUser(t, db, "alice")
+		bob := createTestUser(t, db, "bob")
+		carol := createTestUser(t, db, "carol")
+		createTestFollow(t, db, viewer, alice)
+		createTestFollow(t, db, viewer, bob)
+
+		bobPost := createTestPost(t, db, bob, "bob", "followed", []string{}, base.Add(-time.Hour))
+		carolPost := createTestPost(t, db, carol, "carol", "not followed", []string{}, base.Add(-2*time.Hour))
+		carolPrivate := createTestPost(t, db, carol, "secret", "private", []string{}, base.Add(-3*time.Hour))
+		setPostState(t, db, carolPrivate, PostPublished, VisibilityPrivate)
+
+		r := repost(t, s, alice, carolPost, "worth a read", base)
+		repost(t, s, alice, bobPost, "", base.Add(time.Minute))
+		repost(t, s, alice, carolPrivate, "", base.Add(2*time.Minute))
+
+		feed, err := posts.GetUserFeed(testContext(t), viewer, feedQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertFeed(t, feed, bobPost, carolPost)
+
+		if feed[0].RepostedBy == nil || feed[0].RepostedBy.UserID != alice {
+			t.Errorf("expected bob's post once, reposted by alice, got %+v", feed[0].RepostedBy)
+		}
+		by := feed[1].RepostedBy
+		if by == nil || by.ID != r.ID || by.User.Username != "alice" || by.Quote != "worth a read" {
+			t.Errorf("expected carol's post to be attributed to alice, got %+v", by)
+		}
+		if feed[1].RepostCount != 1 {
+			t.Errorf("expected 1 repost, got %d", feed[1].RepostCount)
+		}
+
+		timeline, err := timelines.Get(testContext(t), viewer, 20, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertFeed(t, timeline, bobPost, carolPost)
+		if timeline[1].RepostedBy == nil || timeline[1].RepostedBy.ID != r.ID {
+			t.Errorf("expected carol's post to be attributed to alice, got %+v", timeline[1].RepostedBy)
+		}
+
+		own, err := posts.GetUserFeed(testContext(t), bob, feedQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertFeed(t, own, bobPost)
+		if own[0].RepostedBy != nil {
+			t.Errorf("expected bob's own post not to be a repost for him, got %+v", own[0].RepostedBy)
+		}
+	})
+}
@@ -0,0 +1,89 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// PostRevision is a version of a post's title, content and tags. A revision
+// is saved with every version of a post, including the current one.
+type PostRevision struct {
+	PostID  int64    `json:"post_id"`
+	Version int      `json:"version"`
+	Title   string   `json:"title"`
+	Content string   `json:"content"`
+	Tags    []string `json:"tags"`
+	// EditedBy is the user who saved the version, nil once they're deleted.
+	EditedBy  *int64 `json:"edited_by"`
+	CreatedAt string `json:"created_at"`
+}
+
+type RevisionStore struct {
+	db *sql.DB
+}
+
+// GetByPostID returns the revisions of a post, newest first.
+func (s *RevisionStore) GetByPostID(ctx context.Context, postID int64) ([]PostRevision, error) {
+	query := `
+		SELECT post_id, version, title, content, tags, edited_by, created_at
+		FROM post_revisions WHERE post_id = $1
+		ORDER BY version DESC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []PostRevision{}
+	for rows.Next() {
+		var r PostRevision
+		if err := rows.Scan(&r.PostID, &r.Version, &r.Title, &r.Content, pq.Array(&r.Tags), &r.EditedBy, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, r)
+	}
+
+	return revisions, rows.Err()
+}
+
+func (s *RevisionStore) Get(ctx context.Context, postID int64, version int) (*PostRevision, error) {
+	query := `
+		SELECT post_id, version, title, content, tags, edited_by, created_at
+		FROM post_revisions WHERE post_id = $1 AND version = $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var r PostRevision
+	err := s.db.QueryRowContext(ctx, query, postID, version).Scan(&r.PostID, &r.Version, &r.Title, &r.Content, pq.Array(&r.Tags), &r.EditedBy, &r.CreatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &r, nil
+}
+
+// saveRevision records the current version of the post, saved by editorID.
+func saveRevision(ctx context.Context, tx *sql.Tx, post *Post, editorID int64) error {
+	query := `
+		INSERT INTO post_revisions (post_id, version, title, content, tags, edited_by)
+		VALUES ($1, $2, $3, $4, COALESCE($5::varchar(100)[], '{}'), $6)
+	`
+
+	_, err := tx.ExecContext(ctx, query, post.ID, post.Version, post.Title, post.Content, pq.Array(post.Tags), editorID)
+	return err
+}
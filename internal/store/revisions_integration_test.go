@@ -0,0 +1,108 @@
+//go:build integration
+
+package store
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestRevisionStore(t *testing.T) {
+	t.Run("should keep every version of a post", func(t *testing.T) {
+		db := newTestDB(t)
+		posts := &PostStore{db: db}
+		s := &RevisionStore{db: db}
+		author := createTestUser(t, db, "author")
+		moderator := createTestUser(t, db, "moderator")
+
+		post := &Post{UserID: author, Title: "hi", Content: "first draft"}
+		if err := posts.Create(testContext(t), post); err != nil {
+			t.Fatal(err)
+		}
+
+		post.Content = "second draft"
+		post.Tags = []string{"go"}
+		if err := posts.Update(testContext(t), post, moderator); err != nil {
+			t.Fatal(err)
+		}
+
+		revisions, err := s.GetByPostID(testContext(t), post.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(revisions) != 2 || revisions[0].Version != 1 || revisions[1].Version != 0 {
+			t.Fatalf("expected versions 1 and 0, got %+v", revisions)
+		}
+		if revisions[1].Content != "first draft" || len(revisions[1].Tags) != 0 || *revisions[1].EditedBy != author {
+			t.Errorf("unexpected first revision %+v", revisions[1])
+		}
+		if revisions[0].Content != "second draft" || !slices.Equal(revisions[0].Tags, []string{"go"}) || *revisions[0].EditedBy != moderator {
+			t.Errorf("unexpected second revision %+v", revisions[0])
+		}
+
+		got, err := s.Get(testContext(t), post.ID, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Content != "first draft" {
+			t.Errorf("expected the first draft, got %q", got.Content)
+		}
+
+		if _, err := s.Get(testContext(t), post.ID, 2); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound for a missing version, got %v", err)
+		}
+	})
+
+	t.Run("should not save a revision for a stale update", func(t *testing.T) {
+		db := newTestDB(t)
+		posts := &PostStore{db: db}
+		author := createTestUser(t, db, "author")
+
+		post := &Post{UserID: author, Title: "hi", Content: "there"}
+		if err := posts.Create(testContext(t), post); err != nil {
+			t.Fatal(err)
+		}
+
+		stale := *post
+		if err := posts.Update(testContext(t), post, author); err != nil {
+			t.Fatal(err)
+		}
+		if err := posts.Update(testContext(t), &stale, author); !errors.Is(err, ErrVersionConflict) {
+			t.Fatalf("expected ErrVersionConflict, got %v", err)
+		}
+
+		if n := count(t, db, "post_revisions", "post_id = $1", post.ID); n != 2 {
+			t.Errorf("expected 2 revisions, got %d", n)
+		}
+	})
+
+	t.Run("should forget the editor once deleted", func(t *testing.T) {
+		db := newTestDB(t)
+		posts := &PostStore{db: db}
+		s := &RevisionStore{db: db}
+		author := createTestUser(t, db, "author")
+		moderator := createTestUser(t, db, "moderator")
+
+		post := &Post{UserID: author, Title: "hi", Content: "there"}
+		if err := posts.Create(testContext(t), post); err != nil {
+			t.Fatal(err)
+		}
+		if err := posts.Update(testContext(t), post, moderator); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := db.Exec(`DELETE FROM users WHERE id = $1`, moderator); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := s.Get(testContext(t), post.ID, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.EditedBy != nil {
+			t.Errorf("expected no editor, got %d", *got.EditedBy)
+		}
+	})
+}
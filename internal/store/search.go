@@ -0,0 +1,307 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/lib/pq"
+)
+
+// SearchLanguages maps the languages search can be run in to their Postgres
+// text search configuration. Every configuration needs its own indexes, see
+// migration 000018.
+var SearchLanguages = map[string]string{
+	"en": "english",
+	"es": "spanish",
+}
+
+const (
+	SearchPosts    = "posts"
+	SearchComments = "comments"
+	SearchUsers    = "users"
+
+	// Snippets are delimited with control characters by Postgres and turned
+	// into <mark> tags once the rest of the text has been escaped.
+	highlightStart = "\x01"
+	highlightStop  = "\x02"
+
+	headlineOptions = `StartSel="` + highlightStart + `", StopSel="` + highlightStop + `", MaxWords=35, MinWords=15, MaxFragments=2`
+)
+
+type SearchQuery struct {
+	Query    string     `json:"q" validate:"required,min=2,max=100"`
+	Type     string     `json:"type" validate:"omitempty,oneof=posts comments users"`
+	Author   string     `json:"author" validate:"max=255"`
+	Tag      string     `json:"tag" validate:"max=100"`
+	Since    *time.Time `json:"since"`
+	Until    *time.Time `json:"until"`
+	Language string     `json:"lang" validate:"required,oneof=en es"`
+	Limit    int        `json:"limit" validate:"gte=1,lte=50"`
+	Offset   int        `json:"offset" validate:"gte=0"`
+}
+
+func (q SearchQuery) Parse(r *http.Request) (SearchQuery, error) {
+	qs := r.URL.Query()
+
+	q.Query = strings.TrimSpace(qs.Get("q"))
+	q.Type = qs.Get("type")
+	q.Author = qs.Get("author")
+	q.Tag = qs.Get("tag")
+
+	if lang := qs.Get("lang"); lang != "" {
+		q.Language = lang
+	}
+
+	if limit := qs.Get("limit"); limit != "" {
+		l, err := ParseIntParam("limit", limit)
+		if err != nil {
+			return q, err
+		}
+		q.Limit = l
+	}
+
+	if offset := qs.Get("offset"); offset != "" {
+		o, err := ParseIntParam("offset", offset)
+		if err != nil {
+			return q, err
+		}
+		q.Offset = o
+	}
+
+	if since := qs.Get("since"); since != "" {
+		t, err := parseSearchTime(since)
+		if err != nil {
+			return q, &ParamError{Param: "since", Rule: "datetime", Want: "a date like 2006-01-02 or an RFC 3339 timestamp", Err: err}
+		}
+		q.Since = &t
+	}
+
+	if until := qs.Get("until"); until != "" {
+		t, err := parseSearchTime(until)
+		if err != nil {
+			return q, &ParamError{Param: "until", Rule: "datetime", Want: "a date like 2006-01-02 or an RFC 3339 timestamp", Err: err}
+		}
+		q.Until = &t
+	}
+
+	return q, nil
+}
+
+// parseSearchTime accepts either a date or a full RFC 3339 timestamp.
+func parseSearchTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+
+	return time.Parse(time.RFC3339, s)
+}
+
+type PostSearchResult struct {
+	ID           int64    `json:"id"`
+	Title        string   `json:"title"`
+	UserID       int64    `json:"user_id"`
+	Username     string   `json:"username"`
+	Tags         []string `json:"tags"`
+	CreatedAt    string   `json:"created_at"`
+	Rank         float64  `json:"rank"`
+	TitleSnippet string   `json:"title_snippet"`
+	Snippet      string   `json:"snippet"`
+}
+
+type CommentSearchResult struct {
+	ID        int64   `json:"id"`
+	PostID    int64   `json:"post_id"`
+	UserID    int64   `json:"user_id"`
+	Username  string  `json:"username"`
+	CreatedAt string  `json:"created_at"`
+	Rank      float64 `json:"rank"`
+	Snippet   string  `json:"snippet"`
+}
+
+type UserSearchResult struct {
+	ID       int64   `json:"id"`
+	Username string  `json:"username"`
+	Rank     float64 `json:"rank"`
+}
+
+type SearchStore struct {
+	db *sql.DB
+}
+
+// Posts searches the titles and content of public posts. Title matches rank
+// higher.
+func (s *SearchStore) Posts(ctx context.Context, q SearchQuery) ([]PostSearchResult, error) {
+	config := SearchLanguages[q.Language]
+	vector := fmt.Sprintf(`(setweight(to_tsvector('%[1]s', p.title), 'A') || setweight(to_tsvector('%[1]s', p.content), 'B'))`, config)
+
+	query := `
+		WITH q AS (SELECT websearch_to_tsquery('` + config + `', $1) AS query)
+		SELECT p.id, p.title, p.user_id, u.username, p.tags, p.created_at, r.rank,
+		ts_headline('` + config + `', p.title, q.query, '` + headlineOptions + `, HighlightAll=true'),
+		ts_headline('` + config + `', p.content, q.query, '` + headlineOptions + `')
+		FROM (
+			SELECT p.id, ts_rank(` + vector + `, q.query) AS rank
+			FROM posts p, q
+			WHERE ` + vector + ` @@ q.query AND ` + publicPost + `
+			AND ($2::text = '' OR p.user_id = (SELECT id FROM users WHERE lower(username) = lower($2)))
+			AND ($3::text = '' OR p.tags @> ARRAY[$3]::varchar[])
+			AND ($4::timestamptz IS NULL OR p.created_at >= $4)
+			AND ($5::timestamptz IS NULL OR p.created_at < $5)
+			ORDER BY rank DESC, p.id DESC
+			LIMIT $6 OFFSET $7
+		) r
+		JOIN posts p ON p.id = r.id
+		JOIN users u ON u.id = p.user_id
+		CROSS JOIN q
+		ORDER BY r.rank DESC, p.id DESC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, q.Query, q.Author, q.Tag, q.Since, q.Until, q.Limit, q.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []PostSearchResult{}
+	for rows.Next() {
+		var r PostSearchResult
+		if err := rows.Scan(&r.ID, &r.Title, &r.UserID, &r.Username, pq.Array(&r.Tags), &r.CreatedAt, &r.Rank, &r.TitleSnippet, &r.Snippet); err != nil {
+			return nil, err
+		}
+		r.TitleSnippet = highlight(r.TitleSnippet)
+		r.Snippet = highlight(r.Snippet)
+		results = append(results, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// Comments searches the content of visible comments on public posts. The
+// tag filter matches hashtags used in the comment itself.
+func (s *SearchStore) Comments(ctx context.Context, q SearchQuery) ([]CommentSearchResult, error) {
+	config := SearchLanguages[q.Language]
+	vector := `to_tsvector('` + config + `', c.content)`
+
+	query := `
+		WITH q AS (SELECT websearch_to_tsquery('` + config + `', $1) AS query)
+		SELECT c.id, c.post_id, COALESCE(c.user_id, 0), COALESCE(u.username, ''), c.created_at, r.rank,
+		ts_headline('` + config + `', c.content, q.query, '` + headlineOptions + `')
+		FROM (
+			SELECT c.id, ts_rank(` + vector + `, q.query) AS rank
+			FROM comments c JOIN posts p ON p.id = c.post_id, q
+			WHERE ` + vector + ` @@ q.query AND ` + publicPost + ` AND c.hidden_at IS NULL
+			AND ($2::text = '' OR c.user_id = (SELECT id FROM users WHERE lower(username) = lower($2)))
+			AND ($3::text = '' OR EXISTS (SELECT 1 FROM tag_usages tu WHERE tu.comment_id = c.id AND tu.tag = $3))
+			AND ($4::timestamptz IS NULL OR c.created_at >= $4)
+			AND ($5::timestamptz IS NULL OR c.created_at < $5)
+			ORDER BY rank DESC, c.id DESC
+			LIMIT $6 OFFSET $7
+		) r
+		JOIN comments c ON c.id = r.id
+		LEFT JOIN users u ON u.id = c.user_id
+		CROSS JOIN q
+		ORDER BY r.rank DESC, c.id DESC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, q.Query, q.Author, q.Tag, q.Since, q.Until, q.Limit, q.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []CommentSearchResult{}
+	for rows.Next() {
+		var r CommentSearchResult
+		if err := rows.Scan(&r.ID, &r.PostID, &r.UserID, &r.Username, &r.CreatedAt, &r.Rank, &r.Snippet); err != nil {
+			return nil, err
+		}
+		r.Snippet = highlight(r.Snippet)
+		results = append(results, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// Users searches usernames by prefix, so "ali" finds "alice". An exact match
+// comes first. The author, tag and date filters don't apply to users.
+func (s *SearchStore) Users(ctx context.Context, q SearchQuery) ([]UserSearchResult, error) {
+	prefix := prefixQuery(q.Query)
+	if prefix == "" {
+		return []UserSearchResult{}, nil
+	}
+
+	query := `
+		SELECT id, username, ts_rank(to_tsvector('simple', username), to_tsquery('simple', $1)) AS rank
+		FROM users
+		WHERE to_tsvector('simple', username) @@ to_tsquery('simple', $1) AND is_active AND suspended_at IS NULL
+		ORDER BY lower(username) = lower($2) DESC, rank DESC, username
+		LIMIT $3 OFFSET $4
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, prefix, q.Query, q.Limit, q.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []UserSearchResult{}
+	for rows.Next() {
+		var r UserSearchResult
+		if err := rows.Scan(&r.ID, &r.Username, &r.Rank); err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// prefixQuery turns free text into a tsquery matching words that start with
+// each of its terms. Anything but letters and digits separates terms, which
+// also keeps tsquery operators out of the query.
+func prefixQuery(s string) string {
+	terms := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for i, t := range terms {
+		terms[i] = t + ":*"
+	}
+
+	return strings.Join(terms, " & ")
+}
+
+// highlight escapes a ts_headline snippet for HTML and wraps the matches in
+// <mark> tags.
+func highlight(s string) string {
+	s = html.EscapeString(s)
+	s = strings.ReplaceAll(s, highlightStart, "<mark>")
+	return strings.ReplaceAll(s, highlightStop, "</mark>")
+}
@@ -0,0 +1,139 @@
+//go:build integration
+
+package store
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSearchStore(t *testing.T) {
+	search := func(mods ...func(*SearchQuery)) SearchQuery {
+		q := SearchQuery{Language: "en", Limit: 10}
+		for _, mod := range mods {
+			mod(&q)
+		}
+		return q
+	}
+
+	t.Run("should search posts with stemming and filters", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &SearchStore{db: db}
+		now := time.Now()
+		alice := createTestUser(t, db, "alice")
+		bob := createTestUser(t, db, "bob")
+
+		titled := createTestPost(t, db, alice, "Running Postgres", "tuning tips", []string{"ops"}, now)
+		body := createTestPost(t, db, alice, "Notes", "I run it & more", []string{}, now.Add(-time.Hour))
+		bobs := createTestPost(t, db, bob, "Running late", "sorry", []string{"ops"}, now.Add(-48*time.Hour))
+		createTestPost(t, db, bob, "Unrelated", "nothing here", []string{}, now)
+
+		results, err := s.Posts(testContext(t), search(func(q *SearchQuery) { q.Query = "run" }))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 3 || results[2].ID != body {
+			t.Fatalf("expected 3 matches with the content match last, got %+v", results)
+		}
+		for _, r := range results {
+			if r.ID == titled && !strings.Contains(r.TitleSnippet, "<mark>Running</mark>") {
+				t.Errorf("expected the title to be highlighted, got %q", r.TitleSnippet)
+			}
+			if r.ID == body && !strings.Contains(r.Snippet, "&amp;") {
+				t.Errorf("expected the snippet to be escaped, got %q", r.Snippet)
+			}
+		}
+
+		results, err = s.Posts(testContext(t), search(func(q *SearchQuery) { q.Query = "run"; q.Author = "ALICE"; q.Tag = "ops" }))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 || results[0].ID != titled {
+			t.Errorf("expected only alice's tagged post, got %+v", results)
+		}
+
+		since := now.Add(-72 * time.Hour)
+		until := now.Add(-24 * time.Hour)
+		results, err = s.Posts(testContext(t), search(func(q *SearchQuery) { q.Query = "run"; q.Since = &since; q.Until = &until }))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 || results[0].ID != bobs {
+			t.Errorf("expected only bob's older post, got %+v", results)
+		}
+	})
+
+	t.Run("should search comments by hashtag", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &SearchStore{db: db}
+		comments := &CommentStore{db: db}
+		alice := createTestUser(t, db, "alice")
+		postID := createTestPost(t, db, alice, "hi", "there", []string{}, time.Now())
+
+		tagged := &Comment{PostID: postID, UserID: alice, Content: "great tips on indexing #postgres"}
+		plain := &Comment{PostID: postID, UserID: alice, Content: "more indexes please"}
+		for _, c := range []*Comment{tagged, plain} {
+			if err := comments.Create(testContext(t), c); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		results, err := s.Comments(testContext(t), search(func(q *SearchQuery) { q.Query = "index" }))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 2 {
+			t.Fatalf("expected 2 matches, got %+v", results)
+		}
+
+		results, err = s.Comments(testContext(t), search(func(q *SearchQuery) { q.Query = "index"; q.Tag = "postgres" }))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 || results[0].ID != tagged.ID || results[0].Username != "alice" {
+			t.Errorf("expected the tagged comment, got %+v", results)
+		}
+	})
+
+	t.Run("should search active users by prefix", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &SearchStore{db: db}
+		alice := createTestUser(t, db, "alice")
+		alicia := createTestUser(t, db, "alicia")
+		alison := createTestUser(t, db, "alison")
+		alix := createTestUser(t, db, "alix")
+		createTestUser(t, db, "bob")
+
+		if _, err := db.Exec(`UPDATE users SET is_active = false WHERE id = $1`, alison); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Exec(`UPDATE users SET suspended_at = NOW() WHERE id = $1`, alix); err != nil {
+			t.Fatal(err)
+		}
+
+		results, err := s.Users(testContext(t), search(func(q *SearchQuery) { q.Query = "ali" }))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 2 || results[0].ID != alice || results[1].ID != alicia {
+			t.Fatalf("expected alice and alicia, got %+v", results)
+		}
+
+		results, err = s.Users(testContext(t), search(func(q *SearchQuery) { q.Query = "Alicia" }))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 || results[0].ID != alicia {
+			t.Errorf("expected alicia, got %+v", results)
+		}
+
+		results, err = s.Users(testContext(t), search(func(q *SearchQuery) { q.Query = "&!" }))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 0 {
+			t.Errorf("expected no results, got %+v", results)
+		}
+	})
+}
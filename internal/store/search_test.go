@@ -0,0 +1,28 @@
+package store
+
+import "testing"
+
+func TestHighlight(t *testing.T) {
+	got := highlight("use <b>\x01go\x02</b> & \x01postgres\x02")
+	want := "use &lt;b&gt;<mark>go</mark>&lt;/b&gt; &amp; <mark>postgres</mark>"
+
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestPrefixQuery(t *testing.T) {
+	tests := map[string]string{
+		"ali":          "ali:*",
+		"Bob_99":       "bob:* & 99:*",
+		"a & b | !c:*": "a:* & b:* & c:*",
+		"  ":           "",
+		"josé')--":     "josé:*",
+	}
+
+	for in, want := range tests {
+		if got := prefixQuery(in); got != want {
+			t.Errorf("prefixQuery(%q): expected %q, got %q", in, want, got)
+		}
+	}
+}
@@ -9,29 +9,46 @@ import (
 )
 
 var (
-	ErrNotFound          = errors.New("record not found")
-	ErrConflict          = errors.New("resource already exists")
-	ErrDuplicateEmail    = errors.New("duplicate email")
-	ErrDuplicateUsername = errors.New("duplicate username")
-	QueryTimeoutDuration = 5 * time.Second
+	ErrNotFound           = errors.New("record not found")
+	ErrConflict           = errors.New("resource already exists")
+	ErrDuplicateEmail     = errors.New("duplicate email")
+	ErrDuplicateUsername  = errors.New("duplicate username")
+	ErrVersionConflict    = errors.New("the record was changed by another request")
+	ErrUsernameCooldown   = errors.New("the username was changed too recently")
+	ErrTooManyAttachments = fmt.Errorf("a post can have at most %d attachments", MaxAttachments)
+	QueryTimeoutDuration  = 5 * time.Second
 )
 
 type Storage struct {
 	Posts interface {
 		Create(context.Context, *Post) error
-		GetByID(context.Context, int64) (*Post, error)
+		GetByID(context.Context, int64, int64) (*Post, error)
 		Delete(context.Context, int64) error
-		Update(context.Context, *Post) error
+		Update(context.Context, *Post, int64) error
+		PublishDue(context.Context, int) ([]Post, error)
 		GetUserFeed(context.Context, int64, PaginatedFeedQuery) ([]PostWithMetadata, error)
+		GetFeedCandidates(context.Context, int64, PaginatedFeedQuery, time.Time, int) ([]FeedCandidate, error)
 	}
 	Users interface {
 		Create(context.Context, *sql.Tx, *User) error
 		GetByID(context.Context, int64) (*User, error)
-		CreateAndInvite(context.Context, *User, string, time.Duration) error
-		Activate(context.Context, string) error
-		Delete(context.Context, int64) error
+		CreateAndInvite(context.Context, *User, string, time.Duration, *OutboxMessage) error
+		Activate(context.Context, string) (int64, error)
+		UpdateProfile(context.Context, *User, time.Duration, *EmailChange) error
+		ScheduleDeletion(context.Context, int64, time.Duration) (string, error)
+		CancelDeletion(context.Context, int64) error
+		GetDueDeletions(context.Context, int) ([]int64, error)
+		Delete(context.Context, int64) ([]string, error)
 		GetByEmail(context.Context, string) (*User, error)
 	}
+	Exports interface {
+		Request(context.Context, int64) (*Export, error)
+		ClaimDue(context.Context, int, time.Duration) ([]Export, error)
+		Archive(context.Context, int64) ([]byte, error)
+		MarkReady(context.Context, int64, string, int64, time.Duration) error
+		MarkFailed(context.Context, int64, string, time.Time, bool) error
+		DeleteExpired(context.Context, int) ([]string, error)
+	}
 	Comments interface {
 		GetByPostID(context.Context, int64) ([]Comment, error)
 		Create(context.Context, *Comment) error
@@ -39,19 +56,96 @@ type Storage struct {
 	Followers interface {
 		Follow(context.Context, int64, int64) error
 		Unfollow(context.Context, int64, int64) error
+		GetFollowing(context.Context, int64) ([]int64, error)
+		FollowersAmong(context.Context, int64, []int64) ([]int64, error)
 	}
 	Roles interface {
 		GetByName(context.Context, string) (*Role, error)
 	}
+	Search interface {
+		Posts(context.Context, SearchQuery) ([]PostSearchResult, error)
+		Comments(context.Context, SearchQuery) ([]CommentSearchResult, error)
+		Users(context.Context, SearchQuery) ([]UserSearchResult, error)
+	}
+	Timelines interface {
+		Get(context.Context, int64, int, int) ([]PostWithMetadata, error)
+		ClaimJobs(context.Context, int, time.Duration) ([]TimelineJob, error)
+		CompleteJob(context.Context, int64) error
+		FailJob(context.Context, int64, string, time.Time, bool) error
+		FanOut(context.Context, int64, int) (bool, error)
+		Backfill(context.Context, int64, int64, int) error
+		Remove(context.Context, int64, int64) error
+	}
+	Revisions interface {
+		GetByPostID(context.Context, int64) ([]PostRevision, error)
+		Get(context.Context, int64, int) (*PostRevision, error)
+	}
+	Attachments interface {
+		Create(context.Context, *Attachment) error
+		GetByID(context.Context, int64) (*Attachment, error)
+		Delete(context.Context, int64) error
+	}
+	Reactions interface {
+		Set(context.Context, int64, int64, string) error
+		Delete(context.Context, int64, int64) error
+	}
+	Bookmarks interface {
+		Add(context.Context, int64, int64) error
+		Delete(context.Context, int64, int64) error
+		List(context.Context, int64, BookmarkQuery) ([]PostWithMetadata, error)
+	}
+	Reposts interface {
+		Create(context.Context, *Repost) error
+		Delete(context.Context, int64, int64) error
+	}
+	Tags interface {
+		GetPosts(context.Context, string, PaginatedFeedQuery) ([]PostWithMetadata, error)
+		Trending(context.Context, time.Time, int) ([]TrendingTag, error)
+	}
+	Notifications interface {
+		Create(context.Context, *Notification) error
+		GetByUserID(context.Context, int64, NotificationQuery) ([]Notification, error)
+		CountUnread(context.Context, int64) (int, error)
+		MarkRead(context.Context, int64, []int64) (int64, error)
+		GetPreferences(context.Context, int64) ([]NotificationPreference, error)
+		SetPreference(context.Context, int64, NotificationPreference) error
+	}
+	Reports interface {
+		Create(context.Context, *Report, int) (bool, error)
+		Queue(context.Context, int, int) ([]ReviewItem, error)
+		GetByID(context.Context, int64) (*Report, error)
+		Resolve(context.Context, int64, int64, string, string) (*ModerationAction, error)
+	}
+	Outbox interface {
+		Enqueue(context.Context, *OutboxMessage) error
+		ClaimDue(context.Context, int, time.Duration) ([]OutboxMessage, error)
+		MarkSent(context.Context, int64) error
+		MarkFailed(context.Context, int64, string, time.Time, bool) error
+		GetByID(context.Context, int64) (*OutboxMessage, error)
+		List(context.Context, string, int, int) ([]OutboxMessage, error)
+		Retry(context.Context, int64) error
+	}
 }
 
 func NewStorage(db *sql.DB) Storage {
 	return Storage{
-		Posts:     &PostStore{db: db},
-		Users:     &UserStore{db: db},
-		Comments:  &CommentStore{db: db},
-		Followers: &FollowerStore{db: db},
-		Roles:     &RoleStore{db: db},
+		Posts:         &PostStore{db: db},
+		Users:         &UserStore{db: db},
+		Exports:       &ExportStore{db: db},
+		Comments:      &CommentStore{db: db},
+		Followers:     &FollowerStore{db: db},
+		Roles:         &RoleStore{db: db},
+		Reactions:     &ReactionStore{db: db},
+		Bookmarks:     &BookmarkStore{db: db},
+		Reposts:       &RepostStore{db: db},
+		Revisions:     &RevisionStore{db: db},
+		Attachments:   &AttachmentStore{db: db},
+		Timelines:     &TimelineStore{db: db},
+		Tags:          &TagStore{db: db},
+		Search:        &SearchStore{db: db},
+		Outbox:        &OutboxStore{db: db},
+		Notifications: &NotificationStore{db: db},
+		Reports:       &ReportStore{db: db},
 	}
 }
 
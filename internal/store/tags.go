@@ -0,0 +1,179 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type Mention struct {
+	UserID   int64  `json:"user_id"`
+	Username string `json:"username"`
+}
+
+type TrendingTag struct {
+	Tag   string `json:"tag"`
+	Uses  int    `json:"uses"`
+	Users int    `json:"users"`
+}
+
+type TagStore struct {
+	db *sql.DB
+}
+
+// GetPosts returns the public posts tagged with tag, either explicitly or
+// through a hashtag in their content.
+func (s *TagStore) GetPosts(ctx context.Context, tag string, fq PaginatedFeedQuery) ([]PostWithMetadata, error) {
+	query := `
+		SELECT p.id, p.title, p.content, p.user_id, p.tags, p.created_at, p.updated_at, p.version, p.status, p.visibility,
+		u.username,
+		(SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id) AS comment_count,
+		(SELECT COUNT(*) FROM reactions r WHERE r.post_id = p.id) AS reaction_count,
+		(SELECT COUNT(*) FROM reposts rc WHERE rc.post_id = p.id) AS repost_count,
+		` + attachmentsColumn + `
+		FROM posts p
+		JOIN users u ON u.id = p.user_id
+		WHERE p.tags @> ARRAY[$1]::varchar[] AND ` + publicPost + `
+		ORDER BY p.created_at ` + fq.Sort + `
+		LIMIT $2 OFFSET $3
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, tag, fq.Limit, fq.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []PostWithMetadata{}
+	for rows.Next() {
+		var p PostWithMetadata
+		if err := rows.Scan(&p.ID, &p.Title, &p.Content, &p.UserID, pq.Array(&p.Tags), &p.CreatedAt, &p.UpdatedAt, &p.Version, &p.Status, &p.Visibility, &p.User.Username, &p.CommentCount, &p.ReactionCount, &p.RepostCount, (*attachmentList)(&p.Attachments)); err != nil {
+			return nil, err
+		}
+		p.User.ID = p.UserID
+		posts = append(posts, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+// Trending returns the most used tags since the given time. Tags are ranked
+// by how many different users used them, so one user repeating a tag can't
+// push it to the top. Only tags used on public posts, or in their comments,
+// count.
+func (s *TagStore) Trending(ctx context.Context, since time.Time, limit int) ([]TrendingTag, error) {
+	query := `
+		SELECT tu.tag, COUNT(*) AS uses, COUNT(DISTINCT tu.user_id) AS users
+		FROM tag_usages tu
+		JOIN posts p ON p.id = tu.post_id
+		WHERE tu.created_at >= $1 AND ` + publicPost + `
+		GROUP BY tu.tag
+		ORDER BY users DESC, uses DESC, tag
+		LIMIT $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []TrendingTag{}
+	for rows.Next() {
+		var t TrendingTag
+		if err := rows.Scan(&t.Tag, &t.Uses, &t.Users); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// setTagUsages replaces the tags used by a post, or by one of its comments
+// when commentID is set. Usages that are kept keep their original time.
+func setTagUsages(ctx context.Context, tx *sql.Tx, postID int64, commentID *int64, userID int64, tags []string) error {
+	query := `
+		DELETE FROM tag_usages
+		WHERE post_id = $1 AND comment_id IS NOT DISTINCT FROM $2::bigint AND NOT (tag = ANY($3::varchar[]))
+	`
+
+	if _, err := tx.ExecContext(ctx, query, postID, commentID, pq.Array(tags)); err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO tag_usages (tag, post_id, comment_id, user_id)
+		SELECT t, $1::bigint, $2::bigint, $3::bigint FROM unnest($4::varchar[]) AS t
+		ON CONFLICT DO NOTHING
+	`
+
+	_, err := tx.ExecContext(ctx, query, postID, commentID, userID, pq.Array(tags))
+	return err
+}
+
+// setMentions replaces the users mentioned in a post, or in one of its
+// comments when commentID is set, and returns the ones that exist. Usernames
+// are matched case-insensitively and unknown ones are ignored.
+func setMentions(ctx context.Context, tx *sql.Tx, postID int64, commentID *int64, usernames []string) ([]Mention, error) {
+	query := `
+		DELETE FROM mentions
+		WHERE post_id = $1 AND comment_id IS NOT DISTINCT FROM $2::bigint
+		AND user_id NOT IN (SELECT id FROM users WHERE lower(username) = ANY($3::text[]))
+	`
+
+	if _, err := tx.ExecContext(ctx, query, postID, commentID, pq.Array(usernames)); err != nil {
+		return nil, err
+	}
+
+	query = `
+		INSERT INTO mentions (post_id, comment_id, user_id)
+		SELECT $1::bigint, $2::bigint, id FROM users WHERE lower(username) = ANY($3::text[])
+		ON CONFLICT DO NOTHING
+	`
+
+	if _, err := tx.ExecContext(ctx, query, postID, commentID, pq.Array(usernames)); err != nil {
+		return nil, err
+	}
+
+	query = `
+		SELECT u.id, u.username
+		FROM mentions m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.post_id = $1 AND m.comment_id IS NOT DISTINCT FROM $2::bigint
+		ORDER BY u.username
+	`
+
+	rows, err := tx.QueryContext(ctx, query, postID, commentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mentions := []Mention{}
+	for rows.Next() {
+		var m Mention
+		if err := rows.Scan(&m.UserID, &m.Username); err != nil {
+			return nil, err
+		}
+		mentions = append(mentions, m)
+	}
+
+	return mentions, rows.Err()
+}
@@ -0,0 +1,74 @@
+//go:build integration
+
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTagStore(t *testing.T) {
+	t.Run("should list tagged posts", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &TagStore{db: db}
+		now := time.Now()
+		alice := createTestUser(t, db, "alice")
+
+		older := createTestPost(t, db, alice, "older", "post", []string{"go"}, now.Add(-time.Hour))
+		newer := createTestPost(t, db, alice, "newer", "post", []string{"go", "sql"}, now)
+		createTestPost(t, db, alice, "untagged", "post", []string{"sql"}, now)
+
+		posts, err := s.GetPosts(testContext(t), "go", PaginatedFeedQuery{Limit: 10, Sort: "desc"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertFeed(t, posts, newer, older)
+
+		posts, err = s.GetPosts(testContext(t), "go", PaginatedFeedQuery{Limit: 1, Offset: 1, Sort: "desc"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertFeed(t, posts, older)
+	})
+
+	t.Run("should rank trending tags by users", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &TagStore{db: db}
+		posts := &PostStore{db: db}
+		alice := createTestUser(t, db, "alice")
+		bob := createTestUser(t, db, "bob")
+
+		for _, p := range []*Post{
+			{UserID: alice, Title: "a", Content: "a", Tags: []string{"go"}},
+			{UserID: bob, Title: "b", Content: "b", Tags: []string{"go"}},
+			{UserID: alice, Title: "c", Content: "c", Tags: []string{"sql"}},
+			{UserID: alice, Title: "d", Content: "d", Tags: []string{"sql"}},
+			{UserID: alice, Title: "e", Content: "e", Tags: []string{"sql"}},
+		} {
+			if err := posts.Create(testContext(t), p); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// Usages from before the window don't count.
+		oldID := createTestPost(t, db, bob, "old", "post", []string{}, time.Now().Add(-48*time.Hour))
+		if _, err := db.Exec(`INSERT INTO tag_usages (tag, post_id, user_id, created_at) VALUES ('sql', $1, $2, NOW() - interval '2 days')`, oldID, bob); err != nil {
+			t.Fatal(err)
+		}
+
+		tags, err := s.Trending(testContext(t), time.Now().Add(-time.Hour), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []TrendingTag{{Tag: "go", Uses: 2, Users: 2}, {Tag: "sql", Uses: 3, Users: 1}}
+		if len(tags) != len(want) {
+			t.Fatalf("expected %v, got %v", want, tags)
+		}
+		for i := range want {
+			if tags[i] != want[i] {
+				t.Fatalf("expected %v, got %v", want, tags)
+			}
+		}
+	})
+}
@@ -0,0 +1,273 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	TimelineJobFanout   = "fanout"
+	TimelineJobFollow   = "follow"
+	TimelineJobUnfollow = "unfollow"
+)
+
+// TimelineJob is queued work for the timeline worker. For fanout jobs UserID
+// is the post's author and TargetID the post; for follow and unfollow jobs
+// UserID is the follower and TargetID the followed user.
+type TimelineJob struct {
+	ID       int64  `json:"id"`
+	Type     string `json:"type"`
+	UserID   int64  `json:"user_id"`
+	TargetID int64  `json:"target_id"`
+	Attempts int    `json:"attempts"`
+}
+
+type TimelineStore struct {
+	db *sql.DB
+}
+
+func enqueueTimelineJob(ctx context.Context, tx *sql.Tx, jobType string, userID, targetID int64) error {
+	query := `INSERT INTO timeline_jobs (type, user_id, target_id) VALUES ($1, $2, $3)`
+
+	_, err := tx.ExecContext(ctx, query, jobType, userID, targetID)
+	return err
+}
+
+// Get returns a page of userID's home timeline, newest first. Materialized
+// entries are merged with the posts that weren't fanned out, which come from
+// authors with too many followers or are still waiting for the worker, and
+// with the posts reposted by userID and the users they follow, which aren't
+// fanned out. Each post is in the timeline at most once, at its latest
+// appearance. Posts that were made private since they were fanned out are
+// left out.
+func (s *TimelineStore) Get(ctx context.Context, userID int64, limit, offset int) ([]PostWithMetadata, error) {
+	query := `
+		WITH ids AS (
+			(
+				SELECT post_id, created_at, NULL::bigint AS repost_id FROM timeline_entries
+				WHERE user_id = $1
+				ORDER BY created_at DESC, post_id DESC
+				LIMIT $2 + $3
+			)
+			UNION
+			(
+				SELECT p.id, p.created_at, NULL FROM posts p
+				WHERE NOT p.fanned_out AND p.status = 'published'
+				AND (p.user_id = $1 OR p.user_id IN (SELECT user_id FROM followers WHERE follower_id = $1))
+				ORDER BY p.created_at DESC, p.id DESC
+				LIMIT $2 + $3
+			)
+			UNION
+			(
+				SELECT r.post_id, r.created_at, r.id FROM reposts r
+				WHERE r.user_id = $1 OR r.user_id IN (SELECT user_id FROM followers WHERE follower_id = $1)
+				ORDER BY r.created_at DESC, r.id DESC
+				LIMIT $2 + $3
+			)
+		), entries AS (
+			SELECT DISTINCT ON (post_id) post_id, created_at, repost_id
+			FROM ids
+			ORDER BY post_id, created_at DESC
+		)
+		SELECT p.id, p.title, p.content, p.user_id, p.tags, p.created_at, p.updated_at, p.version, p.status, p.visibility,
+		u.username,
+		(SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id) AS comment_count,
+		(SELECT COUNT(*) FROM reactions r WHERE r.post_id = p.id) AS reaction_count,
+		(SELECT COUNT(*) FROM reposts rc WHERE rc.post_id = p.id) AS repost_count,
+		` + attachmentsColumn + `,
+		` + repostColumn + `
+		FROM entries e
+		JOIN posts p ON p.id = e.post_id
+		JOIN users u ON u.id = p.user_id
+		LEFT JOIN reposts rp ON rp.id = e.repost_id
+		LEFT JOIN users ru ON ru.id = rp.user_id
+		WHERE p.status = 'published' AND ` + visibleTo("$1") + `
+		ORDER BY e.created_at DESC, p.id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	feed := []PostWithMetadata{}
+	for rows.Next() {
+		var p PostWithMetadata
+		if err := rows.Scan(&p.ID, &p.Title, &p.Content, &p.UserID, pq.Array(&p.Tags), &p.CreatedAt, &p.UpdatedAt, &p.Version, &p.Status, &p.Visibility, &p.User.Username, &p.CommentCount, &p.ReactionCount, &p.RepostCount, (*attachmentList)(&p.Attachments), repostRef{&p.RepostedBy}); err != nil {
+			return nil, err
+		}
+		p.User.ID = p.UserID
+		feed = append(feed, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return feed, nil
+}
+
+// ClaimJobs leases up to limit due jobs, the same way the email outbox does.
+func (s *TimelineStore) ClaimJobs(ctx context.Context, limit int, lease time.Duration) ([]TimelineJob, error) {
+	query := `
+		UPDATE timeline_jobs SET attempts = attempts + 1, next_attempt_at = $2
+		WHERE id IN (
+			SELECT id FROM timeline_jobs
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, type, user_id, target_id, attempts
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, limit, time.Now().Add(lease))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []TimelineJob{}
+	for rows.Next() {
+		var j TimelineJob
+		if err := rows.Scan(&j.ID, &j.Type, &j.UserID, &j.TargetID, &j.Attempts); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, j)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+func (s *TimelineStore) CompleteJob(ctx context.Context, id int64) error {
+	query := `DELETE FROM timeline_jobs WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// FailJob records a failed attempt. Dead jobs are kept for inspection and
+// not claimed again.
+func (s *TimelineStore) FailJob(ctx context.Context, id int64, lastErr string, nextAttemptAt time.Time, dead bool) error {
+	query := `
+		UPDATE timeline_jobs
+		SET status = CASE WHEN $4 THEN 'dead' ELSE 'pending' END, last_error = $2, next_attempt_at = $3
+		WHERE id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, id, lastErr, nextAttemptAt, dead)
+	return err
+}
+
+// FanOut copies a post into the timelines of its author and their followers
+// and reports whether it did. Posts by authors with more than threshold
+// followers are left to be merged in when timelines are read. Fanning out a
+// deleted post is a no-op.
+func (s *TimelineStore) FanOut(ctx context.Context, postID int64, threshold int) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	fannedOut := false
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `
+			SELECT p.fanned_out, u.followers_count
+			FROM posts p JOIN users u ON u.id = p.user_id
+			WHERE p.id = $1
+			FOR UPDATE OF p
+		`
+
+		var followers int
+		err := tx.QueryRowContext(ctx, query, postID).Scan(&fannedOut, &followers)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil || fannedOut || followers > threshold {
+			return err
+		}
+
+		query = `
+			INSERT INTO timeline_entries (user_id, post_id, author_id, created_at)
+			SELECT f.follower_id, p.id, p.user_id, p.created_at
+			FROM posts p JOIN followers f ON f.user_id = p.user_id
+			WHERE p.id = $1
+			UNION ALL
+			SELECT p.user_id, p.id, p.user_id, p.created_at
+			FROM posts p
+			WHERE p.id = $1
+			ON CONFLICT DO NOTHING
+		`
+
+		if _, err := tx.ExecContext(ctx, query, postID); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE posts SET fanned_out = true WHERE id = $1`, postID); err != nil {
+			return err
+		}
+
+		fannedOut = true
+		return nil
+	})
+
+	return fannedOut, err
+}
+
+// Backfill copies the latest limit posts of authorID into followerID's
+// timeline, as long as they still follow them.
+func (s *TimelineStore) Backfill(ctx context.Context, followerID, authorID int64, limit int) error {
+	query := `
+		INSERT INTO timeline_entries (user_id, post_id, author_id, created_at)
+		SELECT $1, p.id, p.user_id, p.created_at
+		FROM posts p
+		WHERE p.user_id = $2 AND p.status = 'published'
+		AND EXISTS (SELECT 1 FROM followers WHERE follower_id = $1 AND user_id = $2)
+		ORDER BY p.created_at DESC
+		LIMIT $3
+		ON CONFLICT DO NOTHING
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, followerID, authorID, limit)
+	return err
+}
+
+// Remove drops authorID's posts from followerID's timeline, unless they
+// followed them again in the meantime.
+func (s *TimelineStore) Remove(ctx context.Context, followerID, authorID int64) error {
+	query := `
+		DELETE FROM timeline_entries
+		WHERE user_id = $1 AND author_id = $2
+		AND NOT EXISTS (SELECT 1 FROM followers WHERE follower_id = $1 AND user_id = $2)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, followerID, authorID)
+	return err
+}
@@ -0,0 +1,190 @@
+//go:build integration
+
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimelineStore(t *testing.T) {
+	t.Run("should lease, fail and complete jobs", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &TimelineStore{db: db}
+		followers := &FollowerStore{db: db}
+		alice := createTestUser(t, db, "alice")
+		bob := createTestUser(t, db, "bob")
+
+		if err := followers.Follow(testContext(t), alice, bob); err != nil {
+			t.Fatal(err)
+		}
+		// NOW() is frozen for the whole test transaction.
+		if _, err := db.Exec(`UPDATE timeline_jobs SET next_attempt_at = NOW() - interval '1 minute'`); err != nil {
+			t.Fatal(err)
+		}
+
+		jobs, err := s.ClaimJobs(testContext(t), 10, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(jobs) != 1 {
+			t.Fatalf("expected 1 job, got %+v", jobs)
+		}
+		job := jobs[0]
+		if job.Type != TimelineJobFollow || job.UserID != alice || job.TargetID != bob || job.Attempts != 1 {
+			t.Errorf("unexpected job %+v", job)
+		}
+
+		if jobs, _ := s.ClaimJobs(testContext(t), 10, time.Minute); len(jobs) != 0 {
+			t.Errorf("expected leased jobs to be skipped, got %+v", jobs)
+		}
+
+		if err := s.FailJob(testContext(t), job.ID, "boom", time.Now().Add(-time.Minute), false); err != nil {
+			t.Fatal(err)
+		}
+		jobs, err = s.ClaimJobs(testContext(t), 10, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(jobs) != 1 || jobs[0].Attempts != 2 {
+			t.Fatalf("expected the failed job to be retried, got %+v", jobs)
+		}
+
+		if err := s.FailJob(testContext(t), job.ID, "boom", time.Now().Add(-time.Minute), true); err != nil {
+			t.Fatal(err)
+		}
+		if jobs, _ := s.ClaimJobs(testContext(t), 10, time.Minute); len(jobs) != 0 {
+			t.Errorf("expected dead jobs to be skipped, got %+v", jobs)
+		}
+
+		if err := s.CompleteJob(testContext(t), job.ID); err != nil {
+			t.Fatal(err)
+		}
+		if n := count(t, db, "timeline_jobs", "id = $1", job.ID); n != 0 {
+			t.Errorf("expected the job to be deleted")
+		}
+	})
+
+	t.Run("should fan out posts below the threshold", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &TimelineStore{db: db}
+		alice := createTestUser(t, db, "alice")
+		bob := createTestUser(t, db, "bob")
+		createTestFollow(t, db, bob, alice)
+		if _, err := db.Exec(`UPDATE users SET followers_count = 1 WHERE id = $1`, alice); err != nil {
+			t.Fatal(err)
+		}
+
+		postID := createTestPost(t, db, alice, "hi", "there", []string{}, time.Now())
+
+		fannedOut, err := s.FanOut(testContext(t), postID, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fannedOut {
+			t.Error("expected the post to be left for fan-out on read")
+		}
+		if n := count(t, db, "timeline_entries", "post_id = $1", postID); n != 0 {
+			t.Errorf("expected no entries, got %d", n)
+		}
+
+		fannedOut, err = s.FanOut(testContext(t), postID, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !fannedOut {
+			t.Error("expected the post to be fanned out")
+		}
+		if n := count(t, db, "timeline_entries", "post_id = $1 AND user_id IN ($2, $3)", postID, alice, bob); n != 2 {
+			t.Errorf("expected entries for the author and follower, got %d", n)
+		}
+		if n := count(t, db, "posts", "id = $1 AND fanned_out", postID); n != 1 {
+			t.Error("expected the post to be marked as fanned out")
+		}
+
+		if fannedOut, err := s.FanOut(testContext(t), -1, 10); err != nil || fannedOut {
+			t.Errorf("expected deleted posts to be skipped, got %v, %v", fannedOut, err)
+		}
+	})
+
+	t.Run("should merge posts that weren't fanned out", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &TimelineStore{db: db}
+		now := time.Now()
+		viewer := createTestUser(t, db, "viewer")
+		alice := createTestUser(t, db, "alice")
+		celebrity := createTestUser(t, db, "celebrity")
+		stranger := createTestUser(t, db, "stranger")
+		createTestFollow(t, db, viewer, alice)
+		createTestFollow(t, db, viewer, celebrity)
+
+		fanned := createTestPost(t, db, alice, "fanned", "out", []string{}, now.Add(-2*time.Hour))
+		if _, err := s.FanOut(testContext(t), fanned, 10); err != nil {
+			t.Fatal(err)
+		}
+		pending := createTestPost(t, db, celebrity, "too many", "followers", []string{}, now.Add(-time.Hour))
+		own := createTestPost(t, db, viewer, "own", "post", []string{}, now)
+		createTestPost(t, db, stranger, "stranger", "post", []string{}, now)
+
+		feed, err := s.Get(testContext(t), viewer, 10, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertFeed(t, feed, own, pending, fanned)
+
+		feed, err = s.Get(testContext(t), viewer, 1, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertFeed(t, feed, pending)
+	})
+
+	t.Run("should backfill and remove followed users' posts", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &TimelineStore{db: db}
+		now := time.Now()
+		viewer := createTestUser(t, db, "viewer")
+		alice := createTestUser(t, db, "alice")
+
+		oldest := createTestPost(t, db, alice, "1", "post", []string{}, now.Add(-3*time.Hour))
+		middle := createTestPost(t, db, alice, "2", "post", []string{}, now.Add(-2*time.Hour))
+		newest := createTestPost(t, db, alice, "3", "post", []string{}, now.Add(-time.Hour))
+
+		// Only current followers are backfilled.
+		if err := s.Backfill(testContext(t), viewer, alice, 2); err != nil {
+			t.Fatal(err)
+		}
+		if n := count(t, db, "timeline_entries", "user_id = $1", viewer); n != 0 {
+			t.Errorf("expected no entries, got %d", n)
+		}
+
+		createTestFollow(t, db, viewer, alice)
+		if err := s.Backfill(testContext(t), viewer, alice, 2); err != nil {
+			t.Fatal(err)
+		}
+		if n := count(t, db, "timeline_entries", "user_id = $1 AND post_id IN ($2, $3)", viewer, middle, newest); n != 2 {
+			t.Errorf("expected the 2 newest posts, got %d", n)
+		}
+		if n := count(t, db, "timeline_entries", "post_id = $1", oldest); n != 0 {
+			t.Errorf("expected the oldest post to be left out")
+		}
+
+		// Users who followed again keep their entries.
+		if err := s.Remove(testContext(t), viewer, alice); err != nil {
+			t.Fatal(err)
+		}
+		if n := count(t, db, "timeline_entries", "user_id = $1", viewer); n != 2 {
+			t.Errorf("expected entries to be kept, got %d", n)
+		}
+
+		if err := (&FollowerStore{db: db}).Unfollow(testContext(t), viewer, alice); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Remove(testContext(t), viewer, alice); err != nil {
+			t.Fatal(err)
+		}
+		if n := count(t, db, "timeline_entries", "user_id = $1", viewer); n != 0 {
+			t.Errorf("expected entries to be removed, got %d", n)
+		}
+	})
+}
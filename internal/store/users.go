@@ -7,18 +7,38 @@ import (
 	"encoding/hex"
 	"time"
 
+	"github.com/lib/pq"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type User struct {
-	ID        int64    `json:"id"`
-	Username  string   `json:"username"`
-	Email     string   `json:"email"`
-	Password  password `json:"-"`
-	CreatedAt string   `json:"created_at"`
-	IsActive  bool     `json:"is_active"`
-	RoleID    int64    `json:"role_id"`
-	Role      Role     `json:"role"`
+	ID          int64    `json:"id"`
+	Username    string   `json:"username"`
+	Email       string   `json:"email"`
+	Password    password `json:"-"`
+	CreatedAt   string   `json:"created_at"`
+	IsActive    bool     `json:"is_active"`
+	RoleID      int64    `json:"role_id"`
+	Role        Role     `json:"role"`
+	Locale      string   `json:"locale"`
+	DisplayName string   `json:"display_name"`
+	Bio         string   `json:"bio"`
+	AvatarURL   string   `json:"avatar_url"`
+	Website     string   `json:"website"`
+	Location    string   `json:"location"`
+	// DeletionScheduledAt is when the account will be deleted, unless the
+	// user cancels it before then.
+	DeletionScheduledAt *string `json:"deletion_scheduled_at,omitempty"`
+}
+
+// EmailChange is a pending change of a user's email address, which takes
+// effect once they confirm it with the invitation token sent to the new
+// address.
+type EmailChange struct {
+	Email   string
+	Token   string
+	Expiry  time.Duration
+	Message *OutboxMessage
 }
 
 type password struct {
@@ -43,7 +63,7 @@ type UserStore struct {
 }
 
 func (s *UserStore) Create(ctx context.Context, tx *sql.Tx, user *User) error {
-	query := `INSERT INTO users (username, email, password, role_id) VALUES ($1, $2, $3, (SELECT id FROM roles WHERE name = $4)) RETURNING id, created_at`
+	query := `INSERT INTO users (username, email, password, role_id, locale) VALUES ($1, $2, $3, (SELECT id FROM roles WHERE name = $4), $5) RETURNING id, created_at`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
@@ -53,7 +73,11 @@ func (s *UserStore) Create(ctx context.Context, tx *sql.Tx, user *User) error {
 		role = "user"
 	}
 
-	row := tx.QueryRowContext(ctx, query, user.Username, user.Email, user.Password.hash, role)
+	if user.Locale == "" {
+		user.Locale = "en"
+	}
+
+	row := tx.QueryRowContext(ctx, query, user.Username, user.Email, user.Password.hash, role, user.Locale)
 
 	err := row.Scan(&user.ID, &user.CreatedAt)
 	if err != nil {
@@ -70,7 +94,7 @@ func (s *UserStore) Create(ctx context.Context, tx *sql.Tx, user *User) error {
 }
 
 func (s *UserStore) GetByID(ctx context.Context, id int64) (*User, error) {
-	query := `SELECT users.id, username, email, password, created_at, roles.* FROM users JOIN roles ON users.role_id = roles.id WHERE users.id = $1 AND users.is_active = true`
+	query := `SELECT users.id, username, email, password, created_at, locale, display_name, bio, avatar_url, website, location, deletion_scheduled_at, roles.* FROM users JOIN roles ON users.role_id = roles.id WHERE users.id = $1 AND users.is_active = true AND users.suspended_at IS NULL`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
@@ -78,7 +102,7 @@ func (s *UserStore) GetByID(ctx context.Context, id int64) (*User, error) {
 	row := s.db.QueryRowContext(ctx, query, id)
 
 	user := &User{}
-	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.Password.hash, &user.CreatedAt, &user.Role.ID, &user.Role.Name, &user.Role.Level, &user.Role.Description)
+	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.Password.hash, &user.CreatedAt, &user.Locale, &user.DisplayName, &user.Bio, &user.AvatarURL, &user.Website, &user.Location, &user.DeletionScheduledAt, &user.Role.ID, &user.Role.Name, &user.Role.Level, &user.Role.Description)
 	if err != nil {
 		switch err {
 		case sql.ErrNoRows:
@@ -90,26 +114,41 @@ func (s *UserStore) GetByID(ctx context.Context, id int64) (*User, error) {
 	return user, nil
 }
 
-func (s *UserStore) CreateAndInvite(ctx context.Context, user *User, token string, invitationExp time.Duration) error {
+// CreateAndInvite creates the user and its invitation, and enqueues the
+// invitation email in the outbox within the same transaction.
+func (s *UserStore) CreateAndInvite(ctx context.Context, user *User, token string, invitationExp time.Duration, msg *OutboxMessage) error {
 	return withTx(s.db, ctx, func(tx *sql.Tx) error {
 		if err := s.Create(ctx, tx, user); err != nil {
 			return err
 		}
 
-		if err := s.createUserInvitation(ctx, tx, token, invitationExp, user.ID); err != nil {
+		if err := s.createUserInvitation(ctx, tx, token, invitationExp, user.ID, ""); err != nil {
 			return err
 		}
 
+		if msg != nil {
+			if err := enqueueOutboxMessage(ctx, tx, msg); err != nil {
+				return err
+			}
+		}
+
 		return nil
 	})
 }
 
-func (s *UserStore) Activate(ctx context.Context, token string) error {
-	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+// Activate activates the user the invitation token was sent to, and moves
+// them to the new email address when it confirms an email change.
+// Activate activates the user the invitation token was sent to, or confirms
+// their new email, and returns their ID.
+func (s *UserStore) Activate(ctx context.Context, token string) (int64, error) {
+	var id int64
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
 		user, err := s.getUserFromInvitation(ctx, tx, token)
 		if err != nil {
 			return err
 		}
+		id = user.ID
 
 		user.IsActive = true
 
@@ -123,10 +162,15 @@ func (s *UserStore) Activate(ctx context.Context, token string) error {
 
 		return nil
 	})
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
 }
 
 func (s *UserStore) getUserFromInvitation(ctx context.Context, tx *sql.Tx, token string) (*User, error) {
-	query := `SELECT u.id, u.username, u.email, u.created_at, u.is_active FROM users u JOIN user_invitations ui ON u.id = ui.user_id WHERE ui.token = $1 AND ui.expiry > $2`
+	query := `SELECT u.id, u.username, COALESCE(ui.email, u.email), u.created_at, u.is_active FROM users u JOIN user_invitations ui ON u.id = ui.user_id WHERE ui.token = $1 AND ui.expiry > $2`
 
 	hash := sha256.Sum256([]byte(token))
 	hashToken := hex.EncodeToString(hash[:])
@@ -147,13 +191,15 @@ func (s *UserStore) getUserFromInvitation(ctx context.Context, tx *sql.Tx, token
 	return user, nil
 }
 
-func (s *UserStore) createUserInvitation(ctx context.Context, tx *sql.Tx, token string, invitationExp time.Duration, userID int64) error {
-	query := `INSERT INTO user_invitations (token, user_id, expiry) VALUES ($1, $2, $3)`
+// createUserInvitation stores the invitation token, which confirms a change
+// to email when it's set and a new account otherwise.
+func (s *UserStore) createUserInvitation(ctx context.Context, tx *sql.Tx, token string, invitationExp time.Duration, userID int64, email string) error {
+	query := `INSERT INTO user_invitations (token, user_id, expiry, email) VALUES ($1, $2, $3, NULLIF($4::text, ''))`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	_, err := tx.ExecContext(ctx, query, token, userID, time.Now().Add(invitationExp))
+	_, err := tx.ExecContext(ctx, query, token, userID, time.Now().Add(invitationExp), email)
 	if err != nil {
 		return err
 	}
@@ -168,11 +214,77 @@ func (s *UserStore) update(ctx context.Context, tx *sql.Tx, user *User) error {
 
 	_, err := tx.ExecContext(ctx, query, user.Username, user.Email, user.IsActive, user.ID)
 	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Constraint == "users_email_key" {
+			return ErrDuplicateEmail
+		}
 		return err
 	}
 	return nil
 }
 
+// UpdateProfile saves the user's username and public profile. Usernames can
+// only change once per usernameCooldown, and ErrUsernameCooldown is returned
+// when the last change is more recent. A change of email address is stored
+// along with its invitation, and its message enqueued, for the user to
+// confirm it; the address is checked to be free, and ErrDuplicateEmail
+// returned otherwise.
+func (s *UserStore) UpdateProfile(ctx context.Context, user *User, usernameCooldown time.Duration, change *EmailChange) error {
+	query := `
+		UPDATE users SET username = $2, display_name = $3, bio = $4, avatar_url = $5, website = $6, location = $7,
+		username_changed_at = CASE WHEN username = $2 THEN username_changed_at ELSE NOW() END
+		WHERE id = $1
+		AND (username = $2 OR username_changed_at IS NULL OR username_changed_at <= NOW() - make_interval(secs => $8))
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, query, user.ID, user.Username, user.DisplayName, user.Bio, user.AvatarURL, user.Website, user.Location, usernameCooldown.Seconds())
+		if err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Constraint == "users_username_key" {
+				return ErrDuplicateUsername
+			}
+			return err
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrUsernameCooldown
+		}
+
+		if change == nil {
+			return nil
+		}
+
+		var taken bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE email = $1)`, change.Email).Scan(&taken); err != nil {
+			return err
+		}
+		if taken {
+			return ErrDuplicateEmail
+		}
+
+		// Only the latest change can be confirmed
+		if _, err := tx.ExecContext(ctx, `DELETE FROM user_invitations WHERE user_id = $1 AND email IS NOT NULL`, user.ID); err != nil {
+			return err
+		}
+
+		if err := s.createUserInvitation(ctx, tx, change.Token, change.Expiry, user.ID, change.Email); err != nil {
+			return err
+		}
+
+		if change.Message != nil {
+			return enqueueOutboxMessage(ctx, tx, change.Message)
+		}
+
+		return nil
+	})
+}
+
 func (s *UserStore) deleteUserInvitation(ctx context.Context, tx *sql.Tx, userID int64) error {
 	query := `DELETE FROM user_invitations WHERE user_id = $1`
 
@@ -186,9 +298,108 @@ func (s *UserStore) deleteUserInvitation(ctx context.Context, tx *sql.Tx, userID
 	return nil
 }
 
-func (s *UserStore) Delete(ctx context.Context, id int64) error {
-	return withTx(s.db, ctx, func(tx *sql.Tx) error {
-		if err := s.delete(ctx, tx, id); err != nil {
+// ScheduleDeletion schedules the deletion of the user's account once the
+// grace period has passed, and returns when that will be. Asking again keeps
+// the earlier date.
+func (s *UserStore) ScheduleDeletion(ctx context.Context, id int64, grace time.Duration) (string, error) {
+	query := `
+		UPDATE users SET deletion_scheduled_at = COALESCE(deletion_scheduled_at, NOW() + make_interval(secs => $2))
+		WHERE id = $1
+		RETURNING deletion_scheduled_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var scheduledAt string
+	err := s.db.QueryRowContext(ctx, query, id, grace.Seconds()).Scan(&scheduledAt)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return "", ErrNotFound
+		default:
+			return "", err
+		}
+	}
+
+	return scheduledAt, nil
+}
+
+// CancelDeletion keeps the user's account. It returns ErrNotFound when no
+// deletion is scheduled or the grace period is already over.
+func (s *UserStore) CancelDeletion(ctx context.Context, id int64) error {
+	query := `
+		UPDATE users SET deletion_scheduled_at = NULL
+		WHERE id = $1 AND deletion_scheduled_at > NOW()
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// GetDueDeletions returns up to limit users whose grace period is over.
+// Deletions can't be cancelled by then.
+func (s *UserStore) GetDueDeletions(ctx context.Context, limit int) ([]int64, error) {
+	query := `
+		SELECT id FROM users
+		WHERE deletion_scheduled_at <= NOW()
+		ORDER BY deletion_scheduled_at
+		LIMIT $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// Delete removes the user along with their posts, which take the comments,
+// reactions and attachments on them along, and everything else tied to
+// their account. Their comments on other users' posts are kept without an
+// author. It returns the blob keys of their images and data exports, which
+// the caller deletes once the rows pointing at them are gone.
+func (s *UserStore) Delete(ctx context.Context, id int64) ([]string, error) {
+	var keys []string
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		var err error
+		keys, err = s.deleteContent(ctx, tx, id)
+		if err != nil {
 			return err
 		}
 
@@ -196,8 +407,77 @@ func (s *UserStore) Delete(ctx context.Context, id int64) error {
 			return err
 		}
 
-		return nil
+		return s.delete(ctx, tx, id)
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// deleteContent deletes what the user's foreign keys don't cascade to, and
+// returns the blob keys of what goes away.
+func (s *UserStore) deleteContent(ctx context.Context, tx *sql.Tx, id int64) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var email string
+	err := tx.QueryRowContext(ctx, `SELECT email FROM users WHERE id = $1 FOR UPDATE`, id).Scan(&email)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	query := `
+		SELECT key FROM attachments WHERE user_id = $1
+		UNION ALL
+		SELECT thumbnail_key FROM attachments WHERE user_id = $1
+		UNION ALL
+		SELECT key FROM user_exports WHERE user_id = $1 AND key <> ''
+	`
+
+	rows, err := tx.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []string{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	queries := []string{
+		`UPDATE users u SET followers_count = u.followers_count - 1
+		FROM followers f WHERE f.follower_id = $1 AND u.id = f.user_id`,
+		`DELETE FROM posts WHERE user_id = $1`,
+		`DELETE FROM timeline_jobs WHERE user_id = $1 OR (type <> 'fanout' AND target_id = $1)`,
+	}
+
+	for _, query := range queries {
+		if _, err := tx.ExecContext(ctx, query, id); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM email_outbox WHERE email = $1`, email); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
 }
 
 func (s *UserStore) delete(ctx context.Context, tx *sql.Tx, id int64) error {
@@ -214,7 +494,7 @@ func (s *UserStore) delete(ctx context.Context, tx *sql.Tx, id int64) error {
 }
 
 func (s *UserStore) GetByEmail(ctx context.Context, email string) (*User, error) {
-	query := `SELECT id, username, email, password, created_at FROM users WHERE email = $1 AND is_active = true`
+	query := `SELECT id, username, email, password, created_at FROM users WHERE email = $1 AND is_active = true AND suspended_at IS NULL`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
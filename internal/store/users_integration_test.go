@@ -0,0 +1,416 @@
+//go:build integration
+
+package store
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestUserStore(t *testing.T) {
+	invite := func(t *testing.T, db *sql.DB, username, email string, exp time.Duration) (*User, string) {
+		t.Helper()
+
+		user := &User{Username: username, Email: email}
+		if err := user.Password.Set("password"); err != nil {
+			t.Fatal(err)
+		}
+
+		// Handlers store the hash of the token they email.
+		plain := username + "-token"
+		hash := sha256.Sum256([]byte(plain))
+
+		msg := &OutboxMessage{Template: "user_invitation", Username: username, Email: email}
+		if err := (&UserStore{db: db}).CreateAndInvite(testContext(t), user, hex.EncodeToString(hash[:]), exp, msg); err != nil {
+			t.Fatal(err)
+		}
+
+		return user, plain
+	}
+
+	t.Run("should create an inactive user with an invitation and email", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &UserStore{db: db}
+
+		user, _ := invite(t, db, "alice", "alice@example.com", time.Hour)
+
+		if user.ID == 0 || user.CreatedAt == "" {
+			t.Fatalf("expected the id and creation time to be set, got %+v", user)
+		}
+		if user.Locale != "en" {
+			t.Errorf("expected the default locale, got %q", user.Locale)
+		}
+		if n := count(t, db, "user_invitations", "user_id = $1", user.ID); n != 1 {
+			t.Errorf("expected 1 invitation, got %d", n)
+		}
+		if n := count(t, db, "email_outbox", "email = $1 AND template = 'user_invitation'", user.Email); n != 1 {
+			t.Errorf("expected 1 queued email, got %d", n)
+		}
+
+		if _, err := s.GetByID(testContext(t), user.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected inactive users to be hidden, got %v", err)
+		}
+		if _, err := s.GetByEmail(testContext(t), user.Email); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected inactive users to be hidden, got %v", err)
+		}
+	})
+
+	t.Run("should map unique violations", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &UserStore{db: db}
+
+		invite(t, db, "alice", "alice@example.com", time.Hour)
+
+		// Emails are case-insensitive.
+		dup := &User{Username: "alice2", Email: "ALICE@example.com"}
+		if err := s.CreateAndInvite(testContext(t), dup, "t1", time.Hour, nil); !errors.Is(err, ErrDuplicateEmail) {
+			t.Errorf("expected ErrDuplicateEmail, got %v", err)
+		}
+
+		dup = &User{Username: "alice", Email: "other@example.com"}
+		if err := s.CreateAndInvite(testContext(t), dup, "t2", time.Hour, nil); !errors.Is(err, ErrDuplicateUsername) {
+			t.Errorf("expected ErrDuplicateUsername, got %v", err)
+		}
+
+		if n := count(t, db, "users", "true"); n != 1 {
+			t.Errorf("expected failed invitations to be rolled back, got %d users", n)
+		}
+	})
+
+	t.Run("should create users with a role", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &UserStore{db: db}
+		ctx := testContext(t)
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		user := &User{Username: "root", Email: "root@example.com", Role: Role{Name: "admin"}}
+		if err := s.Create(ctx, tx, user); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+
+		if n := count(t, db, "users", "id = $1 AND role_id = (SELECT id FROM roles WHERE name = 'admin')", user.ID); n != 1 {
+			t.Error("expected the user to be an admin")
+		}
+	})
+
+	t.Run("should activate users once", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &UserStore{db: db}
+
+		user, token := invite(t, db, "alice", "alice@example.com", time.Hour)
+
+		if _, err := s.Activate(testContext(t), token); err != nil {
+			t.Fatal(err)
+		}
+		if n := count(t, db, "user_invitations", "user_id = $1", user.ID); n != 0 {
+			t.Errorf("expected the invitation to be deleted, got %d", n)
+		}
+
+		got, err := s.GetByID(testContext(t), user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Username != "alice" || got.Role.Name != "user" || got.Role.Level != 1 {
+			t.Errorf("unexpected user %+v", got)
+		}
+
+		got, err = s.GetByEmail(testContext(t), "alice@example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.ID != user.ID || len(got.Password.hash) == 0 {
+			t.Errorf("unexpected user %+v", got)
+		}
+
+		if _, err := s.Activate(testContext(t), token); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("should not activate expired invitations", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &UserStore{db: db}
+
+		_, token := invite(t, db, "alice", "alice@example.com", -time.Minute)
+
+		if _, err := s.Activate(testContext(t), token); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("should delete users and their invitations", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &UserStore{db: db}
+
+		user, _ := invite(t, db, "alice", "alice@example.com", time.Hour)
+
+		if _, err := s.Delete(testContext(t), user.ID); err != nil {
+			t.Fatal(err)
+		}
+		if n := count(t, db, "users", "id = $1", user.ID); n != 0 {
+			t.Errorf("expected the user to be deleted")
+		}
+		if n := count(t, db, "user_invitations", "user_id = $1", user.ID); n != 0 {
+			t.Errorf("expected the invitation to be deleted")
+		}
+		if n := count(t, db, "email_outbox", "email = $1", user.Email); n != 0 {
+			t.Errorf("expected their emails to be deleted")
+		}
+	})
+
+	t.Run("should delete the user's content and anonymize their comments", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &UserStore{db: db}
+		alice := createTestUser(t, db, "alice")
+		bob := createTestUser(t, db, "bob")
+
+		if err := (&FollowerStore{db: db}).Follow(testContext(t), alice, bob); err != nil {
+			t.Fatal(err)
+		}
+		createTestFollow(t, db, bob, alice)
+
+		post := createTestPost(t, db, alice, "Hello", "world", nil, time.Now())
+		bobsPost := createTestPost(t, db, bob, "Hi", "there", nil, time.Now())
+		createTestComment(t, db, post, bob, "on alice's post")
+		comment := createTestComment(t, db, bobsPost, alice, "on bob's post")
+		createTestReaction(t, db, bobsPost, alice)
+
+		attachment := &Attachment{PostID: post, UserID: alice, Key: "attachments/a.jpg", ThumbnailKey: "attachments/a_thumb.jpg", ContentType: "image/jpeg"}
+		if err := (&AttachmentStore{db: db}).Create(testContext(t), attachment); err != nil {
+			t.Fatal(err)
+		}
+
+		keys, err := s.Delete(testContext(t), alice)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sort.Strings(keys)
+		if len(keys) != 2 || keys[0] != attachment.Key || keys[1] != attachment.ThumbnailKey {
+			t.Errorf("expected the attachment's blob keys, got %v", keys)
+		}
+		if n := count(t, db, "posts", "user_id = $1", alice); n != 0 {
+			t.Errorf("expected the posts to be deleted, got %d", n)
+		}
+		if n := count(t, db, "comments", "post_id = $1", post); n != 0 {
+			t.Errorf("expected the comments on their posts to be deleted, got %d", n)
+		}
+		if n := count(t, db, "comments", "id = $1 AND user_id IS NULL", comment); n != 1 {
+			t.Errorf("expected their comment on bob's post to be anonymized")
+		}
+		if n := count(t, db, "reactions", "user_id = $1", alice); n != 0 {
+			t.Errorf("expected the reactions to be deleted, got %d", n)
+		}
+		if n := count(t, db, "users", "id = $1 AND followers_count = 0", bob); n != 1 {
+			t.Errorf("expected bob to lose a follower")
+		}
+
+		comments, err := (&CommentStore{db: db}).GetByPostID(testContext(t), bobsPost)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(comments) != 1 || comments[0].UserID != 0 || comments[0].User.Username != "" {
+			t.Errorf("expected an anonymous comment, got %+v", comments)
+		}
+
+		if _, err := s.Delete(testContext(t), alice); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("should schedule and cancel deletions", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &UserStore{db: db}
+		alice := createTestUser(t, db, "alice")
+		bob := createTestUser(t, db, "bob")
+
+		scheduledAt, err := s.ScheduleDeletion(testContext(t), alice, 24*time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		again, err := s.ScheduleDeletion(testContext(t), alice, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if again != scheduledAt {
+			t.Errorf("expected the first date to be kept, got %s and %s", scheduledAt, again)
+		}
+
+		user, err := s.GetByID(testContext(t), alice)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user.DeletionScheduledAt == nil || *user.DeletionScheduledAt != scheduledAt {
+			t.Errorf("expected the deletion date on the user, got %v", user.DeletionScheduledAt)
+		}
+
+		due, err := s.GetDueDeletions(testContext(t), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(due) != 0 {
+			t.Errorf("expected no due deletions during the grace period, got %v", due)
+		}
+
+		if err := s.CancelDeletion(testContext(t), alice); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.CancelDeletion(testContext(t), alice); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound without a scheduled deletion, got %v", err)
+		}
+
+		if _, err := s.ScheduleDeletion(testContext(t), bob, -time.Minute); err != nil {
+			t.Fatal(err)
+		}
+
+		due, err = s.GetDueDeletions(testContext(t), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(due) != 1 || due[0] != bob {
+			t.Errorf("expected bob's deletion to be due, got %v", due)
+		}
+
+		if err := s.CancelDeletion(testContext(t), bob); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected due deletions not to be cancelled, got %v", err)
+		}
+	})
+
+	t.Run("should update profiles and rate limit username changes", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &UserStore{db: db}
+		id := createTestUser(t, db, "alice")
+		createTestUser(t, db, "bob")
+
+		user, err := s.GetByID(testContext(t), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		user.DisplayName = "Alice"
+		user.Bio = "Gopher"
+		if err := s.UpdateProfile(testContext(t), user, time.Hour, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		user.Username = "bob"
+		if err := s.UpdateProfile(testContext(t), user, time.Hour, nil); !errors.Is(err, ErrDuplicateUsername) {
+			t.Errorf("expected ErrDuplicateUsername, got %v", err)
+		}
+
+		user.Username = "alicia"
+		if err := s.UpdateProfile(testContext(t), user, time.Hour, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		user.Username = "ally"
+		if err := s.UpdateProfile(testContext(t), user, time.Hour, nil); !errors.Is(err, ErrUsernameCooldown) {
+			t.Errorf("expected ErrUsernameCooldown, got %v", err)
+		}
+		// NOW() is frozen for the whole test transaction.
+		if err := s.UpdateProfile(testContext(t), user, 0, nil); err != nil {
+			t.Errorf("expected the change to be allowed after the cooldown, got %v", err)
+		}
+
+		got, err := s.GetByID(testContext(t), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Username != "ally" || got.DisplayName != "Alice" || got.Bio != "Gopher" {
+			t.Errorf("unexpected profile %+v", got)
+		}
+	})
+
+	t.Run("should change the email once confirmed", func(t *testing.T) {
+		db := newTestDB(t)
+		s := &UserStore{db: db}
+		id := createTestUser(t, db, "alice")
+		createTestUser(t, db, "bob")
+
+		user, err := s.GetByID(testContext(t), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		change := func(email, token string) *EmailChange {
+			hash := sha256.Sum256([]byte(token))
+			return &EmailChange{
+				Email:   email,
+				Token:   hex.EncodeToString(hash[:]),
+				Expiry:  time.Hour,
+				Message: &OutboxMessage{Template: "email_change", Username: user.Username, Email: email},
+			}
+		}
+
+		if err := s.UpdateProfile(testContext(t), user, time.Hour, change("BOB@example.com", "t0")); !errors.Is(err, ErrDuplicateEmail) {
+			t.Errorf("expected ErrDuplicateEmail, got %v", err)
+		}
+
+		if err := s.UpdateProfile(testContext(t), user, time.Hour, change("old@example.com", "t1")); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.UpdateProfile(testContext(t), user, time.Hour, change("new@example.com", "t2")); err != nil {
+			t.Fatal(err)
+		}
+		if n := count(t, db, "email_outbox", "email = $1 AND template = 'email_change'", "new@example.com"); n != 1 {
+			t.Errorf("expected 1 queued email, got %d", n)
+		}
+		if got, _ := s.GetByID(testContext(t), id); got.Email != "alice@example.com" {
+			t.Errorf("expected the email to change only once confirmed, got %s", got.Email)
+		}
+
+		if _, err := s.Activate(testContext(t), "t1"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected earlier changes to be dropped, got %v", err)
+		}
+		activated, err := s.Activate(testContext(t), "t2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if activated != id {
+			t.Errorf("expected user %d to be activated, got %d", id, activated)
+		}
+		if got, _ := s.GetByID(testContext(t), id); got.Email != "new@example.com" {
+			t.Errorf("expected the new email, got %s", got.Email)
+		}
+	})
+
+	t.Run("should return ErrNotFound for unknown users", func(t *testing.T) {
+		s := &UserStore{db: newTestDB(t)}
+
+		if _, err := s.GetByID(testContext(t), -1); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+		if _, err := s.GetByEmail(testContext(t), "nobody@example.com"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+}
+
+func TestRoleStore(t *testing.T) {
+	s := &RoleStore{db: newTestDB(t)}
+
+	role, err := s.GetByName(testContext(t), "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if role.Level != 3 {
+		t.Errorf("expected level 3, got %d", role.Level)
+	}
+
+	if _, err := s.GetByName(testContext(t), "owner"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
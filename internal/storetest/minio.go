@@ -0,0 +1,132 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+const minioImage = "minio/minio:RELEASE.2024-10-13T13-34-11Z"
+
+// MinIO is an S3 compatible server.
+type MinIO struct {
+	// Endpoint is the host and port of the server, without a scheme.
+	Endpoint  string
+	AccessKey string
+	SecretKey string
+	stop      func() error
+}
+
+// StartMinIO connects to TEST_S3_ENDPOINT when set, with the credentials in
+// TEST_S3_ACCESS_KEY and TEST_S3_SECRET_KEY, and otherwise starts a server
+// from the local minio binary or from a container. It returns ErrUnavailable
+// when none of them is an option.
+func StartMinIO() (*MinIO, error) {
+	if endpoint := os.Getenv("TEST_S3_ENDPOINT"); endpoint != "" {
+		m := &MinIO{
+			Endpoint:  endpoint,
+			AccessKey: os.Getenv("TEST_S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("TEST_S3_SECRET_KEY"),
+		}
+		return m, pingMinIO(endpoint)
+	}
+
+	if m, err := startLocalMinIO(); err != ErrUnavailable {
+		return m, err
+	}
+
+	return startMinIOContainer()
+}
+
+// Stop shuts the server down, unless it came from TEST_S3_ENDPOINT.
+func (m *MinIO) Stop() error {
+	if m.stop == nil {
+		return nil
+	}
+	return m.stop()
+}
+
+const (
+	minioUser     = "minioadmin"
+	minioPassword = "minioadmin"
+)
+
+func startLocalMinIO() (*MinIO, error) {
+	bin, err := exec.LookPath("minio")
+	if err != nil {
+		return nil, ErrUnavailable
+	}
+
+	port, err := freePort()
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "storetest-minio-")
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := "127.0.0.1:" + strconv.Itoa(port)
+
+	cmd := exec.Command(bin, "server", dir, "--address", endpoint, "--quiet")
+	cmd.Env = append(os.Environ(), "MINIO_ROOT_USER="+minioUser, "MINIO_ROOT_PASSWORD="+minioPassword)
+	if err := cmd.Start(); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	stop := func() error {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return os.RemoveAll(dir)
+	}
+
+	if err := pingMinIO(endpoint); err != nil {
+		stop()
+		return nil, err
+	}
+
+	return &MinIO{Endpoint: endpoint, AccessKey: minioUser, SecretKey: minioPassword, stop: stop}, nil
+}
+
+func startMinIOContainer() (*MinIO, error) {
+	env := []string{"MINIO_ROOT_USER=" + minioUser, "MINIO_ROOT_PASSWORD=" + minioPassword}
+	id, port, err := runContainer(minioImage, "9000", env, "server", "/data")
+	if err != nil {
+		return nil, err
+	}
+
+	stop := func() error { return stopContainer(id) }
+
+	endpoint := "127.0.0.1:" + port
+	if err := pingMinIO(endpoint); err != nil {
+		stop()
+		return nil, err
+	}
+
+	return &MinIO{Endpoint: endpoint, AccessKey: minioUser, SecretKey: minioPassword, stop: stop}, nil
+}
+
+func pingMinIO(endpoint string) error {
+	return waitFor(startTimeout, func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+endpoint+"/minio/health/ready", nil)
+		if err != nil {
+			return err
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return errors.New(resp.Status)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,120 @@
+package storetest
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strconv"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const redisImage = "redis:7.4.1-alpine"
+
+// Redis is a server tests get clients for.
+type Redis struct {
+	Addr string
+	stop func() error
+}
+
+// StartRedis connects to TEST_REDIS_ADDR when set, and otherwise starts a
+// server from the local redis-server binary or from a container. It returns
+// ErrUnavailable when none of them is an option.
+//
+// Tests flush the server's database, so TEST_REDIS_ADDR must not point at one
+// holding data anybody cares about.
+func StartRedis() (*Redis, error) {
+	if addr := os.Getenv("TEST_REDIS_ADDR"); addr != "" {
+		return &Redis{Addr: addr}, pingRedis(addr)
+	}
+
+	if r, err := startLocalRedis(); err != ErrUnavailable {
+		return r, err
+	}
+
+	return startRedisContainer()
+}
+
+// Stop shuts the server down, unless it came from TEST_REDIS_ADDR.
+func (r *Redis) Stop() error {
+	if r.stop == nil {
+		return nil
+	}
+	return r.stop()
+}
+
+// Client returns a client on an empty database. It is flushed again and
+// closed when the test ends.
+func (r *Redis) Client(t testing.TB) *redis.Client {
+	t.Helper()
+
+	rdb := redis.NewClient(&redis.Options{Addr: r.Addr})
+	if err := rdb.FlushDB(context.Background()).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		rdb.FlushDB(context.Background())
+		rdb.Close()
+	})
+
+	return rdb
+}
+
+func startLocalRedis() (*Redis, error) {
+	bin, err := exec.LookPath("redis-server")
+	if err != nil {
+		return nil, ErrUnavailable
+	}
+
+	port, err := freePort()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(bin, "--port", strconv.Itoa(port), "--bind", "127.0.0.1", "--save", "", "--appendonly", "no")
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	stop := func() error {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil
+	}
+
+	addr := "127.0.0.1:" + strconv.Itoa(port)
+	if err := pingRedis(addr); err != nil {
+		stop()
+		return nil, err
+	}
+
+	return &Redis{Addr: addr, stop: stop}, nil
+}
+
+func startRedisContainer() (*Redis, error) {
+	id, port, err := runContainer(redisImage, "6379", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	stop := func() error { return stopContainer(id) }
+
+	addr := "127.0.0.1:" + port
+	if err := pingRedis(addr); err != nil {
+		stop()
+		return nil, err
+	}
+
+	return &Redis{Addr: addr, stop: stop}, nil
+}
+
+func pingRedis(addr string) error {
+	rdb := redis.NewClient(&redis.Options{Addr: addr})
+	defer rdb.Close()
+
+	return waitFor(startTimeout, func(ctx context.Context) error {
+		return rdb.Ping(ctx).Err()
+	})
+}
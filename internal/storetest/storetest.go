@@ -0,0 +1,97 @@
+// Package storetest provides disposable Postgres, Redis and S3 compatible
+// instances for integration tests.
+//
+// Instances are found in this order: an address from the environment
+// (TEST_DB_ADDR, TEST_REDIS_ADDR, TEST_S3_ENDPOINT), a server started from
+// local binaries, and finally a Docker container. Tests that need a database
+// should only be built with the integration build tag.
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ErrUnavailable is returned when no instance could be found or started.
+var ErrUnavailable = errors.New("storetest: no instance available")
+
+// startTimeout is how long a server gets to accept connections.
+const startTimeout = 30 * time.Second
+
+// freePort asks the kernel for a port nobody is listening on.
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// runContainer starts a throwaway container publishing port and returns its
+// ID and the host port it was mapped to. cmd overrides the image's command
+// when given.
+func runContainer(image, port string, env []string, cmd ...string) (string, string, error) {
+	if _, err := exec.LookPath("docker"); err != nil {
+		return "", "", ErrUnavailable
+	}
+
+	args := []string{"run", "-d", "--rm", "-p", "127.0.0.1::" + port}
+	for _, e := range env {
+		args = append(args, "-e", e)
+	}
+	args = append(args, image)
+	args = append(args, cmd...)
+
+	out, err := exec.Command("docker", args...).Output()
+	if err != nil {
+		return "", "", fmt.Errorf("docker run %s: %w", image, err)
+	}
+	id := strings.TrimSpace(string(out))
+
+	out, err = exec.Command("docker", "port", id, port).Output()
+	if err != nil {
+		stopContainer(id)
+		return "", "", fmt.Errorf("docker port: %w", err)
+	}
+
+	// The first line looks like 127.0.0.1:49153.
+	line := strings.SplitN(strings.TrimSpace(string(out)), "\n", 2)[0]
+	_, hostPort, err := net.SplitHostPort(line)
+	if err != nil {
+		stopContainer(id)
+		return "", "", err
+	}
+
+	return id, hostPort, nil
+}
+
+func stopContainer(id string) error {
+	return exec.Command("docker", "stop", id).Run()
+}
+
+// waitFor retries ping until it succeeds or timeout elapses.
+func waitFor(timeout time.Duration, ping func(context.Context) error) error {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		err := ping(ctx)
+		cancel()
+
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return err
+		}
+
+		time.Sleep(200 * time.Millisecond)
+	}
+}
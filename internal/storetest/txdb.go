@@ -0,0 +1,160 @@
+package storetest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+// TxDB returns a handle to the database at addr whose work all happens in a
+// single transaction, rolled back when the test ends, so tests can share a
+// database without seeing each other's rows.
+//
+// Transactions begun on the handle become savepoints, and so does every
+// statement run outside of one, which lets a test assert on a failed
+// statement and keep going.
+func TxDB(t testing.TB, addr string) *sql.DB {
+	t.Helper()
+
+	connector, err := pq.NewConnector(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := sql.OpenDB(&txConnector{Connector: connector})
+	// All statements must run on the connection holding the transaction.
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	return db
+}
+
+type txConnector struct {
+	*pq.Connector
+}
+
+func (c *txConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tc := &txConn{conn: conn}
+	if err := tc.exec(ctx, "BEGIN"); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return tc, nil
+}
+
+type txConn struct {
+	conn driver.Conn
+	// depth is the number of savepoints standing in for open transactions.
+	depth int
+	seq   int
+}
+
+func (c *txConn) exec(ctx context.Context, query string) error {
+	_, err := c.conn.(driver.ExecerContext).ExecContext(ctx, query, nil)
+	return err
+}
+
+func (c *txConn) Prepare(query string) (driver.Stmt, error) {
+	return c.conn.Prepare(query)
+}
+
+func (c *txConn) Close() error {
+	err := c.exec(context.Background(), "ROLLBACK")
+	if closeErr := c.conn.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (c *txConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *txConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	c.seq++
+	name := fmt.Sprintf("tx_%d", c.seq)
+
+	if err := c.exec(ctx, "SAVEPOINT "+name); err != nil {
+		return nil, err
+	}
+	c.depth++
+
+	return &txSavepoint{conn: c, name: name}, nil
+}
+
+func (c *txConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if c.depth > 0 {
+		return c.conn.(driver.ExecerContext).ExecContext(ctx, query, args)
+	}
+
+	if err := c.exec(ctx, "SAVEPOINT stmt"); err != nil {
+		return nil, err
+	}
+
+	result, err := c.conn.(driver.ExecerContext).ExecContext(ctx, query, args)
+	if err != nil {
+		return nil, c.rollbackStatement(err)
+	}
+
+	return result, c.exec(ctx, "RELEASE SAVEPOINT stmt")
+}
+
+// QueryContext can't release its savepoint while the rows are being read, so
+// it leaves it in place. Later statements reuse the name, which Postgres
+// resolves to the most recent savepoint.
+func (c *txConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if c.depth > 0 {
+		return c.conn.(driver.QueryerContext).QueryContext(ctx, query, args)
+	}
+
+	if err := c.exec(ctx, "SAVEPOINT stmt"); err != nil {
+		return nil, err
+	}
+
+	rows, err := c.conn.(driver.QueryerContext).QueryContext(ctx, query, args)
+	if err != nil {
+		return nil, c.rollbackStatement(err)
+	}
+
+	return rows, nil
+}
+
+func (c *txConn) rollbackStatement(err error) error {
+	// The statement's context may be what failed, so don't reuse it.
+	if rbErr := c.exec(context.Background(), "ROLLBACK TO SAVEPOINT stmt"); rbErr != nil {
+		return fmt.Errorf("%w (rollback: %v)", err, rbErr)
+	}
+	return err
+}
+
+type txSavepoint struct {
+	conn *txConn
+	name string
+}
+
+func (tx *txSavepoint) Commit() error {
+	tx.conn.depth--
+	return tx.conn.exec(context.Background(), "RELEASE SAVEPOINT "+tx.name)
+}
+
+func (tx *txSavepoint) Rollback() error {
+	tx.conn.depth--
+	if err := tx.conn.exec(context.Background(), "ROLLBACK TO SAVEPOINT "+tx.name); err != nil {
+		return err
+	}
+	return tx.conn.exec(context.Background(), "RELEASE SAVEPOINT "+tx.name)
+}
@@ -0,0 +1,128 @@
+package timeline
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kuluruvineeth/social-go/internal/outbox"
+	"github.com/kuluruvineeth/social-go/internal/store"
+	"go.uber.org/zap"
+)
+
+type Store interface {
+	ClaimJobs(context.Context, int, time.Duration) ([]store.TimelineJob, error)
+	CompleteJob(context.Context, int64) error
+	FailJob(context.Context, int64, string, time.Time, bool) error
+	FanOut(context.Context, int64, int) (bool, error)
+	Backfill(context.Context, int64, int64, int) error
+	Remove(context.Context, int64, int64) error
+}
+
+type Config struct {
+	PollInterval time.Duration
+	BatchSize    int
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	Lease        time.Duration
+	// FanoutThreshold is the follower count above which an author's posts are
+	// no longer copied into every follower's timeline but merged in on read.
+	FanoutThreshold int
+	// BackfillLimit is how many of a user's latest posts are copied into a
+	// new follower's timeline.
+	BackfillLimit int
+}
+
+// Worker materializes home timelines: it fans new posts out to followers'
+// timelines and backfills or trims timelines when users follow or unfollow
+// each other.
+type Worker struct {
+	store  Store
+	logger *zap.SugaredLogger
+	cfg    Config
+	now    func() time.Time
+}
+
+func NewWorker(store Store, logger *zap.SugaredLogger, cfg Config) *Worker {
+	return &Worker{
+		store:  store,
+		logger: logger,
+		cfg:    cfg,
+		now:    time.Now,
+	}
+}
+
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+
+	w.logger.Infow("timeline worker has started", "poll_interval", w.cfg.PollInterval.String(), "fanout_threshold", w.cfg.FanoutThreshold)
+
+	for {
+		if _, err := w.ProcessBatch(ctx); err != nil {
+			w.logger.Errorw("failed to process timeline jobs", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			w.logger.Info("timeline worker has stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessBatch claims and runs a single batch of due jobs and returns how many
+// were claimed.
+func (w *Worker) ProcessBatch(ctx context.Context) (int, error) {
+	jobs, err := w.store.ClaimJobs(ctx, w.cfg.BatchSize, w.cfg.Lease)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, job := range jobs {
+		if err := w.run(ctx, job); err != nil {
+			w.fail(ctx, job, err)
+			continue
+		}
+
+		if err := w.store.CompleteJob(ctx, job.ID); err != nil {
+			w.logger.Errorw("failed to complete timeline job", "id", job.ID, "error", err)
+		}
+	}
+
+	return len(jobs), nil
+}
+
+func (w *Worker) run(ctx context.Context, job store.TimelineJob) error {
+	switch job.Type {
+	case store.TimelineJobFanout:
+		fannedOut, err := w.store.FanOut(ctx, job.TargetID, w.cfg.FanoutThreshold)
+		if err == nil && !fannedOut {
+			w.logger.Debugw("post left for fan-out on read", "post_id", job.TargetID, "author_id", job.UserID)
+		}
+		return err
+	case store.TimelineJobFollow:
+		return w.store.Backfill(ctx, job.UserID, job.TargetID, w.cfg.BackfillLimit)
+	case store.TimelineJobUnfollow:
+		return w.store.Remove(ctx, job.UserID, job.TargetID)
+	default:
+		return fmt.Errorf("unknown timeline job type %q", job.Type)
+	}
+}
+
+func (w *Worker) fail(ctx context.Context, job store.TimelineJob, jobErr error) {
+	dead := job.Attempts >= w.cfg.MaxAttempts
+	next := w.now().Add(outbox.Backoff(job.Attempts, w.cfg.BaseBackoff, w.cfg.MaxBackoff))
+
+	if dead {
+		w.logger.Errorw("timeline job dead-lettered", "id", job.ID, "type", job.Type, "attempts", job.Attempts, "error", jobErr)
+	} else {
+		w.logger.Warnw("timeline job failed", "id", job.ID, "type", job.Type, "attempts", job.Attempts, "next_attempt_at", next, "error", jobErr)
+	}
+
+	if err := w.store.FailJob(ctx, job.ID, jobErr.Error(), next, dead); err != nil {
+		w.logger.Errorw("failed to mark timeline job as failed", "id", job.ID, "error", err)
+	}
+}
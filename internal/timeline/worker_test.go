@@ -0,0 +1,124 @@
+package timeline
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kuluruvineeth/social-go/internal/store"
+	"go.uber.org/zap"
+)
+
+type fakeStore struct {
+	due       []store.TimelineJob
+	completed []int64
+	failed    map[int64]bool
+	calls     []string
+	err       error
+}
+
+func (s *fakeStore) ClaimJobs(ctx context.Context, limit int, lease time.Duration) ([]store.TimelineJob, error) {
+	jobs := s.due
+	s.due = nil
+	return jobs, nil
+}
+
+func (s *fakeStore) CompleteJob(ctx context.Context, id int64) error {
+	s.completed = append(s.completed, id)
+	return nil
+}
+
+func (s *fakeStore) FailJob(ctx context.Context, id int64, lastErr string, next time.Time, dead bool) error {
+	s.failed[id] = dead
+	return nil
+}
+
+func (s *fakeStore) FanOut(ctx context.Context, postID int64, threshold int) (bool, error) {
+	s.calls = append(s.calls, "fanout")
+	return threshold > 0, s.err
+}
+
+func (s *fakeStore) Backfill(ctx context.Context, followerID, authorID int64, limit int) error {
+	s.calls = append(s.calls, "backfill")
+	return s.err
+}
+
+func (s *fakeStore) Remove(ctx context.Context, followerID, authorID int64) error {
+	s.calls = append(s.calls, "remove")
+	return s.err
+}
+
+func newTestWorker(s *fakeStore) *Worker {
+	return NewWorker(s, zap.NewNop().Sugar(), Config{
+		BatchSize:       10,
+		MaxAttempts:     3,
+		BaseBackoff:     time.Second,
+		MaxBackoff:      time.Minute,
+		Lease:           time.Minute,
+		FanoutThreshold: 1000,
+		BackfillLimit:   20,
+	})
+}
+
+func TestWorkerProcessBatch(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should dispatch jobs by type and complete them", func(t *testing.T) {
+		s := &fakeStore{failed: map[int64]bool{}, due: []store.TimelineJob{
+			{ID: 1, Type: store.TimelineJobFanout, UserID: 1, TargetID: 10, Attempts: 1},
+			{ID: 2, Type: store.TimelineJobFollow, UserID: 2, TargetID: 1, Attempts: 1},
+			{ID: 3, Type: store.TimelineJobUnfollow, UserID: 2, TargetID: 1, Attempts: 1},
+		}}
+
+		n, err := newTestWorker(s).ProcessBatch(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if n != 3 || len(s.completed) != 3 {
+			t.Fatalf("expected 3 completed jobs, got %v", s.completed)
+		}
+
+		want := []string{"fanout", "backfill", "remove"}
+		for i, call := range want {
+			if s.calls[i] != call {
+				t.Errorf("expected calls %v, got %v", want, s.calls)
+				break
+			}
+		}
+	})
+
+	t.Run("should reschedule failures until the attempt limit", func(t *testing.T) {
+		s := &fakeStore{failed: map[int64]bool{}, err: errors.New("db down"), due: []store.TimelineJob{
+			{ID: 1, Type: store.TimelineJobFanout, Attempts: 1},
+			{ID: 2, Type: store.TimelineJobFollow, Attempts: 3},
+		}}
+
+		if _, err := newTestWorker(s).ProcessBatch(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(s.completed) != 0 {
+			t.Errorf("expected no completed jobs, got %v", s.completed)
+		}
+		if dead, ok := s.failed[1]; !ok || dead {
+			t.Errorf("expected job 1 to be retried")
+		}
+		if !s.failed[2] {
+			t.Errorf("expected job 2 to be dead-lettered")
+		}
+	})
+
+	t.Run("should dead-letter unknown job types", func(t *testing.T) {
+		s := &fakeStore{failed: map[int64]bool{}, due: []store.TimelineJob{{ID: 1, Type: "repost", Attempts: 3}}}
+
+		if _, err := newTestWorker(s).ProcessBatch(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		if !s.failed[1] {
+			t.Errorf("expected job 1 to be dead-lettered")
+		}
+	})
+}
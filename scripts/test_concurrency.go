@@ -5,19 +5,41 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
 	"sync"
 )
 
+// UpdatePostPayload is a JSON Merge Patch, fields left nil aren't sent and
+// stay as they are.
 type UpdatePostPayload struct {
-	Title   *string `json:"title" `
-	Content *string `json:"content"`
+	Title   *string `json:"title,omitempty"`
+	Content *string `json:"content,omitempty"`
 }
 
-func updatePost(postID int, p UpdatePostPayload, wg *sync.WaitGroup) {
-	defer wg.Done()
+// fetchETag returns the ETag of the post, which both updates send as
+// If-Match so that the second one fails instead of overwriting the first.
+func fetchETag(url, token string) (string, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
 
-	// Construct the URL for the update endpoint
-	url := fmt.Sprintf("http://localhost:8080/v1/posts/%d", postID)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetching the post: %s", resp.Status)
+	}
+
+	return resp.Header.Get("ETag"), nil
+}
+
+func updatePost(url, token, etag string, p UpdatePostPayload, wg *sync.WaitGroup) {
+	defer wg.Done()
 
 	// Create the JSON payload
 	b, _ := json.Marshal(p)
@@ -28,33 +50,43 @@ func updatePost(postID int, p UpdatePostPayload, wg *sync.WaitGroup) {
 		return
 	}
 
-	// Set headers as needed, for example:
-	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("If-Match", etag)
 
 	// Send the request
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		fmt.Println("Error sending request:", err)
 		return
 	}
 	defer resp.Body.Close()
 
+	// One update succeeds, the other gets 412 Precondition Failed, or 409
+	// Conflict when both read the post before either saved it.
 	fmt.Println("Update response status:", resp.Status)
 }
 
 func main() {
 	var wg sync.WaitGroup
 
-	// Assuming the post ID to update is 1
+	// Assuming the post ID to update is 13, owned by the user of TOKEN
 	postID := 13
+	url := fmt.Sprintf("http://localhost:8080/v1/posts/%d", postID)
+	token := os.Getenv("TOKEN")
+
+	etag, err := fetchETag(url, token)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
 
 	// Simulate User A and User B updating the same post concurrently
 	wg.Add(2)
 	content := "NEW CONTENT FROM USER B"
 	title := "NEW TITLE FROM USER A"
 
-	go updatePost(postID, UpdatePostPayload{Title: &title}, &wg)
-	go updatePost(postID, UpdatePostPayload{Content: &content}, &wg)
+	go updatePost(url, token, etag, UpdatePostPayload{Title: &title}, &wg)
+	go updatePost(url, token, etag, UpdatePostPayload{Content: &content}, &wg)
 	wg.Wait()
 }